@@ -0,0 +1,30 @@
+// Package all bundles the analyzers of pkgrank, for drivers that run several
+// of them at once, such as multichecker and go vet -vettool, see
+// cmd/pkgrank-vet.
+package all
+
+import (
+	"github.com/arclabs561/pkgrank/analyzers/callgraph"
+	"github.com/arclabs561/pkgrank/analyzers/cycles"
+	"github.com/arclabs561/pkgrank/analyzers/depgraph"
+	"github.com/arclabs561/pkgrank/analyzers/layers"
+	"github.com/arclabs561/pkgrank/analyzers/modver"
+	"github.com/arclabs561/pkgrank/analyzers/typedeps"
+	"github.com/arclabs561/pkgrank/analyzers/unusedpkg"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzers returns the analyzers of pkgrank. Drivers run each once per
+// package, so the facts of those that others require, such as modver's for
+// depgraph, flow to them rather than being computed again.
+func Analyzers() []*analysis.Analyzer {
+	return []*analysis.Analyzer{
+		modver.Analyzer,
+		depgraph.Analyzer,
+		typedeps.Analyzer,
+		callgraph.Analyzer,
+		layers.Analyzer,
+		cycles.Analyzer,
+		unusedpkg.Analyzer,
+	}
+}
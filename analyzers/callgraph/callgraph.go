@@ -0,0 +1,155 @@
+// Package callgraph defines an Analyzer that constructs a graph of calls
+// between functions and methods (e.g. pkg.A calls pkg.B).
+package callgraph
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/arclabs561/pkgrank/shared"
+	"github.com/rs/zerolog"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/static"
+)
+
+// Analyzer builds the call graph of each package. Its result is a *Result,
+// so that other analyzers may require it.
+//
+// Each pass only sees the SSA program of its package and dependencies, so
+// the cha algorithm resolves a dynamic call to the methods of those
+// packages alone: calls through an interface to the methods of a type
+// declared in an importing package are missing from the graph.
+var Analyzer = &analysis.Analyzer{
+	Name: "callgraph",
+	Doc: "construct a graph of calls between functions\n\n" +
+		"With -algo=cha, dynamic calls resolve to the methods of the package " +
+		"and its dependencies only, missing those of the packages importing it.",
+	FactTypes:        []analysis.Fact{(*callsFact)(nil)},
+	Run:              run,
+	RunDespiteErrors: true,
+	Requires:         []*analysis.Analyzer{buildssa.Analyzer},
+	ResultType:       reflect.TypeOf((*Result)(nil)),
+}
+
+// Algorithm is a call graph construction algorithm.
+type Algorithm string
+
+// Available call graph construction algorithms.
+const (
+	// AlgorithmStatic only includes calls to statically known callees.
+	AlgorithmStatic Algorithm = "static"
+	// AlgorithmCHA additionally resolves dynamic calls by class hierarchy
+	// analysis, i.e. to every method of a matching signature in the package
+	// or its dependencies.
+	AlgorithmCHA Algorithm = "cha"
+)
+
+var (
+	algo     string
+	rootPkg  string
+	output   string
+	graphOut string
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&algo, "algo", string(AlgorithmCHA),
+		"call graph algorithm: static or cha")
+	Analyzer.Flags.StringVar(&rootPkg, "root", "",
+		"package whose transitive call graph is written")
+	Analyzer.Flags.StringVar(&output, "output", string(graph.FormatEdgeList),
+		"format of the root package's graph: edgelist, json, dot or html")
+	Analyzer.Flags.StringVar(&graphOut, "graph-out", "",
+		"file to write the root package's graph to, stdout if empty")
+}
+
+// callsFact holds the calls made by the functions of a single package.
+type callsFact struct {
+	graph.Graph
+}
+
+func (f callsFact) AFact() {}
+
+// Result is the call graph of a package.
+type Result struct {
+	// Graph holds the calls made by the functions of the package.
+	Graph *graph.Graph
+	// deps holds the call graphs of every transitive dependency, shared
+	// with their package facts.
+	deps []*graph.Graph
+}
+
+// Transitive returns a new graph merging the call graphs of the package and
+// all of its transitive dependencies.
+func (r *Result) Transitive() (*graph.Graph, error) {
+	return graph.Merge(r.Graph.Container, append([]*graph.Graph{r.Graph}, r.deps...)...)
+}
+
+// Logger is the logger of the analyzer's passes, see shared.Logger for its
+// default.
+var Logger *zerolog.Logger
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	log := shared.Logger(Logger).With().Str("pkg", pass.Pkg.Path()).Str("algo", algo).Logger()
+	format, err := graph.ParseFormat(output)
+	if err != nil {
+		return nil, err
+	}
+	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+	var cg *callgraph.Graph
+	switch Algorithm(algo) {
+	case AlgorithmStatic:
+		cg = static.CallGraph(ssainput.Pkg.Prog)
+	case AlgorithmCHA:
+		cg = cha.CallGraph(ssainput.Pkg.Prog)
+	default:
+		return nil, fmt.Errorf("unsupported call graph algorithm: %q", algo)
+	}
+	res := &Result{}
+	for _, pf := range pass.AllPackageFacts() {
+		if f, ok := pf.Fact.(*callsFact); ok && pf.Package != pass.Pkg {
+			res.deps = append(res.deps, &f.Graph)
+		}
+	}
+	f := callsFact{Graph: graph.Graph{
+		Container:       pass.Pkg.Path(),
+		AddedContainers: map[string]struct{}{pass.Pkg.Path(): {}},
+	}}
+	f.Graph.SetLogger(&log)
+	// The program also holds the (bodiless) functions of every
+	// dependency, so only calls made from this package are kept. Every call
+	// site adds one to the weight of its edge.
+	err = callgraph.GraphVisitEdges(cg, func(e *callgraph.Edge) error {
+		if e.Caller.Func.Pkg != ssainput.Pkg {
+			return nil
+		}
+		_, err := f.Graph.AddEdge(graph.NewDirectedEdge(
+			pass.Pkg.Path(), e.Caller.Func.String(), e.Callee.Func.String()))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	pass.ExportPackageFact(&f)
+	log.Info().Int("graphOrder", f.Graph.Order()).
+		Int("graphSize", f.Graph.Size()).
+		Msg("exported package fact")
+	res.Graph = &f.Graph
+	if pass.Pkg.Path() == rootPkg {
+		g, err := res.Transitive()
+		if err != nil {
+			return nil, err
+		}
+		log.Info().Int("graphOrder", g.Order()).
+			Int("graphSize", g.Size()).
+			Str("output", output).
+			Msg("writing graph")
+		if err := graph.WriteFile(graphOut, g, format); err != nil {
+			return nil, fmt.Errorf("failed to write graph: %w", err)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,46 @@
+package callgraph
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestInterfaceCall(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "iface"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := algo
+	t.Cleanup(func() { algo = prev })
+	static := graph.NewDirectedEdge("example.com/iface/a", "example.com/iface/a.G", "example.com/iface/a.F").Key()
+	dynamic := graph.NewDirectedEdge("example.com/iface/a", "example.com/iface/a.F", "(example.com/iface/a.T).M").Key()
+	for _, tt := range []struct {
+		algo Algorithm
+		want bool
+	}{
+		{AlgorithmStatic, false},
+		{AlgorithmCHA, true},
+	} {
+		t.Run(string(tt.algo), func(t *testing.T) {
+			algo = string(tt.algo)
+			results := analysistest.Run(t, dir, Analyzer, "example.com/iface/a")
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			res, ok := results[0].Result.(*Result)
+			if !ok {
+				t.Fatalf("got result %T, want *Result", results[0].Result)
+			}
+			if _, ok := res.Graph.Edges[static]; !ok {
+				t.Error("no edge from G to F")
+			}
+			// The call of I.M resolves to T.M by class hierarchy analysis.
+			if _, ok := res.Graph.Edges[dynamic]; ok != tt.want {
+				t.Errorf("got edge from F to T.M %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
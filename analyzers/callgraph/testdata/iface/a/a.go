@@ -0,0 +1,15 @@
+package a // want package:"."
+
+type I interface{ M() }
+
+type T struct{}
+
+func (T) M() {}
+
+func F(i I) {
+	i.M()
+}
+
+func G() {
+	F(T{})
+}
@@ -0,0 +1,81 @@
+// Package cycles defines an Analyzer that reports imports closing a cycle
+// between modules (e.g. module A imports module B, which imports module A).
+// Go rejects import cycles between packages but not between modules, whose
+// cycles tie their versions and releases together.
+package cycles
+
+import (
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/analyzers/modver"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer reports the imports of each package that close a cycle between
+// its module and the module of the imported package.
+var Analyzer = &analysis.Analyzer{
+	Name:             "cycles",
+	Doc:              "reports imports that close an import cycle between modules",
+	FactTypes:        []analysis.Fact{(*reachFact)(nil)},
+	Run:              run,
+	RunDespiteErrors: true,
+	Requires:         []*analysis.Analyzer{modver.Analyzer},
+}
+
+// reachFact holds the modules that a package imports transitively, each with
+// the chain of modules through which it does, starting with the package's
+// own module.
+type reachFact struct {
+	Modules map[string][]string
+}
+
+func (f reachFact) AFact() {}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	mv, _ := pass.ResultOf[modver.Analyzer].(*modver.ModVerFact)
+	if mv == nil {
+		return nil, nil
+	}
+	module := mv.Path
+	f := reachFact{Modules: map[string][]string{module: {module}}}
+	imports := make(map[string]*types.Package)
+	for _, imp := range pass.Pkg.Imports() {
+		imports[imp.Path()] = imp
+	}
+	reported := make(map[string]bool)
+	for _, file := range pass.Files {
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || imports[path] == nil {
+				continue
+			}
+			var dep reachFact
+			if !pass.ImportPackageFact(imports[path], &dep) {
+				continue
+			}
+			for m, chain := range dep.Modules {
+				switch {
+				case chain[0] == module:
+					// Imported from the package's own module.
+					if _, ok := f.Modules[m]; !ok {
+						f.Modules[m] = chain
+					}
+				case m == module:
+					if !reported[path] {
+						reported[path] = true
+						pass.Reportf(spec.Pos(), "import of %s closes an import cycle between modules %s",
+							path, strings.Join(append([]string{module}, chain...), " -> "))
+					}
+				default:
+					if _, ok := f.Modules[m]; !ok {
+						f.Modules[m] = append([]string{module}, chain...)
+					}
+				}
+			}
+		}
+	}
+	pass.ExportPackageFact(&f)
+	return nil, nil
+}
@@ -0,0 +1,19 @@
+package cycles_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/arclabs561/pkgrank/analyzers/cycles"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestCycle(t *testing.T) {
+	// Module a imports module b, which imports module a back. Imports
+	// within module a close no cycle.
+	dir, err := filepath.Abs(filepath.Join("testdata", "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, dir, cycles.Analyzer, "example.com/a/...")
+}
@@ -0,0 +1,6 @@
+package a // want package:`example.com/b:\[example.com/a example.com/b\]`
+
+import (
+	_ "example.com/a/c"
+	_ "example.com/b/b" // want "import of example.com/b/b closes an import cycle between modules example.com/a -> example.com/b -> example.com/a"
+)
@@ -0,0 +1 @@
+package c // want package:`example.com/a:\[example.com/a\]`
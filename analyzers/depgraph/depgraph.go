@@ -3,44 +3,271 @@
 package depgraph
 
 import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
 	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/arclabs561/pkgrank/analyzers/modver"
+	"github.com/arclabs561/pkgrank/cache"
 	"github.com/arclabs561/pkgrank/graph"
-	"github.com/rs/zerolog/log"
+	"github.com/arclabs561/pkgrank/shared"
+	"github.com/rs/zerolog"
 	"golang.org/x/tools/go/analysis"
 )
 
+// Analyzer builds the dependency graph of each package. Its result is a
+// *Result, so that other analyzers may require it. It writes nothing unless
+// its flags ask for it, e.g. -root for the graph of a package, so that it
+// runs under any driver, such as unitchecker for go vet or gopls.
 var Analyzer = &analysis.Analyzer{
 	Name:             "depgraph",
 	Doc:              "construct a graph of dependencies between containers",
 	FactTypes:        []analysis.Fact{(*graphFact)(nil)},
 	Run:              run,
 	RunDespiteErrors: true,
+	Requires:         []*analysis.Analyzer{modver.Analyzer},
+	ResultType:       reflect.TypeOf((*Result)(nil)),
 }
 
+// graphFact holds only the direct dependency edges of a package. The
+// transitive graph is merged from the facts of every dependency on demand,
+// which keeps each fact's size proportional to its package's imports.
 type graphFact struct {
 	graph.Graph
+	// Key is the cache key of the graph, which depends on the keys of the
+	// package's dependencies, so that a change invalidates its importers.
+	Key cache.Key
 }
 
 func (f graphFact) AFact() {}
 
-var rootPkg = os.Getenv("DEPGRAPH_ROOT_PKG")
+// gobGraphFact is the gob encoding of a graphFact.
+type gobGraphFact struct {
+	Graph graph.Graph
+	Key   cache.Key
+}
+
+// GobEncode encodes the fact for drivers that serialize facts, such as
+// unitchecker and gopls. Otherwise, the GobEncode promoted from Graph would
+// leave Key out.
+func (f graphFact) GobEncode() ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(gobGraphFact{Graph: f.Graph, Key: f.Key}); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// GobDecode decodes a fact encoded by GobEncode.
+func (f *graphFact) GobDecode(b []byte) error {
+	var g gobGraphFact
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&g); err != nil {
+		return err
+	}
+	f.Graph, f.Key = g.Graph, g.Key
+	return nil
+}
+
+// Result is the dependency graph of a package.
+type Result struct {
+	// Graph holds the direct dependency edges of the package.
+	Graph *graph.Graph
+	// deps holds the direct dependency graphs of every transitive
+	// dependency, shared with their package facts.
+	deps []*graph.Graph
+}
+
+// Transitive returns a new graph merging the direct dependency graphs of the
+// package and all of its transitive dependencies.
+func (r *Result) Transitive() (*graph.Graph, error) {
+	return graph.MergeParallel(r.Graph.Container, runtime.GOMAXPROCS(0), append([]*graph.Graph{r.Graph}, r.deps...)...)
+}
+
+// Granularity is the kind of node in the dependency graph.
+type Granularity string
+
+// Available granularities.
+const (
+	// GranularityPackage has packages as nodes and imports as edges.
+	GranularityPackage Granularity = "package"
+	// GranularityFile has Go files as nodes, identified by their package
+	// path and base name, and references between files as edges.
+	GranularityFile Granularity = "file"
+)
+
+var (
+	rootPkg       string
+	output        string
+	graphOut      string
+	granularity   string
+	tests         bool
+	xtests        bool
+	excludeKinds  string
+	skipGenerated bool
+	cacheDir      string
+	edgesOut      string
+	progressOut   string
+)
 
 func init() {
-	if rootPkg == "" {
-		panic("DEPGRAPH_ROOT_PKG not set")
+	Analyzer.Flags.StringVar(&rootPkg, "root", os.Getenv("DEPGRAPH_ROOT_PKG"),
+		"package whose transitive graph is written, none if empty; defaults to $DEPGRAPH_ROOT_PKG")
+	Analyzer.Flags.StringVar(&output, "output", string(graph.FormatEdgeList),
+		"format of the root package's graph: edgelist, json, dot or html")
+	Analyzer.Flags.StringVar(&graphOut, "graph-out", "",
+		"file to write the root package's graph to, stdout if empty")
+	Analyzer.Flags.StringVar(&granularity, "granularity", string(GranularityPackage),
+		"kind of node in the graph: package or file")
+	Analyzer.Flags.BoolVar(&tests, "tests", false,
+		"include imports of local packages' own _test.go files as test edges")
+	Analyzer.Flags.BoolVar(&xtests, "xtests", false,
+		"include imports of local packages' external test packages as test edges")
+	Analyzer.Flags.StringVar(&excludeKinds, "exclude-kinds", "",
+		"comma separated edge kinds to exclude, e.g. blank,dot")
+	Analyzer.Flags.BoolVar(&skipGenerated, "skip-generated", false,
+		"leave generated files out, with their imports and uses, rather than tagging their edges as generated")
+	Analyzer.Flags.StringVar(&cacheDir, "cache", cache.DefaultDir(),
+		"directory to cache each package's edges in, disabled if empty")
+	Analyzer.Flags.StringVar(&edgesOut, "edges-out", "",
+		"file to stream each package's direct edges to as JSON lines as it is analyzed, stdout if -, disabled if empty")
+	Analyzer.Flags.StringVar(&progressOut, "progress-out", "",
+		"file to write a graph.PassEvent to as JSON lines as each package is analyzed, stdout if -, disabled if empty")
+}
+
+// Logger is the logger of the analyzer's passes, see shared.Logger for its
+// default.
+var Logger *zerolog.Logger
+
+// cacheVersion is incremented whenever the edges computed for a package
+// change, invalidating cached edges.
+const cacheVersion = 6
+
+var (
+	openCacheOnce sync.Once
+	edgeCache     *cache.Cache
+)
+
+// openCache returns the cache of edges, or nil if it is disabled.
+func openCache(log *zerolog.Logger) *cache.Cache {
+	openCacheOnce.Do(func() {
+		if cacheDir == "" {
+			return
+		}
+		c, err := cache.Open(cacheDir)
+		if err != nil {
+			log.Warn().Err(err).Msg("disabling cache")
+			return
+		}
+		edgeCache = c
+	})
+	return edgeCache
+}
+
+var (
+	edgesMu  sync.Mutex
+	edgesEnc *json.Encoder
+)
+
+// streamEdges writes the direct edges of a package to the -edges-out file, one
+// JSON object per line in sorted order, as soon as its pass computes them.
+func streamEdges(g *graph.Graph) error {
+	if edgesOut == "" {
+		return nil
 	}
+	edgesMu.Lock()
+	defer edgesMu.Unlock()
+	if edgesEnc == nil {
+		w := os.Stdout
+		if edgesOut != "-" {
+			f, err := os.Create(edgesOut)
+			if err != nil {
+				return fmt.Errorf("failed to create edges output: %w", err)
+			}
+			w = f
+		}
+		edgesEnc = json.NewEncoder(w)
+	}
+	for _, edge := range g.SortedEdges() {
+		if err := edgesEnc.Encode(edge); err != nil {
+			return fmt.Errorf("failed to write edge: %w", err)
+		}
+	}
+	return nil
+}
+
+var (
+	progressMu  sync.Mutex
+	progressEnc *json.Encoder
+)
+
+// reportPass writes a graph.PassEvent of the package to the -progress-out
+// file once its pass finishes.
+func reportPass(pkg string, cached bool) error {
+	if progressOut == "" {
+		return nil
+	}
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	if progressEnc == nil {
+		w := os.Stdout
+		if progressOut != "-" {
+			f, err := os.Create(progressOut)
+			if err != nil {
+				return fmt.Errorf("failed to create progress output: %w", err)
+			}
+			w = f
+		}
+		progressEnc = json.NewEncoder(w)
+	}
+	if err := progressEnc.Encode(graph.PassEvent{Package: pkg, Cached: cached}); err != nil {
+		return fmt.Errorf("failed to write progress: %w", err)
+	}
+	return nil
 }
 
 // Run is the runner for an analysis pass
 func run(pass *analysis.Pass) (interface{}, error) {
-	log := log.With().Str("pkg", pass.Pkg.Path()).Str("name", pass.Pkg.Name()).Logger()
+	log := shared.Logger(Logger).With().Str("pkg", pass.Pkg.Path()).Str("name", pass.Pkg.Name()).Logger()
 	log.Info().Msg("running pass over package")
-	ok := pass.ImportPackageFact(pass.Pkg, (*graphFact)(nil))
-	if ok {
+	format, err := graph.ParseFormat(output)
+	if err != nil {
+		return nil, err
+	}
+	switch Granularity(granularity) {
+	case GranularityPackage, GranularityFile:
+	default:
+		return nil, fmt.Errorf("unsupported granularity: %q", granularity)
+	}
+	exclude, err := graph.ParseEdgeKind(excludeKinds)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	for _, pf := range pass.AllPackageFacts() {
+		if f, ok := pf.Fact.(*graphFact); ok && pf.Package != pass.Pkg {
+			res.deps = append(res.deps, &f.Graph)
+		}
+	}
+	var visited graphFact
+	if pass.ImportPackageFact(pass.Pkg, &visited) {
 		log.Info().Msg("already visited package")
-		return nil, nil
+		res.Graph = &visited.Graph
+		return res, nil
 	}
 	f := graphFact{Graph: graph.Graph{
 		Container:       pass.Pkg.Path(),
@@ -48,38 +275,597 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		Nodes:           nil,
 		Edges:           nil,
 	}}
+	f.Graph.SetLogger(&log)
+	depKeys := make(map[string]cache.Key, len(pass.Pkg.Imports()))
 	for _, dep := range pass.Pkg.Imports() {
-		log.Info().Str("dep", dep.Path()).Msg("adding dependency")
-		f.Graph.AddEdge(graph.NewDirectedEdge(pass.Pkg.Path(), pass.Pkg.Path(), dep.Path()))
 		var g graphFact
-		if pass.ImportPackageFact(dep, &g) {
-			overlap := f.Graph.Add(g.Graph)
-			log.Info().Int("graphOrder", g.Graph.Order()).
-				Int("graphSize", g.Graph.Size()).
-				Str("dep", dep.Path()).
-				Int("overlap", overlap).
-				Msg("imported dependecy's package fact")
+		if !pass.ImportPackageFact(dep, &g) {
+			// Drivers visit packages in dependency order, but may not
+			// have analyzed a dependency that failed to load. Its edges
+			// are missing, and so is a key to cache the package's by.
+			log.Warn().Str("dep", dep.Path()).Msg("no fact of dependency")
+			depKeys = nil
+			continue
+		}
+		if g.Key == (cache.Key{}) {
+			// The dependency was not cached, so nothing identifies its
+			// edges, and a change to them would not invalidate the
+			// package's.
+			log.Debug().Str("dep", dep.Path()).Msg("no cache key of dependency")
+			depKeys = nil
+		}
+		if depKeys != nil {
+			depKeys[dep.Path()] = g.Key
+		}
+	}
+	c := openCache(&log)
+	if depKeys == nil {
+		c = nil
+	}
+	if c != nil {
+		key, err := cacheKey(pass, depKeys)
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to compute cache key")
+			c = nil
+		}
+		f.Key = key
+	}
+	cached := loadCached(&log, c, &f)
+	if !cached {
+		var err error
+		if Granularity(granularity) == GranularityFile {
+			err = addFileEdges(pass, &f.Graph)
 		} else {
-			// This is a bug in the analysis driver, whose document
-			// requires that packages are visited in dependency
-			// topological order.
-			log.Fatal().Str("pkg", dep.Path()).Msg("failed to import package fact")
+			err = addPackageEdges(pass, &f.Graph, exclude)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			storeCached(&log, c, &f)
 		}
 	}
 	pass.ExportPackageFact(&f)
+	if err := streamEdges(&f.Graph); err != nil {
+		return nil, err
+	}
+	if err := reportPass(pass.Pkg.Path(), cached); err != nil {
+		return nil, err
+	}
 	log.Info().Int("graphOrder", f.Graph.Order()).
 		Int("graphSize", f.Graph.Size()).
 		Int("deps", len(pass.Pkg.Imports())).
 		Msg("exported package fact")
-	if pass.Pkg.Path() == rootPkg {
-		log.Info().Msg("writing graph")
-		for _, edge := range f.Graph.Edges {
-			edge, ok := edge.(*graph.DirectedEdge)
-			if !ok {
-				panic(fmt.Sprintf("unsupport edge type: %T", edge))
+	res.Graph = &f.Graph
+	if pass.Pkg.Path() == rootPkg && !isTestVariant(pass) {
+		g, err := res.Transitive()
+		if err != nil {
+			return nil, err
+		}
+		for prefix, versions := range g.ModuleVersions() {
+			if len(versions) > 1 {
+				log.Warn().Str("module", prefix).Strs("versions", versions).
+					Msg("multiple major versions of module in graph")
+			}
+		}
+		log.Info().Int("graphOrder", g.Order()).
+			Int("graphSize", g.Size()).
+			Str("output", output).
+			Msg("writing graph")
+		if err := graph.WriteFile(graphOut, g, format); err != nil {
+			return nil, fmt.Errorf("failed to write graph: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// cacheKey hashes everything that the edges of the package depend on: the
+// analyzer's flags, the package's module, its files and the keys of its
+// dependencies.
+func cacheKey(pass *analysis.Pass, depKeys map[string]cache.Key) (cache.Key, error) {
+	h := cache.NewHash("depgraph", cacheVersion)
+	h.String(pass.Pkg.Path())
+	h.String(fmt.Sprintf("%s %t %t %s %t", granularity, tests, xtests, excludeKinds, skipGenerated))
+	if mv, ok := pass.ResultOf[modver.Analyzer].(*modver.ModVerFact); ok && mv != nil {
+		h.String(mv.String())
+	}
+	for _, file := range pass.Files {
+		if err := h.File(pass.Fset.Position(file.Pos()).Filename); err != nil {
+			return cache.Key{}, err
+		}
+	}
+	if (tests || xtests) && len(pass.Files) > 0 {
+		dir := filepath.Dir(pass.Fset.Position(pass.Files[0].Pos()).Filename)
+		names, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+		if err != nil {
+			return cache.Key{}, err
+		}
+		for _, name := range names {
+			if err := h.File(name); err != nil {
+				return cache.Key{}, err
+			}
+		}
+	}
+	deps := make([]string, 0, len(depKeys))
+	for dep := range depKeys {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+	for _, dep := range deps {
+		h.String(dep)
+		h.String(depKeys[dep].String())
+	}
+	return h.Sum(), nil
+}
+
+// loadCached loads the graph of f from the cache by its key, and reports
+// whether it was cached.
+func loadCached(log *zerolog.Logger, c *cache.Cache, f *graphFact) bool {
+	if c == nil {
+		return false
+	}
+	data, ok := c.Get(f.Key)
+	if !ok {
+		return false
+	}
+	var g graph.Graph
+	if err := json.Unmarshal(data, &g); err != nil {
+		log.Warn().Err(err).Stringer("key", f.Key).Msg("ignoring corrupt cache entry")
+		return false
+	}
+	g.AddedContainers = f.AddedContainers
+	g.SetLogger(f.Logger())
+	f.Graph = g
+	log.Debug().Str("pkg", f.Container).Stringer("key", f.Key).Msg("loaded cached edges")
+	return true
+}
+
+// storeCached stores the graph of f in the cache by its key.
+func storeCached(log *zerolog.Logger, c *cache.Cache, f *graphFact) {
+	data, err := json.Marshal(f.Graph)
+	if err == nil {
+		err = c.Put(f.Key, data)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("pkg", f.Container).Msg("failed to cache edges")
+	}
+}
+
+// nodeData annotates a node with the module version it belongs to.
+func nodeData(mv *modver.ModVerFact) *graph.NodeData {
+	data := &graph.NodeData{Module: mv.Path, Version: mv.Version}
+	if mv.Vendored {
+		data.Attrs.SetBool(graph.AttrVendored, true)
+	}
+	return data
+}
+
+// countTypes returns the number of package-level named types declared by pkg,
+// and how many of them are interfaces.
+func countTypes(pkg *types.Package) (all, interfaces int) {
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		all++
+		if types.IsInterface(tn.Type()) {
+			interfaces++
+		}
+	}
+	return all, interfaces
+}
+
+// countExported returns the number of exported identifiers of pkg, its
+// package-level ones and the exported methods of its exported types.
+func countExported(pkg *types.Package) int64 {
+	var n int64
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		n++
+		tn, ok := obj.(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		for i := 0; i < named.NumMethods(); i++ {
+			if named.Method(i).Exported() {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// countLines returns the number of lines of the package's Go files.
+func countLines(pass *analysis.Pass) int64 {
+	var lines int64
+	for _, file := range pass.Files {
+		if f := pass.Fset.File(file.Pos()); f != nil {
+			lines += int64(f.LineCount())
+		}
+	}
+	return lines
+}
+
+// addPackageEdges adds an edge from the package to each of its imports,
+// except for those of any excluded kind.
+func addPackageEdges(pass *analysis.Pass, g *graph.Graph, exclude graph.EdgeKind) error {
+	data := &graph.NodeData{}
+	if mv, ok := pass.ResultOf[modver.Analyzer].(*modver.ModVerFact); ok && mv != nil {
+		data = nodeData(mv)
+	}
+	data.Types, data.Interfaces = countTypes(pass.Pkg)
+	data.Attrs.SetInt(graph.AttrLOC, countLines(pass))
+	data.Attrs.SetInt(graph.AttrFiles, int64(len(pass.Files)))
+	data.Attrs.SetInt(graph.AttrExported, countExported(pass.Pkg))
+	g.AddNode(graph.NodeKey{ID: pass.Pkg.Path()}, data)
+	files := pass.Files
+	if skipGenerated {
+		files = handWritten(pass.Files)
+	}
+	symbols := usedSymbols(pass, files)
+	provenance := importProvenance(pass.Pkg, pass.Fset, files)
+	generated := generatedFiles(pass.Pkg, pass.Fset, files)
+	for _, dep := range pass.Pkg.Imports() {
+		if skipGenerated && len(provenance[dep.Path()]) == 0 {
+			// Only generated files import it.
+			continue
+		}
+		g.Logger().Debug().Str("pkg", pass.Pkg.Path()).Str("dep", dep.Path()).Msg("adding dependency")
+		edge := graph.NewDirectedEdge(pass.Pkg.Path(), pass.Pkg.Path(), dep.Path())
+		// Weight the edge by how much of the dependency's API is used, but
+		// never below one so that e.g. blank imports are still counted.
+		edge.Symbols = symbols[dep.Path()]
+		edge.EdgeWeight = math.Max(1, float64(len(edge.Symbols)))
+		edge.Provenance = provenance[dep.Path()]
+		edge.Kind = graph.ImportKind(edge.Provenance) | graph.GeneratedKind(edge.Provenance, generated)
+		if depData := depNodeData(pass, dep, dep.Path()); depData != nil {
+			edge.Kind |= moduleKind(depData)
+		} else {
+			edge.Kind |= packageKind(pass, dep.Path())
+		}
+		if edge.Kind&exclude != 0 {
+			continue
+		}
+		if _, err := g.AddEdge(edge); err != nil {
+			return err
+		}
+	}
+	// Only the tests of local modules are considered, since the tests of
+	// versioned or standard library dependencies are never built.
+	mv, _ := pass.ResultOf[modver.Analyzer].(*modver.ModVerFact)
+	local := mv != nil && mv.Version == "" && mv.Path != "std"
+	if (tests || xtests) && local {
+		if err := addTestEdges(pass, g, exclude); err != nil {
+			g.Logger().Warn().Err(err).Str("pkg", pass.Pkg.Path()).Msg("failed to add test edges")
+		}
+	}
+	return nil
+}
+
+// addTestEdges adds an edge tagged graph.EdgeKindTest from the package to each
+// package that is only imported by its test files. Test files are parsed from
+// the package directory, since the analysis driver loads them as separate
+// package variants.
+func addTestEdges(pass *analysis.Pass, g *graph.Graph, exclude graph.EdgeKind) error {
+	if len(pass.Files) == 0 {
+		return nil
+	}
+	dir := filepath.Dir(pass.Fset.Position(pass.Files[0].Pos()).Filename)
+	names, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return err
+	}
+	prod := make(map[string]struct{})
+	for _, imp := range pass.Pkg.Imports() {
+		prod[imp.Path()] = struct{}{}
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range names {
+		if ok, err := build.Default.MatchFile(dir, filepath.Base(name)); err != nil || !ok {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		external := strings.HasSuffix(file.Name.Name, "_test")
+		if external && !xtests || !external && !tests || skipGenerated && ast.IsGenerated(file) {
+			continue
+		}
+		files = append(files, file)
+	}
+	generated := generatedFiles(pass.Pkg, fset, files)
+	for path, provenance := range importProvenance(pass.Pkg, fset, files) {
+		if _, ok := prod[path]; ok || path == pass.Pkg.Path() {
+			continue
+		}
+		edge := graph.NewDirectedEdge(pass.Pkg.Path(), pass.Pkg.Path(), path)
+		edge.Kind = graph.EdgeKindTest | graph.ImportKind(provenance) |
+			graph.GeneratedKind(provenance, generated) | packageKind(pass, path)
+		edge.Provenance = provenance
+		if edge.Kind&exclude != 0 {
+			continue
+		}
+		if _, err := g.AddEdge(edge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generatedFiles returns the identifiers of the given files of pkg that are
+// generated, as used by graph.GeneratedKind.
+func generatedFiles(pkg *types.Package, fset *token.FileSet, files []*ast.File) map[string]bool {
+	generated := make(map[string]bool)
+	for _, file := range files {
+		if ast.IsGenerated(file) {
+			generated[fileID(pkg, fset.Position(file.Pos()).Filename)] = true
+		}
+	}
+	return generated
+}
+
+// handWritten returns the files that are not generated.
+func handWritten(files []*ast.File) []*ast.File {
+	var kept []*ast.File
+	for _, file := range files {
+		if !ast.IsGenerated(file) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// moduleKind returns the kinds of an edge to a package with the given data,
+// as set by nodeData.
+func moduleKind(data *graph.NodeData) graph.EdgeKind {
+	var kind graph.EdgeKind
+	if data.Module == "std" {
+		kind |= graph.EdgeKindStdlib
+	}
+	if vendored, _ := data.Attrs.Bool(graph.AttrVendored); vendored {
+		kind |= graph.EdgeKindVendored
+	}
+	return kind
+}
+
+// packageKind returns the kinds of an edge to the package with the given
+// path whose module is unknown, by graph.PackageKind, except that packages of
+// the pass's module are never of the standard library.
+func packageKind(pass *analysis.Pass, path string) graph.EdgeKind {
+	kind := graph.PackageKind(path, "")
+	if mv, ok := pass.ResultOf[modver.Analyzer].(*modver.ModVerFact); ok && mv != nil {
+		if path == mv.Path || strings.HasPrefix(path, mv.Path+"/") {
+			kind &^= graph.EdgeKindStdlib
+		}
+	}
+	return kind
+}
+
+// importProvenance returns the location and style of every import in the
+// given files of pkg, keyed by import path.
+func importProvenance(pkg *types.Package, fset *token.FileSet, files []*ast.File) map[string][]graph.Provenance {
+	provenance := make(map[string][]graph.Provenance)
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			posn := fset.Position(spec.Pos())
+			p := graph.Provenance{
+				File: fileID(pkg, posn.Filename),
+				Line: posn.Line,
+			}
+			if spec.Name != nil {
+				switch spec.Name.Name {
+				case "_":
+					p.Style = graph.ImportStyleBlank
+				case ".":
+					p.Style = graph.ImportStyleDot
+				default:
+					p.Style = graph.ImportStyleNamed
+				}
+			}
+			provenance[path] = append(provenance[path], p)
+		}
+	}
+	return provenance
+}
+
+// isTestVariant reports whether the pass is over a package's test variant,
+// which the analysis driver loads in addition to the package itself.
+func isTestVariant(pass *analysis.Pass) bool {
+	for _, file := range pass.Files {
+		if strings.HasSuffix(pass.Fset.Position(file.Pos()).Filename, "_test.go") {
+			return true
+		}
+	}
+	return false
+}
+
+// addFileEdges adds an edge from each file of the package to every other file
+// that declares an object it references, weighted by the number of distinct
+// objects referenced.
+func addFileEdges(pass *analysis.Pass, g *graph.Graph) error {
+	type fileRef struct {
+		src, dst string
+	}
+	refs := make(map[fileRef]map[types.Object]struct{})
+	pkgs := make(map[string]*types.Package)
+	for ident, obj := range pass.TypesInfo.Uses {
+		if obj.Pkg() == nil || !obj.Pos().IsValid() {
+			continue
+		}
+		ref := fileRef{
+			src: fileID(pass.Pkg, pass.Fset.Position(ident.Pos()).Filename),
+			dst: fileID(obj.Pkg(), pass.Fset.Position(obj.Pos()).Filename),
+		}
+		if ref.src == ref.dst {
+			continue
+		}
+		if refs[ref] == nil {
+			refs[ref] = make(map[types.Object]struct{})
+		}
+		refs[ref][obj] = struct{}{}
+		pkgs[ref.src] = pass.Pkg
+		pkgs[ref.dst] = obj.Pkg()
+	}
+	for ref, objs := range refs {
+		edge := graph.NewDirectedEdge(pass.Pkg.Path(), ref.src, ref.dst)
+		edge.EdgeWeight = float64(len(objs))
+		if _, err := g.AddEdge(edge); err != nil {
+			return err
+		}
+	}
+	// Every file of the package is a node, so that the graphs of its
+	// importers can take the module of any of them from its fact.
+	mv, _ := pass.ResultOf[modver.Analyzer].(*modver.ModVerFact)
+	for _, file := range pass.Files {
+		pkgs[fileID(pass.Pkg, pass.Fset.Position(file.Pos()).Filename)] = pass.Pkg
+	}
+	for id, pkg := range pkgs {
+		var data *graph.NodeData
+		if pkg == pass.Pkg {
+			if mv != nil {
+				data = nodeData(mv)
 			}
-			fmt.Println(edge.Src, edge.Dst)
+		} else if depData := depNodeData(pass, pkg, id); depData != nil {
+			data = moduleData(depData)
+		}
+		g.AddNode(graph.NodeKey{ID: id}, data)
+	}
+	return nil
+}
+
+// depNodeData returns the data of the node id in the graph of the fact of
+// dep, which holds the module of dep's nodes as set by nodeData, or nil if
+// their module is unknown. Facts of other analyzers such as modver are not
+// available for dependencies, since drivers only pass on an analyzer's own
+// facts.
+func depNodeData(pass *analysis.Pass, dep *types.Package, id string) *graph.NodeData {
+	var df graphFact
+	if !pass.ImportPackageFact(dep, &df) {
+		return nil
+	}
+	data := df.Nodes[graph.NodeKey{ID: id}].Data
+	if data == nil || data.Module == "" {
+		return nil
+	}
+	return data
+}
+
+// moduleData returns a copy of the module annotations of data, as set by
+// nodeData.
+func moduleData(data *graph.NodeData) *graph.NodeData {
+	module := &graph.NodeData{Module: data.Module, Version: data.Version}
+	if vendored, _ := data.Attrs.Bool(graph.AttrVendored); vendored {
+		module.Attrs.SetBool(graph.AttrVendored, true)
+	}
+	return module
+}
+
+// fileID identifies a Go file by its package path and base name, so that IDs
+// don't depend on the location of the module cache.
+func fileID(pkg *types.Package, filename string) string {
+	return pkg.Path() + "/" + filepath.Base(filename)
+}
+
+// usedSymbols returns the sorted, distinct exported identifiers of each
+// imported package that are referenced by the given files of the package,
+// keyed by import path. Methods and fields are qualified by their receiver or
+// struct type name where it is known, see symbolName.
+func usedSymbols(pass *analysis.Pass, files []*ast.File) map[string][]string {
+	inFiles := make(map[*token.File]bool, len(files))
+	for _, file := range files {
+		inFiles[pass.Fset.File(file.Pos())] = true
+	}
+	seen := make(map[string]map[string]struct{})
+	structs := make(map[*types.Package]map[*types.Var]string)
+	for id, obj := range pass.TypesInfo.Uses {
+		if obj.Pkg() == nil || obj.Pkg() == pass.Pkg || !obj.Exported() {
+			continue
+		}
+		if len(files) < len(pass.Files) && !inFiles[pass.Fset.File(id.Pos())] {
+			continue
+		}
+		path := obj.Pkg().Path()
+		if seen[path] == nil {
+			seen[path] = make(map[string]struct{})
+		}
+		seen[path][symbolName(obj, structs)] = struct{}{}
+	}
+	symbols := make(map[string][]string, len(seen))
+	for path, set := range seen {
+		for name := range set {
+			symbols[path] = append(symbols[path], name)
+		}
+		sort.Strings(symbols[path])
+	}
+	return symbols
+}
+
+// symbolName returns the name of obj, qualified by the receiver type name of
+// a method or the struct type name of a field. structs caches the struct type
+// names of the fields of each package, see structFields.
+func symbolName(obj types.Object, structs map[*types.Package]map[*types.Var]string) string {
+	if v, ok := obj.(*types.Var); ok && v.IsField() {
+		fields, ok := structs[v.Pkg()]
+		if !ok {
+			fields = structFields(v.Pkg())
+			structs[v.Pkg()] = fields
+		}
+		if name, ok := fields[v.Origin()]; ok {
+			return name + "." + obj.Name()
+		}
+		return obj.Name()
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return obj.Name()
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return obj.Name()
+	}
+	t := recv.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name() + "." + obj.Name()
+	}
+	return obj.Name()
+}
+
+// structFields returns the name of the struct type declared at the package
+// level of pkg that declares each field. Fields of anonymous structs are
+// left out.
+func structFields(pkg *types.Package) map[*types.Var]string {
+	fields := make(map[*types.Var]string)
+	for _, name := range pkg.Scope().Names() {
+		tn, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		st, ok := tn.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			fields[st.Field(i)] = tn.Name()
 		}
 	}
-	return nil, nil
+	return fields
 }
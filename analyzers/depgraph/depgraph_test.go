@@ -0,0 +1,107 @@
+package depgraph
+
+import (
+	"bytes"
+	"encoding/gob"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// setFlags sets flags of the analyzer for the duration of the test.
+func setFlags(t *testing.T, flags map[string]string) {
+	t.Helper()
+	for name, value := range flags {
+		name := name
+		prev := Analyzer.Flags.Lookup(name).Value.String()
+		if err := Analyzer.Flags.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = Analyzer.Flags.Set(name, prev) })
+	}
+}
+
+func TestGraphFactGob(t *testing.T) {
+	// Drivers such as unitchecker encode facts as analysis.Fact values of
+	// registered types.
+	gob.Register(&graphFact{})
+	fact := &graphFact{Graph: graph.Graph{Container: "a"}, Key: [32]byte{1, 2, 3}}
+	fact.AddEdge(graph.NewDirectedEdge("a", "a", "b"))
+
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(struct{ Fact analysis.Fact }{fact}); err != nil {
+		t.Fatal(err)
+	}
+	var got struct{ Fact analysis.Fact }
+	if err := gob.NewDecoder(&b).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	g, ok := got.Fact.(*graphFact)
+	if !ok {
+		t.Fatalf("decoded %T, want *graphFact", got.Fact)
+	}
+	if g.Key != fact.Key {
+		t.Errorf("key %v, want %v", g.Key, fact.Key)
+	}
+	if g.Container != "a" || g.Size() != 1 || g.Order() != 2 {
+		t.Errorf("graph of container %q with %d edges and %d nodes, want a with 1 and 2",
+			g.Container, g.Size(), g.Order())
+	}
+}
+
+func TestFileGranularity(t *testing.T) {
+	setFlags(t, map[string]string{"granularity": "file", "cache": ""})
+	dir, err := filepath.Abs(filepath.Join("testdata", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := analysistest.Run(t, dir, Analyzer, "example.com/file/a")
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	res, ok := results[0].Result.(*Result)
+	if !ok {
+		t.Fatalf("got result %T, want *Result", results[0].Result)
+	}
+	for _, dst := range []string{"example.com/file/b/y.go", "example.com/file/b/z.go"} {
+		key := graph.NewDirectedEdge("example.com/file/a", "example.com/file/a/a.go", dst).Key()
+		if _, ok := res.Graph.Edges[key]; !ok {
+			t.Errorf("no edge from a.go to %s", dst)
+		}
+		// The module of the files of dependencies comes from their facts.
+		if data := res.Graph.Nodes[graph.NodeKey{ID: dst}].Data; data == nil || data.Module != "example.com/file" {
+			t.Errorf("%s has data %+v, want module example.com/file", dst, data)
+		}
+	}
+}
+
+func TestUsedSymbols(t *testing.T) {
+	setFlags(t, map[string]string{"cache": ""})
+	dir, err := filepath.Abs(filepath.Join("testdata", "symbols"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := analysistest.Run(t, dir, Analyzer, "example.com/symbols/a")
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	res, ok := results[0].Result.(*Result)
+	if !ok {
+		t.Fatalf("got result %T, want *Result", results[0].Result)
+	}
+	key := graph.NewDirectedEdge("example.com/symbols/a", "example.com/symbols/a", "example.com/symbols/b").Key()
+	edge, ok := res.Graph.Edges[key].(*graph.DirectedEdge)
+	if !ok {
+		t.Fatal("no edge from a to b")
+	}
+	// Promoted fields are qualified by the struct type declaring them, and
+	// fields of generic types by their origin.
+	want := []string{"Func", "P", "P.X", "T", "T.F", "T.M", "U", "V.G"}
+	if !reflect.DeepEqual(edge.Symbols, want) {
+		t.Errorf("got symbols %v, want %v", edge.Symbols, want)
+	}
+}
@@ -0,0 +1,5 @@
+package a // want package:"."
+
+import "example.com/file/b"
+
+var X = b.Y + b.Z
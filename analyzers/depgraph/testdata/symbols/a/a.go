@@ -0,0 +1,9 @@
+package a // want package:"."
+
+import "example.com/symbols/b"
+
+func F(u b.U) int {
+	b.T{F: 1}.M()
+	b.Func()
+	return u.G + b.P[int]{X: 1}.X
+}
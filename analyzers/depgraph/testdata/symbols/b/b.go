@@ -0,0 +1,13 @@
+package b
+
+type T struct{ F int }
+
+func (T) M() {}
+
+type U struct{ V }
+
+type V struct{ G int }
+
+type P[E any] struct{ X E }
+
+func Func() {}
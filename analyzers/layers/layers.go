@@ -0,0 +1,67 @@
+// Package layers defines an Analyzer that reports imports which violate the
+// layering of an architecture policy file.
+package layers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"sync"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/arclabs561/pkgrank/shared"
+	"github.com/rs/zerolog"
+	"golang.org/x/tools/go/analysis"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "layers",
+	Doc:  "reports imports between layers that the architecture policy does not allow",
+	Run:  run,
+}
+
+var policyFile string
+
+func init() {
+	Analyzer.Flags.StringVar(&policyFile, "policy", graph.DefaultPolicyFile,
+		"architecture policy file declaring layers and their allowed imports")
+}
+
+var (
+	loadOnce sync.Once
+	policy   *graph.Policy
+	loadErr  error
+)
+
+// Logger is the logger of the analyzer's passes, see shared.Logger for its
+// default.
+var Logger *zerolog.Logger
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	loadOnce.Do(func() {
+		policy, loadErr = graph.LoadPolicy(policyFile)
+		if errors.Is(loadErr, fs.ErrNotExist) && policyFile == graph.DefaultPolicyFile {
+			// Nothing to check, e.g. when bundled with other analyzers.
+			policy, loadErr = &graph.Policy{}, nil
+			shared.Logger(Logger).Debug().Str("policy", policyFile).Msg("no policy")
+		} else if loadErr == nil {
+			shared.Logger(Logger).Debug().Str("policy", policyFile).Int("layers", len(policy.Layers)).Msg("loaded policy")
+		}
+	})
+	if loadErr != nil {
+		return nil, fmt.Errorf("failed to load policy: %w", loadErr)
+	}
+	for _, file := range pass.Files {
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			if v, ok := policy.Check(pass.Pkg.Path(), path); ok {
+				pass.Reportf(spec.Pos(), "layer %s must not import layer %s: %s", v.From, v.To, path)
+			}
+		}
+	}
+	return nil, nil
+}
@@ -1,79 +1,275 @@
+// Package modver defines an Analyzer that resolves the module path and version
+// that each package belongs to, and exports it as a package fact.
 package modver
 
 import (
+	"bufio"
 	"fmt"
-	"go/token"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/arclabs561/pkgrank/shared"
+	"github.com/rs/zerolog"
 	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
 	"golang.org/x/tools/go/analysis"
 )
 
 var Analyzer = &analysis.Analyzer{
-	Name:      "moduleVersion",
-	Doc:       "finds the module version of its Pass.Pkg.Path()",
-	FactTypes: []analysis.Fact{(*ModVerFact)(nil)},
-	Run:       run,
-	Requires:  []*analysis.Analyzer{},
+	Name:             "moduleVersion",
+	Doc:              "finds the module version of its Pass.Pkg.Path()",
+	FactTypes:        []analysis.Fact{(*ModVerFact)(nil)},
+	Run:              run,
+	Requires:         []*analysis.Analyzer{},
+	ResultType:       reflect.TypeOf((*ModVerFact)(nil)),
+	RunDespiteErrors: true,
 }
 
-type ModVerFact struct{}
+// ModVerFact is the module that a package belongs to.
+type ModVerFact struct {
+	// Path is the module path, e.g. "github.com/rs/zerolog". Standard
+	// library packages belong to the "std" module.
+	Path string
+	// Version is the module version, e.g. "v1.30.0". It is empty for
+	// the main module and any other module not resolved from the module
+	// cache.
+	Version string
+	// Vendored reports whether the package is in a vendor directory, from
+	// which Path is that of the vendoring module.
+	Vendored bool
+}
 
 func (f ModVerFact) AFact() {}
 
+// String returns the module in path@version form, or only the path if the
+// version is unknown.
+func (f ModVerFact) String() string {
+	if f.Version == "" {
+		return f.Path
+	}
+	return f.Path + "@" + f.Version
+}
+
+// Logger is the logger of the analyzer's passes, see shared.Logger for its
+// default.
+var Logger *zerolog.Logger
+
 func run(pass *analysis.Pass) (interface{}, error) {
-	// rootPath := pass.Pkg.GoFiles[0]
-	// dir := filepath.Dir(rootPath)
-
-	// // Traverse up until we find go.mod or hit the filesystem root.
-	// for {
-	// 	gomod := filepath.Join(dir, "go.mod")
-	// 	if _, err := os.Stat(gomod); err == nil {
-	// 		version, err := getModuleVersionFromGoMod(gomod, pass.Pkg.Path())
-	// 		if err != nil {
-	// 			return nil, err
-	// 		}
-	// 		// Return the version as the result of the analyzer.
-	// 		return version, nil
-	// 	}
-	// 	parent := filepath.Dir(dir)
-	// 	if parent == dir {
-	// 		return nil, errors.New("go.mod not found")
-	// 	}
-	// 	dir = parent
-	// }
-
-	fmt.Println("\n", pass.Pkg.Path())
-	pass.Fset.Iterate(func(f *token.File) bool {
-		fmt.Println(f.Name())
-		return false
-	})
-
-	return nil, nil
+	log := shared.Logger(Logger).With().Str("pkg", pass.Pkg.Path()).Logger()
+	if len(pass.Files) == 0 {
+		log.Debug().Msg("no files in package, skipping module resolution")
+		return (*ModVerFact)(nil), nil
+	}
+	if pass.Pkg.Name() == "main" && strings.HasSuffix(pass.Pkg.Path(), ".test") {
+		// The main package that go test generates in the build cache to run
+		// the tests of a package belongs to no module.
+		log.Debug().Msg("test main package, skipping module resolution")
+		return (*ModVerFact)(nil), nil
+	}
+	file := pass.Fset.File(pass.Files[0].Pos())
+	if file == nil {
+		return nil, fmt.Errorf("no position information for package %s", pass.Pkg.Path())
+	}
+	dir := filepath.Dir(file.Name())
+	f, err := resolveDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve module of %s: %w", pass.Pkg.Path(), err)
+	}
+	if root, ok := vendorRoot(dir); ok {
+		f, err = resolveVendored(root, pass.Pkg.Path(), f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve vendored module of %s: %w", pass.Pkg.Path(), err)
+		}
+	}
+	log.Debug().Stringer("module", f).Msg("resolved module")
+	pass.ExportPackageFact(f)
+	return f, nil
 }
 
-func getModuleVersionFromGoMod(file string, pkgPath string) (string, error) {
-	data, err := os.ReadFile(file)
+// resolved caches module resolution by directory, since every package of a
+// module walks up to the same go.mod.
+var resolved sync.Map // map[string]*ModVerFact
+
+// resolveDir walks up from dir until it finds the root of a module, either by
+// a go.mod file or by a module cache directory named path@version.
+func resolveDir(dir string) (*ModVerFact, error) {
+	if f, ok := resolved.Load(dir); ok {
+		return f.(*ModVerFact), nil
+	}
+	var f *ModVerFact
+	gomod := filepath.Join(dir, "go.mod")
+	base := filepath.Base(dir)
+	switch i := strings.LastIndex(base, "@"); {
+	case i >= 0:
+		// A module cache directory, <GOMODCACHE>/<escaped path>@<escaped version>.
+		version, err := module.UnescapeVersion(base[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		path, err := modulePath(gomod)
+		if err != nil {
+			path, err = moduleCachePath(dir)
+			if err != nil {
+				return nil, err
+			}
+		}
+		f = &ModVerFact{Path: path, Version: version}
+	case fileExists(gomod):
+		path, err := modulePath(gomod)
+		if err != nil {
+			return nil, err
+		}
+		f = &ModVerFact{Path: path}
+		if path == "std" || path == "cmd" {
+			f.Version = goRootVersion(filepath.Dir(dir))
+		}
+	default:
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("go.mod not found")
+		}
+		var err error
+		f, err = resolveDir(parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	resolved.Store(dir, f)
+	return f, nil
+}
+
+// vendorRoot returns the root of the module in whose vendor directory dir
+// is, if any.
+func vendorRoot(dir string) (string, bool) {
+	for {
+		if fileExists(filepath.Join(dir, "go.mod")) {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		if filepath.Base(dir) == "vendor" && fileExists(filepath.Join(parent, "go.mod")) {
+			return parent, true
+		}
+		dir = parent
+	}
+}
+
+// vendored caches the modules of vendored packages by module root, as listed
+// by vendor/modules.txt.
+var vendored sync.Map // map[string]map[string]*ModVerFact
+
+// resolveVendored returns the module of the package at path vendored by the
+// module at root, which walking up from the package's directory resolved to
+// f, the vendoring module. The go command lists the module path and version
+// of each vendored package in vendor/modules.txt, e.g.
+//
+//	# golang.org/x/mod v0.14.0
+//	## explicit; go 1.18
+//	golang.org/x/mod/semver
+//
+// Packages that it does not list are of f, marked as vendored.
+func resolveVendored(root, path string, f *ModVerFact) (*ModVerFact, error) {
+	mods, ok := vendored.Load(root)
+	if !ok {
+		parsed, err := parseModulesTxt(filepath.Join(root, "vendor", "modules.txt"))
+		if err != nil {
+			return nil, err
+		}
+		mods, _ = vendored.LoadOrStore(root, parsed)
+	}
+	if mv, ok := mods.(map[string]*ModVerFact)[graph.CanonicalPath(path)]; ok {
+		return mv, nil
+	}
+	// Resolved facts are shared by directory, so copy it.
+	v := *f
+	v.Vendored = true
+	return &v, nil
+}
+
+// parseModulesTxt returns the modules of the packages listed in a
+// vendor/modules.txt file, keyed by package path.
+func parseModulesTxt(name string) (map[string]*ModVerFact, error) {
+	file, err := os.Open(name)
 	if err != nil {
-		return "", err
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	mods := make(map[string]*ModVerFact)
+	var mod *ModVerFact
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "## "):
+		case strings.HasPrefix(line, "# "):
+			// A module, possibly replaced: # path version [=> path version]
+			fields := strings.Fields(strings.TrimPrefix(line, "# "))
+			if len(fields) == 0 {
+				mod = nil
+				continue
+			}
+			mod = &ModVerFact{Path: fields[0], Vendored: true}
+			if len(fields) > 1 && fields[1] != "=>" {
+				mod.Version = fields[1]
+			}
+		case line != "" && mod != nil:
+			mods[line] = mod
+		}
 	}
+	return mods, scanner.Err()
+}
 
-	modFile, err := modfile.Parse("go.mod", data, nil)
+// modulePath returns the module path declared in the given go.mod file.
+func modulePath(gomod string) (string, error) {
+	data, err := os.ReadFile(gomod)
 	if err != nil {
 		return "", err
 	}
+	path := modfile.ModulePath(data)
+	if path == "" {
+		return "", fmt.Errorf("no module directive in %s", gomod)
+	}
+	return path, nil
+}
 
-	// Check if the pkgPath corresponds to the main module
-	if modFile.Module.Mod.Path == pkgPath {
-		return modFile.Module.Mod.Version, nil
+// moduleCachePath derives a module path from its module cache directory, for
+// modules which predate go.mod files.
+func moduleCachePath(dir string) (string, error) {
+	const sep = string(filepath.Separator) + "pkg" + string(filepath.Separator) + "mod" + string(filepath.Separator)
+	i := strings.LastIndex(dir, sep)
+	if i < 0 {
+		return "", fmt.Errorf("not a module cache directory: %s", dir)
 	}
+	escaped := filepath.ToSlash(dir[i+len(sep):])
+	escaped = escaped[:strings.LastIndex(escaped, "@")]
+	return module.UnescapePath(escaped)
+}
 
-	// Check if the pkgPath corresponds to a dependency
-	for _, require := range modFile.Require {
-		if require.Mod.Path == pkgPath {
-			return require.Mod.Version, nil
-		}
+// goRootVersion returns the go version of the GOROOT that contains the
+// standard library, read from its VERSION file.
+func goRootVersion(goroot string) string {
+	file, err := os.Open(filepath.Join(goroot, "VERSION"))
+	if err != nil {
+		return ""
 	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return ""
+}
 
-	return "", fmt.Errorf("package path %s not found in go.mod", pkgPath)
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
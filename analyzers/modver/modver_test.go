@@ -0,0 +1,108 @@
+package modver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles writes files by path relative to dir, creating their
+// directories.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolveDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"pkg/mod/example.com/m@v1.0.0/go.mod":                "module example.com/m\n",
+		"pkg/mod/example.com/m@v1.0.0/sub/sub.go":            "package sub\n",
+		"pkg/mod/github.com/!burnt!sushi/toml@v0.3.1/lex.go": "package toml\n",
+		"pkg/mod/example.com/old@v2.0.0+incompatible/p/p.go": "package p\n",
+		"pkg/mod/example.com/pre@v1.0.0-!r!c1/pre.go":        "package pre\n",
+		"goroot/VERSION":                            "go1.21.0\ntime 2023-08-08T15:00:00Z\n",
+		"goroot/src/go.mod":                         "module std\n",
+		"goroot/src/net/http/http.go":               "package http\n",
+		"main/go.mod":                               "module example.com/main\n",
+		"main/internal/x/x.go":                      "package x\n",
+		"main/nested/go.mod":                        "module example.com/nested\n",
+		"main/nested/y/y.go":                        "package y\n",
+		"pkg/mod/example.com/bad@v1.0.0/go.mod":     "// no module directive\n",
+		"pkg/mod/example.com/invalid@V1/invalid.go": "package invalid\n",
+	})
+	for _, tt := range []struct {
+		dir  string
+		want ModVerFact
+	}{
+		{"pkg/mod/example.com/m@v1.0.0/sub", ModVerFact{Path: "example.com/m", Version: "v1.0.0"}},
+		// Modules without a go.mod are named after their directory, whose
+		// upper case letters are escaped.
+		{"pkg/mod/github.com/!burnt!sushi/toml@v0.3.1", ModVerFact{Path: "github.com/BurntSushi/toml", Version: "v0.3.1"}},
+		{"pkg/mod/example.com/old@v2.0.0+incompatible/p", ModVerFact{Path: "example.com/old", Version: "v2.0.0+incompatible"}},
+		{"pkg/mod/example.com/pre@v1.0.0-!r!c1", ModVerFact{Path: "example.com/pre", Version: "v1.0.0-RC1"}},
+		{"pkg/mod/example.com/bad@v1.0.0", ModVerFact{Path: "example.com/bad", Version: "v1.0.0"}},
+		{"goroot/src/net/http", ModVerFact{Path: "std", Version: "go1.21.0"}},
+		{"main/internal/x", ModVerFact{Path: "example.com/main"}},
+		{"main/nested/y", ModVerFact{Path: "example.com/nested"}},
+	} {
+		f, err := resolveDir(filepath.Join(dir, filepath.FromSlash(tt.dir)))
+		if err != nil {
+			t.Errorf("%s: %v", tt.dir, err)
+			continue
+		}
+		if *f != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.dir, *f, tt.want)
+		}
+	}
+	// Escaped versions have no upper case letters.
+	if f, err := resolveDir(filepath.Join(dir, "pkg", "mod", "example.com", "invalid@V1")); err == nil {
+		t.Errorf("resolved a badly escaped version to %+v", f)
+	}
+}
+
+func TestResolveVendored(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("testdata", "vendoring"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "vendor", "golang.org", "x", "mod", "semver")
+	if got, ok := vendorRoot(dir); !ok || got != root {
+		t.Fatalf("got vendor root %q, %t, want %q", got, ok, root)
+	}
+	if _, ok := vendorRoot(root); ok {
+		t.Error("got a vendor root of the module root")
+	}
+	vendoring := &ModVerFact{Path: "example.com/vendoring"}
+	for _, tt := range []struct {
+		path string
+		want ModVerFact
+	}{
+		{"golang.org/x/mod/semver", ModVerFact{Path: "golang.org/x/mod", Version: "v0.14.0", Vendored: true}},
+		// Packages of replaced modules are of the replaced module, at the
+		// version of a module replacement or none of a directory.
+		{"example.com/old/p", ModVerFact{Path: "example.com/old", Version: "v1.0.0", Vendored: true}},
+		{"example.com/local/q", ModVerFact{Path: "example.com/local", Vendored: true}},
+		{"example.com/vendoring/vendor/golang.org/x/mod/semver", ModVerFact{Path: "golang.org/x/mod", Version: "v0.14.0", Vendored: true}},
+		{"example.com/unlisted", ModVerFact{Path: "example.com/vendoring", Vendored: true}},
+	} {
+		f, err := resolveVendored(root, tt.path, vendoring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *f != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.path, *f, tt.want)
+		}
+	}
+	if vendoring.Vendored {
+		t.Error("resolving an unlisted package modified the vendoring module")
+	}
+}
@@ -0,0 +1,3 @@
+module example.com/vendoring
+
+go 1.21
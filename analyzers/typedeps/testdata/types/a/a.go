@@ -0,0 +1,18 @@
+package a // want package:"."
+
+import "example.com/types/b"
+
+type T struct {
+	b.Base
+	Items map[string][]*b.Item
+}
+
+func (T) Read() {}
+
+type P struct{}
+
+func (*P) Read() {}
+
+type G[E any] struct{ b.Item }
+
+func (G[E]) Read() {}
@@ -0,0 +1,7 @@
+package b
+
+type Reader interface{ Read() }
+
+type Base struct{}
+
+type Item struct{}
@@ -0,0 +1,224 @@
+// Package typedeps defines an Analyzer that constructs a graph of dependencies
+// between named types across packages (e.g. type A embeds type B).
+package typedeps
+
+import (
+	"fmt"
+	"go/types"
+	"reflect"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/arclabs561/pkgrank/shared"
+	"github.com/rs/zerolog"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer builds the type dependency graph of each package. Its result is a
+// *Result, so that other analyzers may require it.
+var Analyzer = &analysis.Analyzer{
+	Name:             "typedeps",
+	Doc:              "construct a graph of dependencies between types across packages",
+	FactTypes:        []analysis.Fact{(*typesFact)(nil)},
+	Run:              run,
+	RunDespiteErrors: true,
+	ResultType:       reflect.TypeOf((*Result)(nil)),
+}
+
+// Kind is the kind of a type dependency. Edges of each kind are kept in their
+// own container, see Container.
+type Kind string
+
+// Available kinds of type dependencies.
+const (
+	// KindEmbed is a struct embedding another type.
+	KindEmbed Kind = "embed"
+	// KindField is a struct with a field referring to another type.
+	KindField Kind = "field"
+	// KindImplements is a type implementing an interface.
+	KindImplements Kind = "implements"
+)
+
+var kinds = []Kind{KindEmbed, KindField, KindImplements}
+
+// Container returns the edge container for type dependencies of the given
+// kind declared in the package at path.
+func Container(path string, kind Kind) string {
+	return path + "#" + string(kind)
+}
+
+var (
+	rootPkg  string
+	output   string
+	graphOut string
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&rootPkg, "root", "",
+		"package whose transitive type graph is written")
+	Analyzer.Flags.StringVar(&output, "output", string(graph.FormatEdgeList),
+		"format of the root package's graph: edgelist, json, dot or html")
+	Analyzer.Flags.StringVar(&graphOut, "graph-out", "",
+		"file to write the root package's graph to, stdout if empty")
+}
+
+// typesFact holds the type dependencies declared in a single package.
+type typesFact struct {
+	graph.Graph
+}
+
+func (f typesFact) AFact() {}
+
+// Result is the type dependency graph of a package.
+type Result struct {
+	// Graph holds the type dependencies declared in the package.
+	Graph *graph.Graph
+	// deps holds the type dependency graphs of every transitive
+	// dependency, shared with their package facts.
+	deps []*graph.Graph
+}
+
+// Transitive returns a new graph merging the type dependency graphs of the
+// package and all of its transitive dependencies.
+func (r *Result) Transitive() (*graph.Graph, error) {
+	return graph.Merge(r.Graph.Container, append([]*graph.Graph{r.Graph}, r.deps...)...)
+}
+
+// Logger is the logger of the analyzer's passes, see shared.Logger for its
+// default.
+var Logger *zerolog.Logger
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	log := shared.Logger(Logger).With().Str("pkg", pass.Pkg.Path()).Logger()
+	format, err := graph.ParseFormat(output)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	for _, pf := range pass.AllPackageFacts() {
+		if f, ok := pf.Fact.(*typesFact); ok && pf.Package != pass.Pkg {
+			res.deps = append(res.deps, &f.Graph)
+		}
+	}
+	f := typesFact{Graph: graph.Graph{
+		Container:       pass.Pkg.Path(),
+		AddedContainers: make(map[string]struct{}),
+	}}
+	f.Graph.SetLogger(&log)
+	for _, kind := range kinds {
+		f.Graph.AddedContainers[Container(pass.Pkg.Path(), kind)] = struct{}{}
+	}
+	// addErr is the first error adding an edge.
+	var addErr error
+	addEdge := func(kind Kind, src, dst *types.TypeName) {
+		if dst.Pkg() == nil || dst.Pkg() == pass.Pkg || addErr != nil {
+			return
+		}
+		_, addErr = f.Graph.AddEdge(graph.NewDirectedEdge(
+			Container(pass.Pkg.Path(), kind), typeID(src), typeID(dst)))
+	}
+	ifaces := importedInterfaces(pass.Pkg)
+	scope := pass.Pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		if st, ok := tn.Type().Underlying().(*types.Struct); ok {
+			for i := 0; i < st.NumFields(); i++ {
+				field := st.Field(i)
+				kind := KindField
+				if field.Embedded() {
+					kind = KindEmbed
+				}
+				for _, dst := range namedTypes(field.Type()) {
+					addEdge(kind, tn, dst)
+				}
+			}
+		}
+		if types.IsInterface(tn.Type()) {
+			continue
+		}
+		// Whether an uninstantiated generic type implements an interface
+		// is unspecified.
+		if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			continue
+		}
+		for _, iface := range ifaces {
+			it := iface.Type().Underlying().(*types.Interface)
+			if types.Implements(tn.Type(), it) || types.Implements(types.NewPointer(tn.Type()), it) {
+				addEdge(KindImplements, tn, iface)
+			}
+		}
+	}
+	if addErr != nil {
+		return nil, addErr
+	}
+	pass.ExportPackageFact(&f)
+	log.Info().Int("graphOrder", f.Graph.Order()).
+		Int("graphSize", f.Graph.Size()).
+		Msg("exported package fact")
+	res.Graph = &f.Graph
+	if pass.Pkg.Path() == rootPkg {
+		g, err := res.Transitive()
+		if err != nil {
+			return nil, err
+		}
+		log.Info().Int("graphOrder", g.Order()).
+			Int("graphSize", g.Size()).
+			Str("output", output).
+			Msg("writing graph")
+		if err := graph.WriteFile(graphOut, g, format); err != nil {
+			return nil, fmt.Errorf("failed to write graph: %w", err)
+		}
+	}
+	return res, nil
+}
+
+func typeID(tn *types.TypeName) string {
+	return tn.Pkg().Path() + "." + tn.Name()
+}
+
+// importedInterfaces returns the exported, non-empty, non-generic interfaces
+// declared by the direct imports of pkg.
+func importedInterfaces(pkg *types.Package) []*types.TypeName {
+	var ifaces []*types.TypeName
+	for _, imp := range pkg.Imports() {
+		scope := imp.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || !tn.Exported() {
+				continue
+			}
+			if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+				continue
+			}
+			it, ok := tn.Type().Underlying().(*types.Interface)
+			if !ok || it.NumMethods() == 0 || !it.IsMethodSet() {
+				continue
+			}
+			ifaces = append(ifaces, tn)
+		}
+	}
+	return ifaces
+}
+
+// namedTypes returns the named types that t refers to through pointers,
+// slices, arrays, maps and channels.
+func namedTypes(t types.Type) []*types.TypeName {
+	switch t := t.(type) {
+	case *types.Named:
+		return []*types.TypeName{t.Obj()}
+	case *types.Pointer:
+		return namedTypes(t.Elem())
+	case *types.Slice:
+		return namedTypes(t.Elem())
+	case *types.Array:
+		return namedTypes(t.Elem())
+	case *types.Chan:
+		return namedTypes(t.Elem())
+	case *types.Map:
+		return append(namedTypes(t.Key()), namedTypes(t.Elem())...)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,46 @@
+package typedeps_test
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/arclabs561/pkgrank/analyzers/typedeps"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestTypeDeps(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "types"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := analysistest.Run(t, dir, typedeps.Analyzer, "example.com/types/a")
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	res, ok := results[0].Result.(*typedeps.Result)
+	if !ok {
+		t.Fatalf("got result %T, want *typedeps.Result", results[0].Result)
+	}
+	var got []string
+	for key := range res.Graph.Edges {
+		got = append(got, key.String())
+	}
+	sort.Strings(got)
+	// The generic G is not checked against interfaces.
+	want := []string{
+		"example.com/types/a#embed:example.com/types/a.G->example.com/types/b.Item",
+		"example.com/types/a#embed:example.com/types/a.T->example.com/types/b.Base",
+		"example.com/types/a#field:example.com/types/a.T->example.com/types/b.Item",
+		"example.com/types/a#implements:example.com/types/a.P->example.com/types/b.Reader",
+		"example.com/types/a#implements:example.com/types/a.T->example.com/types/b.Reader",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got edges %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got edge %q, want %q", got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1 @@
+package unused // want "example.com/m/unused is not imported by any package"
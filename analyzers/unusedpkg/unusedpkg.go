@@ -0,0 +1,108 @@
+// Package unusedpkg defines an Analyzer that reports the packages of a
+// module that no package imports, other than main packages, like pkgrank
+// unused does.
+package unusedpkg
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/arclabs561/pkgrank/analyzers/modver"
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/arclabs561/pkgrank/shared"
+	"github.com/rs/zerolog"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer reports each package of the main modules that no package of its
+// module imports. Whether a package is imported depends on the whole
+// module, which the analyzer lists once per module with go list.
+var Analyzer = &analysis.Analyzer{
+	Name:     "unusedpkg",
+	Doc:      "reports packages that no package of their module imports",
+	Run:      run,
+	Requires: []*analysis.Analyzer{modver.Analyzer},
+}
+
+// Logger is the logger of the analyzer's passes, see shared.Logger for its
+// default.
+var Logger *zerolog.Logger
+
+// moduleUnused holds the unused packages of a module, listed once.
+type moduleUnused struct {
+	once   sync.Once
+	unused map[string]bool
+	err    error
+}
+
+// modules caches the unused packages of modules by their root directory.
+var modules sync.Map // map[string]*moduleUnused
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	// Modules from the module cache, including the standard library, have
+	// a version, and are not the ones being checked.
+	mv, _ := pass.ResultOf[modver.Analyzer].(*modver.ModVerFact)
+	if mv == nil || mv.Version != "" || pass.Pkg.Name() == "main" {
+		return nil, nil
+	}
+	var file *ast.File
+	for _, f := range pass.Files {
+		if !strings.HasSuffix(pass.Fset.Position(f.Pos()).Filename, "_test.go") {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return nil, nil
+	}
+	root, ok := moduleRoot(filepath.Dir(pass.Fset.Position(file.Pos()).Filename))
+	if !ok {
+		return nil, nil
+	}
+	v, _ := modules.LoadOrStore(root, &moduleUnused{})
+	m := v.(*moduleUnused)
+	m.once.Do(func() {
+		shared.Logger(Logger).Debug().Str("dir", root).Msg("listing module")
+		m.unused, m.err = unused(root)
+	})
+	if m.err != nil {
+		return nil, fmt.Errorf("failed to list module in %s: %w", root, m.err)
+	}
+	if m.unused[pass.Pkg.Path()] {
+		pass.Reportf(file.Name.Pos(), "%s is not imported by any package", pass.Pkg.Path())
+	}
+	return nil, nil
+}
+
+// unused returns the import paths of the unused packages of the module in
+// dir, see graph.Graph.Unused.
+func unused(dir string) (map[string]bool, error) {
+	g, mains, err := graph.ListModule(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]bool)
+	for _, n := range g.Unused(g.Container, mains...) {
+		paths[n.ID] = true
+	}
+	return paths, nil
+}
+
+// moduleRoot returns the closest directory to dir, or dir itself, that has
+// a go.mod file.
+func moduleRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
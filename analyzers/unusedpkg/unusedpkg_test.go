@@ -0,0 +1,18 @@
+package unusedpkg_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/arclabs561/pkgrank/analyzers/unusedpkg"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestUnused(t *testing.T) {
+	// The main package and the package it imports are used.
+	dir, err := filepath.Abs(filepath.Join("testdata", "m"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, dir, unusedpkg.Analyzer, "example.com/m/...")
+}
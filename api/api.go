@@ -0,0 +1,300 @@
+// Package api serves queries of the dependency graphs of packages over HTTP,
+// caching the analysis of each package.
+//
+// Every endpoint takes the package to analyze as the pkg query parameter,
+// which may have an @version suffix:
+//
+//	/rank?pkg=&n=          packages by PageRank, the top n if positive
+//	/graph?pkg=&format=    the graph in a graph.Format, json by default, or
+//	                       proto with an Accept of application/x-protobuf
+//	/path?pkg=&src=&dst=&k= up to k shortest import paths, src defaults to pkg,
+//	                       with k between 1, the default, and MaxPaths
+//	/cycles?pkg=           strongly connected components of several packages
+//
+// A Server with a Graph function serves that graph instead, ignoring pkg.
+package api
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/rs/zerolog"
+)
+
+// protoContentType is the media type of graphs in graph.FormatProto.
+const protoContentType = "application/x-protobuf"
+
+// MaxPaths is the largest number of paths that /path returns, since
+// enumerating them takes time exponential in the size of the graph.
+const MaxPaths = 100
+
+// Defaults of the limits of a Server returned by NewServer.
+const (
+	// DefaultMaxPackages is the default of Server.MaxPackages.
+	DefaultMaxPackages = 64
+	// DefaultMaxLoads is the default of Server.MaxLoads.
+	DefaultMaxLoads = 4
+)
+
+// Server is an http.Handler serving the API.
+type Server struct {
+	// Load analyzes a package.
+	Load func(pkg string) (*graph.Graph, error)
+	// Graph, if not nil, returns the graph of every request instead of
+	// Load, e.g. to serve the latest analysis of a single package.
+	Graph func() *graph.Graph
+	// TTL is how long an analysis is cached for, forever if zero.
+	TTL time.Duration
+	// MaxPackages is the number of analyses cached, evicting the least
+	// recently used beyond it, unbounded if non-positive.
+	MaxPackages int
+	// MaxLoads is the number of analyses run at once, since each fetches
+	// the modules of a package, unbounded if non-positive.
+	MaxLoads int
+	// Logger logs analyses and errors, zerolog.DefaultContextLogger if nil.
+	Logger *zerolog.Logger
+
+	mux   *http.ServeMux
+	mu    sync.Mutex
+	cache map[string]*list.Element // of *analysis in lru
+	lru   list.List                // most recently used first
+	loads chan struct{}
+}
+
+// analysis is a cached analysis of a package, which is done once loading.
+type analysis struct {
+	pkg    string
+	done   chan struct{}
+	g      *graph.Graph
+	err    error
+	loaded time.Time
+}
+
+// NewServer returns a Server which analyzes packages with
+// graph.TransitiveGraph and caches analyses for ttl, forever if zero, up to
+// DefaultMaxPackages of them and DefaultMaxLoads at once.
+func NewServer(ttl time.Duration) *Server {
+	s := &Server{
+		Load:        graph.TransitiveGraph,
+		TTL:         ttl,
+		MaxPackages: DefaultMaxPackages,
+		MaxLoads:    DefaultMaxLoads,
+		mux:         http.NewServeMux(),
+		cache:       make(map[string]*list.Element),
+	}
+	s.mux.HandleFunc("/rank", s.handleRank)
+	s.mux.HandleFunc("/graph", s.handleGraph)
+	s.mux.HandleFunc("/path", s.handlePath)
+	s.mux.HandleFunc("/cycles", s.handleCycles)
+	return s
+}
+
+// logger returns s.Logger or its default.
+func (s *Server) logger() *zerolog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return zerolog.Ctx(context.Background())
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// graph returns the cached analysis of pkg, analyzing it if there is none or
+// it has expired. Concurrent requests for the same package share an analysis.
+func (s *Server) graph(pkg string) (*graph.Graph, error) {
+	s.mu.Lock()
+	var a *analysis
+	elem, ok := s.cache[pkg]
+	if ok {
+		a = elem.Value.(*analysis)
+		select {
+		case <-a.done:
+			if a.err != nil || (s.TTL > 0 && time.Since(a.loaded) > s.TTL) {
+				s.lru.Remove(elem)
+				delete(s.cache, pkg)
+				ok = false
+			}
+		default:
+		}
+	}
+	if ok {
+		s.lru.MoveToFront(elem)
+	} else {
+		a = &analysis{pkg: pkg, done: make(chan struct{})}
+		s.cache[pkg] = s.lru.PushFront(a)
+		s.evict()
+		if s.loads == nil && s.MaxLoads > 0 {
+			s.loads = make(chan struct{}, s.MaxLoads)
+		}
+		go s.analyze(a, s.loads)
+	}
+	s.mu.Unlock()
+	<-a.done
+	return a.g, a.err
+}
+
+// evict removes the least recently used analyses beyond s.MaxPackages.
+// Requests waiting for an evicted analysis still get it.
+func (s *Server) evict() {
+	for s.MaxPackages > 0 && s.lru.Len() > s.MaxPackages {
+		elem := s.lru.Back()
+		s.lru.Remove(elem)
+		delete(s.cache, elem.Value.(*analysis).pkg)
+	}
+}
+
+// analyze loads the analysis a, holding one of loads, if not nil, meanwhile.
+func (s *Server) analyze(a *analysis, loads chan struct{}) {
+	defer close(a.done)
+	if loads != nil {
+		loads <- struct{}{}
+		defer func() { <-loads }()
+	}
+	s.logger().Info().Str("pkg", a.pkg).Msg("analyzing")
+	a.g, a.err = s.Load(a.pkg)
+	a.loaded = time.Now()
+}
+
+// query returns the graph of the request's pkg, or of s.Graph if set, or
+// writes an error and returns nil.
+func (s *Server) query(w http.ResponseWriter, r *http.Request) *graph.Graph {
+	if s.Graph != nil {
+		return s.Graph()
+	}
+	pkg := r.URL.Query().Get("pkg")
+	if pkg == "" {
+		http.Error(w, "missing pkg", http.StatusBadRequest)
+		return nil
+	}
+	g, err := s.graph(pkg)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to analyze %s: %v", pkg, err), http.StatusInternalServerError)
+		return nil
+	}
+	return g
+}
+
+// intParam returns the named integer query parameter, or def if missing.
+func intParam(r *http.Request, name string, def int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return v, nil
+}
+
+// Rank is the PageRank score of a package.
+type Rank struct {
+	Package string  `json:"package"`
+	Score   float64 `json:"score"`
+}
+
+func (s *Server) handleRank(w http.ResponseWriter, r *http.Request) {
+	n, err := intParam(r, "n", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	g := s.query(w, r)
+	if g == nil {
+		return
+	}
+	results, err := graph.Centrality(*g, graph.PageRankCentrality, graph.WithTop(n))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ranks := make([]Rank, len(results))
+	for i, r := range results {
+		ranks[i] = Rank{Package: r.Node.ID, Score: r.Score}
+	}
+	s.writeJSON(w, ranks)
+}
+
+func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	format := graph.FormatJSON
+	if r.Header.Get("Accept") == protoContentType {
+		format = graph.FormatProto
+	}
+	if raw := r.URL.Query().Get("format"); raw != "" {
+		var err error
+		if format, err = graph.ParseFormat(raw); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	g := s.query(w, r)
+	if g == nil {
+		return
+	}
+	if format == graph.FormatProto {
+		w.Header().Set("Content-Type", protoContentType)
+	}
+	if err := graph.Write(w, g, format); err != nil {
+		s.logger().Error().Err(err).Msg("failed to write graph")
+	}
+}
+
+func (s *Server) handlePath(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	src, dst := q.Get("src"), q.Get("dst")
+	if dst == "" {
+		http.Error(w, "missing dst", http.StatusBadRequest)
+		return
+	}
+	k, err := intParam(r, "k", 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if k <= 0 {
+		// Graph.Paths would enumerate every path.
+		http.Error(w, "invalid k: must be positive", http.StatusBadRequest)
+		return
+	}
+	k = min(k, MaxPaths)
+	g := s.query(w, r)
+	if g == nil {
+		return
+	}
+	if src == "" {
+		src = g.Container
+	}
+	s.writeJSON(w, g.Paths(graph.NodeKey{ID: src}, graph.NodeKey{ID: dst}, k))
+}
+
+func (s *Server) handleCycles(w http.ResponseWriter, r *http.Request) {
+	g := s.query(w, r)
+	if g == nil {
+		return
+	}
+	cycles := [][]graph.NodeKey{}
+	for _, scc := range g.StronglyConnectedComponents() {
+		if len(scc) > 1 {
+			cycles = append(cycles, scc)
+		}
+	}
+	s.writeJSON(w, cycles)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		s.logger().Error().Err(err).Msg("failed to write json")
+	}
+}
@@ -0,0 +1,125 @@
+package api_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/arclabs561/pkgrank/api"
+	"github.com/arclabs561/pkgrank/graph"
+)
+
+// testGraph returns the graph pkg -> x -> y of the container pkg.
+func testGraph(pkg string) *graph.Graph {
+	g := &graph.Graph{Container: pkg}
+	g.AddEdge(graph.NewDirectedEdge(pkg, pkg, "x"))
+	g.AddEdge(graph.NewDirectedEdge(pkg, "x", "y"))
+	return g
+}
+
+// stubServer returns a server whose loads return testGraph, counting them by
+// package.
+func stubServer() (*api.Server, map[string]int) {
+	var mu sync.Mutex
+	loads := make(map[string]int)
+	s := api.NewServer(0)
+	s.Load = func(pkg string) (*graph.Graph, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loads[pkg]++
+		if pkg == "broken" {
+			return nil, errors.New("no such module")
+		}
+		return testGraph(pkg), nil
+	}
+	return s, loads
+}
+
+// get serves a GET of target, returning its status code and decoding a JSON
+// body into v if not nil.
+func get(t *testing.T, h http.Handler, target string, v interface{}) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	if v != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s: %v", target, err)
+		}
+	}
+	return w.Code
+}
+
+func TestServerRank(t *testing.T) {
+	s, _ := stubServer()
+	var ranks []api.Rank
+	if code := get(t, s, "/rank?pkg=a&n=1", &ranks); code != http.StatusOK {
+		t.Fatalf("got status %d", code)
+	}
+	if len(ranks) != 1 || ranks[0].Package != "y" {
+		t.Errorf("got %v, want y first", ranks)
+	}
+	if code := get(t, s, "/rank", nil); code != http.StatusBadRequest {
+		t.Errorf("got status %d without pkg, want %d", code, http.StatusBadRequest)
+	}
+	if code := get(t, s, "/rank?pkg=broken", nil); code != http.StatusInternalServerError {
+		t.Errorf("got status %d for a failed analysis, want %d", code, http.StatusInternalServerError)
+	}
+}
+
+func TestServerPath(t *testing.T) {
+	s, _ := stubServer()
+	for _, tt := range []struct {
+		target string
+		code   int
+		paths  int
+	}{
+		{"/path?pkg=a&dst=y", http.StatusOK, 1},
+		{"/path?pkg=a&dst=y&k=1000000", http.StatusOK, 1},
+		{"/path?pkg=a&dst=y&k=0", http.StatusBadRequest, 0},
+		{"/path?pkg=a&dst=y&k=-1", http.StatusBadRequest, 0},
+		{"/path?pkg=a&dst=y&k=x", http.StatusBadRequest, 0},
+		{"/path?pkg=a", http.StatusBadRequest, 0},
+	} {
+		var paths []graph.Path
+		if code := get(t, s, tt.target, &paths); code != tt.code {
+			t.Errorf("%s: got status %d, want %d", tt.target, code, tt.code)
+		}
+		if len(paths) != tt.paths {
+			t.Errorf("%s: got %d paths, want %d", tt.target, len(paths), tt.paths)
+		}
+	}
+}
+
+func TestServerCache(t *testing.T) {
+	s, loads := stubServer()
+	s.MaxPackages = 2
+	for _, pkg := range []string{"a", "a", "b", "a", "c", "a", "b", "broken", "broken"} {
+		get(t, s, "/cycles?pkg="+pkg, nil)
+	}
+	// b is evicted by c, being used less recently than a, and failed
+	// analyses are retried.
+	want := map[string]int{"a": 1, "b": 2, "c": 1, "broken": 2}
+	for pkg, n := range want {
+		if loads[pkg] != n {
+			t.Errorf("%s loaded %d times, want %d", pkg, loads[pkg], n)
+		}
+	}
+}
+
+func TestServerGraph(t *testing.T) {
+	s, loads := stubServer()
+	s.Graph = func() *graph.Graph { return testGraph("a") }
+	var paths []graph.Path
+	if code := get(t, s, "/path?dst=y", &paths); code != http.StatusOK {
+		t.Fatalf("got status %d", code)
+	}
+	if len(paths) != 1 || paths[0].Nodes[0].ID != "a" {
+		t.Errorf("got %v, want a path from a", paths)
+	}
+	if len(loads) != 0 {
+		t.Errorf("got loads %v, want none", loads)
+	}
+}
@@ -0,0 +1,187 @@
+// Package cache implements a disk cache of analysis results, keyed by a hash
+// of everything that a result depends on, like the go build cache.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Key identifies a cache entry.
+type Key [sha256.Size]byte
+
+func (k Key) String() string {
+	return hex.EncodeToString(k[:])
+}
+
+// Hash computes a Key from everything that an entry depends on.
+type Hash struct {
+	h hash.Hash
+}
+
+// NewHash returns a new Hash, namespaced by the name and version of what is
+// cached, so that a change in either invalidates every entry.
+func NewHash(name string, version int) *Hash {
+	h := &Hash{h: sha256.New()}
+	h.String(fmt.Sprintf("%s v%d", name, version))
+	return h
+}
+
+// String adds s to the hash.
+func (h *Hash) String(s string) {
+	// Prefix the length so that consecutive strings are not ambiguous.
+	fmt.Fprintf(h.h, "%d:%s", len(s), s)
+}
+
+// File adds the name and contents of the named file to the hash.
+func (h *Hash) File(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	h.String(filepath.Base(name))
+	fmt.Fprintf(h.h, "%d:", info.Size())
+	_, err = io.Copy(h.h, f)
+	return err
+}
+
+// Sum returns the key of everything added to the hash.
+func (h *Hash) Sum() Key {
+	var k Key
+	h.h.Sum(k[:0])
+	return k
+}
+
+// Cache is a directory of cache entries.
+type Cache struct {
+	dir string
+}
+
+// DefaultDir returns the default cache directory, in the user's cache
+// directory.
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "pkgrank")
+}
+
+// Open opens the cache in dir, creating it if needed.
+func Open(dir string) (*Cache, error) {
+	if dir == "" {
+		return nil, errors.New("no cache directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// path returns the file of an entry, sharded by the first byte of its key.
+func (c *Cache) path(k Key) string {
+	s := k.String()
+	return filepath.Join(c.dir, s[:2], s)
+}
+
+// Get returns the data of the entry with the given key, and whether there is
+// any.
+func (c *Cache) Get(k Key) ([]byte, bool) {
+	name := c.path(k)
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, false
+	}
+	// Mark the entry as used, for Trim.
+	now := time.Now()
+	_ = os.Chtimes(name, now, now)
+	return data, true
+}
+
+// Put stores data as the entry with the given key. The entry is written to a
+// temporary file first, so that concurrent readers never see partial data.
+func (c *Cache) Put(k Key, data []byte) error {
+	name := c.path(k)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), "*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Trim removes the entries that have not been used for maxAge, and the
+// temporary files of Puts left over as long. It only visits the shard
+// directories of entries, leaving alone other files of the cache directory,
+// e.g. the module cache of the sandbox or installed binaries.
+func (c *Cache) Trim(maxAge time.Duration) error {
+	cutoff := time.Now().Add(-maxAge)
+	shards, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, shard := range shards {
+		if !shard.IsDir() || !isHex(shard.Name(), 1) {
+			continue
+		}
+		dir := filepath.Join(c.dir, shard.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, d := range entries {
+			name := d.Name()
+			isEntry := isHex(name, sha256.Size) && name[:2] == shard.Name()
+			if d.IsDir() || !isEntry && filepath.Ext(name) != ".tmp" {
+				continue
+			}
+			info, err := d.Info()
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // removed concurrently
+			} else if err != nil {
+				return err
+			}
+			if info.ModTime().Before(cutoff) {
+				if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isHex reports whether s is the lower-case hex encoding of n bytes, like the
+// names of the files and shard directories of entries.
+func isHex(s string, n int) bool {
+	if len(s) != 2*n {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && strings.ToLower(s) == s
+}
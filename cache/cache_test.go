@@ -0,0 +1,155 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arclabs561/pkgrank/cache"
+)
+
+func TestCachePutGet(t *testing.T) {
+	c, err := cache.Open(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := cache.NewHash("test", 1).Sum()
+	if _, ok := c.Get(k); ok {
+		t.Fatal("got an entry of an empty cache")
+	}
+	for _, data := range []string{"first", "second"} {
+		if err := c.Put(k, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		got, ok := c.Get(k)
+		if !ok || string(got) != data {
+			t.Errorf("got %q, %t, want %q", got, ok, data)
+		}
+	}
+	if _, err := cache.Open(""); err == nil {
+		t.Error("expected an error without a directory")
+	}
+}
+
+func TestCacheTrim(t *testing.T) {
+	dir := t.TempDir()
+	c, err := cache.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, used := cache.NewHash("stale", 1).Sum(), cache.NewHash("used", 1).Sum()
+	for _, k := range []cache.Key{stale, used} {
+		if err := c.Put(k, []byte(k.String())); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-48 * time.Hour)
+		s := k.String()
+		if err := os.Chtimes(filepath.Join(dir, s[:2], s), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Getting an entry marks it as used.
+	if _, ok := c.Get(used); !ok {
+		t.Fatal("missing entry")
+	}
+	if err := c.Trim(24 * time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(stale); ok {
+		t.Error("stale entry was not trimmed")
+	}
+	if _, ok := c.Get(used); !ok {
+		t.Error("used entry was trimmed")
+	}
+}
+
+func TestCacheTrimSandbox(t *testing.T) {
+	// The default cache directory also holds the module cache of the
+	// sandbox and installed depgraph binaries, whose files keep old
+	// modification times.
+	dir := t.TempDir()
+	c, err := cache.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := cache.NewHash("stale", 1).Sum()
+	if err := c.Put(k, []byte("stale")); err != nil {
+		t.Fatal(err)
+	}
+	s := k.String()
+	files := []string{
+		filepath.Join("sandbox", "gopath", "pkg", "mod", "example.com", "m@v1.0.0", "m.go"),
+		filepath.Join("sandbox", "gocache", "ab", "abcd-d"),
+		filepath.Join("bin", "v1.0.0", "depgraph"),
+		filepath.Join(s[:2], "notes.txt"),
+		"README",
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range append(files, filepath.Join(s[:2], s), filepath.Join(s[:2], "123.tmp")) {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(name, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Trim(24 * time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was trimmed: %v", name, err)
+		}
+	}
+	for _, name := range []string{filepath.Join(s[:2], s), filepath.Join(s[:2], "123.tmp")} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("stale %s was not trimmed", name)
+		}
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.go")
+	write := func(content string) {
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key := func(name string, version int, strs ...string) cache.Key {
+		h := cache.NewHash(name, version)
+		for _, s := range strs {
+			h.String(s)
+		}
+		if err := h.File(file); err != nil {
+			t.Fatal(err)
+		}
+		return h.Sum()
+	}
+	write("package a")
+	base := key("test", 1, "ab", "c")
+	if key("test", 1, "ab", "c") != base {
+		t.Error("same inputs hash to different keys")
+	}
+	for name, k := range map[string]cache.Key{
+		"name":    key("other", 1, "ab", "c"),
+		"version": key("test", 2, "ab", "c"),
+		"strings": key("test", 1, "a", "bc"),
+	} {
+		if k == base {
+			t.Errorf("changing the %s keeps the key", name)
+		}
+	}
+	write("package b")
+	if key("test", 1, "ab", "c") == base {
+		t.Error("changing the file keeps the key")
+	}
+	if err := cache.NewHash("test", 1).File(filepath.Join(dir, "missing.go")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
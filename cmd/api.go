@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/arclabs561/pkgrank/api"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var apiCmd = &cobra.Command{
+	Use:   "api",
+	Short: "Serve an HTTP API to query the dependency graphs of packages.",
+	Args:  cobra.NoArgs,
+	RunE:  runAPI,
+}
+
+func init() {
+	apiCmd.Flags().String("addr", "localhost:8081",
+		"address to listen on.")
+	apiCmd.Flags().Duration("ttl", 0,
+		"how long to cache the analysis of a package, forever if zero.")
+	apiCmd.Flags().Int("max-packages", api.DefaultMaxPackages,
+		"number of analyses to cache, evicting the least recently used, unbounded if non-positive.")
+	apiCmd.Flags().Int("max-loads", api.DefaultMaxLoads,
+		"number of packages to analyze at once, unbounded if non-positive.")
+	rootCmd.AddCommand(apiCmd)
+}
+
+func runAPI(cmd *cobra.Command, args []string) error {
+	addr, _ := cmd.Flags().GetString("addr")
+	ttl, _ := cmd.Flags().GetDuration("ttl")
+	maxPackages, _ := cmd.Flags().GetInt("max-packages")
+	maxLoads, _ := cmd.Flags().GetInt("max-loads")
+
+	s := api.NewServer(ttl)
+	s.MaxPackages, s.MaxLoads = maxPackages, maxLoads
+	log.Info().Str("addr", addr).Msg("serving api")
+	return http.ListenAndServe(addr, s)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var binaryCmd = &cobra.Command{
+	Use:   "binary <file>",
+	Short: "List or write the graph of the modules built into a compiled Go binary.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBinary,
+}
+
+func init() {
+	binaryCmd.Flags().String("format", "",
+		"format to write the graph in, as for graph, or list the modules if empty.")
+	completeValues(binaryCmd, "format", graphFormats()...)
+	binaryCmd.Flags().StringP("output", "o", "",
+		"file to write the graph to, stdout if empty.")
+	addEnrichFlags(binaryCmd)
+	rootCmd.AddCommand(binaryCmd)
+}
+
+func runBinary(cmd *cobra.Command, args []string) error {
+	rawFormat, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	g, err := graph.BinaryGraph(args[0])
+	if err != nil {
+		return err
+	}
+	if err := enrich(cmd, g); err != nil {
+		return err
+	}
+	if rawFormat != "" {
+		format, err := graph.ParseFormat(rawFormat)
+		if err != nil {
+			return err
+		}
+		if format == graph.FormatCycloneDX || format == graph.FormatSPDX {
+			if err := g.AddLicenses(); err != nil {
+				return err
+			}
+		}
+		return graph.WriteFile(output, g, format)
+	}
+	for _, key := range g.SortedNodes() {
+		data := g.Nodes[key].Data
+		line := data.ModuleVersion()
+		if key.ID == g.Container {
+			line = fmt.Sprintf("%s (main package of %s)", key.ID, line)
+		}
+		if replace, ok := data.Attrs.String(graph.AttrReplace); ok {
+			line += " => " + replace
+		}
+		if latest, ok := data.Attrs.String(graph.AttrLatest); ok && latest != data.Version {
+			line += " (latest " + latest + ")"
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check [dir]",
+	Short: "Fail on new cycles, dependencies or metric regressions since a baseline.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runCheck,
+}
+
+func init() {
+	checkCmd.Flags().String("baseline", "pkgrank-baseline.json",
+		"baseline file to check against.")
+	checkCmd.Flags().Bool("write-baseline", false,
+		"whether to write the current state to the baseline file instead of checking.")
+	checkCmd.Flags().StringSlice("max-increase", nil,
+		"metric=amount by which a stats metric may increase, e.g. longestChain=0.")
+	addFindingsFlags(checkCmd, true)
+	rootCmd.AddCommand(checkCmd)
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	baselineFile, _ := cmd.Flags().GetString("baseline")
+	writeBaseline, _ := cmd.Flags().GetBool("write-baseline")
+	rawMaxIncrease, _ := cmd.Flags().GetStringSlice("max-increase")
+
+	maxIncrease := make(map[string]float64, len(rawMaxIncrease))
+	for _, raw := range rawMaxIncrease {
+		name, amount, ok := strings.Cut(raw, "=")
+		if !ok {
+			return fmt.Errorf("invalid --max-increase %q, want metric=amount", raw)
+		}
+		v, err := strconv.ParseFloat(amount, 64)
+		if err != nil {
+			return fmt.Errorf("invalid --max-increase %q: %w", raw, err)
+		}
+		maxIncrease[name] = v
+	}
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	g, _, err := graph.ListModule(dir)
+	if err != nil {
+		return err
+	}
+	current := withoutTests(g).Baseline()
+	if writeBaseline {
+		log.Info().Str("file", baselineFile).Int("edges", len(current.Edges)).Msg("writing baseline")
+		return current.WriteFile(baselineFile)
+	}
+	baseline, err := graph.ReadBaseline(baselineFile)
+	if err != nil {
+		return err
+	}
+	findings, err := baseline.Regressions(current, maxIncrease)
+	if err != nil {
+		return err
+	}
+	return writeFindings(cmd, findings, g.Container)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/arclabs561/pkgrank/cache"
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var churnCmd = &cobra.Command{
+	Use:   "churn [dir]",
+	Short: "Rank the packages of a module by both their centrality and their recent git churn.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runChurn,
+}
+
+func init() {
+	churnCmd.Flags().IntP("num", "n", 16,
+		"top number of packages to show, all if non-positive.")
+	churnCmd.Flags().Duration("since", 90*24*time.Hour,
+		"how far back in the git history to count changes.")
+	churnCmd.Flags().String("cache", cache.DefaultDir(),
+		"directory to cache the git history in, disabled if empty.")
+	churnCmd.Flags().Bool("json", false,
+		"whether to print the ranked packages as JSON.")
+	rootCmd.AddCommand(churnCmd)
+}
+
+func runChurn(cmd *cobra.Command, args []string) error {
+	num, _ := cmd.Flags().GetInt("num")
+	since, _ := cmd.Flags().GetDuration("since")
+	cacheDir, _ := cmd.Flags().GetString("cache")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	var c *cache.Cache
+	if cacheDir != "" {
+		var err error
+		if c, err = cache.Open(cacheDir); err != nil {
+			log.Warn().Err(err).Msg("disabling cache")
+		}
+	}
+	root, commits, err := graph.ReadChurn(dir, time.Now().Add(-since), c)
+	if err != nil {
+		return err
+	}
+	g, _, err := graph.ListModule(dir)
+	if err != nil {
+		return err
+	}
+	if err := g.AddChurn(dir, root, commits); err != nil {
+		return err
+	}
+	ranks, err := graph.Centrality(*g, graph.PageRankCentrality)
+	if err != nil {
+		return err
+	}
+	churns := g.ChurnRanks(ranks)
+	if num > 0 && num < len(churns) {
+		churns = churns[:num]
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(churns)
+	}
+	fmt.Printf("%-6s %-8s %7s %7s %s\n", "danger", "score", "commits", "churn", "package")
+	for _, r := range churns {
+		fmt.Printf("%.4f %.6f %7d %7d %s\n", r.Danger, r.Score, r.Commits, r.Churn, r.Node)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arclabs561/pkgrank/cache"
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove the scratch directories and caches of pkgrank.",
+	Long: `Remove the scratch modules and other work directories that pkgrank left in
+the temp directory, because of --keep-workdir or because it was killed, and
+print them. With --max-age, also remove the entries of the cache that have
+not been used for that long, or with --all, the whole cache, including the
+depgraph binaries installed by the pkgrank library.`,
+	Args: cobra.NoArgs,
+	RunE: runClean,
+}
+
+func init() {
+	cleanCmd.Flags().String("cache", cache.DefaultDir(),
+		"cache directory to clean with --max-age or --all.")
+	cleanCmd.Flags().Duration("max-age", 0,
+		"remove the cache entries not used for this long, none if zero.")
+	cleanCmd.Flags().Bool("all", false,
+		"remove the whole cache directory.")
+	cleanCmd.Flags().Bool("dry-run", false,
+		"print the work directories to remove without removing anything.")
+	rootCmd.AddCommand(cleanCmd)
+}
+
+func runClean(cmd *cobra.Command, args []string) error {
+	cacheDir, _ := cmd.Flags().GetString("cache")
+	maxAge, _ := cmd.Flags().GetDuration("max-age")
+	all, _ := cmd.Flags().GetBool("all")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	dirs, err := graph.Workdirs()
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		fmt.Println(dir)
+		if dryRun {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	if dryRun || cacheDir == "" {
+		return nil
+	}
+	switch {
+	case all:
+		fmt.Println(cacheDir)
+		return os.RemoveAll(cacheDir)
+	case maxAge > 0:
+		c, err := cache.Open(cacheDir)
+		if err != nil {
+			return err
+		}
+		return c.Trim(maxAge)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+// completeValues makes shells complete the flag name of cmd with values,
+// rather than with file names. Shell completion scripts themselves are
+// written by cobra's default completion command.
+func completeValues(cmd *cobra.Command, name string, values ...string) {
+	_ = cmd.RegisterFlagCompletionFunc(name, cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// graphFormats returns the names of graph.Formats.
+func graphFormats() []string {
+	names := make([]string, len(graph.Formats))
+	for i, f := range graph.Formats {
+		names[i] = string(f)
+	}
+	return names
+}
+
+// centralityMeasures are the names of the centrality measures of packages
+// that commands taking --centrality accept.
+var centralityMeasures = []string{string(graph.PageRankCentrality), string(graph.CorenessCentrality)}
+
+// nodeClasses returns the names of graph.NodeClasses.
+func nodeClasses() []string {
+	names := make([]string, len(graph.NodeClasses))
+	for i, c := range graph.NodeClasses {
+		names[i] = string(c)
+	}
+	return names
+}
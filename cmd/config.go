@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFiles are the names of the configuration file looked up from
+// the current directory up to the root of its git repository, in order of
+// precedence within a directory. JSON being YAML, .pkgrank.json is read the
+// same way.
+var defaultConfigFiles = []string{".pkgrank.yaml", ".pkgrank.yml", ".pkgrank.json"}
+
+func init() {
+	rootCmd.PersistentFlags().String("config", "",
+		"YAML file of default flags, or "+defaultConfigFiles[0]+" in the current directory or a parent up to the repository root if empty.")
+}
+
+// config holds default values of flags, which those given on the command line
+// override. Values are strings, numbers, booleans, or lists for flags that
+// take several values, and relative paths are relative to the current
+// directory as on the command line, e.g.
+//
+//	flags:
+//	  cache: .cache/pkgrank
+//	  exclude: [example.com/m/internal/...]
+//	commands:
+//	  graph: {format: html}
+//	  lint: {policy: arch.json}
+type config struct {
+	// Flags are defaults for every command that has these flags.
+	Flags map[string]any `yaml:"flags"`
+	// Commands are defaults for the flags of each command by name, over
+	// Flags. Unlike Flags, each must be a flag of the command.
+	Commands map[string]map[string]any `yaml:"commands"`
+}
+
+// applyConfig sets the flags of cmd that were not given on the command line
+// to their defaults from the configuration file, if there is one, and returns
+// its name.
+func applyConfig(cmd *cobra.Command) (string, error) {
+	name, _ := cmd.Flags().GetString("config")
+	if name == "" {
+		var err error
+		if name, err = findConfig(); err != nil || name == "" {
+			return "", err
+		}
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config: %w", err)
+	}
+	var c config
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && err != io.EOF {
+		return "", fmt.Errorf("invalid config %s: %w", name, err)
+	}
+
+	values := make(map[string]any)
+	for flag, v := range c.Flags {
+		if cmd.Flags().Lookup(flag) != nil {
+			values[flag] = v
+		}
+	}
+	for flag, v := range c.Commands[cmd.Name()] {
+		if cmd.Flags().Lookup(flag) == nil {
+			return "", fmt.Errorf("invalid config %s: %s has no flag --%s", name, cmd.Name(), flag)
+		}
+		values[flag] = v
+	}
+	flags := make([]string, 0, len(values))
+	for flag := range values {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	for _, flag := range flags {
+		if cmd.Flags().Changed(flag) {
+			continue
+		}
+		if err := setFlag(cmd, flag, values[flag]); err != nil {
+			return "", fmt.Errorf("invalid config %s: %w", name, err)
+		}
+	}
+	return name, nil
+}
+
+// findConfig returns the nearest of defaultConfigFiles from the current
+// directory up to the root of its repository, or an empty string if there is
+// none.
+func findConfig() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	root, err := repoRoot(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, base := range defaultConfigFiles {
+			name := filepath.Join(dir, base)
+			if _, err := os.Stat(name); err == nil {
+				return name, nil
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		}
+		if dir == root || filepath.Dir(dir) == dir {
+			return "", nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// setFlag sets a flag to a value decoded from YAML, each element of a list in
+// turn.
+func setFlag(cmd *cobra.Command, flag string, v any) error {
+	var s string
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			if err := setFlag(cmd, flag, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case string:
+		s = v
+	case int:
+		s = strconv.Itoa(v)
+	case float64:
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		s = strconv.FormatBool(v)
+	default:
+		return fmt.Errorf("unsupported value of --%s: %v", flag, v)
+	}
+	if err := cmd.Flags().Set(flag, s); err != nil {
+		return fmt.Errorf("invalid value of --%s: %w", flag, err)
+	}
+	return nil
+}
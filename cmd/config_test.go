@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestApplyConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, map[string]string{
+		"config.yaml": `flags:
+  exclude: [example.com/m/a, example.com/m/b]
+  num: 4
+  min-weight: 0.5
+  tests: true
+  unknown: ignored
+commands:
+  graph:
+    format: html
+`,
+		"config.json":    `{"flags": {"num": 3}, "commands": {"graph": {"format": "json"}}}`,
+		"unknown.yaml":   "commands:\n  graph:\n    formats: html\n",
+		"malformed.yaml": "flag:\n  num: 3\n",
+	})
+	newCmd := func(name string) *cobra.Command {
+		cmd := &cobra.Command{Use: name}
+		cmd.Flags().String("config", "", "")
+		cmd.Flags().String("format", "dot", "")
+		cmd.Flags().StringSlice("exclude", nil, "")
+		cmd.Flags().Int("num", 16, "")
+		cmd.Flags().Float64("min-weight", 0, "")
+		cmd.Flags().Bool("tests", false, "")
+		return cmd
+	}
+
+	cmd := newCmd("graph")
+	if err := cmd.Flags().Parse([]string{"--config", filepath.Join(dir, "config.yaml"), "--num", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applyConfig(cmd); err != nil {
+		t.Fatal(err)
+	}
+	format, _ := cmd.Flags().GetString("format")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	num, _ := cmd.Flags().GetInt("num")
+	minWeight, _ := cmd.Flags().GetFloat64("min-weight")
+	tests, _ := cmd.Flags().GetBool("tests")
+	// Flags on the command line win over the config.
+	if format != "html" || len(exclude) != 2 || num != 2 || minWeight != 0.5 || !tests {
+		t.Errorf("got format %q, exclude %v, num %d, min-weight %g and tests %t",
+			format, exclude, num, minWeight, tests)
+	}
+
+	// JSON is read as YAML.
+	cmd = newCmd("graph")
+	if err := cmd.Flags().Parse([]string{"--config", filepath.Join(dir, "config.json")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applyConfig(cmd); err != nil {
+		t.Fatal(err)
+	}
+	format, _ = cmd.Flags().GetString("format")
+	num, _ = cmd.Flags().GetInt("num")
+	if format != "json" || num != 3 {
+		t.Errorf("got format %q and num %d, want json and 3", format, num)
+	}
+
+	// The flags of a command must exist, and so must the keys of the config.
+	for _, name := range []string{"unknown.yaml", "malformed.yaml"} {
+		cmd = newCmd("graph")
+		if err := cmd.Flags().Parse([]string{"--config", filepath.Join(dir, name)}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := applyConfig(cmd); err == nil {
+			t.Errorf("applied %s", name)
+		}
+	}
+}
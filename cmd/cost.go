@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var costCmd = &cobra.Command{
+	Use:   "cost <pkg>",
+	Short: "Show how many packages, modules and lines of code each direct dependency uniquely brings in.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runCost,
+}
+
+func init() {
+	costCmd.Flags().IntP("num", "n", 16,
+		"top number of dependencies to show, all if non-positive.")
+	costCmd.Flags().Bool("json", false,
+		"whether to print the costs as JSON.")
+	rootCmd.AddCommand(costCmd)
+}
+
+func runCost(cmd *cobra.Command, args []string) error {
+	num, _ := cmd.Flags().GetInt("num")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	costs := g.DependencyCosts(graph.NodeKey{ID: g.Container})
+	if num > 0 && num < len(costs) {
+		costs = costs[:num]
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(costs)
+	}
+	fmt.Printf("%8s %7s %8s %s\n", "packages", "modules", "loc", "module")
+	for _, c := range costs {
+		module := c.Module
+		if c.Version != "" {
+			module += "@" + c.Version
+		}
+		fmt.Printf("%8d %7d %8d %s\n", c.Packages, c.Modules, c.LOC, module)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/arclabs561/pkgrank/crawl"
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var crawlCmd = &cobra.Command{
+	Use:   "crawl [file]",
+	Short: "Rank packages across many modules, listed in a file or from the Go module index.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runCrawl,
+}
+
+func init() {
+	crawlCmd.Flags().Duration("index-since", 0,
+		"crawl the modules published to the Go module index within this duration, instead of a file.")
+	crawlCmd.Flags().Int("index-limit", 100,
+		"maximum number of entries of the Go module index to read.")
+	crawlCmd.Flags().Int("workers", runtime.NumCPU(),
+		"number of modules to analyze at once.")
+	crawlCmd.Flags().Duration("timeout", 10*time.Minute,
+		"how long analyzing a module may take, unlimited if not positive.")
+	crawlCmd.Flags().Int("retries", 3,
+		"number of times to retry a module that fails because of the module proxy.")
+	crawlCmd.Flags().Duration("interval", 0,
+		"least time between starting to analyze any two modules, to limit requests to the module proxy.")
+	crawlCmd.Flags().IntP("num", "n", 16,
+		"top number of packages to show, all if non-positive.")
+	crawlCmd.Flags().String("format", "",
+		"format to write the merged graph in, as for graph, or rank its packages if empty.")
+	completeValues(crawlCmd, "format", graphFormats()...)
+	crawlCmd.Flags().StringP("output", "o", "",
+		"file to write the merged graph to, stdout if empty.")
+	addRankFlags(crawlCmd)
+	addEnrichFlags(crawlCmd)
+	rootCmd.AddCommand(crawlCmd)
+}
+
+func runCrawl(cmd *cobra.Command, args []string) error {
+	indexSince, _ := cmd.Flags().GetDuration("index-since")
+	indexLimit, _ := cmd.Flags().GetInt("index-limit")
+	workers, _ := cmd.Flags().GetInt("workers")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	retries, _ := cmd.Flags().GetInt("retries")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	num, _ := cmd.Flags().GetInt("num")
+	rawFormat, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	var modules []string
+	switch {
+	case indexSince > 0 && len(args) > 0:
+		return fmt.Errorf("--index-since and a module list file are mutually exclusive")
+	case indexSince > 0:
+		var index crawl.Index
+		var err error
+		if modules, err = index.Modules(cmd.Context(), time.Now().Add(-indexSince), indexLimit); err != nil {
+			return err
+		}
+	case len(args) > 0:
+		var r io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		var err error
+		if modules, err = crawl.ReadList(r); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("either a module list file or --index-since is required")
+	}
+	if len(modules) == 0 {
+		return fmt.Errorf("no modules to crawl")
+	}
+
+	done := 0
+	g, err := crawl.Crawl(cmd.Context(), modules, crawl.BatchOptions{
+		Workers:  workers,
+		Timeout:  timeout,
+		Retries:  retries,
+		Interval: interval,
+		Progress: func(r crawl.Result) {
+			done++
+			event := log.Info()
+			if r.Err != nil {
+				event = log.Warn().Err(r.Err)
+			}
+			event.Str("module", r.Module).Int("attempts", r.Attempts).
+				Stringer("dur", r.Duration.Round(time.Millisecond)).
+				Msgf("analyzed %d/%d modules", done, len(modules))
+		},
+	})
+	if g == nil {
+		return err
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to analyze some modules")
+	}
+	if err := enrich(cmd, g); err != nil {
+		return err
+	}
+	if rawFormat != "" {
+		format, err := graph.ParseFormat(rawFormat)
+		if err != nil {
+			return err
+		}
+		return graph.WriteFile(output, g, format)
+	}
+	ranks, err := graph.Centrality(*g, graph.PageRankCentrality, rankOptions(cmd, num)...)
+	if err != nil {
+		return err
+	}
+	if ndjson, _ := cmd.Flags().GetBool("ndjson"); ndjson {
+		return writeJSONLines(ranks)
+	}
+	for _, r := range ranks {
+		fmt.Printf("%.6f %s\n", r.Score, r.Node)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var criticalCmd = &cobra.Command{
+	Use:   "critical <pkg>",
+	Short: "Show the packages and imports whose removal disconnects the graph.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runCritical,
+}
+
+func init() {
+	rootCmd.AddCommand(criticalCmd)
+}
+
+func runCritical(cmd *cobra.Command, args []string) error {
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println("articulation points:")
+	for _, n := range g.ArticulationPoints() {
+		fmt.Printf("  %s\n", n)
+	}
+	fmt.Println("bridges:")
+	for _, b := range g.Bridges() {
+		fmt.Printf("  %s -> %s\n", b[0], b[1])
+	}
+	return nil
+}
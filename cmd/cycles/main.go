@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/arclabs561/pkgrank/analyzers/cycles"
+	"github.com/arclabs561/pkgrank/shared"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	if err := shared.SetGlobalLogger(); err != nil {
+		log.Fatal().Err(err).Msg("failed to configure logging")
+	}
+	singlechecker.Main(cycles.Analyzer)
+}
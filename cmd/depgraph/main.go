@@ -3,10 +3,13 @@ package main
 import (
 	"github.com/arclabs561/pkgrank/analyzers/depgraph"
 	"github.com/arclabs561/pkgrank/shared"
+	"github.com/rs/zerolog/log"
 	"golang.org/x/tools/go/analysis/singlechecker"
 )
 
 func main() {
-	shared.SetGlobalLogger()
+	if err := shared.SetGlobalLogger(); err != nil {
+		log.Fatal().Err(err).Msg("failed to configure logging")
+	}
 	singlechecker.Main(depgraph.Analyzer)
 }
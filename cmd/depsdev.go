@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/arclabs561/pkgrank/cache"
+	"github.com/arclabs561/pkgrank/depsdev"
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// addEnrichFlags adds the flags of enrich to a command.
+func addEnrichFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("deps-dev", false,
+		"attach the latest version, license, scorecard and dependents of modules from deps.dev.")
+	cmd.Flags().String("cache", cache.DefaultDir(),
+		"directory to cache deps.dev responses in for the day, disabled if empty.")
+}
+
+// enrich attaches deps.dev metadata to the nodes of the graph if the flags of
+// addEnrichFlags ask for it. Modules that fail are only logged, since the
+// metadata is optional.
+func enrich(cmd *cobra.Command, g *graph.Graph) error {
+	enabled, _ := cmd.Flags().GetBool("deps-dev")
+	cacheDir, _ := cmd.Flags().GetString("cache")
+
+	if !enabled {
+		return nil
+	}
+	var client depsdev.Client
+	if cacheDir != "" {
+		c, err := cache.Open(cacheDir)
+		if err != nil {
+			log.Warn().Err(err).Msg("disabling cache")
+		}
+		client.Cache = c
+	}
+	if err := client.Enrich(cmd.Context(), g); err != nil {
+		if cmd.Context().Err() != nil {
+			return err
+		}
+		log.Warn().Err(err).Msg("failed to get some modules from deps.dev")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var dominatorsCmd = &cobra.Command{
+	Use:   "dominators <pkg>",
+	Short: "Show how much of the dependency tree each dependency exclusively pulls in.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDominators,
+}
+
+func init() {
+	dominatorsCmd.Flags().IntP("num", "n", 16,
+		"top number of packages to show, all if non-positive.")
+	dominatorsCmd.Flags().Bool("direct", false,
+		"whether to only show packages immediately dominated by the root.")
+	rootCmd.AddCommand(dominatorsCmd)
+}
+
+func runDominators(cmd *cobra.Command, args []string) error {
+	num, _ := cmd.Flags().GetInt("num")
+	direct, _ := cmd.Flags().GetBool("direct")
+
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	root := graph.NodeKey{ID: g.Container}
+	tree := g.Dominators(root)
+	nodes := tree.Dominated(root)
+	if direct {
+		nodes = tree.Children(root)
+	}
+	owned := make(map[graph.NodeKey]int, len(nodes))
+	for _, n := range nodes {
+		owned[n] = tree.Owned(n)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return owned[nodes[i]] > owned[nodes[j]]
+	})
+	for i, n := range nodes {
+		if num > 0 && i >= num {
+			break
+		}
+		idom, _ := tree.Idom(n)
+		fmt.Printf("%d %s (via %s)\n", owned[n], n, idom)
+	}
+	return nil
+}
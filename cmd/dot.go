@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var dotCmd = &cobra.Command{
+	Use:   "dot <file>",
+	Short: "Rank or convert a graph read from a Graphviz DOT file, or stdin if -.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDot,
+}
+
+func init() {
+	dotCmd.Flags().IntP("num", "n", 16,
+		"top number of nodes to show, all if non-positive.")
+	dotCmd.Flags().String("format", "",
+		"format to write the graph in, as for graph, or rank its nodes if empty.")
+	completeValues(dotCmd, "format", graphFormats()...)
+	dotCmd.Flags().StringP("output", "o", "",
+		"file to write the graph to, stdout if empty.")
+	addRankFlags(dotCmd)
+	rootCmd.AddCommand(dotCmd)
+}
+
+func runDot(cmd *cobra.Command, args []string) error {
+	num, _ := cmd.Flags().GetInt("num")
+	rawFormat, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	g, err := graph.ReadDOT(r)
+	if err != nil {
+		return err
+	}
+	if rawFormat != "" {
+		format, err := graph.ParseFormat(rawFormat)
+		if err != nil {
+			return err
+		}
+		return graph.WriteFile(output, g, format)
+	}
+	ranks, err := graph.Centrality(*g, graph.PageRankCentrality, rankOptions(cmd, num)...)
+	if err != nil {
+		return err
+	}
+	if ndjson, _ := cmd.Flags().GetBool("ndjson"); ndjson {
+		return writeJSONLines(ranks)
+	}
+	for _, r := range ranks {
+		fmt.Printf("%.6f %s\n", r.Score, r.Node)
+	}
+	return nil
+}
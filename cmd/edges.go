@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var edgesCmd = &cobra.Command{
+	Use:   "edges <pkg>",
+	Short: "Stream the edges of a package's dependency graph as JSON lines.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runEdges,
+}
+
+func init() {
+	edgesCmd.Flags().Bool("progress", false,
+		"whether to report the number of edges streamed on stderr.")
+	rootCmd.AddCommand(edgesCmd)
+}
+
+func runEdges(cmd *cobra.Command, args []string) error {
+	progress, _ := cmd.Flags().GetBool("progress")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	enc := json.NewEncoder(os.Stdout)
+	n := 0
+	err := graph.TransitiveEdgesStream(ctx, args[0], func(edge *graph.DirectedEdge) error {
+		n++
+		if progress && n%100 == 0 {
+			fmt.Fprintf(os.Stderr, "\r%d edges", n)
+		}
+		return enc.Encode(edge)
+	})
+	if progress {
+		fmt.Fprintf(os.Stderr, "\r%d edges\n", n)
+	}
+	return err
+}
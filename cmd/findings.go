@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+// addFindingsFlags adds the flags shared by commands reporting findings, with
+// the given default of whether to fail on any finding.
+func addFindingsFlags(cmd *cobra.Command, exitCode bool) {
+	cmd.Flags().String("format", "text",
+		"format of the findings: text or sarif.")
+	completeValues(cmd, "format", "text", "sarif")
+	cmd.Flags().Bool("exit-code", exitCode,
+		"whether to exit with a non-zero status if there are any findings.")
+}
+
+// writeFindings reports the findings in the format of the command's flags,
+// with locations relative to the module at root, and returns an error if the
+// command should fail because of them.
+func writeFindings(cmd *cobra.Command, findings []graph.Finding, root string) error {
+	format, _ := cmd.Flags().GetString("format")
+	exitCode, _ := cmd.Flags().GetBool("exit-code")
+
+	switch format {
+	case "text":
+		for _, f := range findings {
+			if len(f.Locations) > 0 {
+				fmt.Printf("%s:%d: %v\n", f.Locations[0].File, f.Locations[0].Line, f)
+			} else {
+				fmt.Println(f)
+			}
+		}
+	case "sarif":
+		if err := graph.WriteSARIF(os.Stdout, findings, root); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported findings format: %s", format)
+	}
+	if exitCode && len(findings) > 0 {
+		return fmt.Errorf("found %d problems", len(findings))
+	}
+	return nil
+}
+
+// withoutTests returns the graph without imports only made by tests. Tests
+// may import the packages that import the package under test, which is not a
+// cycle that the go command rejects.
+func withoutTests(g *graph.Graph) *graph.Graph {
+	var testEdges [][2]graph.NodeKey
+	for _, edge := range g.SortedEdges() {
+		if edge, ok := edge.(*graph.DirectedEdge); ok && edge.Kind.Has(graph.EdgeKindTest) {
+			testEdges = append(testEdges, [2]graph.NodeKey{edge.Src, edge.Dst})
+		}
+	}
+	return g.Without(nil, testEdges)
+}
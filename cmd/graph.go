@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph <pkg>",
+	Short: "Write or render the dependency graph of a package.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runGraph,
+}
+
+func init() {
+	graphCmd.Flags().String("render", "",
+		"graphviz image format to render, e.g. svg or png, or write --format if empty.")
+	completeValues(graphCmd, "render", "svg", "png", "pdf")
+	graphCmd.Flags().String("format", string(graph.FormatDOT),
+		"format of the graph if not rendered: edgelist, json, ndjson lines of edges, proto, dot, html, cypher, pajek, an mtx adjacency matrix, parquet tables in the --output directory, or a cyclonedx or spdx SBOM.")
+	completeValues(graphCmd, "format", graphFormats()...)
+	graphCmd.Flags().String("layout", "dot",
+		"graphviz layout program to render with, e.g. dot or sfdp.")
+	completeValues(graphCmd, "layout", "dot", "neato", "fdp", "sfdp", "circo", "twopi")
+	graphCmd.Flags().String("collapse", "",
+		"collapse packages into their module, or into their first N path elements if a number.")
+	completeValues(graphCmd, "collapse", "module")
+	graphCmd.Flags().Float64("min-weight", 0,
+		"drop imports weighing less, i.e. in fewer packages' graphs, and the packages left isolated.")
+	graphCmd.Flags().Float64("top-percent", 100,
+		"only keep this percentage of the heaviest imports, and the packages left with any.")
+	graphCmd.Flags().StringArray("configs", nil,
+		"build configuration goos/goarch[:tag,...] to construct the graph under, repeated to merge the graphs of several, or the current one if none.")
+	graphCmd.Flags().StringP("output", "o", "",
+		"file to write to, stdout if empty, or graph.<render> when rendering, or directory of parquet tables.")
+	addViewFlags(graphCmd)
+	addEnrichFlags(graphCmd)
+	rootCmd.AddCommand(graphCmd)
+}
+
+func runGraph(cmd *cobra.Command, args []string) error {
+	render, _ := cmd.Flags().GetString("render")
+	rawFormat, _ := cmd.Flags().GetString("format")
+	layout, _ := cmd.Flags().GetString("layout")
+	output, _ := cmd.Flags().GetString("output")
+	collapse, _ := cmd.Flags().GetString("collapse")
+	minWeight, _ := cmd.Flags().GetFloat64("min-weight")
+	topPercent, _ := cmd.Flags().GetFloat64("top-percent")
+	rawConfigs, _ := cmd.Flags().GetStringArray("configs")
+
+	if topPercent <= 0 || topPercent > 100 {
+		return fmt.Errorf("invalid --top-percent %g, must be in (0, 100]", topPercent)
+	}
+
+	format, err := graph.ParseFormat(rawFormat)
+	if err != nil {
+		return err
+	}
+	configs := make([]graph.BuildConfig, len(rawConfigs))
+	for i, raw := range rawConfigs {
+		if configs[i], err = graph.ParseBuildConfig(raw); err != nil {
+			return err
+		}
+	}
+	g, err := loadGraph(cmd, args[0], configs...)
+	if err != nil {
+		return err
+	}
+	if g, err = applyView(cmd, g); err != nil {
+		return err
+	}
+	switch collapse {
+	case "":
+	case "module":
+		g = g.CollapseBy(g.ByModule())
+	default:
+		n, err := strconv.Atoi(collapse)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid --collapse %q, must be module or a positive number", collapse)
+		}
+		g = g.CollapseBy(graph.ByPathPrefix(n))
+	}
+	if minWeight > 0 || topPercent < 100 {
+		g = g.Prune(minWeight, topPercent)
+	}
+	if err := enrich(cmd, g); err != nil {
+		return err
+	}
+	if format == graph.FormatCycloneDX || format == graph.FormatSPDX {
+		if err := g.AddLicenses(); err != nil {
+			return err
+		}
+	}
+	if render == "" {
+		return graph.WriteFile(output, g, format)
+	}
+	if output == "" {
+		output = "graph." + render
+	}
+	if err := graph.Render(g, layout, render, output); err != nil {
+		return err
+	}
+	fmt.Println(output)
+	return nil
+}
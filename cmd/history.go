@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arclabs561/pkgrank/cache"
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [dir]",
+	Short: "Show how the graph of a module and the ranks of its packages changed across git tags.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runHistory,
+}
+
+func init() {
+	historyCmd.Flags().String("tags", "",
+		"range of semantic version tags to analyze, from..to, both inclusive and either open if empty.")
+	historyCmd.Flags().IntP("num", "n", 16,
+		"top number of movers to show, all if non-positive.")
+	historyCmd.Flags().String("cache", cache.DefaultDir(),
+		"directory to cache the graph of each tagged commit in, disabled if empty.")
+	historyCmd.Flags().Bool("json", false,
+		"whether to print the time series and movers as JSON.")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	rawTags, _ := cmd.Flags().GetString("tags")
+	num, _ := cmd.Flags().GetInt("num")
+	cacheDir, _ := cmd.Flags().GetString("cache")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	from, to, ok := strings.Cut(rawTags, "..")
+	if !ok {
+		return fmt.Errorf("invalid --tags %q, want from..to", rawTags)
+	}
+	tags, err := graph.Tags(dir, from, to)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("no tags in %s", rawTags)
+	}
+	var c *cache.Cache
+	if cacheDir != "" {
+		if c, err = cache.Open(cacheDir); err != nil {
+			log.Warn().Err(err).Msg("disabling cache")
+		}
+	}
+	revs := make([]*graph.Revision, len(tags))
+	for i, tag := range tags {
+		log.Info().Str("tag", tag).Msg("analyzing")
+		if revs[i], err = graph.ReadRevision(dir, tag, c); err != nil {
+			return err
+		}
+	}
+	points := graph.History(revs)
+	movers := graph.TopMovers(points, num)
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Points []graph.HistoryPoint `json:"points"`
+			Movers []graph.RankShift    `json:"movers"`
+		}{points, movers})
+	}
+	fmt.Printf("%-12s %-10s %5s %5s %6s %9s %9s\n", "tag", "date", "nodes", "edges", "cycles", "+/-nodes", "+/-edges")
+	for _, p := range points {
+		fmt.Printf("%-12s %-10s %5d %5d %6d %9s %9s\n", p.Rev, p.Time.Format(time.DateOnly), p.Order, p.Size, p.Cycles,
+			fmt.Sprintf("+%d/-%d", p.AddedNodes, p.RemovedNodes), fmt.Sprintf("+%d/-%d", p.AddedEdges, p.RemovedEdges))
+	}
+	if len(movers) == 0 {
+		return nil
+	}
+	fmt.Printf("\ntop movers from %s to %s:\n", tags[0], tags[len(tags)-1])
+	for _, s := range movers {
+		fmt.Printf("%+.6f %.6f -> %.6f %s\n", s.Delta(), s.Before, s.After, s.Node)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var hotspotsCmd = &cobra.Command{
+	Use:   "hotspots <pkg>",
+	Short: "Report fan-in and fan-out, flagging god packages with both high.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHotspots,
+}
+
+func init() {
+	hotspotsCmd.Flags().Int("min-in", 0,
+		"minimum fan-in of a god package, an outlier by --sigma if non-positive.")
+	hotspotsCmd.Flags().Int("min-out", 0,
+		"minimum fan-out of a god package, an outlier by --sigma if non-positive.")
+	hotspotsCmd.Flags().Float64("sigma", 2,
+		"standard deviations above the mean for a fan to be an outlier.")
+	hotspotsCmd.Flags().Bool("all", false,
+		"whether to report the fans of all packages, not only god packages.")
+	rootCmd.AddCommand(hotspotsCmd)
+}
+
+func runHotspots(cmd *cobra.Command, args []string) error {
+	minIn, _ := cmd.Flags().GetInt("min-in")
+	minOut, _ := cmd.Flags().GetInt("min-out")
+	sigma, _ := cmd.Flags().GetFloat64("sigma")
+	all, _ := cmd.Flags().GetBool("all")
+
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	fans := g.Fans()
+	outIn, outOut := graph.OutlierThresholds(fans, sigma)
+	if minIn <= 0 {
+		minIn = outIn
+	}
+	if minOut <= 0 {
+		minOut = outOut
+	}
+	report := graph.GodPackages(fans, minIn, minOut)
+	if all {
+		report = fans
+	}
+	fmt.Printf("god packages have fan-in >= %d and fan-out >= %d\n", minIn, minOut)
+	fmt.Printf("%5s %5s %s\n", "in", "out", "package")
+	for _, fan := range report {
+		mark := ""
+		if fan.In >= minIn && fan.Out >= minOut {
+			mark = " (god)"
+		}
+		fmt.Printf("%5d %5d %s%s\n", fan.In, fan.Out, fan.Node.ID, mark)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var impactCmd = &cobra.Command{
+	Use:   "impact <pkg>",
+	Short: "Simulate removing dependencies and report the impact.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runImpact,
+}
+
+func init() {
+	impactCmd.Flags().StringSlice("remove", nil,
+		"packages to remove from the graph.")
+	impactCmd.Flags().StringSlice("remove-edge", nil,
+		"imports to remove from the graph, as src->dst.")
+	impactCmd.Flags().IntP("num", "n", 16,
+		"top number of rank shifts to show, all if non-positive.")
+	rootCmd.AddCommand(impactCmd)
+}
+
+func runImpact(cmd *cobra.Command, args []string) error {
+	removeNodes, _ := cmd.Flags().GetStringSlice("remove")
+	removeEdges, _ := cmd.Flags().GetStringSlice("remove-edge")
+	num, _ := cmd.Flags().GetInt("num")
+
+	var nodes []graph.NodeKey
+	for _, id := range removeNodes {
+		nodes = append(nodes, graph.NodeKey{ID: id})
+	}
+	var edges [][2]graph.NodeKey
+	for _, e := range removeEdges {
+		src, dst, ok := strings.Cut(e, "->")
+		if !ok {
+			return fmt.Errorf("invalid edge %q, want src->dst", e)
+		}
+		edges = append(edges, [2]graph.NodeKey{{ID: src}, {ID: dst}})
+	}
+	if len(nodes) == 0 && len(edges) == 0 {
+		return fmt.Errorf("nothing to remove, use --remove or --remove-edge")
+	}
+
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	impact := g.Impact(graph.NodeKey{ID: g.Container}, nodes, edges)
+	fmt.Printf("transitive dependencies: %d -> %d (%d dropped)\n",
+		impact.Before, impact.After, len(impact.Unreachable))
+	for _, n := range impact.Unreachable {
+		fmt.Printf("  - %s\n", n)
+	}
+	fmt.Println("rank shifts:")
+	for i, s := range impact.RankShifts {
+		if num > 0 && i >= num {
+			break
+		}
+		fmt.Printf("%+.6f %.6f -> %.6f %s\n", s.Delta(), s.Before, s.After, s.Node)
+	}
+	return nil
+}
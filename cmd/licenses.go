@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var licensesCmd = &cobra.Command{
+	Use:   "licenses <pkg>",
+	Short: "Report the licenses of the dependencies of a package and where they enter the tree.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runLicenses,
+}
+
+func init() {
+	licensesCmd.Flags().String("policy", graph.DefaultLicensePolicyFile,
+		"license policy file, licenses are not checked if the default is missing.")
+	addFindingsFlags(licensesCmd, true)
+	addEnrichFlags(licensesCmd)
+	rootCmd.AddCommand(licensesCmd)
+}
+
+func runLicenses(cmd *cobra.Command, args []string) error {
+	policyFile, _ := cmd.Flags().GetString("policy")
+	format, _ := cmd.Flags().GetString("format")
+
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	// Prefer licenses detected from the modules' own files to deps.dev's.
+	if err := enrich(cmd, g); err != nil {
+		return err
+	}
+	if err := g.AddLicenses(); err != nil {
+		return err
+	}
+	licenses := g.Licenses(graph.NodeKey{ID: g.Container})
+
+	var findings []graph.Finding
+	policy, err := graph.LoadLicensePolicy(policyFile)
+	switch {
+	case err == nil:
+		findings = graph.LicenseFindings(licenses, *policy)
+	case errors.Is(err, fs.ErrNotExist) && !cmd.Flags().Changed("policy"):
+	default:
+		return err
+	}
+	if format == "text" {
+		for i, l := range licenses {
+			if i == 0 || l.License != licenses[i-1].License {
+				fmt.Println(l.License)
+			}
+			mv := l.Module
+			if l.Version != "" {
+				mv += "@" + l.Version
+			}
+			ids := make([]string, len(l.Path.Nodes))
+			for j, n := range l.Path.Nodes {
+				ids[j] = n.ID
+			}
+			fmt.Printf("  %s via %s\n", mv, strings.Join(ids, " -> "))
+		}
+	}
+	return writeFindings(cmd, findings, g.Container)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [dir]",
+	Short: "Report import cycles, layering violations and unused packages of a module.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runLint,
+}
+
+func init() {
+	lintCmd.Flags().String("policy", graph.DefaultPolicyFile,
+		"architecture policy file, layering is not checked if the default is missing.")
+	addFindingsFlags(lintCmd, false)
+	rootCmd.AddCommand(lintCmd)
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	policyFile, _ := cmd.Flags().GetString("policy")
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	g, mains, err := graph.ListModule(dir)
+	if err != nil {
+		return err
+	}
+	findings := withoutTests(g).CycleFindings()
+
+	policy, err := graph.LoadPolicy(policyFile)
+	switch {
+	case err == nil:
+		findings = append(findings, g.ViolationFindings(*policy)...)
+	case errors.Is(err, fs.ErrNotExist) && !cmd.Flags().Changed("policy"):
+	default:
+		return err
+	}
+	findings = append(findings, graph.UnusedFindings(g.Unused(g.Container, mains...))...)
+	return writeFindings(cmd, findings, g.Container)
+}
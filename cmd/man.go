@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var manCmd = &cobra.Command{
+	Use:   "man [dir]",
+	Short: "Write man pages of pkgrank and each of its commands.",
+	Long: `Write man pages of pkgrank and each of its commands into dir, the current
+directory by default, generated from the commands' definitions and flags,
+e.g. pkgrank.1 and pkgrank-graph.1, to view with man -l or to install in a
+man1 directory of the MANPATH.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMan,
+}
+
+func init() {
+	rootCmd.AddCommand(manCmd)
+}
+
+func runMan(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writeManPages(cmd.Root(), dir)
+}
+
+// writeManPages writes the man page of cmd and those of its available
+// subcommands into dir.
+func writeManPages(cmd *cobra.Command, dir string) error {
+	name := strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+	if err := os.WriteFile(filepath.Join(dir, name+".1"), manPage(cmd), 0644); err != nil {
+		return err
+	}
+	for _, sub := range cmd.Commands() {
+		if !sub.IsAvailableCommand() || sub.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		if err := writeManPages(sub, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manPage returns the man page of cmd in the roff format of man(7).
+func manPage(cmd *cobra.Command) []byte {
+	var b bytes.Buffer
+	name := strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+	fmt.Fprintf(&b, ".TH %q 1 \"\" \"pkgrank\" \"pkgrank manual\"\n", strings.ToUpper(name))
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", roff(name), roff(cmd.Short))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n\\fB%s\\fP\n", roff(cmd.UseLine()))
+	description := cmd.Long
+	if description == "" {
+		description = cmd.Short
+	}
+	fmt.Fprintf(&b, ".SH DESCRIPTION\n%s\n", roff(description))
+	if cmd.HasAvailableLocalFlags() {
+		b.WriteString(".SH OPTIONS\n")
+		manFlags(&b, cmd.LocalFlags())
+	}
+	if cmd.HasAvailableInheritedFlags() {
+		b.WriteString(".SH OPTIONS INHERITED FROM PARENT COMMANDS\n")
+		manFlags(&b, cmd.InheritedFlags())
+	}
+	var related []string
+	if cmd.HasParent() {
+		related = append(related, strings.ReplaceAll(cmd.Parent().CommandPath(), " ", "-"))
+	}
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() && !sub.IsAdditionalHelpTopicCommand() {
+			related = append(related, strings.ReplaceAll(sub.CommandPath(), " ", "-"))
+		}
+	}
+	if len(related) > 0 {
+		sort.Strings(related)
+		refs := make([]string, len(related))
+		for i, r := range related {
+			refs[i] = "\\fB" + roff(r) + "\\fP(1)"
+		}
+		fmt.Fprintf(&b, ".SH SEE ALSO\n%s\n", strings.Join(refs, ", "))
+	}
+	return b.Bytes()
+}
+
+// manFlags writes the visible flags of fs as tagged paragraphs.
+func manFlags(b *bytes.Buffer, fs *pflag.FlagSet) {
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		b.WriteString(".TP\n")
+		if f.Shorthand != "" {
+			fmt.Fprintf(b, "\\fB\\-%s\\fP, ", roff(f.Shorthand))
+		}
+		fmt.Fprintf(b, "\\fB\\-\\-%s\\fP", roff(f.Name))
+		if typ, _ := pflag.UnquoteUsage(f); typ != "" {
+			fmt.Fprintf(b, " \\fI%s\\fP", roff(typ))
+		}
+		b.WriteString("\n")
+		usage := f.Usage
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "[]" && f.DefValue != "0" {
+			usage += fmt.Sprintf(" Defaults to %s.", f.DefValue)
+		}
+		fmt.Fprintf(b, "%s\n", roff(usage))
+	})
+}
+
+// roff escapes s for text in roff, so that backslashes and hyphens are
+// printed as is and lines do not start with control characters.
+func roff(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
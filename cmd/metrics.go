@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics <pkg>",
+	Short: "Show coupling, abstractness and size metrics alongside centrality.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMetrics,
+}
+
+func init() {
+	metricsCmd.Flags().IntP("num", "n", 16,
+		"top number of packages to show, all if non-positive.")
+	addRankFlags(metricsCmd)
+	addViewFlags(metricsCmd)
+	addEnrichFlags(metricsCmd)
+	rootCmd.AddCommand(metricsCmd)
+}
+
+func runMetrics(cmd *cobra.Command, args []string) error {
+	num, _ := cmd.Flags().GetInt("num")
+
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	if g, err = applyView(cmd, g); err != nil {
+		return err
+	}
+	if err := enrich(cmd, g); err != nil {
+		return err
+	}
+	ranks, err := graph.Centrality(*g, graph.PageRankCentrality, rankOptions(cmd, num)...)
+	if err != nil {
+		return err
+	}
+	metrics := g.PackageMetrics()
+	if ndjson, _ := cmd.Flags().GetBool("ndjson"); ndjson {
+		type metricsRank struct {
+			graph.RankResult
+			graph.PackageMetrics
+			LOC      int64 `json:"loc"`
+			Files    int64 `json:"files"`
+			Exported int64 `json:"exported"`
+		}
+		lines := make([]metricsRank, len(ranks))
+		for i, r := range ranks {
+			lines[i] = metricsRank{RankResult: r, PackageMetrics: metrics[r.Node]}
+			if data := g.Nodes[r.Node].Data; data != nil {
+				lines[i].LOC, _ = data.Attrs.Int(graph.AttrLOC)
+				lines[i].Files, _ = data.Attrs.Int(graph.AttrFiles)
+				lines[i].Exported, _ = data.Attrs.Int(graph.AttrExported)
+			}
+		}
+		return writeJSONLines(lines)
+	}
+	fmt.Printf("%-8s %4s %4s %5s %5s %5s %6s %5s %5s %s\n",
+		"score", "Ca", "Ce", "I", "A", "D", "loc", "files", "exp", "package")
+	for _, r := range ranks {
+		m := metrics[r.Node]
+		var attrs graph.Attrs
+		if data := g.Nodes[r.Node].Data; data != nil {
+			attrs = data.Attrs
+		}
+		loc, _ := attrs.Int(graph.AttrLOC)
+		files, _ := attrs.Int(graph.AttrFiles)
+		exported, _ := attrs.Int(graph.AttrExported)
+		fmt.Printf("%.6f %4d %4d %5.2f %5.2f %5.2f %6d %5d %5d %s\n",
+			r.Score, m.Afferent, m.Efferent, m.Instability, m.Abstractness, m.Distance,
+			loc, files, exported, r.Node)
+	}
+	return nil
+}
+
+// addRankFlags adds the flags of rankOptions to a command ranking by
+// centrality.
+func addRankFlags(cmd *cobra.Command) {
+	cmd.Flags().String("weight-by", "",
+		"numeric node attribute to multiply scores by, summed over the nodes each one reaches, e.g. loc, files or exported.")
+	completeValues(cmd, "weight-by", graph.AttrLOC, graph.AttrFiles, graph.AttrExported)
+	cmd.Flags().Float64("popularity", 0,
+		"weight from 0 to 1 of the dependents of modules from --deps-dev to blend into scores.")
+	cmd.Flags().Bool("ndjson", false,
+		"whether to print one JSON object per rank and line.")
+}
+
+// rankOptions returns the centrality options of the flags of addRankFlags,
+// keeping the top num nodes.
+func rankOptions(cmd *cobra.Command, num int) []graph.CentralityOption {
+	weightBy, _ := cmd.Flags().GetString("weight-by")
+	popularity, _ := cmd.Flags().GetFloat64("popularity")
+
+	opts := []graph.CentralityOption{graph.WithTop(num)}
+	if popularity != 0 {
+		opts = append(opts, graph.WithPopularity(graph.AttrDependents, popularity))
+	}
+	if weightBy != "" {
+		opts = append(opts, graph.WithWeightAttr(weightBy))
+	}
+	return opts
+}
+
+// writeJSONLines writes each item to stdout as a line of JSON, as soon as it
+// is encoded.
+func writeJSONLines[T any](items []T) error {
+	enc := json.NewEncoder(os.Stdout)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
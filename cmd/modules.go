@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var modulesCmd = &cobra.Command{
+	Use:   "modules <pkg>",
+	Short: "Rank the modules of the dependencies of a package by the centrality of their packages.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runModules,
+}
+
+func init() {
+	modulesCmd.Flags().IntP("num", "n", 16,
+		"top number of modules to show, all if non-positive.")
+	modulesCmd.Flags().Int("packages", 3,
+		"top number of packages to show of each module, none if non-positive.")
+	modulesCmd.Flags().String("centrality", string(graph.PageRankCentrality),
+		"centrality measure of packages: pagerank or coreness.")
+	completeValues(modulesCmd, "centrality", centralityMeasures...)
+	addRankFlags(modulesCmd)
+	addViewFlags(modulesCmd)
+	addEnrichFlags(modulesCmd)
+	rootCmd.AddCommand(modulesCmd)
+}
+
+func runModules(cmd *cobra.Command, args []string) error {
+	num, _ := cmd.Flags().GetInt("num")
+	packages, _ := cmd.Flags().GetInt("packages")
+	rawMeasure, _ := cmd.Flags().GetString("centrality")
+
+	measure, err := graph.NewCentralityMeasure(rawMeasure)
+	if err != nil {
+		return err
+	}
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	if g, err = applyView(cmd, g); err != nil {
+		return err
+	}
+	if err := enrich(cmd, g); err != nil {
+		return err
+	}
+	if err := g.AddModuleHyperEdges(); err != nil {
+		return err
+	}
+	modules, err := graph.ModuleCentrality(*g, measure, rankOptions(cmd, num)...)
+	if err != nil {
+		return err
+	}
+	if ndjson, _ := cmd.Flags().GetBool("ndjson"); ndjson {
+		for i, m := range modules {
+			if len(m.Packages) > packages {
+				modules[i].Packages = m.Packages[:max(packages, 0)]
+			}
+		}
+		return writeJSONLines(modules)
+	}
+	for _, m := range modules {
+		fmt.Printf("%.6f %s (%d packages)%s\n", m.Score, m.Module, len(m.Packages), moduleInfo(g, m))
+		for i, p := range m.Packages {
+			if i >= packages {
+				break
+			}
+			fmt.Printf("  %.6f %s\n", p.Score, p.Node)
+		}
+	}
+	return nil
+}
+
+// moduleInfo formats the deps.dev metadata of a module, as attached by enrich
+// to its packages, or returns an empty string if there is none.
+func moduleInfo(g *graph.Graph, m graph.ModuleRank) string {
+	if len(m.Packages) == 0 {
+		return ""
+	}
+	data := g.Nodes[m.Packages[0].Node].Data
+	if data == nil {
+		return ""
+	}
+	var info []string
+	if latest, ok := data.Attrs.String(graph.AttrLatest); ok && latest != data.Version {
+		info = append(info, "latest "+latest)
+	}
+	if license, ok := data.Attrs.String(graph.AttrLicense); ok {
+		info = append(info, license)
+	}
+	if score, ok := data.Attrs.Float(graph.AttrScorecard); ok {
+		info = append(info, fmt.Sprintf("scorecard %.1f", score))
+	}
+	if n, ok := data.Attrs.Int(graph.AttrDependents); ok {
+		info = append(info, fmt.Sprintf("%d dependents", n))
+	}
+	if len(info) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(info, ", ") + "]"
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var ownersCmd = &cobra.Command{
+	Use:   "owners [dir]",
+	Short: "Summarize the ranks of the teams owning the packages of a module, and the imports between them.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runOwners,
+}
+
+func init() {
+	ownersCmd.Flags().String("codeowners", "",
+		"CODEOWNERS file, or that of the repository's root, .github or docs directory if empty.")
+	ownersCmd.Flags().String("ownership", graph.DefaultOwnershipFile,
+		"JSON file assigning packages to teams, ahead of CODEOWNERS, ignored if missing.")
+	ownersCmd.Flags().IntP("num", "n", 16,
+		"top number of cross-team imports to show, all if non-positive.")
+	ownersCmd.Flags().Bool("json", false,
+		"whether to print the teams and cross-team imports as JSON.")
+	rootCmd.AddCommand(ownersCmd)
+}
+
+func runOwners(cmd *cobra.Command, args []string) error {
+	codeOwnersFile, _ := cmd.Flags().GetString("codeowners")
+	ownershipFile, _ := cmd.Flags().GetString("ownership")
+	num, _ := cmd.Flags().GetInt("num")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	ownership, err := graph.LoadOwnership(ownershipFile)
+	if errors.Is(err, os.ErrNotExist) && !cmd.Flags().Changed("ownership") {
+		ownership, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	root, err := repoRoot(dir)
+	if err != nil {
+		return err
+	}
+	var codeOwners *graph.CodeOwners
+	if codeOwnersFile != "" {
+		f, err := os.Open(codeOwnersFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if codeOwners, err = graph.ParseCodeOwners(f); err != nil {
+			return err
+		}
+	} else {
+		codeOwners, err = graph.FindCodeOwners(root)
+		if errors.Is(err, os.ErrNotExist) && ownership != nil {
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	g, _, err := graph.ListModule(dir)
+	if err != nil {
+		return err
+	}
+	g = withoutTests(g)
+	if err := g.AddOwners(dir, root, codeOwners, ownership); err != nil {
+		return err
+	}
+	teams := g.TeamRanks()
+	edges := g.CrossTeamEdges()
+	if num > 0 && num < len(edges) {
+		edges = edges[:num]
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Teams []graph.TeamRank `json:"teams"`
+			Edges []graph.TeamEdge `json:"edges"`
+		}{teams, edges})
+	}
+	fmt.Printf("%-8s %8s %7s %10s %s\n", "score", "packages", "imports", "importedBy", "team")
+	for _, t := range teams {
+		fmt.Printf("%.6f %8d %7d %10d %s\n", t.Score, t.Packages, t.Imports, t.ImportedBy, t.Team)
+	}
+	if len(edges) > 0 {
+		fmt.Println("\ncross-team imports:")
+		for _, e := range edges {
+			fmt.Printf("  %s\n", e)
+		}
+	}
+	return nil
+}
+
+// repoRoot returns the root of the git repository of dir, or dir itself if
+// it is not in one.
+func repoRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d, nil
+		}
+		if filepath.Dir(d) == d {
+			return abs, nil
+		}
+	}
+}
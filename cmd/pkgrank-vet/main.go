@@ -0,0 +1,18 @@
+// Command pkgrank-vet runs all the analyzers of pkgrank in one invocation,
+// e.g. pkgrank-vet -layers.policy=arch.json -depgraph.root=example.com/m ./...
+// or go vet -vettool=$(which pkgrank-vet) ./...
+package main
+
+import (
+	"github.com/arclabs561/pkgrank/analyzers/all"
+	"github.com/arclabs561/pkgrank/shared"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+func main() {
+	if err := shared.SetGlobalLogger(); err != nil {
+		log.Fatal().Err(err).Msg("failed to configure logging")
+	}
+	multichecker.Main(all.Analyzers()...)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.PersistentFlags().String("cpuprofile", "",
+		"file to write a pprof CPU profile of the command to.")
+	rootCmd.PersistentFlags().String("memprofile", "",
+		"file to write a pprof heap profile to when the command ends.")
+	rootCmd.PersistentFlags().String("trace", "",
+		"file to write an execution trace of the command to.")
+	rootCmd.PersistentPostRunE = stopProfiles
+}
+
+// profileFiles are the open CPU profile and trace files, closed by
+// stopProfiles.
+var profileFiles []*os.File
+
+func startProfiles(cmd *cobra.Command, args []string) error {
+	cpuprofile, _ := cmd.Flags().GetString("cpuprofile")
+	traceFile, _ := cmd.Flags().GetString("trace")
+
+	if cpuprofile != "" {
+		f, err := os.Create(cpuprofile)
+		if err != nil {
+			return fmt.Errorf("failed to create cpu profile: %w", err)
+		}
+		profileFiles = append(profileFiles, f)
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return fmt.Errorf("failed to start cpu profile: %w", err)
+		}
+	}
+	if traceFile != "" {
+		f, err := os.Create(traceFile)
+		if err != nil {
+			return fmt.Errorf("failed to create trace: %w", err)
+		}
+		profileFiles = append(profileFiles, f)
+		if err := trace.Start(f); err != nil {
+			return fmt.Errorf("failed to start trace: %w", err)
+		}
+	}
+	return nil
+}
+
+func stopProfiles(cmd *cobra.Command, args []string) error {
+	memprofile, _ := cmd.Flags().GetString("memprofile")
+
+	pprof.StopCPUProfile()
+	trace.Stop()
+	for _, f := range profileFiles {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	profileFiles = nil
+	if memprofile != "" {
+		f, err := os.Create(memprofile)
+		if err != nil {
+			return fmt.Errorf("failed to create heap profile: %w", err)
+		}
+		defer f.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			return fmt.Errorf("failed to write heap profile: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func init() {
+	rootCmd.PersistentFlags().String("progress-mode", "auto",
+		"how to report the progress of analyses on stderr: bar, json, none, or auto for a bar on a terminal and none otherwise.")
+	completeValues(rootCmd, "progress-mode", "auto", "bar", "json", "none")
+}
+
+// progress renders the progress of the command's analyses, if enabled by
+// startProgress.
+var progress *progressRenderer
+
+// progressFuncs are called with the progress of the command's analyses:
+// that of progress and of the report, if enabled.
+var progressFuncs []graph.ProgressFunc
+
+// startProgress reports the progress of analyses run with the command's
+// context to progressFuncs, rendering it as its --progress-mode asks.
+func startProgress(cmd *cobra.Command) error {
+	mode, _ := cmd.Flags().GetString("progress-mode")
+
+	fd := int(os.Stderr.Fd())
+	switch mode {
+	case "auto":
+		mode = "none"
+		if term.IsTerminal(fd) {
+			mode = "bar"
+		}
+	case "bar", "json", "none":
+	default:
+		return fmt.Errorf("unsupported progress mode: %q", mode)
+	}
+	if mode != "none" {
+		progress = &progressRenderer{w: os.Stderr, json: mode == "json", width: 80}
+		if width, _, err := term.GetSize(fd); err == nil && width > 0 {
+			progress.width = width
+		}
+		progressFuncs = append(progressFuncs, progress.report)
+	}
+	if len(progressFuncs) > 0 {
+		cmd.SetContext(graph.WithProgress(cmd.Context(), func(p graph.Progress) {
+			for _, fn := range progressFuncs {
+				fn(p)
+			}
+		}))
+	}
+	return nil
+}
+
+// stopProgress renders the last progress and clears the progress bar.
+func stopProgress() {
+	if progress != nil {
+		progress.finish()
+		progress = nil
+	}
+}
+
+// loadGraph returns the transitive dependency graph of pkg without the kinds
+// of edges of --exclude-kinds, reporting progress to the command's context,
+// followed by PhaseRank since commands rank the graph once loaded. The graph
+// merges those of configs, if any, see graph.TransitiveGraphMatrix.
+func loadGraph(cmd *cobra.Command, pkg string, configs ...graph.BuildConfig) (*graph.Graph, error) {
+	var g *graph.Graph
+	var err error
+	if len(configs) > 0 {
+		g, err = graph.TransitiveGraphMatrix(cmd.Context(), pkg, configs...)
+	} else {
+		g, err = graph.TransitiveGraphContext(cmd.Context(), pkg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if g, err = excludeKinds(cmd, g); err != nil {
+		return nil, err
+	}
+	reportGraph(g)
+	graph.ReportProgress(cmd.Context(), graph.Progress{Phase: graph.PhaseRank})
+	return g, nil
+}
+
+const (
+	// barInterval and jsonInterval are the minimum time between renderings
+	// of progress within a phase, as a bar or a JSON event.
+	barInterval  = 100 * time.Millisecond
+	jsonInterval = time.Second
+	// barWidth is the number of characters of a progress bar.
+	barWidth = 30
+)
+
+// progressRenderer writes progress to w as a bar redrawn in place, or as
+// JSON events, at most every interval and whenever the phase changes.
+type progressRenderer struct {
+	mu      sync.Mutex
+	w       io.Writer
+	json    bool
+	width   int
+	last    graph.Progress
+	written time.Time
+	// pending is whether the last progress was not rendered, and drawn
+	// whether the bar is on the screen.
+	pending bool
+	drawn   bool
+}
+
+func (r *progressRenderer) report(p graph.Progress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	interval := barInterval
+	if r.json {
+		interval = jsonInterval
+	}
+	changed := p.Phase != r.last.Phase
+	r.last = p
+	if !changed && time.Since(r.written) < interval {
+		r.pending = true
+		return
+	}
+	r.written, r.pending = time.Now(), false
+	r.render()
+}
+
+func (r *progressRenderer) render() {
+	if r.json {
+		_ = json.NewEncoder(r.w).Encode(r.last)
+		return
+	}
+	// The command's output follows ranking, so the bar gives way to it.
+	if r.last.Phase == graph.PhaseRank {
+		r.clear()
+		return
+	}
+	line := fmt.Sprintf("%-7s", r.last.Phase)
+	if r.last.Total > 0 {
+		done := min(r.last.Done, r.last.Total)
+		filled := barWidth * done / r.last.Total
+		line += fmt.Sprintf(" [%s%s] %d/%d", strings.Repeat("=", filled),
+			strings.Repeat(" ", barWidth-filled), r.last.Done, r.last.Total)
+	}
+	if r.last.Package != "" {
+		line += " " + r.last.Package
+	}
+	// Leave the last column empty, so that terminals do not wrap the line.
+	if n := r.width - 1; len(line) > n {
+		line = line[:n]
+	}
+	fmt.Fprintf(r.w, "\r%-*s", r.width-1, line)
+	r.drawn = true
+}
+
+// clear erases the progress bar.
+func (r *progressRenderer) clear() {
+	if r.drawn {
+		fmt.Fprintf(r.w, "\r%s\r", strings.Repeat(" ", r.width-1))
+		r.drawn = false
+	}
+}
+
+func (r *progressRenderer) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.json {
+		if r.pending {
+			r.render()
+		}
+		return
+	}
+	r.clear()
+}
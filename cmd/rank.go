@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var rankCmd = &cobra.Command{
+	Use:   "rank <pkg>",
+	Short: "Rank the dependencies of a package in a table of selected columns.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRank,
+}
+
+// rankColumns are the columns of the rank command, in their default order,
+// followed by those not shown by default.
+var rankColumns = []string{"rank", "score", "package", "in", "out", "module", "version", "class"}
+
+// defaultRankColumns are the columns of the rank command shown by default.
+var defaultRankColumns = rankColumns[:len(rankColumns)-1]
+
+func init() {
+	rankCmd.Flags().StringSlice("columns", defaultRankColumns,
+		"columns to show, in order: "+strings.Join(rankColumns, ", ")+".")
+	completeValues(rankCmd, "columns", rankColumns...)
+	rankCmd.Flags().String("sort", "rank",
+		"column to sort by, ascending for rank and text, descending for score and degrees.")
+	completeValues(rankCmd, "sort", rankColumns...)
+	rankCmd.Flags().Int("limit", 16,
+		"number of rows to show after sorting, all if non-positive.")
+	rankCmd.Flags().String("format", "table",
+		"output format: table, csv or json.")
+	completeValues(rankCmd, "format", "table", "csv", "json")
+	rankCmd.Flags().String("centrality", string(graph.PageRankCentrality),
+		"centrality measure of packages: pagerank or coreness.")
+	completeValues(rankCmd, "centrality", centralityMeasures...)
+	rankCmd.Flags().StringSlice("class", nil,
+		"only rank the packages of these classes among themselves, e.g. own for the architecture of the module or third-party for its dependencies, see --first-party.")
+	completeValues(rankCmd, "class", nodeClasses()...)
+	addRankFlags(rankCmd)
+	addViewFlags(rankCmd)
+	addEnrichFlags(rankCmd)
+	rootCmd.AddCommand(rankCmd)
+}
+
+func runRank(cmd *cobra.Command, args []string) error {
+	columns, _ := cmd.Flags().GetStringSlice("columns")
+	sortBy, _ := cmd.Flags().GetString("sort")
+	limit, _ := cmd.Flags().GetInt("limit")
+	format, _ := cmd.Flags().GetString("format")
+	rawMeasure, _ := cmd.Flags().GetString("centrality")
+	rawClasses, _ := cmd.Flags().GetStringSlice("class")
+
+	for _, c := range append([]string{sortBy}, columns...) {
+		if !slices.Contains(rankColumns, c) {
+			return fmt.Errorf("unknown column %q, want one of %s", c, strings.Join(rankColumns, ", "))
+		}
+	}
+	switch format {
+	case "table", "csv", "json":
+	default:
+		return fmt.Errorf("unsupported format: %q", format)
+	}
+	measure, err := graph.NewCentralityMeasure(rawMeasure)
+	if err != nil {
+		return err
+	}
+	keep := make(map[graph.NodeClass]bool, len(rawClasses))
+	for _, raw := range rawClasses {
+		c, err := graph.ParseNodeClass(raw)
+		if err != nil {
+			return err
+		}
+		keep[c] = true
+	}
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	if g, err = applyView(cmd, g); err != nil {
+		return err
+	}
+	if err := enrich(cmd, g); err != nil {
+		return err
+	}
+	// Rank every node, so that ranks are positions among all of them.
+	ranks, err := graph.Centrality(*g, measure, rankOptions(cmd, 0)...)
+	if err != nil {
+		return err
+	}
+	nodes := g.RankedNodes(ranks)
+	classes := classify(cmd, g)
+	for i := range nodes {
+		nodes[i].Class = classes[nodes[i].Node]
+	}
+	if len(keep) > 0 {
+		// Rank the kept nodes among themselves, scored within the whole
+		// graph.
+		nodes = slices.DeleteFunc(nodes, func(n graph.RankedNode) bool {
+			return !keep[n.Class]
+		})
+		for i := range nodes {
+			nodes[i].Rank = i + 1
+		}
+	}
+	sortRankedNodes(nodes, sortBy)
+	if limit > 0 && limit < len(nodes) {
+		nodes = nodes[:limit]
+	}
+
+	ndjson, _ := cmd.Flags().GetBool("ndjson")
+	switch {
+	case ndjson || format == "json":
+		rows := make([]map[string]any, len(nodes))
+		for i, n := range nodes {
+			rows[i] = rankRow(n, columns)
+		}
+		if ndjson {
+			return writeJSONLines(rows)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case format == "csv":
+		w := csv.NewWriter(os.Stdout)
+		if err := w.Write(columns); err != nil {
+			return err
+		}
+		for _, n := range nodes {
+			if err := w.Write(rankCells(n, columns)); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(columns, "\t"))
+		for _, n := range nodes {
+			fmt.Fprintln(w, strings.Join(rankCells(n, columns), "\t"))
+		}
+		return w.Flush()
+	}
+}
+
+// sortRankedNodes sorts nodes stably by the column by, in ascending order
+// for rank and text, and descending for score and degrees.
+func sortRankedNodes(nodes []graph.RankedNode, by string) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		switch by {
+		case "score":
+			return a.Score > b.Score
+		case "in":
+			return a.InDegree > b.InDegree
+		case "out":
+			return a.OutDegree > b.OutDegree
+		case "package":
+			return a.Node.ID < b.Node.ID
+		case "module":
+			return a.Module < b.Module
+		case "version":
+			return a.Version < b.Version
+		case "class":
+			return a.Class < b.Class
+		default:
+			return a.Rank < b.Rank
+		}
+	})
+}
+
+// rankRow returns the columns of n as a JSON object.
+func rankRow(n graph.RankedNode, columns []string) map[string]any {
+	row := make(map[string]any, len(columns))
+	for _, c := range columns {
+		switch c {
+		case "rank":
+			row[c] = n.Rank
+		case "score":
+			row[c] = n.Score
+		case "package":
+			row[c] = n.Node.ID
+		case "in":
+			row[c] = n.InDegree
+		case "out":
+			row[c] = n.OutDegree
+		case "module":
+			row[c] = n.Module
+		case "version":
+			row[c] = n.Version
+		case "class":
+			row[c] = string(n.Class)
+		}
+	}
+	return row
+}
+
+// rankCells returns the columns of n as text.
+func rankCells(n graph.RankedNode, columns []string) []string {
+	cells := make([]string, len(columns))
+	for i, c := range columns {
+		switch v := rankRow(n, []string{c})[c].(type) {
+		case float64:
+			cells[i] = strconv.FormatFloat(v, 'f', 6, 64)
+		default:
+			cells[i] = fmt.Sprint(v)
+		}
+	}
+	return cells
+}
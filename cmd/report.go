@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.PersistentFlags().String("report", "",
+		"file to write a JSON report of the run to when the command ends: timings of its phases, package counts, cache hit rate and warnings.")
+}
+
+// reportVersion is the version of the report's schema, incremented whenever
+// its fields change incompatibly.
+const reportVersion = 1
+
+// report collects the report of the run if enabled by startReport, which
+// writeReport writes to reportFile.
+var (
+	report     *runReport
+	reportFile string
+)
+
+// runReport is the report of a run, for CI dashboards to track the health of
+// analyses across repositories.
+type runReport struct {
+	mu sync.Mutex
+
+	Version int       `json:"version"`
+	Command string    `json:"command"`
+	Args    []string  `json:"args"`
+	Start   time.Time `json:"start"`
+	Seconds float64   `json:"seconds"`
+	// Error is the error that the command failed with, if any.
+	Error string `json:"error,omitempty"`
+	// ErrorClass classifies Error if it is from a command that failed, e.g.
+	// "network failure", see graph.ExecError.
+	ErrorClass string `json:"errorClass,omitempty"`
+	// Phases are the phases of analyses in the order they ran, see
+	// graph.Phase.
+	Phases   []phaseReport  `json:"phases"`
+	Packages packagesReport `json:"packages"`
+	// Graph counts the nodes and edges of the last graph analyzed, if any.
+	Graph *graphReport `json:"graph,omitempty"`
+	// Warnings are the messages logged at warning level or above.
+	Warnings []string `json:"warnings"`
+
+	phaseStart time.Time
+}
+
+type phaseReport struct {
+	Phase   graph.Phase `json:"phase"`
+	Seconds float64     `json:"seconds"`
+}
+
+// packagesReport counts the packages listed for analysis, those analyzed,
+// and those of which depgraph loaded the edges from its cache.
+type packagesReport struct {
+	Total        int     `json:"total"`
+	Analyzed     int     `json:"analyzed"`
+	Cached       int     `json:"cached"`
+	CacheHitRate float64 `json:"cacheHitRate"`
+}
+
+type graphReport struct {
+	Nodes int `json:"nodes"`
+	Edges int `json:"edges"`
+}
+
+// startReport starts collecting the report of the command if it has a
+// --report file. It must be called before startProgress, which reports
+// progress to it.
+func startReport(cmd *cobra.Command, args []string) error {
+	reportFile, _ = cmd.Flags().GetString("report")
+	if reportFile == "" {
+		return nil
+	}
+	report = &runReport{
+		Version:  reportVersion,
+		Command:  cmd.CommandPath(),
+		Args:     args,
+		Start:    time.Now(),
+		Phases:   []phaseReport{},
+		Warnings: []string{},
+	}
+	if args == nil {
+		report.Args = []string{}
+	}
+	log.Logger = log.Logger.Hook(zerolog.HookFunc(report.warn))
+	progressFuncs = append(progressFuncs, report.progress)
+	return nil
+}
+
+func (r *runReport) warn(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level < zerolog.WarnLevel {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, msg)
+}
+
+func (r *runReport) progress(p graph.Progress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n := len(r.Phases); n == 0 || r.Phases[n-1].Phase != p.Phase {
+		r.endPhase()
+		r.Phases = append(r.Phases, phaseReport{Phase: p.Phase})
+		r.phaseStart = time.Now()
+	}
+	if p.Phase != graph.PhaseAnalyze {
+		return
+	}
+	if p.Total > 0 {
+		r.Packages.Total = p.Total
+	}
+	if p.Done > 0 {
+		r.Packages.Analyzed++
+		if p.Cached {
+			r.Packages.Cached++
+		}
+	}
+}
+
+// endPhase records the duration of the last phase.
+func (r *runReport) endPhase() {
+	if n := len(r.Phases); n > 0 {
+		r.Phases[n-1].Seconds += time.Since(r.phaseStart).Seconds()
+	}
+}
+
+// reportGraph records the size of the graph g in the report, if enabled.
+func reportGraph(g *graph.Graph) {
+	if report == nil {
+		return
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.Graph = &graphReport{Nodes: g.Order(), Edges: g.Size()}
+}
+
+// writeReport completes the report of a command that returned err, and
+// writes it to the --report file.
+func writeReport(err error) error {
+	if report == nil {
+		return nil
+	}
+	r := report
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endPhase()
+	r.Seconds = time.Since(r.Start).Seconds()
+	if err != nil {
+		r.Error = err.Error()
+		for _, class := range []error{graph.ErrNetwork, graph.ErrNoToolchain, graph.ErrModuleNotFound} {
+			if errors.Is(err, class) {
+				r.ErrorClass = class.Error()
+				break
+			}
+		}
+	}
+	if r.Packages.Analyzed > 0 {
+		r.Packages.CacheHitRate = float64(r.Packages.Cached) / float64(r.Packages.Analyzed)
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(reportFile, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
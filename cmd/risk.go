@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var riskCmd = &cobra.Command{
+	Use:   "risk <pkg>",
+	Short: "Rank the dependency modules of a package by a composite supply-chain risk score.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRisk,
+}
+
+func init() {
+	riskCmd.Flags().IntP("num", "n", 16,
+		"top number of modules to show, all if non-positive.")
+	riskCmd.Flags().StringSlice("weight", nil,
+		"factor=weight overriding the default weight of a risk factor, e.g. vulns=5 or age=0.")
+	riskCmd.Flags().String("vulns", "",
+		"file of govulncheck -json output to count vulnerabilities from, - for stdin, or none if empty.")
+	riskCmd.Flags().Bool("govulncheck", false,
+		"whether to run govulncheck on the package to count vulnerabilities, instead of --vulns.")
+	riskCmd.Flags().Bool("json", false,
+		"whether to print the ranked modules as JSON.")
+	addEnrichFlags(riskCmd)
+	rootCmd.AddCommand(riskCmd)
+}
+
+func runRisk(cmd *cobra.Command, args []string) error {
+	num, _ := cmd.Flags().GetInt("num")
+	rawWeights, _ := cmd.Flags().GetStringSlice("weight")
+	vulnsInput, _ := cmd.Flags().GetString("vulns")
+	runGovulncheck, _ := cmd.Flags().GetBool("govulncheck")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	weights := graph.DefaultRiskWeights()
+	for _, raw := range rawWeights {
+		name, weight, ok := strings.Cut(raw, "=")
+		if !ok {
+			return fmt.Errorf("invalid --weight %q, want factor=weight", raw)
+		}
+		v, err := strconv.ParseFloat(weight, 64)
+		if err != nil {
+			return fmt.Errorf("invalid --weight %q: %w", raw, err)
+		}
+		weights[name] = v
+	}
+	if err := weights.Valid(); err != nil {
+		return err
+	}
+
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	if vulnsInput != "" || runGovulncheck {
+		vulns, err := readVulns(vulnsInput, args[0])
+		if err != nil {
+			return err
+		}
+		g.AddVulns(vulns)
+	}
+	if err := enrich(cmd, g); err != nil {
+		return err
+	}
+	risks, err := g.ModuleRisks(weights, time.Now())
+	if err != nil {
+		return err
+	}
+	if num > 0 && num < len(risks) {
+		risks = risks[:num]
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(risks)
+	}
+	fmt.Printf("%-6s %-10s %4s %6s %5s %5s %s\n", "risk", "centrality", "deps", "age", "maint", "vulns", "module")
+	for _, r := range risks {
+		age, maintainers := "-", "-"
+		if !r.Published.IsZero() {
+			age = fmt.Sprintf("%.0fd", time.Since(r.Published).Hours()/24)
+		}
+		if r.Maintainers > 0 {
+			maintainers = strconv.Itoa(r.Maintainers)
+		}
+		fmt.Printf("%.4f %.8f %4d %6s %5s %5d %s\n",
+			r.Score, r.Centrality, r.Dependencies, age, maintainers, len(r.Vulns), r.Module)
+	}
+	return nil
+}
@@ -1,26 +1,93 @@
 package cmd
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
-	"github.com/arclabs561/pkgrank/pkg"
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/arclabs561/pkgrank/shared"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
 // Execute executes the root command.
 func Execute() {
-	rootCmd.Execute()
+	err := rootCmd.Execute()
+	stopProgress()
+	if rerr := writeReport(err); rerr != nil {
+		fmt.Fprintln(os.Stderr, "Error:", rerr)
+		os.Exit(1)
+	}
+	if err != nil {
+		var execErr *graph.ExecError
+		if errors.As(err, &execErr) && execErr.Hint() != "" {
+			fmt.Fprintln(os.Stderr, "Hint:", execErr.Hint())
+		}
+		os.Exit(1)
+	}
 }
 
 var rootCmd = &cobra.Command{
-	Use:          "pkgrank",
+	Use:          "pkgrank [dir]",
 	Short:        "Discover the graph centrality of Go packages.",
+	Args:         cobra.MaximumNArgs(1),
 	RunE:         runRoot,
 	SilenceUsage: true,
+	// Set flags from the configuration file before configuring logging,
+	// the report, progress and profiling of any command.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		config, err := applyConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if err := shared.SetGlobalLogger(logFlags.Options()...); err != nil {
+			return err
+		}
+		if config != "" {
+			log.Debug().Str("file", config).Msg("using config")
+		}
+		if skip, _ := cmd.Flags().GetBool("skip-generated"); skip {
+			cmd.SetContext(graph.WithSkipGenerated(cmd.Context()))
+		}
+		if err := startReport(cmd, args); err != nil {
+			return err
+		}
+		if err := startProgress(cmd); err != nil {
+			return err
+		}
+		return startProfiles(cmd, args)
+	},
 }
 
+// logFlags are the logging flags of every command, logging info events by
+// default.
+var logFlags = shared.Flags{Level: "info"}
+
 func init() {
+	fs := flag.NewFlagSet("log", flag.ContinueOnError)
+	logFlags.Register(fs)
+	rootCmd.PersistentFlags().AddGoFlagSet(fs)
+	completeValues(rootCmd, "log-level", "trace", "debug", "info", "warn", "error", "disabled")
+	completeValues(rootCmd, "log-format", "console", "json")
+	rootCmd.PersistentFlags().StringVar(&graph.DefaultExecConfig.Go, "go", "",
+		"path of the go command to fetch modules and list packages with, go in the PATH if empty.")
+	rootCmd.PersistentFlags().StringVar(&graph.DefaultExecConfig.Proxy, "goproxy", "",
+		"GOPROXY of go commands, e.g. a private module proxy, that of the environment if empty.")
+	rootCmd.PersistentFlags().StringVar(&graph.DefaultExecConfig.Private, "goprivate", "",
+		"GOPRIVATE of go commands, that of the environment if empty.")
+	rootCmd.PersistentFlags().StringVar(&graph.DefaultExecConfig.NoSumDB, "gonosumdb", "",
+		"GONOSUMDB of go commands, that of the environment if empty.")
+	rootCmd.PersistentFlags().StringVar(&graph.DefaultExecConfig.Flags, "goflags", "",
+		"flags to add to the GOFLAGS of go commands, e.g. -mod=mod.")
+	rootCmd.PersistentFlags().BoolVar(&graph.DefaultExecConfig.KeepWorkdir, "keep-workdir", false,
+		"keep the scratch modules of analyses in the temp directory to debug them, see pkgrank clean.")
+	rootCmd.PersistentFlags().BoolVar(&graph.DefaultExecConfig.Sandbox, "sandbox", false,
+		"isolate go commands analyzing untrusted modules: module proxy only, no cgo or toolchain downloads, and separate caches.")
+	rootCmd.PersistentFlags().StringVar(&graph.DefaultExecConfig.SandboxDir, "sandbox-dir", "",
+		"directory of the GOPATH and caches of --sandbox, sandbox in the cache directory of pkgrank if empty.")
 	rootCmd.Flags().StringP("prefix", "p", "",
 		"filter imports with filter, no filter if empty")
 	rootCmd.Flags().IntP("num", "n", 16,
@@ -29,26 +96,49 @@ func init() {
 		"whether to iterate over package imports instead of go files.")
 }
 
+// runRoot ranks the packages of the module in the given directory, the
+// working directory by default, by the PageRank of their import graph.
 func runRoot(cmd *cobra.Command, args []string) error {
-	root := os.Args[1]
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
 	prefix, _ := cmd.Flags().GetString("prefix")
 	num, _ := cmd.Flags().GetInt("num")
 	iterPkg, _ := cmd.Flags().GetBool("pkg")
 
-	pkgs, err := pkg.ListPackages(root)
+	g, _, err := graph.ListModule(dir)
 	if err != nil {
 		return err
 	}
-	g, err := pkg.BuildGraph(pkgs, prefix, iterPkg)
+	if prefix != "" {
+		g = g.Subgraph(func(n graph.NodeKey) bool {
+			return strings.HasPrefix(n.ID, prefix)
+		})
+	}
+	if !iterPkg {
+		// Weight imports by the number of files that import the package.
+		for _, edge := range g.Edges {
+			if edge, ok := edge.(*graph.DirectedEdge); ok {
+				edge.EdgeWeight = float64(max(1, countFiles(edge.Provenance)))
+			}
+		}
+	}
+	ranks, err := graph.Centrality(*g, graph.PageRankCentrality, graph.WithTop(num))
 	if err != nil {
 		return err
 	}
-	imps, scores := g.Centrality()
-	for i, imp := range imps {
-		if i > 0 && i > num {
-			break
-		}
-		fmt.Printf("%.6f %s\n", scores[i], imp)
+	for _, r := range ranks {
+		fmt.Fprintf(cmd.OutOrStdout(), "%.6f %s\n", r.Score, r.Node.ID)
 	}
 	return nil
 }
+
+// countFiles returns the number of distinct files of the given imports.
+func countFiles(provenance []graph.Provenance) int {
+	files := make(map[string]struct{}, len(provenance))
+	for _, p := range provenance {
+		files[p.File] = struct{}{}
+	}
+	return len(files)
+}
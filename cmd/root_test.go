@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModule writes the files of a module, by path relative to dir.
+func writeModule(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, map[string]string{
+		"go.mod":  "module example.com/m\n\ngo 1.21\n",
+		"a/a.go":  "package a\n\nimport _ \"example.com/m/c\"\n",
+		"b/b.go":  "package b\n\nimport _ \"example.com/m/c\"\n",
+		"b/b2.go": "package b\n\nimport _ \"example.com/m/c\"\n",
+		"c/c.go":  "package c\n\nimport _ \"strings\"\n",
+	})
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{dir, "--prefix", "example.com/m", "-n", "2", "--log-level", "disabled"})
+	defer rootCmd.SetArgs(nil)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %q, want 2 packages", out.String())
+	}
+	// The package imported by every other ranks first, and the prefix
+	// leaves the standard library out.
+	if !strings.HasSuffix(lines[0], " example.com/m/c") {
+		t.Errorf("got %q first, want example.com/m/c", lines[0])
+	}
+	if strings.Contains(out.String(), "strings") {
+		t.Errorf("got %q, want no standard library packages", out.String())
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/arclabs561/pkgrank/api"
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve <pkg|dir>",
+	Short: "Serve a graph explorer and JSON endpoints for a package or local module.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runServe,
+}
+
+func init() {
+	serveCmd.Flags().String("addr", "localhost:8080",
+		"address to listen on.")
+	serveCmd.Flags().Bool("local", false,
+		"whether the argument is the directory of a local module, rather than a package.")
+	serveCmd.Flags().Duration("reload", 0,
+		"interval to re-analyze at, diffing against the previous analysis, never if zero.")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// server serves the latest analysis of a package or module, and its
+// difference from the analysis before it.
+type server struct {
+	load func() (*graph.Graph, error)
+
+	mu       sync.RWMutex
+	current  *graph.Graph
+	previous *graph.Graph
+	loaded   time.Time
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	addr, _ := cmd.Flags().GetString("addr")
+	local, _ := cmd.Flags().GetBool("local")
+	reload, _ := cmd.Flags().GetDuration("reload")
+
+	s := &server{load: func() (*graph.Graph, error) {
+		return loadGraph(cmd, args[0])
+	}}
+	if local {
+		s.load = func() (*graph.Graph, error) {
+			g, _, err := graph.ListModule(args[0])
+			return g, err
+		}
+	}
+	if err := s.reload(); err != nil {
+		return err
+	}
+	if reload > 0 {
+		go func() {
+			for range time.Tick(reload) {
+				if err := s.reload(); err != nil {
+					log.Error().Err(err).Msg("failed to reload graph")
+				}
+			}
+		}()
+	}
+	log.Info().Str("addr", addr).Msg("serving")
+	return http.ListenAndServe(addr, s.handler())
+}
+
+// handler returns the handler of the page and endpoints of the server, those
+// of the api package under /api serving the current analysis.
+func (s *server) handler() http.Handler {
+	queries := api.NewServer(0)
+	queries.Graph = func() *graph.Graph {
+		g, _ := s.graphs()
+		return g
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.Handle("/api/", http.StripPrefix("/api", queries))
+	mux.HandleFunc("/api/diff", s.handleDiff)
+	return mux
+}
+
+func (s *server) reload() error {
+	g, err := s.load()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.previous, s.current = s.current, g
+	s.loaded = time.Now()
+	log.Info().Int("nodes", g.Order()).Int("edges", g.Size()).Msg("loaded graph")
+	return nil
+}
+
+// graphs returns the current and previous analyses, the latter nil if there
+// is only one.
+func (s *server) graphs() (current, previous *graph.Graph) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current, s.previous
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	g, _ := s.graphs()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := graph.WriteHTML(w, g); err != nil {
+		log.Error().Err(err).Msg("failed to write html")
+	}
+}
+
+func (s *server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	current, previous := s.graphs()
+	if previous == nil {
+		previous = current
+	}
+	writeJSON(w, previous.Diff(*current))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Error().Err(err).Msg("failed to write json")
+	}
+}
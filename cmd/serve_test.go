@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arclabs561/pkgrank/graph"
+)
+
+func TestServeHandler(t *testing.T) {
+	g := &graph.Graph{Container: "a"}
+	g.AddEdge(graph.NewDirectedEdge("a", "a", "b"))
+	s := &server{load: func() (*graph.Graph, error) { return g, nil }}
+	if err := s.reload(); err != nil {
+		t.Fatal(err)
+	}
+	h := s.handler()
+	for target, code := range map[string]int{
+		"/":                   http.StatusOK,
+		"/api/rank":           http.StatusOK,
+		"/api/graph":          http.StatusOK,
+		"/api/path?dst=b":     http.StatusOK,
+		"/api/path?dst=b&k=0": http.StatusBadRequest,
+		"/api/cycles":         http.StatusOK,
+		"/api/diff":           http.StatusOK,
+		"/nope":               http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != code {
+			t.Errorf("%s: got status %d, want %d", target, w.Code, code)
+		}
+	}
+}
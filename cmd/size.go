@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var sizeCmd = &cobra.Command{
+	Use:   "size <pkg>",
+	Short: "Attribute the size of a package's binary to its dependencies.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSize,
+}
+
+func init() {
+	sizeCmd.Flags().IntP("num", "n", 16,
+		"top number of packages or modules to show, all if non-positive.")
+	sizeCmd.Flags().String("binary", "",
+		"binary of the package to measure, or build it with go build in the current directory if empty.")
+	sizeCmd.Flags().Bool("modules", false,
+		"whether to total the sizes of the packages of each module instead.")
+	sizeCmd.Flags().Bool("json", false,
+		"whether to print the sizes as JSON.")
+	rootCmd.AddCommand(sizeCmd)
+}
+
+func runSize(cmd *cobra.Command, args []string) error {
+	num, _ := cmd.Flags().GetInt("num")
+	binary, _ := cmd.Flags().GetString("binary")
+	byModule, _ := cmd.Flags().GetBool("modules")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	if binary == "" {
+		dir, err := os.MkdirTemp("", "pkgrank-size-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		binary = filepath.Join(dir, "bin")
+		if out, err := graph.DefaultExecConfig.Command(cmd.Context(), "go", "build", "-o", binary, args[0]).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to build %s, pass --binary instead: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+	}
+	if _, err := buildinfo.ReadFile(binary); err != nil {
+		return fmt.Errorf("%s is not a binary of a main package: %w", binary, err)
+	}
+	symbols, err := graph.ReadSymbolSizes(binary)
+	if err != nil {
+		return err
+	}
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	unattributed := g.AddSizes(symbols)
+	sizes := g.PackageSizes(graph.NodeKey{ID: g.Container})
+
+	if byModule {
+		return printModuleSizes(g, sizes, unattributed, num, asJSON)
+	}
+	if num > 0 && num < len(sizes) {
+		sizes = sizes[:num]
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sizes)
+	}
+	fmt.Printf("%10s %10s %s\n", "retained", "own", "package")
+	for _, s := range sizes {
+		fmt.Printf("%10d %10d %s\n", s.Retained, s.Size, s.Node)
+	}
+	fmt.Printf("%10d %10s (runtime tables and other symbols of no package)\n", unattributed, "")
+	return nil
+}
+
+// printModuleSizes prints the total size of the packages of each module,
+// largest first.
+func printModuleSizes(g *graph.Graph, sizes []graph.PackageSize, unattributed int64, num int, asJSON bool) error {
+	type moduleSize struct {
+		Module string `json:"module"`
+		Size   int64  `json:"size"`
+	}
+	totals := make(map[string]int64)
+	for _, s := range sizes {
+		if data := g.Nodes[s.Node].Data; data != nil {
+			totals[data.ModuleVersion()] += s.Size
+		}
+	}
+	modules := make([]moduleSize, 0, len(totals))
+	for m, size := range totals {
+		modules = append(modules, moduleSize{Module: m, Size: size})
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		if modules[i].Size != modules[j].Size {
+			return modules[i].Size > modules[j].Size
+		}
+		return modules[i].Module < modules[j].Module
+	})
+	if num > 0 && num < len(modules) {
+		modules = modules[:num]
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(modules)
+	}
+	for _, m := range modules {
+		fmt.Printf("%10d %s\n", m.Size, m.Module)
+	}
+	fmt.Printf("%10d (runtime tables and other symbols of no package)\n", unattributed)
+	return nil
+}
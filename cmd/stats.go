@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats <pkg>",
+	Short: "Summarize the structure of a package's dependency graph.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runStats,
+}
+
+func init() {
+	statsCmd.Flags().Bool("json", false,
+		"whether to print the summary as JSON.")
+	statsCmd.Flags().Bool("by-class", false,
+		"whether to also summarize the packages of each class: own, first-party, third-party and stdlib, see --first-party.")
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	byClass, _ := cmd.Flags().GetBool("by-class")
+
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	s := g.Stats()
+	var classes []graph.ClassStats
+	if byClass {
+		classes = g.ClassStats(classify(cmd, g))
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if byClass {
+			return enc.Encode(struct {
+				graph.Stats
+				Classes []graph.ClassStats `json:"classes"`
+			}{s, classes})
+		}
+		return enc.Encode(s)
+	}
+	fmt.Printf("order:          %d\n", s.Order)
+	fmt.Printf("size:           %d\n", s.Size)
+	fmt.Printf("density:        %.6f\n", s.Density)
+	fmt.Printf("max in-degree:  %d\n", s.MaxInDegree)
+	fmt.Printf("max out-degree: %d\n", s.MaxOutDegree)
+	fmt.Printf("avg degree:     %.2f\n", s.AvgDegree)
+	fmt.Printf("diameter:       %d\n", s.Diameter)
+	fmt.Printf("sccs:           %d (%d cycles)\n", s.SCCs, s.Cycles)
+	fmt.Printf("longest chain:  %d\n", s.LongestChain)
+	fmt.Println("in-degree histogram:")
+	printHistogram(s.InDegrees)
+	fmt.Println("out-degree histogram:")
+	printHistogram(s.OutDegrees)
+	if byClass {
+		fmt.Println("classes:")
+		for _, c := range classes {
+			fmt.Printf("  %-11s %5d packages  score %.4f  %8d loc  imports", c.Class, c.Packages, c.Score, c.LOC)
+			for _, to := range graph.NodeClasses {
+				if n := c.Imports[to]; n > 0 {
+					fmt.Printf(" %s:%d", to, n)
+				}
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+func printHistogram(h map[int]int) {
+	degrees := make([]int, 0, len(h))
+	for d := range h {
+		degrees = append(degrees, d)
+	}
+	sort.Ints(degrees)
+	for _, d := range degrees {
+		fmt.Printf("  %4d: %d\n", d, h[d])
+	}
+}
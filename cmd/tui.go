@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui <pkg>",
+	Short: "Interactively browse the ranked dependency graph of a package.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTUI,
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+}
+
+const tuiHelp = "↑/↓ move  enter open  ← back  s sort  / filter  q quit"
+
+// tuiView is a list of packages, either all of them or the imports and
+// importers of one.
+type tuiView struct {
+	// pkg is the package whose neighborhood is listed, empty for all.
+	pkg string
+	// rows are the listed packages, each prefixed by "->" for imports and
+	// "<-" for importers in a neighborhood.
+	rows     []string
+	prefixes []string
+	selected int
+	offset   int
+}
+
+type tui struct {
+	ranks   map[string]float64
+	imports map[string][]string
+	users   map[string][]string
+	byName  bool
+	filter  string
+	views   []*tuiView
+	out     *bufio.Writer
+}
+
+func runTUI(cmd *cobra.Command, args []string) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("tui requires a terminal")
+	}
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	t := &tui{
+		ranks:   make(map[string]float64),
+		imports: make(map[string][]string),
+		users:   make(map[string][]string),
+		out:     bufio.NewWriter(os.Stdout),
+	}
+	for _, edge := range g.SortedEdges() {
+		if edge, ok := edge.(*graph.DirectedEdge); ok {
+			t.imports[edge.Src.ID] = append(t.imports[edge.Src.ID], edge.Dst.ID)
+			t.users[edge.Dst.ID] = append(t.users[edge.Dst.ID], edge.Src.ID)
+		}
+	}
+	ranks, err := graph.Centrality(*g, graph.PageRankCentrality)
+	if err != nil {
+		return err
+	}
+	for _, r := range ranks {
+		t.ranks[r.Node.ID] = r.Score
+	}
+	t.push("")
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+	// Use the alternate screen and hide the cursor while browsing.
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+	return t.loop(bufio.NewReader(os.Stdin), fd)
+}
+
+// push opens a view of the neighborhood of pkg, or of all packages if empty.
+func (t *tui) push(pkg string) {
+	v := &tuiView{pkg: pkg}
+	if pkg == "" {
+		for imp := range t.ranks {
+			if strings.Contains(imp, t.filter) {
+				v.rows = append(v.rows, imp)
+			}
+		}
+		t.sort(v.rows)
+		v.prefixes = make([]string, len(v.rows))
+	} else {
+		imports := append([]string(nil), t.imports[pkg]...)
+		users := append([]string(nil), t.users[pkg]...)
+		t.sort(imports)
+		t.sort(users)
+		for _, imp := range imports {
+			v.rows = append(v.rows, imp)
+			v.prefixes = append(v.prefixes, "->")
+		}
+		for _, user := range users {
+			v.rows = append(v.rows, user)
+			v.prefixes = append(v.prefixes, "<-")
+		}
+	}
+	t.views = append(t.views, v)
+}
+
+// sort sorts pkgs by rank, highest first, or by name.
+func (t *tui) sort(pkgs []string) {
+	sort.Slice(pkgs, func(i, j int) bool {
+		if !t.byName && t.ranks[pkgs[i]] != t.ranks[pkgs[j]] {
+			return t.ranks[pkgs[i]] > t.ranks[pkgs[j]]
+		}
+		return pkgs[i] < pkgs[j]
+	})
+}
+
+func (t *tui) loop(in *bufio.Reader, fd int) error {
+	for {
+		_, height, err := term.GetSize(fd)
+		if err != nil || height < 4 {
+			height = 24
+		}
+		t.draw(height)
+		v := t.views[len(t.views)-1]
+		b, err := in.ReadByte()
+		if err != nil {
+			return err
+		}
+		page := max(1, height-3)
+		switch b {
+		case 'q', 3: // q or ctrl-c
+			return nil
+		case 'j':
+			v.selected++
+		case 'k':
+			v.selected--
+		case '\r':
+			if len(v.rows) > 0 {
+				t.push(v.rows[v.selected])
+			}
+		case 'h', 127: // h or backspace
+			t.pop()
+		case 's':
+			t.byName = !t.byName
+			t.refresh()
+		case '/':
+			filter, err := t.prompt(in, height)
+			if err != nil {
+				return err
+			}
+			t.filter = filter
+			t.views = t.views[:0]
+			t.push("")
+		case 0x1b:
+			// Arrow and page keys are escape sequences, e.g. ESC [ A.
+			if next, _ := in.Peek(1); len(next) == 0 || next[0] != '[' {
+				continue
+			}
+			in.ReadByte()
+			key, _ := in.ReadByte()
+			switch key {
+			case 'A':
+				v.selected--
+			case 'B':
+				v.selected++
+			case 'C':
+				if len(v.rows) > 0 {
+					t.push(v.rows[v.selected])
+				}
+			case 'D':
+				t.pop()
+			case '5', '6':
+				in.ReadByte() // ~
+				if key == '5' {
+					v.selected -= page
+				} else {
+					v.selected += page
+				}
+			}
+		}
+		v.selected = max(0, min(v.selected, len(v.rows)-1))
+	}
+}
+
+// pop returns to the previous view, if any.
+func (t *tui) pop() {
+	if len(t.views) > 1 {
+		t.views = t.views[:len(t.views)-1]
+	}
+}
+
+// refresh rebuilds every view, keeping the selected packages.
+func (t *tui) refresh() {
+	views := t.views
+	t.views = nil
+	for _, old := range views {
+		t.push(old.pkg)
+		v := t.views[len(t.views)-1]
+		for i, row := range v.rows {
+			if len(old.rows) > 0 && row == old.rows[old.selected] {
+				v.selected = i
+			}
+		}
+	}
+}
+
+// prompt reads a line of input at the bottom of the screen.
+func (t *tui) prompt(in *bufio.Reader, height int) (string, error) {
+	var line []byte
+	for {
+		fmt.Fprintf(t.out, "\x1b[%d;1H\x1b[2K/%s", height, line)
+		t.out.Flush()
+		b, err := in.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case b == '\r':
+			return string(line), nil
+		case b == 0x1b || b == 3:
+			return t.filter, nil
+		case b == 127:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+		case b >= ' ':
+			line = append(line, b)
+		}
+	}
+}
+
+func (t *tui) draw(height int) {
+	v := t.views[len(t.views)-1]
+	rows := max(1, height-3)
+	if v.selected < v.offset {
+		v.offset = v.selected
+	}
+	if v.selected >= v.offset+rows {
+		v.offset = v.selected - rows + 1
+	}
+	title := "all packages"
+	if v.pkg != "" {
+		title = fmt.Sprintf("%s (%d imports, %d importers)", v.pkg, len(t.imports[v.pkg]), len(t.users[v.pkg]))
+	}
+	if t.filter != "" {
+		title += fmt.Sprintf(" [filter %q]", t.filter)
+	}
+	fmt.Fprintf(t.out, "\x1b[H\x1b[2J\x1b[1m%s\x1b[0m\r\n", title)
+	for i := v.offset; i < len(v.rows) && i < v.offset+rows; i++ {
+		if i == v.selected {
+			t.out.WriteString("\x1b[7m")
+		}
+		fmt.Fprintf(t.out, "%2s %.6f %s\x1b[0m\r\n", v.prefixes[i], t.ranks[v.rows[i]], v.rows[i])
+	}
+	fmt.Fprintf(t.out, "\x1b[%d;1H\x1b[2m%s\x1b[0m", height-1, tuiHelp)
+	t.out.Flush()
+}
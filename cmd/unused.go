@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var unusedCmd = &cobra.Command{
+	Use:   "unused [dir]",
+	Short: "List packages of a module that no other package imports.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runUnused,
+}
+
+func init() {
+	unusedCmd.Flags().Bool("dead", false,
+		"whether to list all packages unreachable from main packages, not only those never imported.")
+	addFindingsFlags(unusedCmd, false)
+	rootCmd.AddCommand(unusedCmd)
+}
+
+func runUnused(cmd *cobra.Command, args []string) error {
+	dead, _ := cmd.Flags().GetBool("dead")
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	g, mains, err := graph.ListModule(dir)
+	if err != nil {
+		return err
+	}
+	var pkgs []graph.NodeKey
+	if dead {
+		pkgs = g.Dead(g.Container, mains...)
+	} else {
+		pkgs = g.Unused(g.Container, mains...)
+	}
+	return writeFindings(cmd, graph.UnusedFindings(pkgs), g.Container)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.PersistentFlags().String("exclude-kinds", "",
+		"comma separated kinds of imports to leave out of graphs, e.g. test,stdlib: "+
+			strings.Join(edgeKinds(), ", ")+".")
+	completeValues(rootCmd, "exclude-kinds", edgeKinds()...)
+	rootCmd.PersistentFlags().StringSlice("first-party", nil,
+		"prefixes of the paths of first-party modules, e.g. github.com/example, to classify packages as own, first-party, third-party or stdlib.")
+	rootCmd.PersistentFlags().Bool("skip-generated", false,
+		"leave generated files out of graphs, with their imports and uses, rather than tagging the imports only they make as generated.")
+}
+
+// edgeKinds returns the names of the kinds of graph.EdgeKind.
+func edgeKinds() []string {
+	return (^graph.EdgeKind(0)).Names()
+}
+
+// excludeKinds leaves the edges of the kinds of the --exclude-kinds flag out
+// of g, along with the packages that only they import.
+func excludeKinds(cmd *cobra.Command, g *graph.Graph) (*graph.Graph, error) {
+	names, _ := cmd.Flags().GetString("exclude-kinds")
+	kinds, err := graph.ParseEdgeKind(names)
+	if err != nil || kinds == 0 {
+		return g, err
+	}
+	return g.ExcludeKinds(kinds), nil
+}
+
+// classify returns the class of each node of g, with the first-party modules
+// of the --first-party flag.
+func classify(cmd *cobra.Command, g *graph.Graph) map[graph.NodeKey]graph.NodeClass {
+	firstParty, _ := cmd.Flags().GetStringSlice("first-party")
+	return g.Classify(firstParty...)
+}
+
+// addViewFlags adds the flags of applyView to a command.
+func addViewFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("depth", 0,
+		"only keep packages within this many imports of the root package, all if non-positive.")
+	cmd.Flags().String("focus", "",
+		"only keep packages within --radius imports or importers of this package.")
+	cmd.Flags().Int("radius", 1,
+		"number of imports or importers around --focus to keep.")
+	cmd.Flags().StringSlice("exclude", nil,
+		"patterns of packages to leave out, e.g. example.com/m/internal/...")
+}
+
+// applyView restricts the graph to the bounded neighborhood selected by the
+// flags of addViewFlags, before it is written or ranked.
+func applyView(cmd *cobra.Command, g *graph.Graph) (*graph.Graph, error) {
+	depth, _ := cmd.Flags().GetInt("depth")
+	focus, _ := cmd.Flags().GetString("focus")
+	radius, _ := cmd.Flags().GetInt("radius")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+
+	if len(exclude) > 0 {
+		g = g.Exclude(exclude...)
+	}
+	if depth > 0 {
+		g = g.WithinDepth(graph.NodeKey{ID: g.Container}, depth)
+	}
+	if focus != "" {
+		key := graph.NodeKey{ID: focus}
+		if _, ok := g.Nodes[key]; !ok {
+			return nil, fmt.Errorf("focus package %s is not in the graph", focus)
+		}
+		g = g.Neighborhood(key, radius)
+	}
+	return g, nil
+}
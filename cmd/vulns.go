@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var vulnsCmd = &cobra.Command{
+	Use:   "vulns <pkg>",
+	Short: "Rank the known vulnerabilities of a package's dependencies by their centrality and reachability.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runVulns,
+}
+
+func init() {
+	vulnsCmd.Flags().String("input", "",
+		"file of govulncheck -json output to read, - for stdin, or run govulncheck on the package if empty.")
+	vulnsCmd.Flags().String("centrality", string(graph.PageRankCentrality),
+		"centrality measure of packages: pagerank or coreness.")
+	completeValues(vulnsCmd, "centrality", centralityMeasures...)
+	vulnsCmd.Flags().Bool("json", false,
+		"whether to print the ranked vulnerabilities as JSON.")
+	rootCmd.AddCommand(vulnsCmd)
+}
+
+func runVulns(cmd *cobra.Command, args []string) error {
+	input, _ := cmd.Flags().GetString("input")
+	rawMeasure, _ := cmd.Flags().GetString("centrality")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	measure, err := graph.NewCentralityMeasure(rawMeasure)
+	if err != nil {
+		return err
+	}
+	vulns, err := readVulns(input, args[0])
+	if err != nil {
+		return err
+	}
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	ranks, err := g.RankVulns(graph.NodeKey{ID: g.Container}, vulns, measure)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ranks)
+	}
+	if len(ranks) == 0 {
+		fmt.Println("no vulnerabilities")
+	}
+	for _, r := range ranks {
+		reach := "unreachable"
+		if r.Reachable {
+			reach = fmt.Sprintf("depth %d", r.Depth)
+		}
+		if r.Called {
+			reach += ", called"
+		}
+		fixed := ""
+		if r.FixedVersion != "" {
+			fixed = ", fixed in " + r.FixedVersion
+		}
+		fmt.Printf("%.6f %s %s (%s%s)\n", r.Score, r.ID, r.Package, reach, fixed)
+		if r.Summary != "" {
+			fmt.Printf("  %s\n", r.Summary)
+		}
+	}
+	return nil
+}
+
+// readVulns reads the govulncheck -json output of the named file, or of
+// stdin if name is -, or of running govulncheck on pkg if name is empty.
+func readVulns(name, pkg string) ([]graph.Vuln, error) {
+	var r io.Reader
+	switch name {
+	case "":
+		var stderr bytes.Buffer
+		c := graph.DefaultExecConfig.Command(context.Background(), "govulncheck", "-json", pkg)
+		c.Stderr = &stderr
+		out, err := c.Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run govulncheck: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		r = bytes.NewReader(out)
+	case "-":
+		r = os.Stdin
+	default:
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return graph.ParseGovulncheck(r)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [dir]",
+	Short: "Print updated ranks and cycles of a local module as its packages change.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runWatch,
+}
+
+func init() {
+	watchCmd.Flags().Duration("interval", time.Second,
+		"interval to poll for changed files at.")
+	watchCmd.Flags().IntP("num", "n", 16,
+		"top number of packages to show, all if non-positive.")
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	interval, _ := cmd.Flags().GetDuration("interval")
+	num, _ := cmd.Flags().GetInt("num")
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	w, err := graph.NewWatcher(dir)
+	if err != nil {
+		return err
+	}
+	report := func() error {
+		g, _ := w.Graph()
+		ranks, err := graph.Centrality(*g, graph.PageRankCentrality, graph.WithTop(num))
+		if err != nil {
+			return err
+		}
+		for _, r := range ranks {
+			fmt.Printf("%.6f %s\n", r.Score, r.Node)
+		}
+		cycles := withoutTests(g).CycleFindings()
+		if len(cycles) == 0 {
+			fmt.Println("no cycles")
+		}
+		for _, f := range cycles {
+			fmt.Println(f)
+		}
+		return nil
+	}
+	if err := report(); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// Failed polls and ranks are logged, and watching goes on until
+	// interrupted.
+	_ = w.Watch(ctx, interval, func(changed []string) {
+		fmt.Printf("\n%s changed: %s\n", time.Now().Format(time.TimeOnly), strings.Join(changed, ", "))
+		if err := report(); err != nil {
+			log.Warn().Err(err).Msg("failed to rank packages")
+		}
+	})
+	return nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/spf13/cobra"
+)
+
+var whyCmd = &cobra.Command{
+	Use:   "why <pkg> <dep>",
+	Short: "Show the import paths from a package to one of its dependencies.",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runWhy,
+}
+
+func init() {
+	whyCmd.Flags().IntP("num", "k", 1,
+		"number of shortest paths to show, all if non-positive.")
+	rootCmd.AddCommand(whyCmd)
+}
+
+func runWhy(cmd *cobra.Command, args []string) error {
+	k, _ := cmd.Flags().GetInt("num")
+
+	g, err := loadGraph(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	src := graph.NodeKey{ID: g.Container}
+	dst := graph.NodeKey{ID: args[1]}
+	paths := g.Paths(src, dst, k)
+	if len(paths) == 0 {
+		return fmt.Errorf("%s does not depend on %s", src, dst)
+	}
+	for i, path := range paths {
+		if i > 0 {
+			fmt.Println()
+		}
+		ids := make([]string, len(path.Nodes))
+		for i, n := range path.Nodes {
+			ids[i] = n.ID
+		}
+		fmt.Printf("# %d imports, weight %g\n%s\n", path.Len(), path.Weight, strings.Join(ids, "\n"))
+	}
+	return nil
+}
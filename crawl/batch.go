@@ -0,0 +1,179 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/arclabs561/pkgrank/graph"
+)
+
+// BatchOptions configures BatchAnalyze.
+type BatchOptions struct {
+	// Workers is the number of modules analyzed at once, the number of CPUs
+	// if not positive.
+	Workers int
+	// Timeout is how long each attempt at analyzing a module may take, or
+	// unlimited if not positive.
+	Timeout time.Duration
+	// Retries is the number of times to retry a module whose analysis fails
+	// because of the module proxy, as told by graph.IsProxyError.
+	Retries int
+	// Backoff is how long to wait before the first retry, doubling with
+	// each retry, a second if not positive.
+	Backoff time.Duration
+	// Interval is the least time between the start of any two attempts,
+	// limiting the rate of requests to the module proxy, or none if not
+	// positive.
+	Interval time.Duration
+	// Analyze returns the dependency graph of a module, given as a path
+	// with an optional @version suffix. It is graph.TransitiveGraphContext
+	// of the module's root package if nil.
+	Analyze func(ctx context.Context, module string) (*graph.Graph, error)
+	// Progress, if not nil, is called with the result of each module as
+	// soon as it is known, one at a time.
+	Progress func(Result)
+}
+
+// Result is the outcome of analyzing a module.
+type Result struct {
+	Module string
+	// Graph is the graph of the module, nil if Err is not.
+	Graph *graph.Graph
+	Err   error
+	// Attempts is the number of times the module was analyzed, 0 if it
+	// never was before ctx was done.
+	Attempts int
+	// Duration is the total time taken by the attempts, and the waits in
+	// between.
+	Duration time.Duration
+}
+
+// BatchAnalyze analyzes the given modules with a pool of workers, and returns
+// the result of each, in the order of modules. Modules that fail are only
+// reported in their result, so that the others are still analyzed, and
+// those left when ctx is done fail with its error.
+func BatchAnalyze(ctx context.Context, modules []string, opts BatchOptions) []Result {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if opts.Analyze == nil {
+		opts.Analyze = graph.TransitiveGraphContext
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+
+	results := make([]Result, len(modules))
+	for i, module := range modules {
+		results[i] = Result{Module: module}
+	}
+	var mu sync.Mutex
+	next := time.Now()
+	// wait waits for the turn of the next attempt, spacing them by
+	// opts.Interval.
+	wait := func() error {
+		mu.Lock()
+		d := time.Until(next)
+		next = time.Now().Add(max(d, 0) + opts.Interval)
+		mu.Unlock()
+		return sleep(ctx, d)
+	}
+	var progress sync.Mutex
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = analyze(ctx, modules[i], opts, wait)
+				if opts.Progress != nil {
+					progress.Lock()
+					opts.Progress(results[i])
+					progress.Unlock()
+				}
+			}
+		}()
+	}
+feed:
+	for i := range modules {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		for i := range results {
+			if results[i].Attempts == 0 {
+				results[i].Err = err
+			}
+		}
+	}
+	return results
+}
+
+// analyze analyzes a module, retrying on proxy errors.
+func analyze(ctx context.Context, module string, opts BatchOptions, wait func() error) Result {
+	r := Result{Module: module}
+	start := time.Now()
+	backoff := opts.Backoff
+	for {
+		if err := wait(); err != nil {
+			if r.Attempts == 0 {
+				r.Err = err
+			}
+			break
+		}
+		r.Attempts++
+		r.Graph, r.Err = attempt(ctx, module, opts)
+		if r.Err == nil || r.Attempts > opts.Retries || !graph.IsProxyError(r.Err) {
+			break
+		}
+		if err := sleep(ctx, backoff); err != nil {
+			break
+		}
+		backoff *= 2
+	}
+	if r.Err != nil {
+		r.Err = fmt.Errorf("%s: %w", module, r.Err)
+	}
+	r.Duration = time.Since(start)
+	return r
+}
+
+// attempt analyzes a module once, within opts.Timeout.
+func attempt(ctx context.Context, module string, opts BatchOptions) (*graph.Graph, error) {
+	if opts.Timeout <= 0 {
+		return opts.Analyze(ctx, module)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	g, err := opts.Analyze(attemptCtx, module)
+	if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("timed out after %v: %w", opts.Timeout, attemptCtx.Err())
+	}
+	return g, err
+}
+
+// sleep waits for d, or until ctx is done, returning its error.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
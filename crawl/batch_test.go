@@ -0,0 +1,153 @@
+package crawl_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arclabs561/pkgrank/crawl"
+	"github.com/arclabs561/pkgrank/graph"
+)
+
+var (
+	// proxyErr is a transient failure of the module proxy.
+	proxyErr = fmt.Errorf("go get: %w", graph.ErrNetwork)
+	// errBadModule is a permanent failure to analyze a module.
+	errBadModule = errors.New("no such module")
+)
+
+func TestBatchAnalyzeRetries(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		errs     []error // of each attempt, the last one repeating
+		retries  int
+		attempts int
+		wantErr  error
+	}{
+		{name: "success", errs: []error{nil}, retries: 2, attempts: 1},
+		{name: "retried", errs: []error{proxyErr, proxyErr, nil}, retries: 2, attempts: 3},
+		{name: "retries exhausted", errs: []error{proxyErr}, retries: 2, attempts: 3, wantErr: graph.ErrNetwork},
+		{name: "no retries", errs: []error{proxyErr}, attempts: 1, wantErr: graph.ErrNetwork},
+		{name: "not a proxy error", errs: []error{errBadModule, nil}, retries: 2, attempts: 1, wantErr: errBadModule},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			results := crawl.BatchAnalyze(context.Background(), []string{"example.com/m"}, crawl.BatchOptions{
+				Retries: tt.retries,
+				Backoff: time.Millisecond,
+				Analyze: func(ctx context.Context, module string) (*graph.Graph, error) {
+					err := tt.errs[min(calls, len(tt.errs)-1)]
+					calls++
+					if err != nil {
+						return nil, err
+					}
+					return &graph.Graph{Container: module}, nil
+				},
+			})
+			r := results[0]
+			if r.Attempts != tt.attempts || calls != tt.attempts {
+				t.Errorf("got %d attempts and %d calls, want %d", r.Attempts, calls, tt.attempts)
+			}
+			if !errors.Is(r.Err, tt.wantErr) || (tt.wantErr == nil) != (r.Err == nil) {
+				t.Errorf("got error %v, want %v", r.Err, tt.wantErr)
+			}
+			if (r.Graph != nil) != (r.Err == nil) {
+				t.Errorf("got graph %v with error %v", r.Graph, r.Err)
+			}
+		})
+	}
+}
+
+func TestBatchAnalyzeTimeout(t *testing.T) {
+	results := crawl.BatchAnalyze(context.Background(), []string{"example.com/slow"}, crawl.BatchOptions{
+		Timeout: 10 * time.Millisecond,
+		Retries: 2,
+		Analyze: func(ctx context.Context, module string) (*graph.Graph, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	r := results[0]
+	// Timeouts are not proxy errors, so they are not retried.
+	if r.Attempts != 1 {
+		t.Errorf("got %d attempts, want 1", r.Attempts)
+	}
+	if !errors.Is(r.Err, context.DeadlineExceeded) || !strings.Contains(r.Err.Error(), "timed out") {
+		t.Errorf("got error %v, want a timeout", r.Err)
+	}
+}
+
+func TestBatchAnalyzeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	modules := []string{"example.com/a", "example.com/b", "example.com/c"}
+	results := crawl.BatchAnalyze(ctx, modules, crawl.BatchOptions{
+		Workers: 1,
+		Analyze: func(ctx context.Context, module string) (*graph.Graph, error) {
+			cancel()
+			return nil, ctx.Err()
+		},
+	})
+	if len(results) != len(modules) {
+		t.Fatalf("got %d results, want %d", len(results), len(modules))
+	}
+	for i, r := range results {
+		if r.Module != modules[i] {
+			t.Errorf("got result of %s at %d, want %s", r.Module, i, modules[i])
+		}
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("%s: got error %v, want %v", r.Module, r.Err, context.Canceled)
+		}
+	}
+	// Only the first module was attempted before the cancellation.
+	if results[0].Attempts != 1 || results[1].Attempts != 0 || results[2].Attempts != 0 {
+		t.Errorf("got attempts %d, %d and %d, want 1, 0 and 0",
+			results[0].Attempts, results[1].Attempts, results[2].Attempts)
+	}
+}
+
+func TestBatchAnalyzeLimits(t *testing.T) {
+	const workers, interval = 2, 5 * time.Millisecond
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	var starts []time.Time
+	modules := []string{"a", "b", "c", "d", "e", "f"}
+	var progress []string
+	results := crawl.BatchAnalyze(context.Background(), modules, crawl.BatchOptions{
+		Workers:  workers,
+		Interval: interval,
+		Analyze: func(ctx context.Context, module string) (*graph.Graph, error) {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			starts = append(starts, time.Now())
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return &graph.Graph{Container: module}, nil
+		},
+		Progress: func(r crawl.Result) {
+			progress = append(progress, r.Module)
+		},
+	})
+	if maxRunning > workers {
+		t.Errorf("got %d analyses at once, want at most %d", maxRunning, workers)
+	}
+	if span := starts[len(starts)-1].Sub(starts[0]); span < time.Duration(len(modules)-1)*interval {
+		t.Errorf("attempts started within %v, want them spaced by %v", span, interval)
+	}
+	if len(progress) != len(modules) {
+		t.Errorf("got progress of %v, want every module", progress)
+	}
+	for i, r := range results {
+		if r.Err != nil || r.Graph.Container != modules[i] {
+			t.Errorf("got result %+v for %s", r, modules[i])
+		}
+	}
+}
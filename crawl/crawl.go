@@ -0,0 +1,135 @@
+// Package crawl ranks packages across an ecosystem of modules, by analyzing
+// many modules concurrently and merging their dependency graphs into one.
+package crawl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/arclabs561/pkgrank/graph"
+)
+
+// DefaultIndexURL is the base URL of the Go module index.
+const DefaultIndexURL = "https://index.golang.org"
+
+// Crawl analyzes the given modules with BatchAnalyze and merges their graphs
+// with graph.MergeLatest into one graph, of the "ecosystem" container, whose
+// centrality is that of packages across all of the modules. Modules that
+// fail to be analyzed are left out, and their errors are joined and returned
+// along with the graph of the others, unless ctx is done.
+func Crawl(ctx context.Context, modules []string, opts BatchOptions) (*graph.Graph, error) {
+	results := BatchAnalyze(ctx, modules, opts)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var graphs []*graph.Graph
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+			continue
+		}
+		graphs = append(graphs, r.Graph)
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	merged, err := graph.MergeLatest("ecosystem", workers, graphs...)
+	if err != nil {
+		return nil, err
+	}
+	return merged, errors.Join(errs...)
+}
+
+// ReadList reads a list of modules, one path with an optional @version
+// suffix per line. Blank lines and lines starting with # are skipped, as are
+// duplicates.
+func ReadList(r io.Reader) ([]string, error) {
+	var modules []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		modules = append(modules, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read module list: %w", err)
+	}
+	return modules, nil
+}
+
+// Index reads the feed of module versions of the Go module index. The zero
+// value is ready to use.
+type Index struct {
+	// BaseURL is the base URL of the index, DefaultIndexURL if empty.
+	BaseURL string
+	// HTTP is the client to send requests with, http.DefaultClient if nil.
+	HTTP *http.Client
+}
+
+// Modules returns the modules that the index lists as published since the
+// given time, up to limit entries of the feed, each at the last version
+// listed, in module@version form, in the order that they first appear.
+func (x *Index) Modules(ctx context.Context, since time.Time, limit int) ([]string, error) {
+	base := x.BaseURL
+	if base == "" {
+		base = DefaultIndexURL
+	}
+	q := url.Values{"since": {since.UTC().Format(time.RFC3339)}}
+	if limit > 0 {
+		q.Set("limit", fmt.Sprint(limit))
+	}
+	u := strings.TrimSuffix(base, "/") + "/index?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := x.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %s: %s", u, resp.Status)
+	}
+
+	var paths []string
+	versions := make(map[string]string)
+	dec := json.NewDecoder(resp.Body)
+	for dec.More() {
+		var entry struct {
+			Path    string
+			Version string
+		}
+		if err := dec.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", u, err)
+		}
+		if _, ok := versions[entry.Path]; !ok {
+			paths = append(paths, entry.Path)
+		}
+		versions[entry.Path] = entry.Version
+	}
+	modules := make([]string, len(paths))
+	for i, p := range paths {
+		modules[i] = p + "@" + versions[p]
+	}
+	return modules, nil
+}
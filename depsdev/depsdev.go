@@ -0,0 +1,263 @@
+// Package depsdev enriches graphs with metadata of their modules from the
+// deps.dev API, such as their latest version, license, OpenSSF Scorecard and
+// number of dependents.
+package depsdev
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arclabs561/pkgrank/cache"
+	"github.com/arclabs561/pkgrank/graph"
+)
+
+// DefaultBaseURL is the base URL of the deps.dev API.
+const DefaultBaseURL = "https://api.deps.dev"
+
+// cacheVersion is incremented whenever the cached responses are used
+// differently, invalidating them.
+const cacheVersion = 1
+
+// ErrNotFound is returned for modules that deps.dev does not know about, like
+// private ones.
+var ErrNotFound = errors.New("not found on deps.dev")
+
+// Info is the metadata of a module version.
+type Info struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	// Latest is the latest version of the module.
+	Latest string `json:"latest,omitempty"`
+	// Published is when the version was published, if known.
+	Published time.Time `json:"published"`
+	// Licenses are the SPDX license expressions of the version.
+	Licenses []string `json:"licenses,omitempty"`
+	// Scorecard is the OpenSSF Scorecard score of the module's source
+	// repository, from 0 to 10, or 0 if it has none.
+	Scorecard float64 `json:"scorecard,omitempty"`
+	// Dependents is the number of packages depending on the version.
+	Dependents int64 `json:"dependents,omitempty"`
+}
+
+// Client queries the deps.dev API. The zero value is ready to use, without
+// a cache.
+type Client struct {
+	// BaseURL is the base URL of the API, DefaultBaseURL if empty.
+	BaseURL string
+	// HTTP is the client to send requests with, http.DefaultClient if nil.
+	HTTP *http.Client
+	// Cache caches responses for the rest of the day, if not nil.
+	Cache *cache.Cache
+}
+
+// Module returns the metadata of the given version of a module, or of its
+// latest version if version is empty. It returns ErrNotFound if deps.dev does
+// not know the module. Metadata of the version or of its source repository
+// that deps.dev does not have is left empty.
+func (c *Client) Module(ctx context.Context, module, version string) (Info, error) {
+	info := Info{Module: module, Version: version}
+	name := url.PathEscape(module)
+
+	var pkg struct {
+		Versions []struct {
+			VersionKey struct {
+				Version string `json:"version"`
+			} `json:"versionKey"`
+			IsDefault bool `json:"isDefault"`
+		} `json:"versions"`
+	}
+	if err := c.get(ctx, "/v3/systems/go/packages/"+name, &pkg); err != nil {
+		return info, err
+	}
+	for _, v := range pkg.Versions {
+		if v.IsDefault {
+			info.Latest = v.VersionKey.Version
+		}
+	}
+	if info.Version == "" {
+		info.Version = info.Latest
+	}
+	if info.Version == "" {
+		return info, nil
+	}
+	path := "/systems/go/packages/" + name + "/versions/" + url.PathEscape(info.Version)
+
+	var ver struct {
+		PublishedAt     time.Time `json:"publishedAt"`
+		Licenses        []string  `json:"licenses"`
+		RelatedProjects []struct {
+			ProjectKey struct {
+				ID string `json:"id"`
+			} `json:"projectKey"`
+			RelationType string `json:"relationType"`
+		} `json:"relatedProjects"`
+	}
+	if err := c.get(ctx, "/v3"+path, &ver); err != nil && !errors.Is(err, ErrNotFound) {
+		return info, err
+	}
+	info.Published, info.Licenses = ver.PublishedAt, ver.Licenses
+	for _, p := range ver.RelatedProjects {
+		if p.RelationType != "SOURCE_REPO" {
+			continue
+		}
+		var project struct {
+			Scorecard struct {
+				OverallScore float64 `json:"overallScore"`
+			} `json:"scorecard"`
+		}
+		if err := c.get(ctx, "/v3/projects/"+url.PathEscape(p.ProjectKey.ID), &project); err != nil && !errors.Is(err, ErrNotFound) {
+			return info, err
+		}
+		info.Scorecard = project.Scorecard.OverallScore
+		break
+	}
+
+	var dependents struct {
+		DependentCount int64 `json:"dependentCount"`
+	}
+	if err := c.get(ctx, "/v3alpha"+path+":dependents", &dependents); err != nil && !errors.Is(err, ErrNotFound) {
+		return info, err
+	}
+	info.Dependents = dependents.DependentCount
+	return info, nil
+}
+
+// get decodes the JSON response to a GET request of path into v, from the
+// cache if possible.
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	u := strings.TrimSuffix(base, "/") + path
+
+	h := cache.NewHash("depsdev", cacheVersion)
+	// Refresh entries daily, as metadata like the latest version changes.
+	h.String(time.Now().UTC().Format(time.DateOnly))
+	h.String(u)
+	key := h.Sum()
+
+	body, ok := []byte(nil), false
+	if c.Cache != nil {
+		body, ok = c.Cache.Get(key)
+	}
+	if !ok {
+		var err error
+		if body, err = c.fetch(ctx, u); err != nil {
+			return err
+		}
+		if c.Cache != nil {
+			// Failing to cache only costs a request next time.
+			_ = c.Cache.Put(key, body)
+		}
+	}
+	// Missing resources are cached as empty bodies.
+	if len(body) == 0 {
+		return fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", u, err)
+	}
+	return nil
+}
+
+// fetch returns the body of the response to a GET request of u, or an empty
+// body if it is not found.
+func (c *Client) fetch(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return []byte{}, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to get %s: %s", u, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", u, err)
+	}
+	return body, nil
+}
+
+// Enrich sets the AttrLatest, AttrPublished, AttrLicense, AttrScorecard and
+// AttrDependents attributes of the nodes of g from the metadata of their
+// module version, so that they are part of its exports. Modules that deps.dev
+// does not know, and the standard library, are skipped. Errors of other
+// modules are joined, after enriching the rest.
+func (c *Client) Enrich(ctx context.Context, g *graph.Graph) error {
+	type modVer struct{ module, version string }
+	nodes := make(map[modVer][]graph.NodeKey)
+	for key, node := range g.Nodes {
+		if node.Data == nil || node.Data.Module == "" || node.Data.Module == "std" {
+			continue
+		}
+		mv := modVer{node.Data.Module, node.Data.Version}
+		nodes[mv] = append(nodes[mv], key)
+	}
+	mvs := make([]modVer, 0, len(nodes))
+	for mv := range nodes {
+		mvs = append(mvs, mv)
+	}
+	sort.Slice(mvs, func(i, j int) bool {
+		if mvs[i].module != mvs[j].module {
+			return mvs[i].module < mvs[j].module
+		}
+		return mvs[i].version < mvs[j].version
+	})
+
+	var errs []error
+	for _, mv := range mvs {
+		info, err := c.Module(ctx, mv.module, mv.version)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", mv.module, err))
+			continue
+		}
+		for _, key := range nodes[mv] {
+			g.SetNodeAttrs(key, info.setAttrs)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setAttrs sets the attributes of Enrich from the known metadata.
+func (i Info) setAttrs(a *graph.Attrs) {
+	if i.Latest != "" {
+		a.SetString(graph.AttrLatest, i.Latest)
+	}
+	if !i.Published.IsZero() {
+		a.SetString(graph.AttrPublished, i.Published.UTC().Format(time.RFC3339))
+	}
+	if len(i.Licenses) > 0 {
+		a.SetString(graph.AttrLicense, strings.Join(i.Licenses, " AND "))
+	}
+	if i.Scorecard > 0 {
+		a.SetFloat(graph.AttrScorecard, i.Scorecard)
+	}
+	if i.Dependents > 0 {
+		a.SetInt(graph.AttrDependents, i.Dependents)
+	}
+}
@@ -0,0 +1,25 @@
+module github.com/arclabs561/pkgrank/golangci
+
+go 1.21.0
+
+require (
+	github.com/arclabs561/pkgrank v0.0.0
+	github.com/golangci/plugin-module-register v0.1.1
+	golang.org/x/tools v0.18.0
+)
+
+require (
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.19 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/rs/zerolog v1.30.0 // indirect
+	github.com/samber/lo v1.38.1 // indirect
+	golang.org/x/exp v0.0.0-20230905200255-921286631fa9 // indirect
+	golang.org/x/mod v0.15.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	golang.org/x/term v0.12.0 // indirect
+	gonum.org/v1/gonum v0.14.0 // indirect
+)
+
+// The plugin is built from a checkout of pkgrank, see plugin.go.
+replace github.com/arclabs561/pkgrank => ../
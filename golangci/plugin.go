@@ -0,0 +1,92 @@
+// Package golangci is a golangci-lint module plugin running the checks of
+// pkgrank lint, that is the layers, cycles and unusedpkg analyzers, as a
+// single pkgrank linter of an existing lint pipeline. It is a module of its
+// own, so that pkgrank does not depend on golangci-lint.
+//
+// Build a golangci-lint binary with the plugin from a checkout of pkgrank
+// with golangci-lint custom and a .custom-gcl.yml of
+//
+//	version: v1.60.1
+//	plugins:
+//	  - module: github.com/arclabs561/pkgrank/golangci
+//	    path: ./pkgrank/golangci
+//
+// and enable it in .golangci.yml, with the settings of Settings:
+//
+//	linters:
+//	  enable:
+//	    - pkgrank
+//	linters-settings:
+//	  custom:
+//	    pkgrank:
+//	      type: module
+//	      settings:
+//	        policy: .pkgrank-arch.json
+package golangci
+
+import (
+	"fmt"
+
+	"github.com/arclabs561/pkgrank/analyzers/cycles"
+	"github.com/arclabs561/pkgrank/analyzers/layers"
+	"github.com/arclabs561/pkgrank/analyzers/unusedpkg"
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+)
+
+func init() {
+	register.Plugin("pkgrank", New)
+}
+
+// Settings are the settings of the pkgrank linter in .golangci.yml.
+type Settings struct {
+	// Policy is the architecture policy file that the layers analyzer
+	// checks imports against, graph.DefaultPolicyFile by default.
+	Policy string `json:"policy"`
+	// Disable lists the analyzers not to run, e.g. ["unusedpkg"].
+	Disable []string `json:"disable"`
+}
+
+type plugin struct {
+	settings Settings
+}
+
+var _ register.LinterPlugin = (*plugin)(nil)
+
+// New returns the pkgrank linter configured by settings.
+func New(settings any) (register.LinterPlugin, error) {
+	s, err := register.DecodeSettings[Settings](settings)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin{settings: s}, nil
+}
+
+func (p *plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	if p.settings.Policy != "" {
+		if err := layers.Analyzer.Flags.Set("policy", p.settings.Policy); err != nil {
+			return nil, err
+		}
+	}
+	disabled := make(map[string]bool)
+	for _, name := range p.settings.Disable {
+		disabled[name] = true
+	}
+	var analyzers []*analysis.Analyzer
+	for _, a := range []*analysis.Analyzer{layers.Analyzer, cycles.Analyzer, unusedpkg.Analyzer} {
+		if !disabled[a.Name] {
+			analyzers = append(analyzers, a)
+		}
+		delete(disabled, a.Name)
+	}
+	for name := range disabled {
+		return nil, fmt.Errorf("unknown analyzer %q in disable", name)
+	}
+	return analyzers, nil
+}
+
+// GetLoadMode returns the mode of loading packages that the analyzers need:
+// cycles and unusedpkg need the facts of modver, computed from types.
+func (p *plugin) GetLoadMode() string {
+	return register.LoadModeTypesInfo
+}
@@ -0,0 +1,161 @@
+package graph
+
+import "sync"
+
+// adjacency indexes the directed edges of a graph by their nodes, so that the
+// neighbours of a node are found without scanning every edge. It is built on
+// the first query and kept up to date by AddEdge and RemoveEdge.
+type adjacency struct {
+	// others is the number of edges of the graph that are not directed.
+	others int
+	succs  map[NodeKey]map[NodeKey][]EdgeKey
+	preds  map[NodeKey]map[NodeKey][]EdgeKey
+	// succWeights and predWeights are the total weight of the edges
+	// between each pair of nodes, see Graph.successors.
+	succWeights map[NodeKey]map[NodeKey]float64
+	predWeights map[NodeKey]map[NodeKey]float64
+}
+
+// adjMu guards the assignment of the index of a graph by a query, since
+// graphs may be queried concurrently.
+var adjMu sync.Mutex
+
+// adjacency returns the graph's index, building it if there is none.
+func (f *Graph) adjacency() *adjacency {
+	adjMu.Lock()
+	a := f.adj
+	adjMu.Unlock()
+	if a != nil {
+		return a
+	}
+	a = &adjacency{
+		succs:       make(map[NodeKey]map[NodeKey][]EdgeKey),
+		preds:       make(map[NodeKey]map[NodeKey][]EdgeKey),
+		succWeights: make(map[NodeKey]map[NodeKey]float64),
+		predWeights: make(map[NodeKey]map[NodeKey]float64),
+	}
+	for _, edge := range f.Edges {
+		a.add(edge, f.Edges)
+	}
+	// Another query may have built an index meanwhile, which is kept.
+	adjMu.Lock()
+	defer adjMu.Unlock()
+	if f.adj == nil {
+		f.adj = a
+	}
+	return f.adj
+}
+
+// add indexes an edge of edges.
+func (a *adjacency) add(edge Edge, edges map[EdgeKey]Edge) {
+	e, ok := edge.(*DirectedEdge)
+	if !ok {
+		a.others++
+		return
+	}
+	key := edge.Key()
+	if a.succs[e.Src] == nil {
+		a.succs[e.Src] = make(map[NodeKey][]EdgeKey)
+		a.succWeights[e.Src] = make(map[NodeKey]float64)
+	}
+	a.succs[e.Src][e.Dst] = append(a.succs[e.Src][e.Dst], key)
+	if a.preds[e.Dst] == nil {
+		a.preds[e.Dst] = make(map[NodeKey][]EdgeKey)
+		a.predWeights[e.Dst] = make(map[NodeKey]float64)
+	}
+	a.preds[e.Dst][e.Src] = append(a.preds[e.Dst][e.Src], key)
+	a.reweigh(e.Src, e.Dst, edges)
+}
+
+// remove drops an edge, no longer in edges, from the index.
+func (a *adjacency) remove(edge Edge, edges map[EdgeKey]Edge) {
+	e, ok := edge.(*DirectedEdge)
+	if !ok {
+		a.others--
+		return
+	}
+	key := edge.Key()
+	a.succs[e.Src][e.Dst] = removeKey(a.succs[e.Src][e.Dst], key)
+	if len(a.succs[e.Src][e.Dst]) == 0 {
+		delete(a.succs[e.Src], e.Dst)
+	}
+	a.preds[e.Dst][e.Src] = removeKey(a.preds[e.Dst][e.Src], key)
+	if len(a.preds[e.Dst][e.Src]) == 0 {
+		delete(a.preds[e.Dst], e.Src)
+	}
+	a.reweigh(e.Src, e.Dst, edges)
+}
+
+// reweigh sums the weights of the edges from src to dst, rather than
+// updating the sum, so that it does not depend on the order of changes.
+func (a *adjacency) reweigh(src, dst NodeKey, edges map[EdgeKey]Edge) {
+	keys := a.succs[src][dst]
+	if len(keys) == 0 {
+		delete(a.succWeights[src], dst)
+		delete(a.predWeights[dst], src)
+		return
+	}
+	var w float64
+	for _, key := range keys {
+		w += edges[key].Weight()
+	}
+	a.succWeights[src][dst] = w
+	a.predWeights[dst][src] = w
+}
+
+func removeKey(keys []EdgeKey, key EdgeKey) []EdgeKey {
+	for i, k := range keys {
+		if k == key {
+			return append(keys[:i], keys[i+1:]...)
+		}
+	}
+	return keys
+}
+
+// Successors returns the sorted nodes that n has a directed edge to, in time
+// proportional to their number.
+func (f *Graph) Successors(n NodeKey) []NodeKey {
+	return sortedKeys(f.adjacency().succs[n])
+}
+
+// Predecessors returns the sorted nodes that have a directed edge to n, in
+// time proportional to their number.
+func (f *Graph) Predecessors(n NodeKey) []NodeKey {
+	return sortedKeys(f.adjacency().preds[n])
+}
+
+// Degree returns the number of distinct nodes that have a directed edge to n,
+// and that n has a directed edge to.
+func (f *Graph) Degree(n NodeKey) (in, out int) {
+	a := f.adjacency()
+	return len(a.preds[n]), len(a.succs[n])
+}
+
+// incident returns the keys of the edges incident to n.
+func (f *Graph) incident(n NodeKey) []EdgeKey {
+	a := f.adjacency()
+	var keys []EdgeKey
+	if a.others > 0 {
+		for key, edge := range f.Edges {
+			for _, m := range edge.Nodes() {
+				if m == n {
+					keys = append(keys, key)
+					break
+				}
+			}
+		}
+	} else {
+		seen := make(map[EdgeKey]bool)
+		for _, adj := range []map[NodeKey][]EdgeKey{a.succs[n], a.preds[n]} {
+			for _, ks := range adj {
+				for _, key := range ks {
+					if !seen[key] {
+						seen[key] = true
+						keys = append(keys, key)
+					}
+				}
+			}
+		}
+	}
+	return keys
+}
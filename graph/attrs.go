@@ -0,0 +1,196 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Well-known attribute keys.
+const (
+	// AttrLOC is the number of lines of Go code of a node, an int.
+	AttrLOC = "loc"
+	// AttrFiles is the number of Go files of a node, an int.
+	AttrFiles = "files"
+	// AttrExported is the number of exported identifiers of a node, its
+	// package-level ones and the methods of its exported types, an int.
+	AttrExported = "exported"
+	// AttrCommits is the number of recent commits that changed a node's
+	// files, an int, see Graph.AddChurn.
+	AttrCommits = "commits"
+	// AttrChurn is the number of lines that recent commits added to and
+	// deleted from a node's files, an int, see Graph.AddChurn.
+	AttrChurn = "churn"
+	// AttrLicense is the SPDX license identifier of a node's module, a
+	// string.
+	AttrLicense = "license"
+	// AttrSize is the number of bytes that a node's symbols take in a
+	// binary, an int, see Graph.AddSizes.
+	AttrSize = "size"
+	// AttrOwner is the team owning a node, a string, see Graph.AddOwners.
+	AttrOwner = "owner"
+	// AttrCluster is the ID of the cluster a node belongs to, an int.
+	AttrCluster = "cluster"
+	// AttrVulns are the comma-separated IDs of the known vulnerabilities of
+	// a node, a string, see Graph.AddVulns.
+	AttrVulns = "vulns"
+	// AttrLatest is the latest version of a node's module, a string.
+	AttrLatest = "latest"
+	// AttrPublished is when the version of a node's module was published, a
+	// string in RFC 3339 format.
+	AttrPublished = "published"
+	// AttrMaintainers is the number of maintainers of a node's module, an
+	// int.
+	AttrMaintainers = "maintainers"
+	// AttrReplace is the path of the module that replaces a node's module,
+	// a string.
+	AttrReplace = "replace"
+	// AttrScorecard is the OpenSSF Scorecard score of the source repository
+	// of a node's module, from 0 to 10, a float.
+	AttrScorecard = "scorecard"
+	// AttrDependents is the number of packages that depend on a node's
+	// module in its ecosystem, an int.
+	AttrDependents = "dependents"
+	// AttrVendored is whether a node is a package of a vendor directory, a
+	// bool.
+	AttrVendored = "vendored"
+)
+
+// Attrs are named attributes of a node or edge, for data that has no field of
+// its own. Values are strings, int64s, float64s or bools, so that they survive
+// encoding as JSON and gob, and are set and read with the typed methods.
+type Attrs map[string]any
+
+// SetString sets the attribute key to the string v.
+func (a *Attrs) SetString(key, v string) { a.set(key, v) }
+
+// SetInt sets the attribute key to the integer v.
+func (a *Attrs) SetInt(key string, v int64) { a.set(key, v) }
+
+// SetFloat sets the attribute key to the number v.
+func (a *Attrs) SetFloat(key string, v float64) { a.set(key, v) }
+
+// SetBool sets the attribute key to the boolean v.
+func (a *Attrs) SetBool(key string, v bool) { a.set(key, v) }
+
+func (a *Attrs) set(key string, v any) {
+	if *a == nil {
+		*a = make(Attrs)
+	}
+	(*a)[key] = v
+}
+
+// String returns the string attribute key, and whether it is a set string.
+func (a Attrs) String(key string) (string, bool) {
+	v, ok := a[key].(string)
+	return v, ok
+}
+
+// Int returns the integer attribute key, and whether it is a set integer.
+func (a Attrs) Int(key string) (int64, bool) {
+	v, ok := a[key].(int64)
+	return v, ok
+}
+
+// Float returns the numeric attribute key, converting integers, and whether
+// it is a set number.
+func (a Attrs) Float(key string) (float64, bool) {
+	switch v := a[key].(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// Bool returns the boolean attribute key, and whether it is a set boolean.
+func (a Attrs) Bool(key string) (bool, bool) {
+	v, ok := a[key].(bool)
+	return v, ok
+}
+
+// Keys returns the sorted keys of the attributes.
+func (a Attrs) Keys() []string {
+	keys := make([]string, 0, len(a))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Format returns the attributes as sorted key=value pairs separated by sep.
+func (a Attrs) Format(sep string) string {
+	pairs := make([]string, 0, len(a))
+	for _, key := range a.Keys() {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, a[key]))
+	}
+	return strings.Join(pairs, sep)
+}
+
+// clone returns a copy of the attributes, or nil if there are none.
+func (a Attrs) clone() Attrs {
+	if a == nil {
+		return nil
+	}
+	c := make(Attrs, len(a))
+	for key, v := range a {
+		c[key] = v
+	}
+	return c
+}
+
+// mergeAttrs returns the attributes of a, with any missing ones filled in from
+// b. It returns a itself if there is nothing to fill in.
+func mergeAttrs(a, b Attrs) Attrs {
+	var merged Attrs
+	for key, v := range b {
+		if _, ok := a[key]; ok {
+			continue
+		}
+		if merged == nil {
+			merged = make(Attrs, len(a)+len(b))
+			for key, v := range a {
+				merged[key] = v
+			}
+		}
+		merged[key] = v
+	}
+	if merged == nil {
+		return a
+	}
+	return merged
+}
+
+// UnmarshalJSON decodes attributes, keeping integers as int64 rather than
+// float64 so that they read back as they were set.
+func (a *Attrs) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	*a = nil
+	for key, v := range raw {
+		switch v := v.(type) {
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				a.SetInt(key, i)
+			} else if f, err := v.Float64(); err == nil {
+				a.SetFloat(key, f)
+			} else {
+				return fmt.Errorf("invalid number attribute %s: %w", key, err)
+			}
+		case string, bool:
+			a.set(key, v)
+		default:
+			return fmt.Errorf("unsupported attribute %s of type %T", key, v)
+		}
+	}
+	return nil
+}
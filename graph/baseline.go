@@ -0,0 +1,167 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// BaselineVersion is the version of the baseline document written by
+// Baseline.WriteFile. It is incremented on incompatible changes.
+const BaselineVersion = 1
+
+// Baseline is a snapshot of a graph, against which later graphs are checked
+// for regressions.
+type Baseline struct {
+	Version int `json:"version"`
+	// Edges are the sorted source and destination IDs of every edge.
+	Edges [][2]string `json:"edges"`
+	// Cycles are the sorted IDs of each strongly connected component of
+	// more than one node.
+	Cycles [][]string `json:"cycles"`
+	Stats  Stats      `json:"stats"`
+}
+
+// Baseline takes a snapshot of the graph.
+func (f Graph) Baseline() Baseline {
+	b := Baseline{
+		Version: BaselineVersion,
+		Edges:   [][2]string{},
+		Cycles:  [][]string{},
+		Stats:   f.Stats(),
+	}
+	for src, succs := range f.successors() {
+		for dst := range succs {
+			b.Edges = append(b.Edges, [2]string{src.ID, dst.ID})
+		}
+	}
+	sort.Slice(b.Edges, func(i, j int) bool {
+		if b.Edges[i][0] != b.Edges[j][0] {
+			return b.Edges[i][0] < b.Edges[j][0]
+		}
+		return b.Edges[i][1] < b.Edges[j][1]
+	})
+	for _, scc := range f.StronglyConnectedComponents() {
+		if len(scc) < 2 {
+			continue
+		}
+		ids := make([]string, len(scc))
+		for i, n := range scc {
+			ids[i] = n.ID
+		}
+		b.Cycles = append(b.Cycles, ids)
+	}
+	sort.Slice(b.Cycles, func(i, j int) bool {
+		return strings.Join(b.Cycles[i], " ") < strings.Join(b.Cycles[j], " ")
+	})
+	return b
+}
+
+// ReadBaseline reads a baseline written by Baseline.WriteFile.
+func ReadBaseline(name string) (*Baseline, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to decode baseline %s: %w", name, err)
+	}
+	if b.Version != BaselineVersion {
+		return nil, fmt.Errorf("unsupported baseline version: %d", b.Version)
+	}
+	return &b, nil
+}
+
+// WriteFile writes the baseline to the named file as JSON.
+func (b Baseline) WriteFile(name string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(data, '\n'), 0644)
+}
+
+// Metric returns the value of the named statistic, by its JSON name in
+// Stats, e.g. "longestChain".
+func (s Stats) Metric(name string) (float64, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return 0, err
+	}
+	var metrics map[string]interface{}
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return 0, err
+	}
+	v, ok := metrics[name].(float64)
+	if !ok {
+		return 0, fmt.Errorf("unknown metric: %s", name)
+	}
+	return v, nil
+}
+
+// Regressions returns a finding for every cycle and edge of current that is
+// not in the baseline, and every metric named in maxIncrease that increased
+// by more than its allowed amount since the baseline.
+func (b Baseline) Regressions(current Baseline, maxIncrease map[string]float64) ([]Finding, error) {
+	var findings []Finding
+	cycles := make(map[string]bool, len(b.Cycles))
+	for _, cycle := range b.Cycles {
+		cycles[strings.Join(cycle, " ")] = true
+	}
+	for _, cycle := range current.Cycles {
+		if cycles[strings.Join(cycle, " ")] {
+			continue
+		}
+		nodes := make([]NodeKey, len(cycle))
+		for i, id := range cycle {
+			nodes[i] = NodeKey{ID: id}
+		}
+		findings = append(findings, Finding{
+			Rule:    RuleCycle,
+			Level:   LevelError,
+			Message: "new import cycle between " + strings.Join(cycle, ", "),
+			Nodes:   nodes,
+		})
+	}
+	edges := make(map[[2]string]bool, len(b.Edges))
+	for _, edge := range b.Edges {
+		edges[edge] = true
+	}
+	for _, edge := range current.Edges {
+		if edges[edge] {
+			continue
+		}
+		findings = append(findings, Finding{
+			Rule:    RuleDependency,
+			Level:   LevelError,
+			Message: fmt.Sprintf("new dependency of %s on %s", edge[0], edge[1]),
+			Nodes:   []NodeKey{{ID: edge[0]}, {ID: edge[1]}},
+		})
+	}
+	names := make([]string, 0, len(maxIncrease))
+	for name := range maxIncrease {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		before, err := b.Stats.Metric(name)
+		if err != nil {
+			return nil, err
+		}
+		after, err := current.Stats.Metric(name)
+		if err != nil {
+			return nil, err
+		}
+		if after-before > maxIncrease[name] {
+			findings = append(findings, Finding{
+				Rule:    RuleRegression,
+				Level:   LevelError,
+				Message: fmt.Sprintf("%s increased from %v to %v, by more than %v", name, before, after, maxIncrease[name]),
+			})
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// BinaryGraph returns the graph of the modules built into the Go binary of
+// the given file, from its embedded build information, like go version -m.
+// The graph's Container is the path of the binary's main package, or of its
+// main module or file if that is not recorded, whose node imports a node for
+// each module, named by its path, and for the standard library. Build
+// information does not record which module requires which, so every module
+// is a direct dependency of the main package. Replaced modules have the
+// version of their replacement, and its path in AttrReplace.
+func BinaryGraph(name string) (*Graph, error) {
+	info, err := buildinfo.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build info of %s: %w", name, err)
+	}
+	path := info.Path
+	switch {
+	case path == "" && info.Main.Path != "":
+		path = info.Main.Path
+	case path == "":
+		path = strings.TrimSuffix(filepath.Base(name), ".exe")
+	}
+	g := &Graph{
+		Container:       path,
+		AddedContainers: map[string]struct{}{path: {}},
+	}
+	root := NodeKey{ID: path}
+	g.AddNode(root, &NodeData{Module: info.Main.Path, Version: info.Main.Version})
+	g.AddNode(NodeKey{ID: "std"}, &NodeData{Module: "std", Version: info.GoVersion})
+	if _, err := g.AddEdge(NewDirectedEdge(path, path, "std")); err != nil {
+		return nil, err
+	}
+	for _, dep := range info.Deps {
+		data := &NodeData{Module: dep.Path, Version: dep.Version}
+		if r := dep.Replace; r != nil {
+			data.Attrs.SetString(AttrReplace, r.Path)
+			if r.Version != "" {
+				data.Version = r.Version
+			}
+		}
+		g.AddNode(NodeKey{ID: dep.Path}, data)
+		if _, err := g.AddEdge(NewDirectedEdge(path, path, dep.Path)); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
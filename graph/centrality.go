@@ -0,0 +1,162 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// RankResult is the centrality of a node, as measured by Centrality.
+type RankResult struct {
+	Node  NodeKey `json:"node"`
+	Score float64 `json:"score"`
+}
+
+// CentralityOption configures how Centrality measures importance.
+type CentralityOption func(*centralityOptions)
+
+type centralityOptions struct {
+	damping    float64
+	tolerance  float64
+	top        int
+	weightAttr string
+	popularity struct {
+		attr  string
+		blend float64
+	}
+}
+
+func newCentralityOptions(opts []CentralityOption) centralityOptions {
+	o := centralityOptions{
+		damping:   0.85,
+		tolerance: 0.0001,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithDamping sets the damping factor of PageRank, the probability of
+// following an edge rather than jumping to a random node, 0.85 by default.
+func WithDamping(d float64) CentralityOption {
+	return func(o *centralityOptions) {
+		o.damping = d
+	}
+}
+
+// WithTolerance sets the change in ranks between iterations of PageRank below
+// which it stops, 0.0001 by default.
+func WithTolerance(tol float64) CentralityOption {
+	return func(o *centralityOptions) {
+		o.tolerance = tol
+	}
+}
+
+// WithTop keeps only the n most important nodes, or all of them if n is not
+// positive.
+func WithTop(n int) CentralityOption {
+	return func(o *centralityOptions) {
+		o.top = n
+	}
+}
+
+// WithWeightAttr multiplies the score of each node by the sum of the numeric
+// attribute key over the node and the nodes that it reaches, as
+// TransitiveAttr, e.g. to rank packages by both their centrality and the
+// lines of code that they pull in with AttrLOC.
+func WithWeightAttr(key string) CentralityOption {
+	return func(o *centralityOptions) {
+		o.weightAttr = key
+	}
+}
+
+// WithPopularity blends the score of each node with its popularity, the
+// numeric attribute key such as AttrDependents, so that rankings reflect both
+// the structure of the graph and real-world usage. Scores and the logarithms
+// of popularities are normalized to sum to one, and blended with the given
+// weight of popularity, from 0 to 1. Nodes without the attribute have no
+// popularity.
+func WithPopularity(key string, blend float64) CentralityOption {
+	return func(o *centralityOptions) {
+		o.popularity.attr, o.popularity.blend = key, blend
+	}
+}
+
+// Centrality returns the nodes of the directed edges of g, with the most
+// important listed first, as measured by the given centrality measure. Ties
+// are listed in order of ID.
+func Centrality(g Graph, measure CentralityMeasure, opts ...CentralityOption) ([]RankResult, error) {
+	o := newCentralityOptions(opts)
+	ranks, err := centrality(g, measure, o)
+	if err != nil {
+		return nil, err
+	}
+	if o.top > 0 && o.top < len(ranks) {
+		ranks = ranks[:o.top]
+	}
+	return ranks, nil
+}
+
+// centrality returns the ranks of all nodes of Centrality, ignoring o.top.
+func centrality(g Graph, measure CentralityMeasure, o centralityOptions) ([]RankResult, error) {
+	if o.damping <= 0 || o.damping >= 1 {
+		return nil, fmt.Errorf("invalid damping factor %g, must be between 0 and 1", o.damping)
+	}
+	if o.tolerance <= 0 {
+		return nil, fmt.Errorf("invalid tolerance %g, must be positive", o.tolerance)
+	}
+	imps, scores, err := g.ToImportGraph().centrality(measure, o)
+	if err != nil {
+		return nil, err
+	}
+	ranks := make([]RankResult, len(imps))
+	for i, imp := range imps {
+		ranks[i] = RankResult{Node: NodeKey{ID: imp}, Score: scores[i]}
+	}
+	if attr, blend := o.popularity.attr, o.popularity.blend; attr != "" && blend > 0 {
+		if blend > 1 {
+			return nil, fmt.Errorf("invalid popularity blend %g, must be between 0 and 1", blend)
+		}
+		popularity := make(map[NodeKey]float64)
+		var totalPopularity, totalScore float64
+		for _, r := range ranks {
+			if data := g.Nodes[r.Node].Data; data != nil {
+				if v, ok := data.Attrs.Float(attr); ok && v > 0 {
+					popularity[r.Node] = math.Log1p(v)
+					totalPopularity += popularity[r.Node]
+				}
+			}
+			totalScore += r.Score
+		}
+		if len(popularity) == 0 {
+			return nil, fmt.Errorf("no node has a numeric %s attribute to blend in", attr)
+		}
+		for i := range ranks {
+			r := &ranks[i]
+			r.Score = (1-blend)*ratio(r.Score, totalScore) + blend*ratio(popularity[r.Node], totalPopularity)
+		}
+		sortRanks(ranks)
+	}
+	if o.weightAttr != "" {
+		weights := g.TransitiveAttr(o.weightAttr)
+		if len(weights) == 0 {
+			return nil, fmt.Errorf("no node has a numeric %s attribute to weight by", o.weightAttr)
+		}
+		for i := range ranks {
+			ranks[i].Score *= weights[ranks[i].Node]
+		}
+		sortRanks(ranks)
+	}
+	return ranks, nil
+}
+
+// sortRanks sorts ranks by decreasing score, and then by ID.
+func sortRanks(ranks []RankResult) {
+	sort.SliceStable(ranks, func(i, j int) bool {
+		if ranks[i].Score != ranks[j].Score {
+			return ranks[i].Score > ranks[j].Score
+		}
+		return ranks[i].Node.ID < ranks[j].Node.ID
+	})
+}
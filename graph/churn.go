@@ -0,0 +1,217 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arclabs561/pkgrank/cache"
+)
+
+// churnCacheVersion is incremented whenever the cached commits are encoded
+// differently.
+const churnCacheVersion = 1
+
+// Commit is a commit of a git repository and the files that it changed.
+type Commit struct {
+	Hash  string       `json:"hash"`
+	Files []FileChange `json:"files"`
+}
+
+// FileChange is the number of lines that a commit added to and deleted from
+// a file, 0 for binary files. Path is relative to the root of the repository.
+type FileChange struct {
+	Path    string `json:"path"`
+	Added   int64  `json:"added"`
+	Deleted int64  `json:"deleted"`
+}
+
+// ParseNumstat reads the output of git log --numstat --format=%H.
+func ParseNumstat(r io.Reader) ([]Commit, error) {
+	var commits []Commit
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) == 1 {
+			commits = append(commits, Commit{Hash: line})
+			continue
+		}
+		if len(fields) != 3 || len(commits) == 0 {
+			return nil, fmt.Errorf("invalid numstat line %q", line)
+		}
+		change := FileChange{Path: renamedPath(fields[2])}
+		// Binary files have - for both counts.
+		if fields[0] != "-" {
+			var err error
+			if change.Added, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid numstat line %q: %w", line, err)
+			}
+			if change.Deleted, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid numstat line %q: %w", line, err)
+			}
+		}
+		c := &commits[len(commits)-1]
+		c.Files = append(c.Files, change)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read git log output: %w", err)
+	}
+	return commits, nil
+}
+
+// renamedPath returns the new path of a numstat path, which git writes as
+// old => new or dir/{old => new}/file for renamed files.
+func renamedPath(p string) string {
+	if i, j := strings.Index(p, "{"), strings.Index(p, "}"); i >= 0 && j > i {
+		if _, to, ok := strings.Cut(p[i+1:j], " => "); ok {
+			return path.Clean(p[:i] + to + p[j+1:])
+		}
+	}
+	if _, to, ok := strings.Cut(p, " => "); ok {
+		return to
+	}
+	return p
+}
+
+// ReadChurn returns the root of the git repository of dir, and the commits
+// reachable from its HEAD since the given time, from git log. If c is not
+// nil, the commits are cached by HEAD and the day of since.
+func ReadChurn(dir string, since time.Time, c *cache.Cache) (string, []Commit, error) {
+	out, err := doExec(execQuiet, dir, nil, "git", "rev-parse", "--show-toplevel", "HEAD")
+	if err != nil {
+		return "", nil, err
+	}
+	root, head, ok := strings.Cut(out, "\n")
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected git rev-parse output %q", out)
+	}
+	day := since.UTC().Format(time.DateOnly)
+	h := cache.NewHash("churn", churnCacheVersion)
+	h.String(head)
+	h.String(day)
+	key := h.Sum()
+	if c != nil {
+		if data, ok := c.Get(key); ok {
+			var commits []Commit
+			if err := json.Unmarshal(data, &commits); err == nil {
+				return root, commits, nil
+			}
+		}
+	}
+	out, err = doExec(execQuiet, root, nil, "git", "log", "--numstat", "--format=%H", "--since="+day, "HEAD")
+	if err != nil {
+		return "", nil, err
+	}
+	commits, err := ParseNumstat(strings.NewReader(out))
+	if err != nil {
+		return "", nil, err
+	}
+	if c != nil {
+		// Failing to cache only costs running git log again.
+		if data, err := json.Marshal(commits); err == nil {
+			_ = c.Put(key, data)
+		}
+	}
+	return root, commits, nil
+}
+
+// AddChurn sets the AttrCommits and AttrChurn attributes of the nodes of the
+// packages of the module in dir to the number of the commits that changed
+// their Go files, and the lines that they added and deleted. Commits are
+// those of ReadChurn, of the repository at root.
+func (f *Graph) AddChurn(dir, root string, commits []Commit) error {
+	pkgs, err := listPackages(dir, "./...")
+	if err != nil {
+		return err
+	}
+	byDir := make(map[string]NodeKey, len(pkgs))
+	for _, p := range pkgs {
+		byDir[filepath.Clean(p.Dir)] = NodeKey{ID: p.ImportPath}
+	}
+	type churn struct{ commits, lines int64 }
+	churns := make(map[NodeKey]*churn)
+	for _, c := range commits {
+		touched := make(map[NodeKey]bool)
+		for _, file := range c.Files {
+			if !strings.HasSuffix(file.Path, ".go") {
+				continue
+			}
+			key, ok := byDir[filepath.Join(root, filepath.Dir(filepath.FromSlash(file.Path)))]
+			if !ok {
+				continue
+			}
+			if churns[key] == nil {
+				churns[key] = &churn{}
+			}
+			churns[key].lines += file.Added + file.Deleted
+			if !touched[key] {
+				touched[key] = true
+				churns[key].commits++
+			}
+		}
+	}
+	for key, c := range churns {
+		f.SetNodeAttrs(key, func(a *Attrs) {
+			a.SetInt(AttrCommits, c.commits)
+			a.SetInt(AttrChurn, c.lines)
+		})
+	}
+	return nil
+}
+
+// ChurnRank is the centrality of a node and how much it changed, as set by
+// AddChurn.
+type ChurnRank struct {
+	Node    NodeKey `json:"node"`
+	Score   float64 `json:"score"`
+	Commits int64   `json:"commits"`
+	Churn   int64   `json:"churn"`
+	// Danger is the product of Score and Churn, each relative to the
+	// highest among the nodes, from 0 to 1.
+	Danger float64 `json:"danger"`
+}
+
+// ChurnRanks combines the centrality ranks of nodes with their churn, most
+// dangerous first: nodes that are both central and often changed are the
+// likeliest to break what depends on them. Nodes without churn are left out.
+func (f Graph) ChurnRanks(ranks []RankResult) []ChurnRank {
+	var churns []ChurnRank
+	var maxScore, maxChurn float64
+	for _, r := range ranks {
+		data := f.Nodes[r.Node].Data
+		if data == nil {
+			continue
+		}
+		lines, ok := data.Attrs.Int(AttrChurn)
+		if !ok {
+			continue
+		}
+		commits, _ := data.Attrs.Int(AttrCommits)
+		churns = append(churns, ChurnRank{Node: r.Node, Score: r.Score, Commits: commits, Churn: lines})
+		maxScore = max(maxScore, r.Score)
+		maxChurn = max(maxChurn, float64(lines))
+	}
+	for i := range churns {
+		c := &churns[i]
+		c.Danger = ratio(c.Score, maxScore) * ratio(float64(c.Churn), maxChurn)
+	}
+	sort.SliceStable(churns, func(i, j int) bool {
+		if churns[i].Danger != churns[j].Danger {
+			return churns[i].Danger > churns[j].Danger
+		}
+		return churns[i].Node.ID < churns[j].Node.ID
+	})
+	return churns
+}
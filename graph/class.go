@@ -0,0 +1,135 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NodeClass is the origin of a package relative to the analyzed module, to
+// answer questions about its own architecture apart from those about its
+// third-party dependencies.
+type NodeClass string
+
+// Available node classes.
+const (
+	// ClassOwn is the class of the packages of the graph's own module,
+	// that of its container.
+	ClassOwn NodeClass = "own"
+	// ClassFirstParty is the class of the packages of other modules of the
+	// same organization, as given to Classify.
+	ClassFirstParty NodeClass = "first-party"
+	// ClassThirdParty is the class of the packages of any other module.
+	ClassThirdParty NodeClass = "third-party"
+	// ClassStdlib is the class of the packages of the standard library.
+	ClassStdlib NodeClass = "stdlib"
+)
+
+// NodeClasses are the available node classes.
+var NodeClasses = []NodeClass{ClassOwn, ClassFirstParty, ClassThirdParty, ClassStdlib}
+
+// ParseNodeClass returns the NodeClass with the given name.
+func ParseNodeClass(s string) (NodeClass, error) {
+	for _, c := range NodeClasses {
+		if string(c) == s {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown node class: %q", s)
+}
+
+// Classify returns the class of each node of the graph, by its module, or its
+// path if the module is unknown. The own module is that of the container.
+// First-party modules are those whose paths are, or are under, any of the
+// given prefixes, e.g. "github.com/example" for the modules of an
+// organization.
+func (f Graph) Classify(firstParty ...string) map[NodeKey]NodeClass {
+	own := f.Container
+	if data := f.Nodes[NodeKey{ID: f.Container}].Data; data != nil && data.Module != "" {
+		own = data.Module
+	}
+	classes := make(map[NodeKey]NodeClass, len(f.Nodes))
+	for key, node := range f.Nodes {
+		classes[key] = classOf(key.ID, node.Data, own, firstParty)
+	}
+	return classes
+}
+
+// classOf returns the class of the package at path with the given data.
+func classOf(path string, data *NodeData, own string, firstParty []string) NodeClass {
+	module := path
+	if data != nil && data.Module != "" {
+		module = data.Module
+	}
+	switch {
+	case module == "std" || module == path && !hasPathPrefix(path, own) && PackageKind(path, "").Has(EdgeKindStdlib):
+		return ClassStdlib
+	case hasPathPrefix(module, own):
+		return ClassOwn
+	}
+	for _, prefix := range firstParty {
+		if hasPathPrefix(module, strings.TrimSuffix(prefix, "/")) {
+			return ClassFirstParty
+		}
+	}
+	return ClassThirdParty
+}
+
+// hasPathPrefix reports whether path is prefix or under it.
+func hasPathPrefix(path, prefix string) bool {
+	return prefix != "" && (path == prefix || strings.HasPrefix(path, prefix+"/"))
+}
+
+// ClassStats summarizes the packages of a class.
+type ClassStats struct {
+	Class NodeClass `json:"class"`
+	// Packages is the number of packages of the class.
+	Packages int `json:"packages"`
+	// Score is the sum of the PageRank of the packages of the class, out
+	// of one for the whole graph.
+	Score float64 `json:"score"`
+	// LOC is the number of lines of the packages of the class, of those
+	// whose AttrLOC is known.
+	LOC int64 `json:"loc,omitempty"`
+	// Imports counts the imports of the packages of the class by the
+	// class of the imported package.
+	Imports map[NodeClass]int `json:"imports"`
+}
+
+// ClassStats summarizes the packages of each class of classes, as returned
+// by Classify, in the order of NodeClasses, leaving out empty classes.
+func (f Graph) ClassStats(classes map[NodeKey]NodeClass) []ClassStats {
+	ranks := f.pageRank()
+	byClass := make(map[NodeClass]*ClassStats)
+	stats := func(c NodeClass) *ClassStats {
+		s, ok := byClass[c]
+		if !ok {
+			s = &ClassStats{Class: c, Imports: make(map[NodeClass]int)}
+			byClass[c] = s
+		}
+		return s
+	}
+	for key, node := range f.Nodes {
+		s := stats(classes[key])
+		s.Packages++
+		s.Score += ranks[key]
+		if node.Data != nil {
+			if loc, ok := node.Data.Attrs.Int(AttrLOC); ok && loc > 0 {
+				s.LOC += loc
+			}
+		}
+	}
+	for _, edge := range f.Edges {
+		edge, ok := edge.(*DirectedEdge)
+		if !ok {
+			continue
+		}
+		stats(classes[edge.Src]).Imports[classes[edge.Dst]]++
+	}
+	var list []ClassStats
+	for _, c := range NodeClasses {
+		if s, ok := byClass[c]; ok {
+			list = append(list, *s)
+		}
+	}
+	return list
+}
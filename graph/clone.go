@@ -0,0 +1,74 @@
+package graph
+
+// Clone returns a deep copy of the graph, whose nodes and edges can be
+// modified without affecting the graph.
+func (f Graph) Clone() *Graph {
+	g := &Graph{Container: f.Container, logger: f.logger}
+	if f.AddedContainers != nil {
+		g.AddedContainers = make(map[string]struct{}, len(f.AddedContainers))
+		for c := range f.AddedContainers {
+			g.AddedContainers[c] = struct{}{}
+		}
+	}
+	if f.Nodes != nil {
+		g.Nodes = make(map[NodeKey]Node, len(f.Nodes))
+		for key, node := range f.Nodes {
+			if node.Data != nil {
+				data := *node.Data
+				data.Attrs = data.Attrs.clone()
+				node.Data = &data
+			}
+			g.Nodes[key] = node
+		}
+	}
+	if f.Edges != nil {
+		g.Edges = make(map[EdgeKey]Edge, len(f.Edges))
+		for key, edge := range f.Edges {
+			g.Edges[key] = cloneEdge(edge)
+		}
+	}
+	return g
+}
+
+// copyEdge returns a shallow copy of an edge, sharing its slices and
+// attributes, so that its fields can be replaced without affecting the edge.
+// Edges of types other than this package's are returned as is.
+func copyEdge(edge Edge) Edge {
+	switch edge := edge.(type) {
+	case *BaseEdge:
+		e := *edge
+		return &e
+	case *DirectedEdge:
+		e := *edge
+		return &e
+	case *UndirectedEdge:
+		e := *edge
+		return &e
+	case *HyperEdge:
+		e := *edge
+		return &e
+	default:
+		return edge
+	}
+}
+
+// cloneEdge is like copyEdge, but also copies the slices and attributes of the
+// edge.
+func cloneEdge(edge Edge) Edge {
+	edge = copyEdge(edge)
+	switch e := edge.(type) {
+	case *BaseEdge:
+		e.Attrs = e.Attrs.clone()
+	case *DirectedEdge:
+		e.Attrs = e.Attrs.clone()
+		e.Symbols = append([]string(nil), e.Symbols...)
+		e.Configs = append([]string(nil), e.Configs...)
+		e.Provenance = append([]Provenance(nil), e.Provenance...)
+	case *UndirectedEdge:
+		e.Attrs = e.Attrs.clone()
+	case *HyperEdge:
+		e.Attrs = e.Attrs.clone()
+		e.UnorderedSet = append([]NodeKey(nil), e.UnorderedSet...)
+	}
+	return edge
+}
@@ -0,0 +1,85 @@
+package graph
+
+import "strings"
+
+// CollapseBy returns a coarser graph of the graph's container, with a node for
+// each group of nodes for which group returns the same key, e.g. the path of
+// their module, and a directed edge between two groups wherever there is one
+// between their nodes. Parallel edges are merged by DefaultMergeFunc, which
+// sums their weights, and edges within a group are dropped, as are nodes whose
+// key is empty and edges of other types than directed. Collapsed edges keep
+// no symbols, which belong to a single package.
+func (f Graph) CollapseBy(group func(NodeKey) string) *Graph {
+	g := &Graph{
+		Container:       f.Container,
+		AddedContainers: make(map[string]struct{}, len(f.AddedContainers)),
+	}
+	for c := range f.AddedContainers {
+		g.AddedContainers[c] = struct{}{}
+	}
+	groups := make(map[NodeKey]string, len(f.Nodes))
+	for _, key := range f.SortedNodes() {
+		id := group(key)
+		if id == "" {
+			continue
+		}
+		groups[key] = id
+		var data *NodeData
+		if d := f.Nodes[key].Data; d != nil {
+			data = &NodeData{Module: d.Module, Version: d.Version}
+		}
+		n := NodeKey{ID: id}
+		prev, ok := g.Nodes[n]
+		switch {
+		case !ok:
+			g.AddNode(n, data)
+		case prev.Data != nil && (data == nil || data.ModuleVersion() != prev.Data.ModuleVersion()):
+			// Only keep the module of a group of a single module.
+			g.AddNode(n, nil)
+		}
+	}
+	for _, edge := range f.SortedEdges() {
+		edge, ok := edge.(*DirectedEdge)
+		if !ok {
+			continue
+		}
+		src, dst := groups[edge.Src], groups[edge.Dst]
+		if src == "" || dst == "" || src == dst {
+			continue
+		}
+		collapsed := NewDirectedEdge(f.Container, src, dst)
+		collapsed.EdgeWeight = edge.EdgeWeight
+		collapsed.Kind = edge.Kind
+		collapsed.Configs = edge.Configs
+		collapsed.Provenance = edge.Provenance
+		collapsed.Attrs = edge.Attrs
+		// A fresh directed edge between non-empty groups is valid, and
+		// DefaultMergeFunc merges it into any previous one, so adding it
+		// cannot fail.
+		g.AddEdge(collapsed)
+	}
+	return g
+}
+
+// ByModule groups nodes by the path of their module, as CollapseBy's group,
+// leaving out nodes of unknown modules.
+func (f Graph) ByModule() func(NodeKey) string {
+	return func(key NodeKey) string {
+		if data := f.Nodes[key].Data; data != nil {
+			return data.Module
+		}
+		return ""
+	}
+}
+
+// ByPathPrefix groups nodes by the first n elements of their import path, as
+// CollapseBy's group, e.g. by top-level directory of a module.
+func ByPathPrefix(n int) func(NodeKey) string {
+	return func(key NodeKey) string {
+		elems := strings.SplitN(key.ID, "/", n+1)
+		if len(elems) > n {
+			elems = elems[:n]
+		}
+		return strings.Join(elems, "/")
+	}
+}
@@ -0,0 +1,75 @@
+package graph
+
+import (
+	"sync"
+)
+
+// ConcurrentGraph is a Graph that is safe for concurrent use, so that several
+// workers may add to it at once. Every method holds a lock for its duration,
+// so workers that build large subgraphs should build them apart and Add them,
+// or use MergeParallel when all of the graphs are known up front.
+type ConcurrentGraph struct {
+	mu sync.RWMutex
+	g  Graph
+}
+
+// NewConcurrentGraph returns an empty graph for the given container.
+func NewConcurrentGraph(container string) *ConcurrentGraph {
+	return &ConcurrentGraph{g: Graph{
+		Container:       container,
+		AddedContainers: make(map[string]struct{}),
+	}}
+}
+
+// AddEdge is like Graph.AddEdge.
+func (c *ConcurrentGraph) AddEdge(edge Edge, opts ...AddEdgeOption) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.g.AddEdge(edge, opts...)
+}
+
+// AddNode is like Graph.AddNode.
+func (c *ConcurrentGraph) AddNode(key NodeKey, data *NodeData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.g.AddNode(key, data)
+}
+
+// Add is like Graph.Add. The other graph must not be modified until it
+// returns.
+func (c *ConcurrentGraph) Add(other Graph, opts ...AddEdgeOption) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.g.Add(other, opts...)
+}
+
+// Order returns the number of nodes in the graph.
+func (c *ConcurrentGraph) Order() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.g.Order()
+}
+
+// Size returns the number of edges in the graph.
+func (c *ConcurrentGraph) Size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.g.Size()
+}
+
+// View calls fn with the graph while holding a read lock, so that fn sees a
+// consistent graph. fn must not retain or modify the graph.
+func (c *ConcurrentGraph) View(fn func(g Graph)) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	fn(c.g)
+}
+
+// Graph returns the underlying graph. Adding edges merges them into those
+// already in the graph, so it must only be called once every worker has
+// finished adding to it.
+func (c *ConcurrentGraph) Graph() *Graph {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &c.g
+}
@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"sort"
+	"strconv"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+// ToImportGraph returns an ImportGraph of the directed edges of the graph,
+// each pair of nodes weighted by the number of edges between them across
+// containers, so that centrality can be measured on it. Nodes without a
+// directed edge are left out.
+func (f Graph) ToImportGraph() *ImportGraph {
+	ig := NewImportGraph()
+	for _, edge := range f.SortedEdges() {
+		if edge, ok := edge.(*DirectedEdge); ok {
+			ig.UpdateEdge(edge.Src.ID, edge.Dst.ID)
+		}
+	}
+	return ig
+}
+
+// ToGraph returns a Graph of the import graph, with a directed edge of the
+// given container for each edge of the import graph, and every node.
+func (g *ImportGraph) ToGraph(container string) *Graph {
+	f := &Graph{
+		Container:       container,
+		AddedContainers: map[string]struct{}{container: {}},
+		Edges:           make(map[EdgeKey]Edge, g.g.Edges().Len()),
+	}
+	for _, imp := range g.Imports() {
+		f.AddNode(NodeKey{ID: imp}, nil)
+	}
+	it := g.g.WeightedEdges()
+	for it.Next() {
+		e := it.WeightedEdge()
+		edge := NewDirectedEdge(container, g.idToImport[e.From().ID()], g.idToImport[e.To().ID()])
+		edge.EdgeWeight = e.Weight()
+		f.Edges[edge.Key()] = edge
+	}
+	return f
+}
+
+// Imports returns the sorted imports of the nodes of the graph.
+func (g *ImportGraph) Imports() []string {
+	imps := make([]string, 0, len(g.importToID))
+	for imp := range g.importToID {
+		imps = append(imps, imp)
+	}
+	sort.Strings(imps)
+	return imps
+}
+
+// ID returns the ID of the gonum node of an import, and whether it is in the
+// graph.
+func (g *ImportGraph) ID(imp string) (int64, bool) {
+	id, ok := g.importToID[imp]
+	return id, ok
+}
+
+// Import returns the import of a gonum node ID, and whether it is in the
+// graph.
+func (g *ImportGraph) Import(id int64) (string, bool) {
+	imp, ok := g.idToImport[id]
+	return imp, ok
+}
+
+// ToGonum returns a copy of the gonum graph underlying the import graph, for
+// running gonum's algorithms on it. Its node IDs map to imports by Import.
+func (g *ImportGraph) ToGonum() *simple.WeightedDirectedGraph {
+	c := simple.NewWeightedDirectedGraph(0, 0)
+	graph.CopyWeighted(c, g.g)
+	return c
+}
+
+// FromGonum returns an import graph of a gonum directed graph, naming each
+// node by name, or by its ID if name is nil. Edges are weighted as in g if it
+// is weighted, or by one otherwise. Nodes with the same name are merged.
+func FromGonum(g graph.Directed, name func(graph.Node) string) *ImportGraph {
+	if name == nil {
+		name = func(n graph.Node) string {
+			return strconv.FormatInt(n.ID(), 10)
+		}
+	}
+	weighted, _ := g.(graph.Weighted)
+	ig := NewImportGraph()
+	nodes := g.Nodes()
+	for nodes.Next() {
+		from := nodes.Node()
+		ig.AddNode(name(from))
+		to := g.From(from.ID())
+		for to.Next() {
+			weight := 1.0
+			if weighted != nil {
+				weight, _ = weighted.Weight(from.ID(), to.Node().ID())
+			}
+			ig.addWeight(name(from), name(to.Node()), weight)
+		}
+	}
+	return ig
+}
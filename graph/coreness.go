@@ -0,0 +1,53 @@
+package graph
+
+// Coreness returns the k-core number of every node with an edge, ignoring edge
+// direction. A node's coreness is the largest k such that it belongs to a
+// subgraph in which every node has at least k neighbours, so nodes embedded
+// in densely interconnected parts of the graph score higher.
+func (f Graph) Coreness() map[NodeKey]int {
+	return coreness(f.undirected())
+}
+
+// coreness computes k-core numbers of an undirected graph, given as the
+// number of edges between each pair of distinct neighbours, using the
+// Batagelj-Zaversnik algorithm.
+func coreness[K comparable](adj map[K]map[K]int) map[K]int {
+	degree := make(map[K]int, len(adj))
+	maxDegree := 0
+	for n, neighbours := range adj {
+		degree[n] = len(neighbours)
+		maxDegree = max(maxDegree, degree[n])
+	}
+	// Bucket nodes by their current degree, and repeatedly remove a node of
+	// the lowest degree. Neighbours' degrees never drop below the current
+	// one, so the buckets are only scanned once.
+	buckets := make([]map[K]struct{}, maxDegree+1)
+	for i := range buckets {
+		buckets[i] = make(map[K]struct{})
+	}
+	for n, d := range degree {
+		buckets[d][n] = struct{}{}
+	}
+	core := make(map[K]int, len(adj))
+	for d := 0; d <= maxDegree; {
+		if len(buckets[d]) == 0 {
+			d++
+			continue
+		}
+		var n K
+		for n = range buckets[d] {
+			break
+		}
+		delete(buckets[d], n)
+		core[n] = d
+		for m := range adj[n] {
+			if _, done := core[m]; done || degree[m] <= d {
+				continue
+			}
+			delete(buckets[degree[m]], m)
+			degree[m]--
+			buckets[degree[m]][m] = struct{}{}
+		}
+	}
+	return core
+}
@@ -0,0 +1,116 @@
+package graph
+
+import "sort"
+
+// DependencyCost is what a direct dependency module uniquely brings into the
+// dependency tree of a package: the packages that would no longer be reached
+// without the module's packages, including its own, other than those of the
+// standard library, which come with Go either way.
+type DependencyCost struct {
+	Module  string `json:"module"`
+	Version string `json:"version,omitempty"`
+	// Packages is the number of packages that only the module brings in.
+	Packages int `json:"packages"`
+	// Modules is the number of modules of those packages, including the
+	// module itself.
+	Modules int `json:"modules"`
+	// LOC is the total AttrLOC of those packages, 0 if unknown.
+	LOC int64 `json:"loc"`
+}
+
+// DependencyCosts returns the cost of each module that packages of the main
+// module import directly, within the tree of packages that root reaches,
+// costliest first. The main module is that of root, and the standard library
+// is left out.
+func (f Graph) DependencyCosts(root NodeKey) []DependencyCost {
+	module := func(n NodeKey) *NodeData {
+		if data := f.Nodes[n].Data; data != nil && data.Module != "" {
+			return data
+		}
+		return nil
+	}
+	var main string
+	if data := module(root); data != nil {
+		main = data.Module
+	}
+	succs := f.successors()
+	tree := reachableWithout(succs, root, nil)
+
+	pkgs := make(map[string]map[NodeKey]bool)
+	for n := range tree {
+		data := module(n)
+		if data == nil || data.Module == main || data.Module == "std" {
+			continue
+		}
+		if pkgs[data.Module] == nil {
+			pkgs[data.Module] = make(map[NodeKey]bool)
+		}
+		pkgs[data.Module][n] = true
+	}
+	direct := make(map[string]bool)
+	for n := range tree {
+		if data := module(n); n != root && (data == nil || data.Module != main) {
+			continue
+		}
+		for m := range succs[n] {
+			if data := module(m); data != nil && pkgs[data.Module] != nil {
+				direct[data.Module] = true
+			}
+		}
+	}
+
+	costs := make([]DependencyCost, 0, len(direct))
+	for m := range direct {
+		first := sortedKeys(pkgs[m])[0]
+		cost := DependencyCost{Module: m, Version: f.Nodes[first].Data.Version}
+		left := reachableWithout(succs, root, pkgs[m])
+		modules := make(map[string]bool)
+		for n := range tree {
+			data := f.Nodes[n].Data
+			if left[n] || data != nil && data.Module == "std" {
+				continue
+			}
+			cost.Packages++
+			if data == nil {
+				continue
+			}
+			if data.Module != "" {
+				modules[data.Module] = true
+			}
+			loc, _ := data.Attrs.Int(AttrLOC)
+			cost.LOC += loc
+		}
+		cost.Modules = len(modules)
+		costs = append(costs, cost)
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		a, b := costs[i], costs[j]
+		if a.Packages != b.Packages {
+			return a.Packages > b.Packages
+		}
+		if a.LOC != b.LOC {
+			return a.LOC > b.LOC
+		}
+		return a.Module < b.Module
+	})
+	return costs
+}
+
+// reachableWithout returns root and the nodes that it reaches in adj without
+// going through any of the skipped nodes, which are never reached.
+func reachableWithout(adj map[NodeKey]map[NodeKey]float64, root NodeKey, skip map[NodeKey]bool) map[NodeKey]bool {
+	seen := map[NodeKey]bool{root: true}
+	queue := []NodeKey{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for next := range adj[n] {
+			if seen[next] || skip[next] {
+				continue
+			}
+			seen[next] = true
+			queue = append(queue, next)
+		}
+	}
+	return seen
+}
@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"sort"
+)
+
+// undirected returns the undirected view of the directed edges of the graph,
+// counting the edges between each pair of distinct nodes.
+func (f Graph) undirected() map[NodeKey]map[NodeKey]int {
+	adj := make(map[NodeKey]map[NodeKey]int)
+	add := func(a, b NodeKey) {
+		if adj[a] == nil {
+			adj[a] = make(map[NodeKey]int)
+		}
+		adj[a][b]++
+	}
+	for _, edge := range f.Edges {
+		edge, ok := edge.(*DirectedEdge)
+		if !ok || edge.Src == edge.Dst {
+			continue
+		}
+		add(edge.Src, edge.Dst)
+		add(edge.Dst, edge.Src)
+	}
+	return adj
+}
+
+// lowLinks runs Tarjan's depth-first search over the undirected view of the
+// graph, calling cut for every articulation point and bridge for every bridge.
+func (f Graph) lowLinks(cut func(NodeKey), bridge func(a, b NodeKey)) {
+	adj := f.undirected()
+	nodes := make([]NodeKey, 0, len(adj))
+	for n := range adj {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID < nodes[j].ID
+	})
+	neighbours := func(n NodeKey) []NodeKey {
+		ns := make([]NodeKey, 0, len(adj[n]))
+		for m := range adj[n] {
+			ns = append(ns, m)
+		}
+		sort.Slice(ns, func(i, j int) bool {
+			return ns[i].ID < ns[j].ID
+		})
+		return ns
+	}
+	disc := make(map[NodeKey]int)
+	low := make(map[NodeKey]int)
+	cuts := make(map[NodeKey]bool)
+	time := 0
+	var visit func(n, parent NodeKey, root bool)
+	visit = func(n, parent NodeKey, root bool) {
+		time++
+		disc[n], low[n] = time, time
+		children := 0
+		for _, m := range neighbours(n) {
+			if _, ok := disc[m]; !ok {
+				children++
+				visit(m, n, false)
+				low[n] = min(low[n], low[m])
+				if !root && low[m] >= disc[n] {
+					cuts[n] = true
+				}
+				// Parallel edges between n and m are never bridges.
+				if low[m] > disc[n] && adj[n][m] == 1 {
+					bridge(n, m)
+				}
+			} else if m != parent || adj[n][m] > 1 {
+				low[n] = min(low[n], disc[m])
+			}
+		}
+		if root && children > 1 {
+			cuts[n] = true
+		}
+	}
+	for _, n := range nodes {
+		if _, ok := disc[n]; !ok {
+			visit(n, n, true)
+		}
+	}
+	for _, n := range nodes {
+		if cuts[n] {
+			cut(n)
+		}
+	}
+}
+
+// ArticulationPoints returns the sorted nodes whose removal disconnects the
+// graph, ignoring edge direction.
+func (f Graph) ArticulationPoints() []NodeKey {
+	var points []NodeKey
+	f.lowLinks(func(n NodeKey) { points = append(points, n) }, func(NodeKey, NodeKey) {})
+	return points
+}
+
+// Bridges returns the sorted directed edges, as source and destination pairs,
+// whose removal disconnects the graph, ignoring edge direction.
+func (f Graph) Bridges() [][2]NodeKey {
+	succs := f.successors()
+	var bridges [][2]NodeKey
+	f.lowLinks(func(NodeKey) {}, func(a, b NodeKey) {
+		if _, ok := succs[a][b]; ok {
+			bridges = append(bridges, [2]NodeKey{a, b})
+		} else {
+			bridges = append(bridges, [2]NodeKey{b, a})
+		}
+	})
+	sort.Slice(bridges, func(i, j int) bool {
+		if bridges[i][0] != bridges[j][0] {
+			return bridges[i][0].ID < bridges[j][0].ID
+		}
+		return bridges[i][1].ID < bridges[j][1].ID
+	})
+	return bridges
+}
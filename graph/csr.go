@@ -0,0 +1,145 @@
+package graph
+
+import (
+	"math"
+	"sort"
+)
+
+// CSR is a compact, read-only directed graph in compressed sparse row form.
+// Nodes are indexed by int32 in order of their sorted IDs, and the edges from
+// node i are Targets[Offsets[i]:Offsets[i+1]], with corresponding Weights.
+type CSR struct {
+	IDs     []string
+	Offsets []int32
+	Targets []int32
+	Weights []float32
+}
+
+// csrEdge is a weighted edge between node indices.
+type csrEdge struct {
+	src, dst int32
+	weight   float32
+}
+
+// newCSR returns a CSR of the nodes with the given IDs and edges between
+// them, summing the weights of parallel edges.
+func newCSR(ids []string, edges []csrEdge) *CSR {
+	c := &CSR{
+		IDs:     ids,
+		Offsets: make([]int32, len(ids)+1),
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].src != edges[j].src {
+			return edges[i].src < edges[j].src
+		}
+		return edges[i].dst < edges[j].dst
+	})
+	for i, e := range edges {
+		if i > 0 && e.src == edges[i-1].src && e.dst == edges[i-1].dst {
+			c.Weights[len(c.Weights)-1] += e.weight
+			continue
+		}
+		c.Targets = append(c.Targets, e.dst)
+		c.Weights = append(c.Weights, e.weight)
+		c.Offsets[e.src+1]++
+	}
+	for i := 1; i < len(c.Offsets); i++ {
+		c.Offsets[i] += c.Offsets[i-1]
+	}
+	return c
+}
+
+// CSR returns the graph in compressed sparse row form. Like the ImportGraph
+// of the graph, each pair of nodes is weighted by the number of directed
+// edges between them, across containers.
+func (f Graph) CSR() *CSR {
+	ids := make([]string, 0, len(f.Nodes))
+	for key := range f.Nodes {
+		ids = append(ids, key.ID)
+	}
+	sort.Strings(ids)
+	index := make(map[string]int32, len(ids))
+	for i, id := range ids {
+		index[id] = int32(i)
+	}
+	edges := make([]csrEdge, 0, len(f.Edges))
+	for _, edge := range f.Edges {
+		if edge, ok := edge.(*DirectedEdge); ok {
+			edges = append(edges, csrEdge{src: index[edge.Src.ID], dst: index[edge.Dst.ID], weight: 1})
+		}
+	}
+	return newCSR(ids, edges)
+}
+
+// Order returns the number of nodes.
+func (c *CSR) Order() int {
+	return len(c.IDs)
+}
+
+// Size returns the number of distinct directed edges.
+func (c *CSR) Size() int {
+	return len(c.Targets)
+}
+
+// Index returns the index of the node with the given ID, and whether there is
+// any.
+func (c *CSR) Index(id string) (int32, bool) {
+	i := sort.SearchStrings(c.IDs, id)
+	if i < len(c.IDs) && c.IDs[i] == id {
+		return int32(i), true
+	}
+	return 0, false
+}
+
+// PageRank returns the edge-weighted PageRank of every node by index, using
+// the given damping factor and iterating until the 2-norm of the difference
+// between iterations is below tol. It converges to the same ranks as gonum's
+// network.PageRank, with memory linear in the size of the graph.
+func (c *CSR) PageRank(damp, tol float64) []float64 {
+	n := len(c.IDs)
+	if n == 0 {
+		return nil
+	}
+	outWeight := make([]float64, n)
+	for i := 0; i < n; i++ {
+		for e := c.Offsets[i]; e < c.Offsets[i+1]; e++ {
+			outWeight[i] += float64(c.Weights[e])
+		}
+	}
+	rank := make([]float64, n)
+	next := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	for {
+		// Dangling nodes, without out-edges, link to every node.
+		var dangling float64
+		for i := 0; i < n; i++ {
+			if outWeight[i] == 0 {
+				dangling += rank[i]
+			}
+		}
+		base := (1-damp)/float64(n) + damp*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i := 0; i < n; i++ {
+			if outWeight[i] == 0 {
+				continue
+			}
+			share := damp * rank[i] / outWeight[i]
+			for e := c.Offsets[i]; e < c.Offsets[i+1]; e++ {
+				next[c.Targets[e]] += share * float64(c.Weights[e])
+			}
+		}
+		var diff float64
+		for i := range rank {
+			d := next[i] - rank[i]
+			diff += d * d
+		}
+		rank, next = next, rank
+		if math.Sqrt(diff) < tol {
+			return rank
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteCypher writes the graph to w as Cypher statements that load it into
+// Neo4j, e.g. with cypher-shell. Packages are :Package nodes, keyed by path,
+// with their PageRank and attributes as properties, and are IN_MODULE of
+// :Module nodes, keyed by path@version. Imports are IMPORTS relationships
+// with their weight, kinds and used symbols. Statements MERGE rather than
+// CREATE, so that loading several graphs into one database shares their
+// packages and modules.
+func WriteCypher(w io.Writer, g *Graph) error {
+	ranks := g.pageRank()
+	statements := []string{
+		"CREATE CONSTRAINT package_path IF NOT EXISTS FOR (p:Package) REQUIRE p.path IS UNIQUE;",
+		"CREATE CONSTRAINT module_id IF NOT EXISTS FOR (m:Module) REQUIRE m.id IS UNIQUE;",
+	}
+	modules := make(map[string]bool)
+	for _, key := range g.SortedNodes() {
+		data := g.Nodes[key].Data
+		props := map[string]any{"rank": ranks[key]}
+		if data != nil {
+			for k, v := range data.Attrs {
+				props[k] = v
+			}
+			if data.Types > 0 {
+				props["types"], props["interfaces"] = int64(data.Types), int64(data.Interfaces)
+			}
+		}
+		statements = append(statements, fmt.Sprintf("MERGE (p:Package {path: %s}) SET p += %s;",
+			cypherValue(key.ID), cypherMap(props)))
+		if data == nil || data.Module == "" {
+			continue
+		}
+		id := data.ModuleVersion()
+		if !modules[id] {
+			modules[id] = true
+			statements = append(statements, fmt.Sprintf("MERGE (m:Module {id: %s}) SET m.path = %s, m.version = %s;",
+				cypherValue(id), cypherValue(data.Module), cypherValue(data.Version)))
+		}
+		statements = append(statements, fmt.Sprintf(
+			"MATCH (p:Package {path: %s}), (m:Module {id: %s}) MERGE (p)-[:IN_MODULE]->(m);",
+			cypherValue(key.ID), cypherValue(id)))
+	}
+	for _, edge := range g.SortedEdges() {
+		edge, ok := edge.(*DirectedEdge)
+		if !ok {
+			continue
+		}
+		props := map[string]any{"weight": edge.Weight()}
+		if kinds := edge.Kind.Names(); len(kinds) > 0 {
+			props["kinds"] = kinds
+		}
+		if len(edge.Symbols) > 0 {
+			props["symbols"] = edge.Symbols
+		}
+		statements = append(statements, fmt.Sprintf(
+			"MATCH (a:Package {path: %s}), (b:Package {path: %s}) MERGE (a)-[r:IMPORTS]->(b) SET r += %s;",
+			cypherValue(edge.Src.ID), cypherValue(edge.Dst.ID), cypherMap(props)))
+	}
+	for _, s := range statements {
+		if _, err := fmt.Fprintln(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cypherMap returns a Cypher map literal of props, sorted by key.
+func cypherMap(props map[string]any) string {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = "`" + strings.ReplaceAll(k, "`", "``") + "`: " + cypherValue(props[k])
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// cypherValue returns a Cypher literal of v, a string, number, boolean or
+// list of strings.
+func cypherValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		var b strings.Builder
+		b.WriteByte('"')
+		for _, r := range v {
+			switch r {
+			case '"', '\\':
+				b.WriteByte('\\')
+				b.WriteRune(r)
+			case '\n':
+				b.WriteString(`\n`)
+			case '\r':
+				b.WriteString(`\r`)
+			case '\t':
+				b.WriteString(`\t`)
+			default:
+				if r < 0x20 {
+					fmt.Fprintf(&b, `\u%04x`, r)
+				} else {
+					b.WriteRune(r)
+				}
+			}
+		}
+		b.WriteByte('"')
+		return b.String()
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "null"
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []string:
+		items := make([]string, len(v))
+		for i, s := range v {
+			items[i] = cypherValue(s)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return cypherValue(fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,63 @@
+package graph
+
+import "golang.org/x/mod/semver"
+
+// MergeLatest is like MergeParallel, but deduplicates packages across graphs
+// by version: where graphs have different versions of a package, as when
+// they are of modules requiring different versions of a dependency, only the
+// imports and data of its latest version are kept. Versions that are not
+// semantic versions are older than those that are.
+func MergeLatest(container string, workers int, graphs ...*Graph) (*Graph, error) {
+	latest := make(map[NodeKey]string)
+	for _, g := range graphs {
+		for key, node := range g.Nodes {
+			if node.Data == nil {
+				continue
+			}
+			if v, ok := latest[key]; !ok || newerVersion(node.Data.Version, v) {
+				latest[key] = node.Data.Version
+			}
+		}
+	}
+	filtered := make([]*Graph, len(graphs))
+	for i, g := range graphs {
+		version := func(id string) string {
+			if data := g.Nodes[NodeKey{ID: id}].Data; data != nil {
+				return data.Version
+			}
+			return latest[NodeKey{ID: id}]
+		}
+		h := &Graph{
+			Container:       g.Container,
+			AddedContainers: make(map[string]struct{}),
+			Nodes:           make(map[NodeKey]Node, len(g.Nodes)),
+			Edges:           make(map[EdgeKey]Edge, len(g.Edges)),
+		}
+		for key, node := range g.Nodes {
+			if node.Data != nil && node.Data.Version != latest[key] {
+				node.Data = nil
+			}
+			h.Nodes[key] = node
+		}
+		for key, edge := range g.Edges {
+			if version(key.container) == latest[NodeKey{ID: key.container}] {
+				h.Edges[key] = edge
+				h.AddedContainers[key.container] = struct{}{}
+			}
+		}
+		filtered[i] = h
+	}
+	return MergeParallel(container, workers, filtered...)
+}
+
+// newerVersion reports whether version a is newer than b.
+func newerVersion(a, b string) bool {
+	switch {
+	case semver.IsValid(a) && semver.IsValid(b):
+		return semver.Compare(a, b) > 0
+	case semver.IsValid(a) != semver.IsValid(b):
+		return semver.IsValid(a)
+	default:
+		return a > b
+	}
+}
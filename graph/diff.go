@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"sort"
+)
+
+// Diff is the difference between two graphs.
+type Diff struct {
+	AddedNodes   []NodeKey    `json:"addedNodes"`
+	RemovedNodes []NodeKey    `json:"removedNodes"`
+	AddedEdges   [][2]NodeKey `json:"addedEdges"`
+	RemovedEdges [][2]NodeKey `json:"removedEdges"`
+}
+
+// Empty reports whether the graphs are the same.
+func (d Diff) Empty() bool {
+	return len(d.AddedNodes)+len(d.RemovedNodes)+len(d.AddedEdges)+len(d.RemovedEdges) == 0
+}
+
+// Diff returns the nodes and directed edges that are added and removed from
+// the graph by other, sorted. Edges are compared by their source and
+// destination only.
+func (f Graph) Diff(other Graph) Diff {
+	d := Diff{
+		AddedNodes:   []NodeKey{},
+		RemovedNodes: []NodeKey{},
+		AddedEdges:   [][2]NodeKey{},
+		RemovedEdges: [][2]NodeKey{},
+	}
+	for key := range other.Nodes {
+		if _, ok := f.Nodes[key]; !ok {
+			d.AddedNodes = append(d.AddedNodes, key)
+		}
+	}
+	for key := range f.Nodes {
+		if _, ok := other.Nodes[key]; !ok {
+			d.RemovedNodes = append(d.RemovedNodes, key)
+		}
+	}
+	before, after := f.successors(), other.successors()
+	for src, succs := range after {
+		for dst := range succs {
+			if _, ok := before[src][dst]; !ok {
+				d.AddedEdges = append(d.AddedEdges, [2]NodeKey{src, dst})
+			}
+		}
+	}
+	for src, succs := range before {
+		for dst := range succs {
+			if _, ok := after[src][dst]; !ok {
+				d.RemovedEdges = append(d.RemovedEdges, [2]NodeKey{src, dst})
+			}
+		}
+	}
+	for _, nodes := range [][]NodeKey{d.AddedNodes, d.RemovedNodes} {
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].ID < nodes[j].ID
+		})
+	}
+	for _, edges := range [][][2]NodeKey{d.AddedEdges, d.RemovedEdges} {
+		sort.Slice(edges, func(i, j int) bool {
+			if edges[i][0] != edges[j][0] {
+				return edges[i][0].ID < edges[j][0].ID
+			}
+			return edges[i][1].ID < edges[j][1].ID
+		})
+	}
+	return d
+}
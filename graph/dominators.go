@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"sort"
+
+	"gonum.org/v1/gonum/graph/flow"
+)
+
+// DominatorTree is the dominator tree of the nodes reachable from a root. A
+// node dominates another if every path from the root to the other passes
+// through it, i.e. it is solely responsible for pulling the other in.
+type DominatorTree struct {
+	Root     NodeKey
+	idom     map[NodeKey]NodeKey
+	children map[NodeKey][]NodeKey
+}
+
+// Dominators returns the dominator tree of the graph rooted at root.
+func (f Graph) Dominators(root NodeKey) *DominatorTree {
+	d := &DominatorTree{
+		Root:     root,
+		idom:     make(map[NodeKey]NodeKey),
+		children: make(map[NodeKey][]NodeKey),
+	}
+	ig := f.ToImportGraph()
+	id, ok := ig.importToID[root.ID]
+	if !ok {
+		return d
+	}
+	tree := flow.Dominators(ig.g.Node(id), ig.g)
+	for _, n := range f.Descendants(root) {
+		if n == root {
+			continue
+		}
+		dom := tree.DominatorOf(ig.importToID[n.ID])
+		if dom == nil {
+			continue
+		}
+		parent := NodeKey{ID: ig.idToImport[dom.ID()]}
+		d.idom[n] = parent
+		d.children[parent] = append(d.children[parent], n)
+	}
+	for _, children := range d.children {
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].ID < children[j].ID
+		})
+	}
+	return d
+}
+
+// Idom returns the immediate dominator of n, and false if n is the root or
+// is unreachable from it.
+func (d *DominatorTree) Idom(n NodeKey) (NodeKey, bool) {
+	dom, ok := d.idom[n]
+	return dom, ok
+}
+
+// Children returns the sorted nodes immediately dominated by n.
+func (d *DominatorTree) Children(n NodeKey) []NodeKey {
+	return d.children[n]
+}
+
+// Dominated returns the nodes dominated by n, excluding n itself, in
+// depth-first order of the tree.
+func (d *DominatorTree) Dominated(n NodeKey) []NodeKey {
+	var nodes []NodeKey
+	var visit func(n NodeKey)
+	visit = func(n NodeKey) {
+		for _, c := range d.children[n] {
+			nodes = append(nodes, c)
+			visit(c)
+		}
+	}
+	visit(n)
+	return nodes
+}
+
+// Owned returns the number of nodes dominated by n, i.e. the nodes that would
+// no longer be reachable from the root without n.
+func (d *DominatorTree) Owned(n NodeKey) int {
+	return len(d.Dominated(n))
+}
@@ -0,0 +1,244 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/encoding"
+	"gonum.org/v1/gonum/graph/encoding/dot"
+	dotast "gonum.org/v1/gonum/graph/formats/dot"
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+// WriteDOT writes the graph to w in the Graphviz DOT language. Nodes are
+// sized by their PageRank and colored by their module.
+func WriteDOT(w io.Writer, g *Graph) error {
+	ranks := g.pageRank()
+	var maxRank float64
+	for _, rank := range ranks {
+		maxRank = math.Max(maxRank, rank)
+	}
+	keys := make([]NodeKey, 0, len(g.Nodes))
+	for key := range g.Nodes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	if _, err := fmt.Fprintf(w, "digraph %q {\n\tnode [shape=ellipse, style=filled, fixedsize=false];\n", g.Container); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		scale := 0.0
+		if maxRank > 0 {
+			scale = math.Sqrt(ranks[key] / maxRank)
+		}
+		module := ""
+		tooltip := ""
+		if data := g.Nodes[key].Data; data != nil {
+			module = data.Module
+			if len(data.Attrs) > 0 {
+				tooltip = "\n" + data.Attrs.Format("\n")
+			}
+		}
+		tooltip = fmt.Sprintf("%s\nrank %.6f", module, ranks[key]) + tooltip
+		if _, err := fmt.Fprintf(w, "\t%q [fontsize=%.1f, fillcolor=%q, tooltip=%q];\n",
+			key.ID, 8+16*scale, moduleColor(module), tooltip); err != nil {
+			return err
+		}
+	}
+	edges := make([]*DirectedEdge, 0, len(g.Edges))
+	for _, edge := range g.Edges {
+		edge, ok := edge.(*DirectedEdge)
+		if !ok {
+			return fmt.Errorf("unsupported edge type: %T", edge)
+		}
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Src != edges[j].Src {
+			return edges[i].Src.ID < edges[j].Src.ID
+		}
+		return edges[i].Dst.ID < edges[j].Dst.ID
+	})
+	for _, edge := range edges {
+		style := "solid"
+		if edge.Kind.Has(EdgeKindTest) {
+			style = "dashed"
+		}
+		attrs := "style=" + style
+		if len(edge.Attrs) > 0 {
+			attrs += fmt.Sprintf(", tooltip=%q", edge.Attrs.Format("\n"))
+		}
+		if _, err := fmt.Fprintf(w, "\t%q -> %q [%s];\n", edge.Src.ID, edge.Dst.ID, attrs); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// ReadDOT reads a directed graph in the Graphviz DOT language, as written by
+// WriteDOT or by tools like godepgraph and goda, so that it can be ranked like
+// any other. Nodes are named by the first line of their label if they have
+// one, since those tools label nodes with import paths, or else by their DOT
+// ID. Edges are weighted by their weight attribute, 1 by default, and are test
+// edges if dashed, as WriteDOT draws them, though WriteDOT leaves weights
+// out. The graph's Container is the DOT graph's ID.
+func ReadDOT(r io.Reader) (*Graph, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	file, err := dotast.ParseBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dot: %w", err)
+	}
+	if len(file.Graphs) != 1 || !file.Graphs[0].Directed {
+		return nil, errors.New("dot file must hold a single digraph")
+	}
+	src := dotGraph{DirectedGraph: simple.NewDirectedGraph()}
+	if err := dot.Unmarshal(data, &src); err != nil {
+		return nil, fmt.Errorf("failed to read dot: %w", err)
+	}
+	g := &Graph{
+		Container:       src.id,
+		AddedContainers: map[string]struct{}{src.id: {}},
+	}
+	nodes := src.Nodes()
+	for nodes.Next() {
+		g.AddNode(NodeKey{ID: nodes.Node().(*dotNode).name()}, nil)
+	}
+	edges := src.Edges()
+	for edges.Next() {
+		e := edges.Edge().(*dotEdge)
+		edge := NewDirectedEdge(src.id, e.from.name(), e.to.name())
+		edge.EdgeWeight = e.weight
+		if e.dashed {
+			edge.Kind = EdgeKindTest
+		}
+		if _, err := g.AddEdge(edge); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// dotGraph is a gonum graph that dot.Unmarshal builds from dotNodes and
+// dotEdges.
+type dotGraph struct {
+	*simple.DirectedGraph
+	id string
+}
+
+func (g *dotGraph) SetDOTID(id string) { g.id = id }
+
+func (g *dotGraph) NewNode() graph.Node {
+	return &dotNode{id: g.DirectedGraph.NewNode().ID()}
+}
+
+func (g *dotGraph) NewEdge(from, to graph.Node) graph.Edge {
+	return &dotEdge{from: from.(*dotNode), to: to.(*dotNode), weight: 1}
+}
+
+type dotNode struct {
+	id           int64
+	dotID, label string
+}
+
+func (n *dotNode) ID() int64          { return n.id }
+func (n *dotNode) SetDOTID(id string) { n.dotID = id }
+
+func (n *dotNode) SetAttribute(a encoding.Attribute) error {
+	if a.Key == "label" && !strings.HasPrefix(a.Value, "<") {
+		n.label, _, _ = strings.Cut(a.Value, "\n")
+	}
+	return nil
+}
+
+// name returns the name of the node's package.
+func (n *dotNode) name() string {
+	if n.label != "" {
+		return n.label
+	}
+	return n.dotID
+}
+
+type dotEdge struct {
+	from, to *dotNode
+	weight   float64
+	dashed   bool
+}
+
+func (e *dotEdge) From() graph.Node { return e.from }
+func (e *dotEdge) To() graph.Node   { return e.to }
+
+func (e *dotEdge) ReversedEdge() graph.Edge {
+	return &dotEdge{from: e.to, to: e.from, weight: e.weight, dashed: e.dashed}
+}
+
+func (e *dotEdge) SetAttribute(a encoding.Attribute) error {
+	switch a.Key {
+	case "weight":
+		w, err := strconv.ParseFloat(a.Value, 64)
+		if err != nil || w <= 0 {
+			return fmt.Errorf("invalid weight %q", a.Value)
+		}
+		e.weight = w
+	case "style":
+		e.dashed = strings.Contains(a.Value, "dashed")
+	}
+	return nil
+}
+
+// moduleColor returns a light color for the module, the same on every run.
+func moduleColor(module string) string {
+	if module == "" {
+		return "#ffffff"
+	}
+	h := fnv.New32a()
+	h.Write([]byte(module))
+	return fmt.Sprintf("%.3f 0.300 1.000", float64(h.Sum32()%360)/360)
+}
+
+// Render lays out the graph with the Graphviz layout program, e.g. "dot" or
+// "sfdp", and writes an image in the given Graphviz output format, e.g. "svg"
+// or "png", to the named file.
+func Render(g *Graph, layout, format, name string) error {
+	if _, err := exec.LookPath(layout); err != nil {
+		return fmt.Errorf("graphviz layout %q not found, is graphviz installed? %w", layout, err)
+	}
+	dir, err := os.MkdirTemp("", "*-pkgrank")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	dotFile := filepath.Join(dir, "graph.dot")
+	file, err := os.Create(dotFile)
+	if err != nil {
+		return err
+	}
+	if err := WriteDOT(file, g); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	name, err = filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	_, err = doExec(execQuiet, dir, nil, layout, "-T"+format, "-o", name, dotFile)
+	return err
+}
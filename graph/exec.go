@@ -0,0 +1,296 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/cache"
+)
+
+// ExecConfig configures the go commands that this package runs, such as
+// those fetching modules and listing packages, and the environment of the
+// depgraph analyzer, which runs go list itself. Empty fields leave the
+// environment of the process unchanged.
+type ExecConfig struct {
+	// Go is the path of the go command, instead of go in the PATH. Its
+	// directory is put first in the PATH, so that depgraph runs it too.
+	Go string
+	// Proxy sets GOPROXY, e.g. "https://proxy.example.com,direct".
+	Proxy string
+	// Private sets GOPRIVATE, the patterns of module paths to fetch
+	// directly and not check against the checksum database.
+	Private string
+	// NoSumDB sets GONOSUMDB, the patterns of module paths not to check
+	// against the checksum database.
+	NoSumDB string
+	// Flags are added to GOFLAGS, e.g. "-mod=mod". A -mod=vendor flag,
+	// here or in the environment, only applies to commands run in a module
+	// with a vendor directory, such as that of ListModule.
+	Flags string
+	// KeepWorkdir keeps the scratch modules that go commands run in, rather
+	// than removing them once analyzed, and logs where they are, to debug
+	// failures. See Workdirs.
+	KeepWorkdir bool
+	// Sandbox isolates the analysis of untrusted modules from the user's
+	// environment. Analyses only run go mod, go get and go list,
+	// and depgraph, which loads packages with go list and type-checks their
+	// source: none builds or runs the code of modules, nor go generate.
+	// What could still run is what go runs on their behalf, which Sandbox
+	// rules out:
+	//   - fetching from version control systems, which run git and others on
+	//     untrusted repositories, is disabled with GOVCS=*:off, so modules
+	//     are only fetched from the module proxy; GOPRIVATE, GONOPROXY,
+	//     GONOSUMDB and GOINSECURE are cleared, and Private and NoSumDB
+	//     ignored;
+	//   - cgo, which runs the C compiler and pkg-config as #cgo directives
+	//     say when go list preprocesses files, is disabled with
+	//     CGO_ENABLED=0;
+	//   - toolchains that modules require are not downloaded and run, with
+	//     GOTOOLCHAIN=local, and the go env file is ignored with GOENV=off.
+	// GOPATH, the module cache and the build cache are moved to SandboxDir,
+	// so that modules do not reach the user's.
+	Sandbox bool
+	// SandboxDir holds the GOPATH, module cache and build cache of Sandbox,
+	// by default sandbox in the cache directory of pkgrank, see
+	// cache.DefaultDir.
+	SandboxDir string
+}
+
+// DefaultExecConfig is the ExecConfig of contexts without one, and of the
+// functions of this package that do not take a context.
+var DefaultExecConfig ExecConfig
+
+type execConfigKey struct{}
+
+// WithExecConfig returns a copy of ctx with which TransitiveGraphContext
+// and the other functions taking a context run go commands as c configures.
+func WithExecConfig(ctx context.Context, c ExecConfig) context.Context {
+	return context.WithValue(ctx, execConfigKey{}, c)
+}
+
+// execConfig returns the ExecConfig of ctx.
+func execConfig(ctx context.Context) ExecConfig {
+	if c, ok := ctx.Value(execConfigKey{}).(ExecConfig); ok {
+		return c
+	}
+	return DefaultExecConfig
+}
+
+// Command returns a command running name with args, or the go command of
+// c if name is go, in the environment of the process with the variables of
+// c, see exec.CommandContext.
+func (c ExecConfig) Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	if name == "go" && c.Go != "" {
+		name = c.Go
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = c.environ("", nil)
+	return cmd
+}
+
+// environ returns the environment of commands run in dir: that of the
+// process, the variables of c and then envs, which override them except for
+// GOFLAGS, which are all added together, and finally those of Sandbox.
+func (c ExecConfig) environ(dir string, envs map[string]string) []string {
+	vars := make(map[string]string)
+	if c.Go != "" && strings.ContainsRune(c.Go, filepath.Separator) {
+		vars["PATH"] = filepath.Dir(c.Go) + string(filepath.ListSeparator) + os.Getenv("PATH")
+	}
+	for k, v := range map[string]string{
+		"GOPROXY":   c.Proxy,
+		"GOPRIVATE": c.Private,
+		"GONOSUMDB": c.NoSumDB,
+	} {
+		if v != "" {
+			vars[k] = v
+		}
+	}
+	for k, v := range envs {
+		vars[k] = v
+	}
+	var flags []string
+	for _, f := range []string{os.Getenv("GOFLAGS"), c.Flags, envs["GOFLAGS"]} {
+		if f != "" {
+			flags = append(flags, f)
+		}
+	}
+	flags = vendorFlags(dir, flags)
+	if c.Sandbox {
+		for k, v := range c.sandboxEnv() {
+			vars[k] = v
+		}
+		// Keep the module cache writable, for pkgrank clean to remove it.
+		flags = append(flags, "-modcacherw")
+	}
+	if len(flags) > 0 {
+		vars["GOFLAGS"] = strings.Join(flags, " ")
+	} else if os.Getenv("GOFLAGS") != "" {
+		// Override the GOFLAGS of the process whose -mod=vendor was dropped.
+		vars["GOFLAGS"] = ""
+	}
+	env := os.Environ()
+	for k, v := range vars {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// sandboxEnv returns the variables that Sandbox sets.
+func (c ExecConfig) sandboxEnv() map[string]string {
+	dir := c.SandboxDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "pkgrank-sandbox")
+		if cacheDir := cache.DefaultDir(); cacheDir != "" {
+			dir = filepath.Join(cacheDir, "sandbox")
+		}
+	}
+	return map[string]string{
+		"GOPATH":      filepath.Join(dir, "gopath"),
+		"GOMODCACHE":  filepath.Join(dir, "gopath", "pkg", "mod"),
+		"GOCACHE":     filepath.Join(dir, "gocache"),
+		"GOVCS":       "*:off",
+		"GOPRIVATE":   "",
+		"GONOPROXY":   "",
+		"GONOSUMDB":   "",
+		"GOINSECURE":  "",
+		"CGO_ENABLED": "0",
+		"GOTOOLCHAIN": "local",
+		"GOENV":       "off",
+	}
+}
+
+// Classes of ExecError, to test with errors.Is, e.g.
+// errors.Is(err, graph.ErrNetwork).
+var (
+	// ErrNetwork is the class of failures of the module proxy or of the
+	// network that may not happen again, unlike a missing module.
+	ErrNetwork = errors.New("network failure")
+	// ErrNoToolchain is the class of failures to find the go command or
+	// depgraph, or the Go toolchain that a module requires.
+	ErrNoToolchain = errors.New("missing toolchain")
+	// ErrModuleNotFound is the class of failures to find a module, a
+	// version of it, or a package in it.
+	ErrModuleNotFound = errors.New("module not found")
+)
+
+// execFailures are what commands print when they fail in each class.
+var execFailures = map[error][]string{
+	ErrNetwork: {
+		"429 Too Many Requests",
+		"500 Internal Server Error",
+		"502 Bad Gateway",
+		"503 Service Unavailable",
+		"504 Gateway Timeout",
+		"TLS handshake timeout",
+		"connection reset by peer",
+		"connection refused",
+		"i/o timeout",
+		"unexpected EOF",
+	},
+	ErrNoToolchain: {
+		"toolchain not available",
+		"requires go >= ",
+		"cannot find GOROOT",
+	},
+	ErrModuleNotFound: {
+		"no matching versions for query",
+		"404 Not Found",
+		"410 Gone",
+		"unknown revision",
+		"malformed module path",
+		"cannot find module providing package",
+		"no required module provides package",
+		"does not contain package",
+	},
+}
+
+// ExecError is the error of a command that this package ran and that
+// failed, such as a go command fetching a module or the depgraph analyzer.
+// errors.Is classifies it as ErrNetwork, ErrNoToolchain or
+// ErrModuleNotFound, and Hint tells how to remedy it.
+type ExecError struct {
+	// Command is the command line.
+	Command string
+	// Name is the name of the command, e.g. go or depgraph.
+	Name string
+	// ExitCode is the exit code of the command, or -1 if it did not exit,
+	// e.g. because it was not found or was killed.
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	// Err is the error of running the command, e.g. an *exec.ExitError.
+	Err error
+}
+
+// newExecError returns the error of cmd failing with err, having printed
+// stdout and stderr.
+func newExecError(cmd *exec.Cmd, stdout, stderr string, err error) *ExecError {
+	code := -1
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		code = exit.ExitCode()
+	}
+	name := strings.TrimSuffix(filepath.Base(cmd.Args[0]), ".exe")
+	return &ExecError{
+		Command:  fmt.Sprintf("%v", cmd),
+		Name:     name,
+		ExitCode: code,
+		Stdout:   stdout,
+		Stderr:   stderr,
+		Err:      err,
+	}
+}
+
+func (e *ExecError) Error() string {
+	msg := fmt.Sprintf("failed to run cmd '%v': %v", e.Command, e.Err)
+	if e.Stderr != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Stderr)
+	}
+	return msg
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the failure is in the class target, one of ErrNetwork,
+// ErrNoToolchain and ErrModuleNotFound.
+func (e *ExecError) Is(target error) bool {
+	if target == ErrNoToolchain && (errors.Is(e.Err, exec.ErrNotFound) || errors.Is(e.Err, fs.ErrNotExist)) {
+		return true
+	}
+	for _, failure := range execFailures[target] {
+		if strings.Contains(e.Stderr, failure) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hint returns how to remedy the failure, or "" if it is in no class.
+func (e *ExecError) Hint() string {
+	switch {
+	case e.Is(ErrNoToolchain) && e.Name == "depgraph":
+		return "install depgraph with go install github.com/arclabs561/pkgrank/cmd/depgraph@latest and put it in the PATH"
+	case e.Is(ErrNoToolchain):
+		return "install the Go toolchain that the module requires and put go in the PATH, or let go download it with GOTOOLCHAIN=auto"
+	case e.Is(ErrNetwork):
+		return "the module proxy or the network failed, which may not happen again: retry later, or set GOPROXY to another proxy"
+	case e.Is(ErrModuleNotFound):
+		return "check the package path and version, and for private modules, set GOPRIVATE and make sure git can fetch them"
+	}
+	return ""
+}
+
+// IsProxyError reports whether err is from a go command failing to fetch a
+// module because of a transient failure of the module proxy or the network,
+// so that trying again later may succeed. It is errors.Is(err, ErrNetwork).
+func IsProxyError(err error) bool {
+	return errors.Is(err, ErrNetwork)
+}
@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"math"
+	"sort"
+)
+
+// Fan is the number of distinct nodes depending on a node, its fan-in, and
+// the number it depends on, its fan-out.
+type Fan struct {
+	Node NodeKey `json:"node"`
+	In   int     `json:"in"`
+	Out  int     `json:"out"`
+}
+
+// Fans returns the fan-in and fan-out of every node, sorted by the product of
+// the two, highest first, with ties broken by node.
+func (f Graph) Fans() []Fan {
+	succs := f.successors()
+	preds := f.predecessors()
+	fans := make([]Fan, 0, len(f.Nodes))
+	for key := range f.Nodes {
+		fans = append(fans, Fan{Node: key, In: len(preds[key]), Out: len(succs[key])})
+	}
+	sort.Slice(fans, func(i, j int) bool {
+		if a, b := fans[i].In*fans[i].Out, fans[j].In*fans[j].Out; a != b {
+			return a > b
+		}
+		return fans[i].Node.ID < fans[j].Node.ID
+	})
+	return fans
+}
+
+// OutlierThresholds returns the smallest fan-in and fan-out that are more
+// than sigma standard deviations above the mean of fans.
+func OutlierThresholds(fans []Fan, sigma float64) (minIn, minOut int) {
+	if len(fans) == 0 {
+		return 0, 0
+	}
+	var sumIn, sumOut, sqIn, sqOut float64
+	for _, fan := range fans {
+		in, out := float64(fan.In), float64(fan.Out)
+		sumIn, sumOut = sumIn+in, sumOut+out
+		sqIn, sqOut = sqIn+in*in, sqOut+out*out
+	}
+	n := float64(len(fans))
+	threshold := func(sum, sq float64) int {
+		mean := sum / n
+		stddev := math.Sqrt(math.Max(0, sq/n-mean*mean))
+		return int(math.Floor(mean+sigma*stddev)) + 1
+	}
+	return threshold(sumIn, sqIn), threshold(sumOut, sqOut)
+}
+
+// GodPackages returns the fans with both at least minIn fan-in and at least
+// minOut fan-out, in their original order. Such nodes are both widely
+// depended upon and depend on much, and tend to be worth splitting.
+func GodPackages(fans []Fan, minIn, minOut int) []Fan {
+	var gods []Fan
+	for _, fan := range fans {
+		if fan.In >= minIn && fan.Out >= minOut {
+			gods = append(gods, fan)
+		}
+	}
+	return gods
+}
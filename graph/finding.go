@@ -0,0 +1,121 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rules of findings.
+const (
+	RuleCycle      = "cycle"
+	RuleLayering   = "layering"
+	RuleUnused     = "unused"
+	RuleDependency = "dependency"
+	RuleRegression = "regression"
+	RuleLicense    = "license"
+)
+
+// ruleDescriptions describes each rule of findings.
+var ruleDescriptions = map[string]string{
+	RuleCycle:      "Packages depend on each other in a cycle.",
+	RuleLayering:   "An import is not allowed by the architecture policy.",
+	RuleUnused:     "A package is not imported by any other package.",
+	RuleDependency: "A package has a dependency that is not in the baseline.",
+	RuleRegression: "A metric has regressed since the baseline.",
+	RuleLicense:    "A dependency's license is not allowed by the license policy.",
+}
+
+// Level is the severity of a finding.
+type Level string
+
+// Available levels of findings.
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+	LevelNote    Level = "note"
+)
+
+// Finding is a problem found in a graph by one of the rules, shared by the
+// commands that check graphs so that they may be reported alike.
+type Finding struct {
+	Rule    string `json:"rule"`
+	Level   Level  `json:"level"`
+	Message string `json:"message"`
+	// Nodes are the nodes that the finding is about.
+	Nodes []NodeKey `json:"nodes"`
+	// Locations are the imports that the finding is about, if known.
+	Locations []Provenance `json:"locations,omitempty"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Level, f.Rule, f.Message)
+}
+
+// CycleFindings returns a finding for each strongly connected component of
+// more than one node, located at the imports between its nodes.
+func (f Graph) CycleFindings() []Finding {
+	var findings []Finding
+	for _, scc := range f.StronglyConnectedComponents() {
+		if len(scc) < 2 {
+			continue
+		}
+		in := make(map[NodeKey]bool, len(scc))
+		ids := make([]string, len(scc))
+		for i, n := range scc {
+			in[n] = true
+			ids[i] = n.ID
+		}
+		var locations []Provenance
+		for _, edge := range f.Edges {
+			if edge, ok := edge.(*DirectedEdge); ok && in[edge.Src] && in[edge.Dst] {
+				locations = mergeProvenance(locations, edge.Provenance)
+			}
+		}
+		findings = append(findings, Finding{
+			Rule:      RuleCycle,
+			Level:     LevelError,
+			Message:   "import cycle between " + strings.Join(ids, ", "),
+			Nodes:     scc,
+			Locations: locations,
+		})
+	}
+	return findings
+}
+
+// ViolationFindings returns a finding for each import of the graph that the
+// policy does not allow.
+func (f Graph) ViolationFindings(p Policy) []Finding {
+	violations := f.Violations(p)
+	findings := make([]Finding, 0, len(violations))
+	for _, v := range violations {
+		var locations []Provenance
+		for _, edge := range f.Edges {
+			if edge, ok := edge.(*DirectedEdge); ok && edge.Src == v.Src && edge.Dst == v.Dst {
+				locations = mergeProvenance(locations, edge.Provenance)
+			}
+		}
+		findings = append(findings, Finding{
+			Rule:      RuleLayering,
+			Level:     LevelError,
+			Message:   v.String(),
+			Nodes:     []NodeKey{v.Src, v.Dst},
+			Locations: locations,
+		})
+	}
+	return findings
+}
+
+// UnusedFindings returns a finding for each of the given unused nodes, see
+// Graph.Unused.
+func UnusedFindings(nodes []NodeKey) []Finding {
+	findings := make([]Finding, len(nodes))
+	for i, n := range nodes {
+		findings[i] = Finding{
+			Rule:    RuleUnused,
+			Level:   LevelWarning,
+			Message: n.ID + " is not imported by any package",
+			Nodes:   []NodeKey{n},
+		}
+	}
+	return findings
+}
@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sort"
+)
+
+// GobVersion is the version of the gob encoding produced by Graph.GobEncode.
+// It is incremented on incompatible changes, so that graphs encoded by
+// another version of pkgrank, e.g. in the analysis facts of a go vet run
+// with a stale vet tool, fail to decode rather than decode wrong.
+const GobVersion = 1
+
+func init() {
+	// Edges are encoded as Edge interface values, so their concrete types
+	// must be registered.
+	gob.Register(&DirectedEdge{})
+	gob.Register(&UndirectedEdge{})
+	gob.Register(&HyperEdge{})
+}
+
+// gobGraph is the gob encoding of a graph.
+type gobGraph struct {
+	Version         int
+	Container       string
+	AddedContainers []string
+	Nodes           []Node
+	Edges           []Edge
+}
+
+// gobEdgeKey is the gob encoding of an edge key.
+type gobEdgeKey struct {
+	Container string
+	ID        string
+}
+
+var (
+	_ gob.GobEncoder = Graph{}
+	_ gob.GobDecoder = (*Graph)(nil)
+	_ gob.GobEncoder = EdgeKey{}
+	_ gob.GobDecoder = (*EdgeKey)(nil)
+)
+
+// GobEncode encodes the graph with its nodes, edges of any type and added
+// containers, sorted by key, so that analysis facts holding graphs can be
+// serialized by drivers such as unitchecker and gopls, which encode facts
+// with encoding/gob.
+func (f Graph) GobEncode() ([]byte, error) {
+	g := gobGraph{
+		Version:   GobVersion,
+		Container: f.Container,
+		Nodes:     make([]Node, 0, len(f.Nodes)),
+		Edges:     make([]Edge, 0, len(f.Edges)),
+	}
+	for c := range f.AddedContainers {
+		g.AddedContainers = append(g.AddedContainers, c)
+	}
+	sort.Strings(g.AddedContainers)
+	for _, node := range f.Nodes {
+		g.Nodes = append(g.Nodes, node)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	for _, edge := range f.Edges {
+		g.Edges = append(g.Edges, edge)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		return g.Edges[i].Key().String() < g.Edges[j].Key().String()
+	})
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(g); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// GobDecode decodes a graph encoded by GobEncode, replacing the graph's
+// contents.
+func (f *Graph) GobDecode(b []byte) error {
+	var g gobGraph
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&g); err != nil {
+		return err
+	}
+	if g.Version != GobVersion {
+		return fmt.Errorf("unsupported graph gob version: %d", g.Version)
+	}
+	*f = Graph{
+		Container:       g.Container,
+		AddedContainers: make(map[string]struct{}, len(g.AddedContainers)),
+	}
+	for _, c := range g.AddedContainers {
+		f.AddedContainers[c] = struct{}{}
+	}
+	for _, edge := range g.Edges {
+		if _, err := f.AddEdge(edge); err != nil {
+			return err
+		}
+	}
+	for _, node := range g.Nodes {
+		f.AddNode(node.NodeKey, node.Data)
+	}
+	return nil
+}
+
+// GobEncode encodes the key's container and ID, which are unexported.
+func (k EdgeKey) GobEncode() ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(gobEdgeKey{Container: k.container, ID: k.id}); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// GobDecode decodes a key encoded by GobEncode.
+func (k *EdgeKey) GobDecode(b []byte) error {
+	var g gobEdgeKey
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&g); err != nil {
+		return err
+	}
+	*k = NewEdgeKey(g.Container, g.ID)
+	return nil
+}
@@ -2,11 +2,16 @@ package graph
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+	"golang.org/x/mod/module"
 )
 
 var _ map[NodeKey]struct{}
@@ -19,12 +24,55 @@ func (k NodeKey) String() string {
 	return k.ID
 }
 
+// MarshalText encodes the key as its ID, so that keys are plain strings in
+// JSON documents.
+func (k NodeKey) MarshalText() ([]byte, error) {
+	return []byte(k.ID), nil
+}
+
+// UnmarshalText decodes a key encoded by MarshalText.
+func (k *NodeKey) UnmarshalText(b []byte) error {
+	k.ID = string(b)
+	return nil
+}
+
 type Node struct {
 	NodeKey
 	Data *NodeData
 }
 
-type NodeData struct{}
+type NodeData struct {
+	// Module is the path of the module that the node belongs to.
+	Module string
+	// Version is the version of Module, empty if unknown.
+	Version string
+	// Types is the number of package-level named types declared by the
+	// node, of which Interfaces are interfaces.
+	Types      int
+	Interfaces int
+	// Attrs holds any other attributes of the node.
+	Attrs Attrs
+}
+
+// merge fills in any unknown fields of d from other.
+func (d *NodeData) merge(other *NodeData) {
+	if d.Module == "" {
+		d.Module, d.Version = other.Module, other.Version
+	}
+	if d.Types == 0 {
+		d.Types, d.Interfaces = other.Types, other.Interfaces
+	}
+	d.Attrs = mergeAttrs(d.Attrs, other.Attrs)
+}
+
+// ModuleVersion returns the node's module in module@version form, or only
+// the module path if the version is unknown.
+func (d NodeData) ModuleVersion() string {
+	if d.Version == "" {
+		return d.Module
+	}
+	return d.Module + "@" + d.Version
+}
 
 var _ map[EdgeKey]struct{}
 
@@ -33,16 +81,44 @@ type EdgeKey struct {
 	id        string
 }
 
+// NewEdgeKey returns the key of the edge with the given ID in a container,
+// e.g. the ID of a directed edge is "src->dst".
+func NewEdgeKey(container, id string) EdgeKey {
+	return EdgeKey{container: container, id: id}
+}
+
+// Container returns the container of the edge.
+func (k EdgeKey) Container() string {
+	return k.container
+}
+
+// ID returns the ID of the edge within its container.
+func (k EdgeKey) ID() string {
+	return k.id
+}
+
 func (k EdgeKey) String() string {
 	return fmt.Sprintf("%s:%s", k.container, k.id)
 }
 
+// ParseEdgeKey parses an edge key of the form container:id, as returned by
+// EdgeKey.String.
+func ParseEdgeKey(s string) (EdgeKey, error) {
+	container, id, ok := strings.Cut(s, ":")
+	if !ok {
+		return EdgeKey{}, fmt.Errorf("invalid edge key: %q", s)
+	}
+	return NewEdgeKey(container, id), nil
+}
+
+// EdgeKeyFrom is like ParseEdgeKey, but panics if s is not a valid key. It is
+// meant for keys known to be valid, such as literals.
 func EdgeKeyFrom(s string) EdgeKey {
-	parts := strings.SplitN(s, ":", 2)
-	if len(parts) != 2 {
-		panic(fmt.Sprintf("invalid edge key: %q", s))
+	key, err := ParseEdgeKey(s)
+	if err != nil {
+		panic(err)
 	}
-	return EdgeKey{container: parts[0], id: parts[1]}
+	return key
 }
 
 type Edge interface {
@@ -74,6 +150,9 @@ var (
 type BaseEdge struct {
 	EdgeKey    EdgeKey
 	EdgeWeight float64
+	// Attrs holds any other attributes of the edge. Merged edges keep the
+	// attributes of the added edge, filled in from the existing one.
+	Attrs Attrs
 }
 
 func (e BaseEdge) String() string {
@@ -100,6 +179,65 @@ type DirectedEdge struct {
 	BaseEdge
 	Src NodeKey
 	Dst NodeKey
+	// Symbols are the sorted, distinct exported identifiers of Dst that
+	// are referenced by Src, if known.
+	Symbols []string
+	// Kind classifies the edge. Merged edges keep only the kinds common to
+	// both, e.g. an edge is only test-only if it is in every merged edge.
+	Kind EdgeKind
+	// Configs are the sorted build configurations, see BuildConfig, in
+	// which the edge exists, if known.
+	Configs []string
+	// Provenance lists where the edge is introduced, if known.
+	Provenance []Provenance
+}
+
+// ImportStyle is the way a package is imported.
+type ImportStyle string
+
+// Available import styles.
+const (
+	ImportStyleRegular ImportStyle = ""
+	ImportStyleNamed   ImportStyle = "named"
+	ImportStyleBlank   ImportStyle = "blank"
+	ImportStyleDot     ImportStyle = "dot"
+)
+
+// Provenance is the location of an import that introduces an edge.
+type Provenance struct {
+	// File identifies the importing file by its package path and base
+	// name, e.g. "github.com/rs/zerolog/log/log.go".
+	File  string      `json:"file"`
+	Line  int         `json:"line"`
+	Style ImportStyle `json:"style,omitempty"`
+}
+
+func (p Provenance) String() string {
+	s := fmt.Sprintf("%s:%d", p.File, p.Line)
+	if p.Style != ImportStyleRegular {
+		s += fmt.Sprintf(" (%s)", p.Style)
+	}
+	return s
+}
+
+// mergeProvenance returns the sorted union of two provenance slices.
+func mergeProvenance(a, b []Provenance) []Provenance {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	merged := make([]Provenance, 0, len(a)+len(b))
+	merged = append(merged, a...)
+	merged = append(merged, b...)
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].File != merged[j].File {
+			return merged[i].File < merged[j].File
+		}
+		return merged[i].Line < merged[j].Line
+	})
+	return lo.Uniq(merged)
 }
 
 func NewDirectedEdge(container string, srcID, dstID string) *DirectedEdge {
@@ -108,7 +246,7 @@ func NewDirectedEdge(container string, srcID, dstID string) *DirectedEdge {
 	return &DirectedEdge{
 		BaseEdge: BaseEdge{
 			EdgeKey: EdgeKey{
-				id:        fmt.Sprintf("%v->%v", src, dst),
+				id:        srcID + "->" + dstID,
 				container: container,
 			},
 			EdgeWeight: 1,
@@ -153,15 +291,22 @@ type UndirectedEdge struct {
 	Right NodeKey
 }
 
+// NewUndirectedEdge returns an edge between two nodes. Its key does not depend
+// on the order of the nodes, so that edges added in either direction merge.
 func NewUndirectedEdge(container string, leftID, rightID string) *UndirectedEdge {
 	left := NodeKey{ID: leftID}
 	right := NodeKey{ID: rightID}
+	lo, hi := leftID, rightID
+	if hi < lo {
+		lo, hi = hi, lo
+	}
 	return &UndirectedEdge{
 		BaseEdge: BaseEdge{
 			EdgeKey: EdgeKey{
-				id:        fmt.Sprintf("%v~%v", left, right),
+				id:        lo + "~" + hi,
 				container: container,
 			},
+			EdgeWeight: 1,
 		},
 		Left:  left,
 		Right: right,
@@ -190,6 +335,7 @@ type HyperEdge struct {
 }
 
 func NewHyperEdge(container string, ids ...string) *HyperEdge {
+	ids = append([]string(nil), ids...)
 	sort.Strings(ids)
 	keys := make([]NodeKey, len(ids))
 	for i, id := range ids {
@@ -201,6 +347,7 @@ func NewHyperEdge(container string, ids ...string) *HyperEdge {
 				id:        strings.Join(ids, ","),
 				container: container,
 			},
+			EdgeWeight: 1,
 		},
 		UnorderedSet: keys,
 	}
@@ -237,6 +384,14 @@ func (e *DirectedEdge) EdgeType() EdgeType   { return EdgeTypeDirected }
 func (e *UndirectedEdge) EdgeType() EdgeType { return EdgeTypeUndirected }
 func (e *HyperEdge) EdgeType() EdgeType      { return EdgeTypeHyper }
 
+// Graph is a graph of the dependencies between containers. It is not safe for
+// concurrent use: it may be read concurrently, but must not be modified
+// concurrently with any other use. Use ConcurrentGraph to add edges from
+// several goroutines.
+//
+// Edges and node data are copied on write: Add and Merge share them between
+// graphs, and merging replaces them with modified copies rather than
+// modifying them. Clone a graph before modifying its edges or nodes in place.
 type Graph struct {
 	// The Container name for which this graph primarily
 	// represents.
@@ -248,8 +403,34 @@ type Graph struct {
 	Nodes map[NodeKey]Node
 	// Every key's Nodes() must return nodes that are present in the Nodes
 	// map. The converse is not always true. In particular, whevner there
-	// are isolated, edgless nodes.
+	// are isolated, edgless nodes. It may only be modified directly until
+	// the first adjacency query, e.g. Successors, Degree or Paths, which
+	// indexes it: later changes must go through AddEdge and RemoveEdge,
+	// which keep the index up to date.
 	Edges map[EdgeKey]Edge
+
+	// adj indexes Edges for adjacency queries, if any were made.
+	adj *adjacency
+	// logger is set by SetLogger.
+	logger *zerolog.Logger
+}
+
+// Logger returns the logger of changes to the graph, as set by SetLogger.
+// It defaults to zerolog.DefaultContextLogger, as set by
+// shared.SetGlobalLogger, or to a disabled logger if that is nil, so that
+// the package logs nothing unless asked to.
+func (f Graph) Logger() *zerolog.Logger {
+	if f.logger != nil {
+		return f.logger
+	}
+	return zerolog.Ctx(context.Background())
+}
+
+// SetLogger sets the logger of changes to the graph, which are logged at
+// trace and debug levels, e.g. to silence them with a disabled logger. A nil
+// logger restores the default of Logger.
+func (f *Graph) SetLogger(logger *zerolog.Logger) {
+	f.logger = logger
 }
 
 // Order returns the number of nodes in the graph.
@@ -262,6 +443,65 @@ func (f Graph) Size() int {
 	return len(f.Edges)
 }
 
+// AllEdges returns an iterator over the keys and edges of the graph, in no
+// particular order. It is an iter.Seq2[EdgeKey, Edge], so that it can be
+// ranged over by modules using Go 1.23 or later. SortedEdges returns them in a
+// stable order instead.
+func (f Graph) AllEdges() func(yield func(EdgeKey, Edge) bool) {
+	return func(yield func(EdgeKey, Edge) bool) {
+		for key, edge := range f.Edges {
+			if !yield(key, edge) {
+				return
+			}
+		}
+	}
+}
+
+// SortedNodes returns the keys of the nodes of the graph, sorted by ID.
+func (f Graph) SortedNodes() []NodeKey {
+	return sortedKeys(f.Nodes)
+}
+
+// SortedEdges returns the edges of the graph sorted by container, then by the
+// IDs of their nodes, so that iterating over them is the same from run to
+// run.
+func (f Graph) SortedEdges() []Edge {
+	edges := make([]Edge, 0, len(f.Edges))
+	for _, edge := range f.Edges {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		return lessEdge(edges[i], edges[j])
+	})
+	return edges
+}
+
+func lessEdge(a, b Edge) bool {
+	ka, kb := a.Key(), b.Key()
+	if ka.container != kb.container {
+		return ka.container < kb.container
+	}
+	// Avoid allocating the nodes of directed edges, the common case.
+	da, okA := a.(*DirectedEdge)
+	db, okB := b.(*DirectedEdge)
+	if okA && okB {
+		if da.Src != db.Src {
+			return da.Src.ID < db.Src.ID
+		}
+		return da.Dst.ID < db.Dst.ID
+	}
+	na, nb := a.Nodes(), b.Nodes()
+	for i := 0; i < len(na) && i < len(nb); i++ {
+		if na[i] != nb[i] {
+			return na[i].ID < nb[i].ID
+		}
+	}
+	if len(na) != len(nb) {
+		return len(na) < len(nb)
+	}
+	return ka.id < kb.id
+}
+
 func (f Graph) String() string {
 	var buf bytes.Buffer
 	buf.WriteString("\n")
@@ -271,15 +511,10 @@ func (f Graph) String() string {
 	}
 	var sorted []item
 	for _, edge := range f.Edges {
-		switch edge := edge.(type) {
-		case *DirectedEdge:
-			sorted = append(sorted, item{
-				name:   edge.String(),
-				weight: edge.Weight(),
-			})
-		default:
-			log.Fatal().Msgf("unimplemented: %#v", edge)
-		}
+		sorted = append(sorted, item{
+			name:   edge.String(),
+			weight: edge.Weight(),
+		})
 	}
 	sort.Slice(sorted, func(i, j int) bool {
 		if sorted[i].weight != sorted[j].weight {
@@ -296,15 +531,20 @@ func (f Graph) String() string {
 	return buf.String()
 }
 
-func (f *Graph) Add(other Graph, opts ...AddEdgeOptions) int {
+// Add adds the edges and nodes of other to the graph, skipping edges of
+// containers that were already added. It returns the number of skipped edges,
+// or -1 if all of other's containers were already added. Edges are added in
+// the order of SortedEdges, and it stops at the first that fails to be added,
+// returning its error.
+func (f *Graph) Add(other Graph, opts ...AddEdgeOption) (int, error) {
+	log := f.Logger()
 	var keep map[string]struct{}
 	for container := range other.AddedContainers {
-		log := log.With().Str("container", container).Logger()
 		if _, ok := f.AddedContainers[container]; ok {
-			log.Trace().Msgf("skipping already added container")
+			log.Trace().Str("container", container).Msg("skipping already added container")
 			continue
 		}
-		log.Trace().Msgf("keeping new container")
+		log.Trace().Str("container", container).Msg("keeping new container")
 		if keep == nil {
 			keep = make(map[string]struct{})
 		}
@@ -312,8 +552,8 @@ func (f *Graph) Add(other Graph, opts ...AddEdgeOptions) int {
 		f.AddedContainers[container] = struct{}{}
 	}
 	if len(keep) == 0 && len(other.AddedContainers) > 0 {
-		log.Debug().Msgf("no new containers to keep")
-		return -1
+		log.Debug().Msg("no new containers to keep")
+		return -1, nil
 	}
 	if len(other.AddedContainers) > 0 {
 		log.Trace().Str("keep", fmt.Sprintf("%v", keep)).Msgf("keeping %d containers", len(keep))
@@ -321,61 +561,280 @@ func (f *Graph) Add(other Graph, opts ...AddEdgeOptions) int {
 	// Otherwise, even if no kept added containers, then we are adding a bare
 	// graphFact, and we should keep it.
 	overlap := 0
-	for _, edge := range other.Edges {
-		log := log.With().Stringer("edge", edge).Logger()
+	for _, edge := range other.SortedEdges() {
 		if _, ok := keep[edge.Key().container]; !ok && len(other.AddedContainers) > 0 {
 			overlap++
-			log.Trace().Msgf("skipping already added edge")
+			log.Trace().Stringer("edge", edge).Msg("skipping already added edge")
 			continue
 		}
-		_ = f.AddEdge(edge, opts...)
-		// log.Fatal().Stringer("edgeKey", edge.Key()).Msg("edge already exists")
+		if _, err := f.AddEdge(edge, opts...); err != nil {
+			return overlap, err
+		}
+	}
+	for key, node := range other.Nodes {
+		prev, ok := f.Nodes[key]
+		switch {
+		case !ok || prev.Data == nil:
+			f.AddNode(key, node.Data)
+		case node.Data != nil:
+			data := *prev.Data
+			data.merge(node.Data)
+			f.AddNode(key, &data)
+		}
 	}
-	return overlap
+	return overlap, nil
 }
 
-type AddEdgeOptions struct {
-	// Merges toAdd into prev, only modifying prev. Only called if
-	// edge already previously existed.
-	MergeFunc func(prev Edge, toAdd Edge)
+// Merge returns a new graph for the given container that merges all of the
+// given graphs into it.
+func Merge(container string, graphs ...*Graph) (*Graph, error) {
+	g := &Graph{
+		Container:       container,
+		AddedContainers: make(map[string]struct{}),
+	}
+	for _, other := range graphs {
+		if _, err := g.Add(*other); err != nil {
+			return nil, fmt.Errorf("failed to merge graph of %s: %w", other.Container, err)
+		}
+	}
+	return g, nil
 }
 
-var DefaultAddEdgeOptions = AddEdgeOptions{
-	MergeFunc: func(prev Edge, edge Edge) {
-		switch edge := edge.(type) {
-		case *DirectedEdge:
-			edge.EdgeWeight += prev.Weight()
-		default:
-			log.Fatal().Msgf("unimplemented: %#v", edge)
-		}
-	},
+// AddNode adds a node to the graph, replacing the data of any existing node
+// with the same key.
+func (f *Graph) AddNode(key NodeKey, data *NodeData) {
+	if f.Nodes == nil {
+		f.Nodes = make(map[NodeKey]Node)
+	}
+	f.Nodes[key] = Node{NodeKey: key, Data: data}
 }
 
-func (f *Graph) AddEdge(edge Edge, opts ...AddEdgeOptions) bool {
-	if edge.EdgeType() == EdgeTypeBase {
-		log.Error().Msgf("cannot add base edges: %+v", edge)
+// SetNodeAttrs sets attributes of the node with the given key by calling set
+// on a copy of them, leaving the graph's other copies unchanged, and reports
+// whether there is such a node.
+func (f *Graph) SetNodeAttrs(key NodeKey, set func(*Attrs)) bool {
+	node, ok := f.Nodes[key]
+	if !ok {
 		return false
 	}
-	if err := edge.Valid(); err != nil {
-		log.Error().Err(err).Msgf("not adding invalid edge: %+v", edge)
+	var data NodeData
+	if node.Data != nil {
+		data = *node.Data
+		data.Attrs = data.Attrs.clone()
+	}
+	set(&data.Attrs)
+	f.Nodes[key] = Node{NodeKey: key, Data: &data}
+	return true
+}
+
+// ModuleVersions returns the versions of each module present in the graph,
+// keyed by module path with any major version suffix removed. A key with
+// more than one version indicates that several major versions of a module
+// coexist in the graph.
+func (f Graph) ModuleVersions() map[string][]string {
+	seen := make(map[string]map[string]struct{})
+	for _, node := range f.Nodes {
+		if node.Data == nil || node.Data.Module == "" {
+			continue
+		}
+		prefix, _, ok := module.SplitPathVersion(node.Data.Module)
+		if !ok {
+			prefix = node.Data.Module
+		}
+		if seen[prefix] == nil {
+			seen[prefix] = make(map[string]struct{})
+		}
+		seen[prefix][node.Data.ModuleVersion()] = struct{}{}
+	}
+	versions := make(map[string][]string, len(seen))
+	for prefix, set := range seen {
+		for v := range set {
+			versions[prefix] = append(versions[prefix], v)
+		}
+		sort.Strings(versions[prefix])
+	}
+	return versions
+}
+
+// ErrUnsupportedEdge is returned when an operation does not support the type
+// of an edge.
+var ErrUnsupportedEdge = errors.New("unsupported edge type")
+
+// MergeFunc merges prev into toAdd, which replaces it, only modifying toAdd.
+// It is only called if the edge already existed, with edges of the same type,
+// and toAdd is a shallow copy of the added edge, so its fields may be replaced
+// but its slices and attributes must not be modified in place.
+type MergeFunc func(prev Edge, toAdd Edge) error
+
+// DefaultMergeFunc sums the weights of edges, fills in their attributes, and
+// takes the union of the symbols, configurations and provenance of directed
+// edges.
+func DefaultMergeFunc(prev Edge, edge Edge) error {
+	switch edge := edge.(type) {
+	case *DirectedEdge:
+		edge.Attrs = mergeAttrs(edge.Attrs, prev.(*DirectedEdge).Attrs)
+		edge.EdgeWeight += prev.Weight()
+		edge.Symbols = mergeSymbols(prev.(*DirectedEdge).Symbols, edge.Symbols)
+		edge.Kind &= prev.(*DirectedEdge).Kind
+		edge.Configs = mergeSymbols(prev.(*DirectedEdge).Configs, edge.Configs)
+		edge.Provenance = mergeProvenance(prev.(*DirectedEdge).Provenance, edge.Provenance)
+		return nil
+	case *UndirectedEdge:
+		edge.Attrs = mergeAttrs(edge.Attrs, prev.(*UndirectedEdge).Attrs)
+		edge.EdgeWeight += prev.Weight()
+		return nil
+	case *HyperEdge:
+		edge.Attrs = mergeAttrs(edge.Attrs, prev.(*HyperEdge).Attrs)
+		edge.EdgeWeight += prev.Weight()
+		return nil
+	default:
+		return fmt.Errorf("%w for merging: %T", ErrUnsupportedEdge, edge)
+	}
+}
+
+// AddEdgeOption configures how AddEdge adds an edge. Options are applied in
+// order, so a later option overrides an earlier one that sets the same thing.
+type AddEdgeOption func(*addEdgeOptions)
+
+type addEdgeOptions struct {
+	// merge is nil if an existing edge is overwritten.
+	merge    MergeFunc
+	validate bool
+}
+
+func newAddEdgeOptions(opts []AddEdgeOption) addEdgeOptions {
+	o := addEdgeOptions{
+		merge:    DefaultMergeFunc,
+		validate: true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithMergeFunc merges an existing edge into the added one with f, instead of
+// DefaultMergeFunc.
+func WithMergeFunc(f MergeFunc) AddEdgeOption {
+	return func(o *addEdgeOptions) {
+		o.merge = f
+	}
+}
+
+// WithOverwrite replaces an existing edge with the added one, which may be of
+// a different type, without merging them.
+func WithOverwrite() AddEdgeOption {
+	return func(o *addEdgeOptions) {
+		o.merge = nil
+	}
+}
+
+// WithValidation sets whether the added edge must be valid, which it must by
+// default.
+func WithValidation(validate bool) AddEdgeOption {
+	return func(o *addEdgeOptions) {
+		o.validate = validate
+	}
+}
+
+// maxWeightMerge is the MergeFunc of directed edges of a dependency found
+// several times, e.g. under several build configurations or through a vendor
+// directory: the merged edge has the largest of their weights rather than
+// their sum, the union of their symbols, configurations and provenance, and
+// the kinds common to both.
+func maxWeightMerge(prevEdge Edge, toAdd Edge) error {
+	edge, ok := toAdd.(*DirectedEdge)
+	if !ok {
+		return fmt.Errorf("%w for merging: %T", ErrUnsupportedEdge, toAdd)
+	}
+	prev := prevEdge.(*DirectedEdge)
+	edge.EdgeWeight = math.Max(edge.EdgeWeight, prev.EdgeWeight)
+	edge.Symbols = mergeSymbols(prev.Symbols, edge.Symbols)
+	edge.Configs = mergeSymbols(prev.Configs, edge.Configs)
+	edge.Provenance = mergeProvenance(prev.Provenance, edge.Provenance)
+	edge.Kind &= prev.Kind
+	return nil
+}
+
+// mergeSymbols returns the sorted union of two sorted slices.
+func mergeSymbols(a, b []string) []string {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
 	}
-	log := log.With().Str("edgeKey", edge.Key().String()).Logger()
-	opt := DefaultAddEdgeOptions
-	if len(opts) > 0 {
-		// FIXME, merge opts
-		opt = opts[0]
+	merged := make([]string, 0, len(a)+len(b))
+	merged = append(merged, a...)
+	merged = append(merged, b...)
+	sort.Strings(merged)
+	return lo.Uniq(merged)
+}
+
+// AddEdge adds an edge to the graph, along with any of its nodes that are
+// missing, and reports whether an edge with the same key already existed, in
+// which case the edges are merged with DefaultMergeFunc unless opts say
+// otherwise, into a copy of edge. The graph is unchanged if an error is
+// returned.
+func (f *Graph) AddEdge(edge Edge, opts ...AddEdgeOption) (bool, error) {
+	o := newAddEdgeOptions(opts)
+	if err := checkEdge(edge, o.validate); err != nil {
+		return false, err
+	}
+	prev, ok := f.Edges[edge.Key()]
+	if o.merge != nil {
+		var err error
+		if edge, err = mergeEdge(prev, edge, o.merge); err != nil {
+			return false, err
+		}
 	}
 	if f.Edges == nil {
 		f.Edges = make(map[EdgeKey]Edge)
 	}
-	prev, ok := f.Edges[edge.Key()]
-	if ok {
-		if prev.EdgeType() != edge.EdgeType() {
-			log.Fatal().Msgf("cannot add edges of different types: prev=%#v, edge=%#v", prev, edge)
+	for _, key := range edge.Nodes() {
+		if _, ok := f.Nodes[key]; !ok {
+			f.AddNode(key, nil)
 		}
-		opt.MergeFunc(prev, edge)
 	}
 	f.Edges[edge.Key()] = edge
-	log.Trace().Bool("prev", ok).Msgf("added edge")
-	return ok
+	if f.adj != nil {
+		if ok {
+			f.adj.remove(prev, f.Edges)
+		}
+		f.adj.add(edge, f.Edges)
+	}
+	f.Logger().Trace().Stringer("edgeKey", edge.Key()).Bool("prev", ok).Msg("added edge")
+	return ok, nil
+}
+
+// checkEdge returns an error if the edge cannot be added to a graph, or if it
+// is invalid and validate is set.
+func checkEdge(edge Edge, validate bool) error {
+	if edge.EdgeType() == EdgeTypeBase {
+		return fmt.Errorf("%w: cannot add base edge %v", ErrUnsupportedEdge, edge)
+	}
+	if !validate {
+		return nil
+	}
+	if err := edge.Valid(); err != nil {
+		return fmt.Errorf("invalid edge %v: %w", edge, err)
+	}
+	return nil
+}
+
+// mergeEdge returns the edge that replaces prev when edge is added, which is
+// a copy of edge that prev is merged into with merge, if prev exists. Neither
+// edge is modified, as they may be shared with other graphs.
+func mergeEdge(prev, edge Edge, merge MergeFunc) (Edge, error) {
+	if prev == nil {
+		return edge, nil
+	}
+	if prev.EdgeType() != edge.EdgeType() {
+		return nil, fmt.Errorf("cannot add edges of different types: prev=%T, edge=%T", prev, edge)
+	}
+	merged := copyEdge(edge)
+	if err := merge(prev, merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
 }
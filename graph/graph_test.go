@@ -1,15 +1,34 @@
 package graph_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unsafe"
 
-	"github.com/google/go-cmp/cmp"
 	"github.com/arclabs561/pkgrank/graph"
 	"github.com/arclabs561/pkgrank/shared"
+	"github.com/google/go-cmp/cmp"
+	"github.com/rs/zerolog"
+	gonum "gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/network"
 )
 
 func TestGraphFactAdd(t *testing.T) {
-	shared.SetGlobalLogger()
+	if err := shared.SetGlobalLogger(); err != nil {
+		t.Fatal(err)
+	}
 	f := graph.Graph{}
 	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
 	f.AddEdge(graph.NewDirectedEdge("", "B", "C"))
@@ -24,6 +43,2625 @@ func TestGraphFactAdd(t *testing.T) {
 	assertEqual(t, f.Edges[graph.EdgeKeyFrom(":A->C")].Weight(), 1.0)
 }
 
+func TestGraphModuleVersions(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "A", "C"))
+	f.AddNode(graph.NodeKey{ID: "B"}, &graph.NodeData{Module: "example.com/m", Version: "v1.2.0"})
+	f.AddNode(graph.NodeKey{ID: "C"}, &graph.NodeData{Module: "example.com/m/v2", Version: "v2.0.1"})
+
+	assertEqual(t, f.Order(), 3)
+	assertEqual(t, f.ModuleVersions(), map[string][]string{
+		"example.com/m": {"example.com/m/v2@v2.0.1", "example.com/m@v1.2.0"},
+	})
+}
+
+func TestGraphJSONRoundTrip(t *testing.T) {
+	f := graph.Graph{Container: "A"}
+	f.AddEdge(graph.NewDirectedEdge("A", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("A", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("B", "B", "C"))
+	f.AddNode(graph.NodeKey{ID: "C"}, &graph.NodeData{Module: "example.com/m", Version: "v1.0.0"})
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g graph.Graph
+	if err := json.Unmarshal(b, &g); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, g.Container, "A")
+	assertEqual(t, g.Order(), 3)
+	assertEqual(t, g.Size(), 2)
+	assertEqual(t, g.Edges[graph.EdgeKeyFrom("A:A->B")].Weight(), 2.0)
+	assertEqual(t, g.Nodes[graph.NodeKey{ID: "C"}].Data.ModuleVersion(), "example.com/m@v1.0.0")
+}
+
+func TestGraphGobRoundTrip(t *testing.T) {
+	f := graph.Graph{Container: "A", AddedContainers: map[string]struct{}{"D": {}}}
+	edge := graph.NewDirectedEdge("A", "A", "B")
+	edge.Kind = graph.EdgeKindTest
+	edge.Symbols = []string{"F"}
+	edge.Provenance = []graph.Provenance{{File: "a/a.go", Line: 3}}
+	edge.Attrs.SetInt("n", 2)
+	f.AddEdge(edge)
+	f.AddEdge(graph.NewDirectedEdge("B", "B", "C"))
+	f.AddEdge(graph.NewUndirectedEdge("B", "C", "B"))
+	f.AddEdge(graph.NewHyperEdge("C", "C", "A", "B"))
+	data := &graph.NodeData{Module: "example.com/m", Version: "v1.0.0"}
+	data.Attrs.SetBool("ok", true)
+	f.AddNode(graph.NodeKey{ID: "C"}, data)
+
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(f); err != nil {
+		t.Fatal(err)
+	}
+	var g graph.Graph
+	if err := gob.NewDecoder(&b).Decode(&g); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, g.Container, "A")
+	assertEqual(t, g.AddedContainers, f.AddedContainers)
+	assertEqual(t, g.Nodes, f.Nodes)
+	assertEqual(t, len(g.Edges), len(f.Edges))
+	for key, want := range f.Edges {
+		got, ok := g.Edges[key]
+		if !ok {
+			t.Fatalf("missing edge %v", key)
+		}
+		assertEqual(t, fmt.Sprintf("%+v", got), fmt.Sprintf("%+v", want))
+	}
+
+	// Graphs encoded by another version fail to decode.
+	b.Reset()
+	if err := gob.NewEncoder(&b).Encode(struct{ Version int }{graph.GobVersion + 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.GobDecode(b.Bytes()); err == nil {
+		t.Error("GobDecode of another version succeeded")
+	}
+}
+
+func TestGraphProtoRoundTrip(t *testing.T) {
+	f := mustMerge(t, "root", syntheticGraphs(100, 3)...)
+	edge := graph.NewDirectedEdge("root", "a", "b")
+	edge.Kind = graph.EdgeKindTest
+	edge.Symbols = []string{"F", "T"}
+	edge.Configs = []string{"linux/amd64"}
+	edge.Provenance = []graph.Provenance{{File: "a/a.go", Line: 3, Style: graph.ImportStyleBlank}}
+	edge.Attrs.SetBool("ok", false)
+	f.AddEdge(edge)
+	data := &graph.NodeData{Module: "a", Version: "v1.0.0", Types: 2, Interfaces: 1}
+	data.Attrs.SetInt(graph.AttrLOC, -1)
+	data.Attrs.SetFloat(graph.AttrScorecard, 7.5)
+	data.Attrs.SetString(graph.AttrLicense, "")
+	f.AddNode(graph.NodeKey{ID: "a"}, data)
+
+	b, err := f.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g graph.Graph
+	if err := g.UnmarshalProto(b); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(f)
+	got, _ := json.Marshal(g)
+	assertEqual(t, string(got), string(want))
+	if len(b) >= len(want) {
+		t.Errorf("proto of %d bytes is no smaller than json of %d", len(b), len(want))
+	}
+	if err := g.UnmarshalProto(b[:len(b)-1]); err == nil {
+		t.Error("UnmarshalProto of a truncated message succeeded")
+	}
+}
+
+func TestGraphEdgeKindMerge(t *testing.T) {
+	f := graph.Graph{}
+	test := graph.NewDirectedEdge("", "A", "B")
+	test.Kind = graph.EdgeKindTest
+	f.AddEdge(test)
+	assertEqual(t, f.Edges[graph.EdgeKeyFrom(":A->B")].(*graph.DirectedEdge).Kind, graph.EdgeKindTest)
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	assertEqual(t, f.Edges[graph.EdgeKeyFrom(":A->B")].(*graph.DirectedEdge).Kind, graph.EdgeKind(0))
+
+	kind, err := graph.ParseEdgeKind("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, kind, graph.EdgeKindTest)
+	if _, err := graph.ParseEdgeKind("bogus"); err == nil {
+		t.Fatal("expected error for unknown edge kind")
+	}
+}
+
+func TestImportKind(t *testing.T) {
+	blank := graph.Provenance{File: "a.go", Line: 1, Style: graph.ImportStyleBlank}
+	dot := graph.Provenance{File: "b.go", Line: 1, Style: graph.ImportStyleDot}
+	named := graph.Provenance{File: "c.go", Line: 1, Style: graph.ImportStyleNamed}
+
+	assertEqual(t, graph.ImportKind(nil), graph.EdgeKind(0))
+	assertEqual(t, graph.ImportKind([]graph.Provenance{blank, blank}), graph.EdgeKindBlank)
+	assertEqual(t, graph.ImportKind([]graph.Provenance{dot}), graph.EdgeKindDot)
+	assertEqual(t, graph.ImportKind([]graph.Provenance{blank, dot}), graph.EdgeKind(0))
+	assertEqual(t, graph.ImportKind([]graph.Provenance{blank, named}), graph.EdgeKind(0))
+}
+
+func TestPackageKind(t *testing.T) {
+	gen := graph.Provenance{File: "m/gen.go", Line: 3}
+	hand := graph.Provenance{File: "m/m.go", Line: 3}
+	generated := map[string]bool{"m/gen.go": true}
+	assertEqual(t, graph.GeneratedKind([]graph.Provenance{gen}, generated), graph.EdgeKindGenerated)
+	assertEqual(t, graph.GeneratedKind([]graph.Provenance{gen, hand}, generated), graph.EdgeKind(0))
+	assertEqual(t, graph.GeneratedKind(nil, generated), graph.EdgeKind(0))
+
+	assertEqual(t, graph.PackageKind("net/http", ""), graph.EdgeKindStdlib)
+	assertEqual(t, graph.PackageKind("example.com/m", ""), graph.EdgeKind(0))
+	assertEqual(t, graph.PackageKind("example.com/m/vendor/example.com/v", ""), graph.EdgeKindVendored)
+	assertEqual(t, graph.PackageKind("example.com/v", "/src/m/vendor/example.com/v"), graph.EdgeKindVendored)
+	assertEqual(t, graph.InVendor("example.com/vendor"), false)
+
+	kind, err := graph.ParseEdgeKind("generated, vendored,stdlib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, kind.String(), "generated,vendored,stdlib")
+}
+
+func TestGraphExcludeKinds(t *testing.T) {
+	f := graph.Graph{Container: "A"}
+	f.AddEdge(graph.NewDirectedEdge("A", "A", "B"))
+	std := graph.NewDirectedEdge("A", "A", "fmt")
+	std.Kind = graph.EdgeKindStdlib
+	f.AddEdge(std)
+	test := graph.NewDirectedEdge("A", "B", "C")
+	test.Kind = graph.EdgeKindTest
+	f.AddEdge(test)
+	f.AddEdge(graph.NewDirectedEdge("A", "D", "C"))
+	f.AddNode(graph.NodeKey{ID: "E"}, nil)
+
+	g := f.ExcludeKinds(graph.EdgeKindTest | graph.EdgeKindStdlib)
+	assertEqual(t, g.Size(), 2)
+	// fmt was only imported by an excluded edge, while C is still imported
+	// by D and E never was.
+	assertEqual(t, g.Order(), 5)
+	if _, ok := g.Nodes[graph.NodeKey{ID: "fmt"}]; ok {
+		t.Error("fmt was kept")
+	}
+	assertEqual(t, f.Size(), 4)
+}
+
+func TestGraphPaths(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "D"))
+	f.AddEdge(graph.NewDirectedEdge("", "A", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "D"))
+	f.AddEdge(graph.NewDirectedEdge("", "D", "A"))
+
+	ids := func(paths []graph.Path) [][]string {
+		var out [][]string
+		for _, p := range paths {
+			var path []string
+			for _, n := range p.Nodes {
+				path = append(path, n.ID)
+			}
+			out = append(out, path)
+		}
+		return out
+	}
+	a, d := graph.NodeKey{ID: "A"}, graph.NodeKey{ID: "D"}
+	assertEqual(t, ids(f.Paths(a, d, 1)), [][]string{{"A", "B", "D"}})
+	assertEqual(t, ids(f.Paths(a, d, 2)), [][]string{{"A", "B", "D"}, {"A", "C", "D"}})
+	assertEqual(t, ids(f.Paths(a, d, 3)), [][]string{{"A", "B", "D"}, {"A", "C", "D"}, {"A", "C", "B", "D"}})
+	assertEqual(t, ids(f.Paths(a, d, 0)), [][]string{{"A", "B", "D"}, {"A", "C", "D"}, {"A", "C", "B", "D"}})
+	assertEqual(t, ids(f.Paths(d, graph.NodeKey{ID: "E"}, 0)), [][]string(nil))
+
+	// The heaviest of the shortest paths comes first, even if only one is
+	// asked for.
+	heavy := graph.NewDirectedEdge("", "C", "D")
+	heavy.EdgeWeight = 3
+	f.AddEdge(heavy, graph.WithOverwrite())
+	assertEqual(t, ids(f.Paths(a, d, 1)), [][]string{{"A", "C", "D"}})
+	assertEqual(t, ids(f.Paths(a, d, 2)), [][]string{{"A", "C", "D"}, {"A", "B", "D"}})
+	assertEqual(t, f.Paths(a, d, 1)[0].Weight, 4.0)
+}
+
+func TestGraphReachability(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "A", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "D", "C"))
+
+	a, c, d := graph.NodeKey{ID: "A"}, graph.NodeKey{ID: "C"}, graph.NodeKey{ID: "D"}
+	path, ok := f.ShortestPath(a, c)
+	assertEqual(t, ok, true)
+	assertEqual(t, path.Nodes, []graph.NodeKey{a, c})
+	assertEqual(t, f.Reachable(a, d), false)
+	assertEqual(t, f.Reachable(d, c), true)
+	assertEqual(t, f.Descendants(a), []graph.NodeKey{{ID: "B"}, c})
+	assertEqual(t, f.Ancestors(c), []graph.NodeKey{a, {ID: "B"}, d})
+}
+
+func TestGraphImpact(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "A", "D"))
+	f.AddEdge(graph.NewDirectedEdge("", "D", "E"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "E"))
+
+	a, b := graph.NodeKey{ID: "A"}, graph.NodeKey{ID: "B"}
+	impact := f.Impact(a, []graph.NodeKey{b}, nil)
+	assertEqual(t, impact.Before, 4)
+	assertEqual(t, impact.After, 2)
+	assertEqual(t, impact.Unreachable, []graph.NodeKey{b, {ID: "C"}})
+	assertEqual(t, len(impact.RankShifts), 3)
+
+	impact = f.Impact(a, nil, [][2]graph.NodeKey{{a, {ID: "D"}}})
+	assertEqual(t, impact.Unreachable, []graph.NodeKey{{ID: "D"}})
+	assertEqual(t, f.Size(), 5)
+}
+
+func TestGraphDominators(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "A", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "D"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "E"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "E"))
+
+	a, b := graph.NodeKey{ID: "A"}, graph.NodeKey{ID: "B"}
+	tree := f.Dominators(a)
+	idom, ok := tree.Idom(graph.NodeKey{ID: "E"})
+	assertEqual(t, ok, true)
+	assertEqual(t, idom, a)
+	assertEqual(t, tree.Children(a), []graph.NodeKey{b, {ID: "C"}, {ID: "E"}})
+	assertEqual(t, tree.Dominated(b), []graph.NodeKey{{ID: "D"}})
+	assertEqual(t, tree.Owned(a), 4)
+}
+
+func TestGraphCritical(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "A"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "D"))
+	f.AddEdge(graph.NewDirectedEdge("", "D", "E"))
+	f.AddEdge(graph.NewDirectedEdge("", "E", "D"))
+
+	c, d := graph.NodeKey{ID: "C"}, graph.NodeKey{ID: "D"}
+	assertEqual(t, f.ArticulationPoints(), []graph.NodeKey{c, d})
+	assertEqual(t, f.Bridges(), [][2]graph.NodeKey{{c, d}})
+}
+
+func TestGraphCoreness(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "A"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "D"))
+	f.AddEdge(graph.NewDirectedEdge("", "D", "E"))
+
+	assertEqual(t, f.Coreness(), map[graph.NodeKey]int{
+		{ID: "A"}: 2, {ID: "B"}: 2, {ID: "C"}: 2, {ID: "D"}: 1, {ID: "E"}: 1,
+	})
+
+	ig := graph.NewImportGraph()
+	for _, e := range [][2]string{{"A", "B"}, {"B", "C"}, {"C", "A"}, {"C", "D"}} {
+		ig.UpdateEdge(e[0], e[1])
+	}
+	imps, scores, err := ig.CentralityBy(graph.CorenessCentrality)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, imps, []string{"A", "B", "C", "D"})
+	assertEqual(t, scores, []float64{2, 2, 2, 1})
+}
+
+func TestGraphStats(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "D"))
+
+	s := f.Stats()
+	assertEqual(t, s.Order, 4)
+	assertEqual(t, s.Size, 4)
+	assertEqual(t, s.MaxInDegree, 2)
+	assertEqual(t, s.MaxOutDegree, 2)
+	assertEqual(t, s.OutDegrees, map[int]int{0: 1, 1: 2, 2: 1})
+	assertEqual(t, s.Diameter, 3)
+	assertEqual(t, s.SCCs, 3)
+	assertEqual(t, s.Cycles, 1)
+	assertEqual(t, s.LongestChain, 2)
+}
+
+func TestGraphPackageMetrics(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "D"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "E"))
+	f.AddNode(graph.NodeKey{ID: "C"}, &graph.NodeData{Types: 4, Interfaces: 1})
+
+	m := f.PackageMetrics()[graph.NodeKey{ID: "C"}]
+	assertEqual(t, m, graph.PackageMetrics{
+		Afferent:     2,
+		Efferent:     2,
+		Instability:  0.5,
+		Abstractness: 0.25,
+		Distance:     0.25,
+	})
+}
+
+func TestGraphGodPackages(t *testing.T) {
+	f := graph.Graph{}
+	for _, src := range []string{"A", "B", "C"} {
+		f.AddEdge(graph.NewDirectedEdge("", src, "G"))
+	}
+	for _, dst := range []string{"X", "Y"} {
+		f.AddEdge(graph.NewDirectedEdge("", "G", dst))
+	}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "X"))
+
+	fans := f.Fans()
+	assertEqual(t, fans[0], graph.Fan{Node: graph.NodeKey{ID: "G"}, In: 3, Out: 2})
+	assertEqual(t, graph.GodPackages(fans, 2, 2), []graph.Fan{fans[0]})
+
+	minIn, minOut := graph.OutlierThresholds(fans, 1)
+	assertEqual(t, graph.GodPackages(fans, minIn, minOut), []graph.Fan{fans[0]})
+}
+
+func TestGraphUnused(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "m/cmd", "m/a"))
+	f.AddEdge(graph.NewDirectedEdge("", "m/a", "std"))
+	f.AddEdge(graph.NewDirectedEdge("", "m/b", "m/c"))
+	for _, id := range []string{"m/cmd", "m/a", "m/b", "m/c"} {
+		f.AddNode(graph.NodeKey{ID: id}, &graph.NodeData{Module: "m"})
+	}
+	root := graph.NodeKey{ID: "m/cmd"}
+
+	assertEqual(t, f.Unused("m", root), []graph.NodeKey{{ID: "m/b"}})
+	assertEqual(t, f.Dead("m", root), []graph.NodeKey{{ID: "m/b"}, {ID: "m/c"}})
+}
+
+func TestGraphViolations(t *testing.T) {
+	policy := graph.Policy{Layers: []graph.Layer{
+		{Name: "cmd", Packages: []string{"m/cmd/..."}, Allow: []string{"core"}},
+		{Name: "core", Packages: []string{"m/graph", "m/analyzers/*"}},
+	}}
+	assertEqual(t, policy.Valid(), nil)
+
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "m/cmd/x", "m/graph"))
+	f.AddEdge(graph.NewDirectedEdge("", "m/analyzers/a", "m/graph"))
+	f.AddEdge(graph.NewDirectedEdge("", "m/analyzers/a", "m/cmd"))
+	f.AddEdge(graph.NewDirectedEdge("", "m/graph", "std"))
+	assertEqual(t, f.Violations(policy), []graph.Violation{{
+		Src:  graph.NodeKey{ID: "m/analyzers/a"},
+		Dst:  graph.NodeKey{ID: "m/cmd"},
+		From: "core",
+		To:   "cmd",
+	}})
+}
+
+func TestWriteSARIF(t *testing.T) {
+	f := graph.Graph{}
+	edge := graph.NewDirectedEdge("", "m/a", "m/b")
+	edge.Provenance = []graph.Provenance{{File: "m/a/a.go", Line: 3}}
+	f.AddEdge(edge)
+	f.AddEdge(graph.NewDirectedEdge("", "m/b", "m/a"))
+	findings := f.CycleFindings()
+	assertEqual(t, len(findings), 1)
+	assertEqual(t, findings[0].Nodes, []graph.NodeKey{{ID: "m/a"}, {ID: "m/b"}})
+
+	var buf bytes.Buffer
+	if err := graph.WriteSARIF(&buf, findings, "m"); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID    string
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine int }
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, log.Version, graph.SARIFVersion)
+	result := log.Runs[0].Results[0]
+	assertEqual(t, result.RuleID, graph.RuleCycle)
+	assertEqual(t, result.Locations[0].PhysicalLocation.ArtifactLocation.URI, "a/a.go")
+	assertEqual(t, result.Locations[0].PhysicalLocation.Region.StartLine, 3)
+}
+
+func TestBaselineRegressions(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	baseline := f.Baseline()
+
+	f.AddEdge(graph.NewDirectedEdge("", "B", "A"))
+	findings, err := baseline.Regressions(f.Baseline(), map[string]float64{"size": 0, "order": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := make([]string, len(findings))
+	for i, finding := range findings {
+		rules[i] = finding.Rule
+	}
+	assertEqual(t, rules, []string{graph.RuleCycle, graph.RuleDependency, graph.RuleRegression})
+
+	_, err = baseline.Regressions(baseline, map[string]float64{"bogus": 0})
+	assertEqual(t, err != nil, true)
+}
+
+func TestWriteDOT(t *testing.T) {
+	f := graph.Graph{Container: "A"}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	edge := graph.NewDirectedEdge("", "A", "C")
+	edge.Kind = graph.EdgeKindTest
+	f.AddEdge(edge)
+
+	var buf bytes.Buffer
+	if err := graph.Write(&buf, &f, graph.FormatDOT); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{`digraph "A" {`, `"A" -> "B" [style=solid];`, `"A" -> "C" [style=dashed];`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	f := graph.Graph{Container: "<A>"}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+
+	var buf bytes.Buffer
+	if err := graph.Write(&buf, &f, graph.FormatHTML); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{`<title>&lt;A&gt;</title>`, `"edges":[{"src":"A","dst":"B"}]`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in html", want)
+		}
+	}
+}
+
+func TestGraphDiff(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "C"))
+	g := graph.Graph{}
+	g.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	g.AddEdge(graph.NewDirectedEdge("", "A", "D"))
+
+	assertEqual(t, f.Diff(f).Empty(), true)
+	assertEqual(t, f.Diff(g), graph.Diff{
+		AddedNodes:   []graph.NodeKey{{ID: "D"}},
+		RemovedNodes: []graph.NodeKey{{ID: "C"}},
+		AddedEdges:   [][2]graph.NodeKey{{{ID: "A"}, {ID: "D"}}},
+		RemovedEdges: [][2]graph.NodeKey{{{ID: "B"}, {ID: "C"}}},
+	})
+}
+
+func TestMergeParallel(t *testing.T) {
+	graphs := syntheticGraphs(200, 5)
+	graphs = append(graphs, graphs[0]) // already added containers are skipped
+	want, err := json.Marshal(mustMerge(t, "root", graphs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := graph.MergeParallel("root", 4, graphs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(got), string(want))
+}
+
+func TestMergeErrors(t *testing.T) {
+	key := graph.EdgeKeyFrom("a:a->b")
+	f := &graph.Graph{Container: "a"}
+	f.AddEdge(graph.NewDirectedEdge("a", "a", "b"))
+	g := &graph.Graph{
+		Container: "b",
+		Edges:     map[graph.EdgeKey]graph.Edge{key: &graph.BaseEdge{EdgeKey: key}},
+	}
+	if _, err := graph.Merge("root", f, g); err == nil {
+		t.Error("merged base edge")
+	}
+	if _, err := graph.MergeParallel("root", 2, f, g); err == nil {
+		t.Error("merged base edge in parallel")
+	}
+
+	var h graph.Graph
+	if _, err := h.AddEdge(&graph.DirectedEdge{}); err == nil {
+		t.Error("added invalid edge")
+	}
+	assertEqual(t, h.Size(), 0)
+	if _, err := graph.ParseEdgeKey("a->b"); err == nil {
+		t.Error("parsed edge key without container")
+	}
+}
+
+func TestMergeUndirectedAndHyperEdges(t *testing.T) {
+	var f graph.Graph
+	f.AddEdge(graph.NewUndirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewUndirectedEdge("", "B", "A"))
+	f.AddEdge(graph.NewHyperEdge("", "A", "B", "C"))
+	f.AddEdge(graph.NewHyperEdge("", "C", "B", "A"))
+	f.AddEdge(graph.NewHyperEdge("", "C", "D"))
+
+	assertEqual(t, f.Order(), 4)
+	assertEqual(t, f.Size(), 3)
+	assertEqual(t, f.Edges[graph.EdgeKeyFrom(":A~B")].Weight(), 2.0)
+	assertEqual(t, f.Edges[graph.EdgeKeyFrom(":A,B,C")].Weight(), 2.0)
+	assertEqual(t, f.Edges[graph.EdgeKeyFrom(":C,D")].Weight(), 1.0)
+	assertEqual(t, f.String(), "\n      :A,B,C: 2\n      :A~B: 2\n      :C,D: 1\n")
+}
+
+func TestAddEdgeOptions(t *testing.T) {
+	var f graph.Graph
+	keepMax := graph.WithMergeFunc(func(prev, edge graph.Edge) error {
+		edge.(*graph.DirectedEdge).EdgeWeight = math.Max(prev.Weight(), edge.Weight())
+		return nil
+	})
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"), keepMax)
+	assertEqual(t, f.Edges[graph.EdgeKeyFrom(":A->B")].Weight(), 1.0)
+
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	assertEqual(t, f.Edges[graph.EdgeKeyFrom(":A->B")].Weight(), 2.0)
+
+	edge := graph.NewDirectedEdge("", "A", "B")
+	edge.EdgeWeight = 5
+	ok, err := f.AddEdge(edge, keepMax, graph.WithOverwrite())
+	assertEqual(t, ok, true)
+	assertEqual(t, err, nil)
+	assertEqual(t, f.Edges[graph.EdgeKeyFrom(":A->B")].Weight(), 5.0)
+
+	invalid := graph.NewDirectedEdge("", "A", "")
+	if _, err := f.AddEdge(invalid); err == nil {
+		t.Error("added invalid edge")
+	}
+	if _, err := f.AddEdge(invalid, graph.WithValidation(false)); err != nil {
+		t.Error(err)
+	}
+	assertEqual(t, f.Size(), 2)
+}
+
+func TestAttrs(t *testing.T) {
+	var a graph.Attrs
+	a.SetInt(graph.AttrLOC, 120)
+	a.SetString(graph.AttrLicense, "MIT")
+	a.SetFloat("coverage", 0.5)
+	a.SetBool("generated", false)
+	assertEqual(t, a.Format(" "), "coverage=0.5 generated=false license=MIT loc=120")
+
+	f := graph.Graph{Container: "A"}
+	edge := graph.NewDirectedEdge("A", "A", "B")
+	edge.Attrs.SetString("reason", "logging")
+	f.AddEdge(edge)
+	edge = graph.NewDirectedEdge("A", "A", "B")
+	edge.Attrs.SetBool("generated", true)
+	f.AddEdge(edge)
+	f.AddNode(graph.NodeKey{ID: "A"}, &graph.NodeData{Attrs: a})
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g graph.Graph
+	if err := json.Unmarshal(b, &g); err != nil {
+		t.Fatal(err)
+	}
+	attrs := g.Nodes[graph.NodeKey{ID: "A"}].Data.Attrs
+	loc, ok := attrs.Int(graph.AttrLOC)
+	assertEqual(t, loc, int64(120))
+	assertEqual(t, ok, true)
+	coverage, _ := attrs.Float("coverage")
+	assertEqual(t, coverage, 0.5)
+	license, _ := attrs.String(graph.AttrLicense)
+	assertEqual(t, license, "MIT")
+	_, ok = attrs.String(graph.AttrLOC)
+	assertEqual(t, ok, false)
+	merged := g.Edges[graph.EdgeKeyFrom("A:A->B")].(*graph.DirectedEdge).Attrs
+	assertEqual(t, merged.Format(" "), "generated=true reason=logging")
+}
+
+type call struct {
+	sites int
+}
+
+func TestTypedGraph(t *testing.T) {
+	g := graph.NewTypedGraph[string, call]("calls", func(prev, next call) call {
+		return call{sites: prev.sites + next.sites}
+	})
+	a, b, c := graph.NodeKey{ID: "a.F"}, graph.NodeKey{ID: "b.G"}, graph.NodeKey{ID: "c.H"}
+	g.AddNode(a, "func()")
+	g.AddEdge(a, b, call{sites: 1})
+	g.AddEdge(a, b, call{sites: 2})
+	g.AddEdge(a, c, call{sites: 1})
+	g.AddEdge(c, b, call{sites: 1})
+
+	assertEqual(t, g.Order(), 3)
+	assertEqual(t, g.Size(), 3)
+	sig, _ := g.Node(a)
+	assertEqual(t, sig, "func()")
+	ab, _ := g.Edge(a, b)
+	assertEqual(t, ab.sites, 3)
+	assertEqual(t, g.Successors(a), []graph.NodeKey{b, c})
+	assertEqual(t, g.Predecessors(b), []graph.NodeKey{a, c})
+
+	f := g.Graph(nil, func(e call) float64 { return float64(e.sites) })
+	assertEqual(t, f.Size(), 3)
+	assertEqual(t, f.Edges[graph.EdgeKeyFrom("calls:a.F->b.G")].Weight(), 3.0)
+
+	p, err := graph.NewPackageGraph(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, p.Size(), 3)
+	edge, _ := p.Edge(a, b)
+	assertEqual(t, edge.Weight(), 3.0)
+}
+
+func TestRemoveAndSubgraph(t *testing.T) {
+	var f graph.Graph
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "C", "D"))
+	f.AddEdge(graph.NewDirectedEdge("", "E", "B"))
+
+	n := f.Neighborhood(graph.NodeKey{ID: "B"}, 1)
+	assertEqual(t, n.Order(), 4)
+	assertEqual(t, n.Size(), 3)
+	assertEqual(t, f.Neighborhood(graph.NodeKey{ID: "A"}, 0).Order(), 1)
+	assertEqual(t, f.Neighborhood(graph.NodeKey{ID: "Z"}, 3).Order(), 0)
+	d := f.WithinDepth(graph.NodeKey{ID: "A"}, 2)
+	assertEqual(t, d.SortedNodes(), []graph.NodeKey{{ID: "A"}, {ID: "B"}, {ID: "C"}})
+	assertEqual(t, d.Size(), 2)
+	assertEqual(t, f.WithinDepth(graph.NodeKey{ID: "E"}, 5).Order(), 4)
+
+	s := f.Subgraph(func(key graph.NodeKey) bool { return key.ID != "C" })
+	assertEqual(t, s.Order(), 4)
+	assertEqual(t, s.Size(), 2)
+	assertEqual(t, f.Size(), 4)
+
+	assertEqual(t, f.RemoveEdge(graph.EdgeKeyFrom(":A->B")), true)
+	assertEqual(t, f.RemoveEdge(graph.EdgeKeyFrom(":A->B")), false)
+	assertEqual(t, f.Order(), 5)
+	assertEqual(t, f.RemoveNode(graph.NodeKey{ID: "B"}), true)
+	assertEqual(t, f.RemoveNode(graph.NodeKey{ID: "B"}), false)
+	assertEqual(t, f.Order(), 4)
+	assertEqual(t, f.Size(), 1)
+}
+
+func TestAdjacency(t *testing.T) {
+	a, b, c := graph.NodeKey{ID: "A"}, graph.NodeKey{ID: "B"}, graph.NodeKey{ID: "C"}
+	var f graph.Graph
+	f.AddEdge(graph.NewDirectedEdge("x", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("y", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("x", "A", "C"))
+	assertEqual(t, f.Successors(a), []graph.NodeKey{b, c})
+	assertEqual(t, f.Predecessors(b), []graph.NodeKey{a})
+	in, out := f.Degree(a)
+	assertEqual(t, [2]int{in, out}, [2]int{0, 2})
+
+	f.AddEdge(graph.NewDirectedEdge("x", "C", "B"))
+	assertEqual(t, f.Predecessors(b), []graph.NodeKey{a, c})
+	f.RemoveEdge(graph.EdgeKeyFrom("x:A->B"))
+	assertEqual(t, f.Successors(a), []graph.NodeKey{b, c})
+	f.RemoveEdge(graph.EdgeKeyFrom("y:A->B"))
+	assertEqual(t, f.Successors(a), []graph.NodeKey{c})
+
+	// Replacing an edge reweighs the index of path queries.
+	edge := graph.NewDirectedEdge("x", "B", "A")
+	f.AddEdge(edge)
+	edge = graph.NewDirectedEdge("x", "B", "A")
+	edge.EdgeWeight = 3
+	f.AddEdge(edge, graph.WithOverwrite())
+	assertEqual(t, f.Successors(b), []graph.NodeKey{a})
+	assertEqual(t, f.Paths(b, a, 1)[0].Weight, 3.0)
+
+	f.RemoveNode(c)
+	assertEqual(t, f.Successors(a), []graph.NodeKey{})
+	assertEqual(t, f.Size(), 1)
+}
+
+func TestAdjacencyConcurrent(t *testing.T) {
+	// Graphs built without AddEdge have no index until their first query,
+	// which concurrent queries may all make.
+	f := &graph.Graph{Edges: make(map[graph.EdgeKey]graph.Edge)}
+	for i := 0; i < 100; i++ {
+		edge := graph.NewDirectedEdge("x", "A", fmt.Sprint(i))
+		f.AddNode(edge.Src, nil)
+		f.AddNode(edge.Dst, nil)
+		f.Edges[edge.Key()] = edge
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, out := f.Degree(graph.NodeKey{ID: "A"}); out != 100 {
+				t.Errorf("got out degree %d, want 100", out)
+			}
+			if n := len(f.Descendants(graph.NodeKey{ID: "A"})); n != 100 {
+				t.Errorf("got %d descendants, want 100", n)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCloneAndAliasing(t *testing.T) {
+	ab := graph.EdgeKeyFrom(":A->B")
+	weight := func(g *graph.Graph) float64 { return g.Edges[ab].Weight() }
+	f := &graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	g := &graph.Graph{}
+	edge := graph.NewDirectedEdge("", "A", "B")
+	edge.Symbols = []string{"X"}
+	g.AddEdge(edge)
+
+	// Merging into f leaves g and the added edge as they were.
+	if _, err := f.Add(*g); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, weight(f), 2.0)
+	assertEqual(t, weight(g), 1.0)
+	assertEqual(t, edge.Weight(), 1.0)
+	merged := mustMerge(t, "root", f, g)
+	assertEqual(t, weight(merged), 3.0)
+	assertEqual(t, weight(f), 2.0)
+	assertEqual(t, weight(g), 1.0)
+	parallel, err := graph.MergeParallel("root", 2, f, g, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, weight(parallel), 5.0)
+	assertEqual(t, weight(f), 2.0)
+
+	// A clone can be modified in place.
+	f.AddNode(graph.NodeKey{ID: "A"}, &graph.NodeData{Module: "m"})
+	f.Nodes[graph.NodeKey{ID: "A"}].Data.Attrs.SetInt(graph.AttrLOC, 1)
+	c := f.Clone()
+	assertEqual(t, c.String(), f.String())
+	e := c.Edges[ab].(*graph.DirectedEdge)
+	e.EdgeWeight = 10
+	e.Symbols[0] = "Y"
+	c.Nodes[graph.NodeKey{ID: "A"}].Data.Attrs.SetInt(graph.AttrLOC, 2)
+	assertEqual(t, weight(f), 2.0)
+	assertEqual(t, f.Edges[ab].(*graph.DirectedEdge).Symbols, []string{"X"})
+	loc, _ := f.Nodes[graph.NodeKey{ID: "A"}].Data.Attrs.Int(graph.AttrLOC)
+	assertEqual(t, loc, int64(1))
+}
+
+func TestConvert(t *testing.T) {
+	f := &graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("x", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("y", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("x", "B", "C"))
+	f.AddNode(graph.NodeKey{ID: "D"}, nil)
+	ig := f.ToImportGraph()
+	assertEqual(t, ig.Imports(), []string{"A", "B", "C"})
+
+	g := ig.ToGonum()
+	a, _ := ig.ID("A")
+	b, _ := ig.ID("B")
+	w, _ := g.Weight(a, b)
+	assertEqual(t, w, 2.0)
+	imp, ok := ig.Import(b)
+	assertEqual(t, imp, "B")
+	assertEqual(t, ok, true)
+
+	back := graph.FromGonum(g, func(n gonum.Node) string {
+		imp, _ := ig.Import(n.ID())
+		return imp
+	})
+	want, _ := ig.Centrality()
+	got, _ := back.Centrality()
+	assertEqual(t, got, want)
+
+	h := back.ToGraph("root")
+	assertEqual(t, h.Order(), 3)
+	assertEqual(t, h.Size(), 2)
+	assertEqual(t, h.Edges[graph.EdgeKeyFrom("root:A->B")].Weight(), 2.0)
+	assertEqual(t, graph.FromGonum(g, nil).Imports()[0], "0")
+}
+
+func TestEdgeKey(t *testing.T) {
+	key := graph.NewEdgeKey("x", "A->B")
+	if key != graph.NewDirectedEdge("x", "A", "B").Key() {
+		t.Errorf("got %v, want key of directed edge", key)
+	}
+	assertEqual(t, key.Container(), "x")
+	assertEqual(t, key.ID(), "A->B")
+	parsed, err := graph.ParseEdgeKey(key.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != key {
+		t.Errorf("got %v, want %v", parsed, key)
+	}
+	if _, err := graph.ParseEdgeKey("A->B"); err == nil {
+		t.Error("expected error for key without container")
+	}
+}
+
+func TestCentrality(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("", "B", "C"))
+	f.AddEdge(graph.NewDirectedEdge("", "A", "C"))
+
+	ranks, err := graph.Centrality(f, graph.PageRankCentrality)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imps, scores := f.ToImportGraph().Centrality()
+	assertEqual(t, len(ranks), len(imps))
+	for i, r := range ranks {
+		assertEqual(t, r, graph.RankResult{Node: graph.NodeKey{ID: imps[i]}, Score: scores[i]})
+	}
+	assertEqual(t, ranks[0].Node, graph.NodeKey{ID: "C"})
+
+	top, err := graph.Centrality(f, graph.CorenessCentrality, graph.WithTop(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, top, []graph.RankResult{{Node: graph.NodeKey{ID: "A"}, Score: 2}})
+
+	undamped, err := graph.Centrality(f, graph.PageRankCentrality, graph.WithDamping(0.5), graph.WithTolerance(1e-8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undamped[0].Score >= ranks[0].Score {
+		t.Errorf("got score %g with less damping, want less than %g", undamped[0].Score, ranks[0].Score)
+	}
+	if _, err := graph.Centrality(f, graph.PageRankCentrality, graph.WithDamping(1)); err == nil {
+		t.Error("expected error for damping factor of 1")
+	}
+	if _, err := graph.Centrality(f, "bogus"); err == nil {
+		t.Error("expected error for unknown measure")
+	}
+}
+
+func TestModuleCentrality(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "a/x", "b/x"))
+	f.AddEdge(graph.NewDirectedEdge("", "a/y", "b/x"))
+	f.AddEdge(graph.NewDirectedEdge("", "b/x", "b/y"))
+	for _, id := range []string{"a/x", "a/y"} {
+		f.AddNode(graph.NodeKey{ID: id}, &graph.NodeData{Module: "a"})
+	}
+	for _, id := range []string{"b/x", "b/y"} {
+		f.AddNode(graph.NodeKey{ID: id}, &graph.NodeData{Module: "b"})
+	}
+	edges := f.ModuleHyperEdges()
+	assertEqual(t, len(edges), 2)
+	assertEqual(t, edges[0].Key().Container(), "a")
+	assertEqual(t, edges[0].Nodes(), []graph.NodeKey{{ID: "a/x"}, {ID: "a/y"}})
+
+	want, err := graph.ModuleCentrality(f, graph.PageRankCentrality)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := f.AddModuleHyperEdges(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertEqual(t, f.Size(), 5)
+	got, err := graph.ModuleCentrality(f, graph.PageRankCentrality)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, got, want)
+
+	ranks, err := graph.Centrality(f, graph.PageRankCentrality)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := 0.0
+	for _, r := range ranks {
+		sum += r.Score
+	}
+	assertEqual(t, len(got), 2)
+	assertEqual(t, got[0].Module, "b")
+	assertEqual(t, got[0].Packages[0].Node, graph.NodeKey{ID: "b/y"})
+	if math.Abs(got[0].Score+got[1].Score-sum) > 1e-9 {
+		t.Errorf("got module scores summing to %g, want %g", got[0].Score+got[1].Score, sum)
+	}
+	top, err := graph.ModuleCentrality(f, graph.PageRankCentrality, graph.WithTop(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, top, got[:1])
+}
+
+func TestCollapseBy(t *testing.T) {
+	f := graph.Graph{Container: "root"}
+	f.AddEdge(graph.NewDirectedEdge("x", "a/x", "b/x"))
+	f.AddEdge(graph.NewDirectedEdge("y", "a/y", "b/x"))
+	f.AddEdge(graph.NewDirectedEdge("x", "a/y", "b/y"))
+	f.AddEdge(graph.NewDirectedEdge("x", "b/x", "b/y"))
+	f.AddEdge(graph.NewDirectedEdge("x", "b/y", "c"))
+	for _, id := range []string{"a/x", "a/y"} {
+		f.AddNode(graph.NodeKey{ID: id}, &graph.NodeData{Module: "a"})
+	}
+	f.AddNode(graph.NodeKey{ID: "b/x"}, &graph.NodeData{Module: "b"})
+
+	g := f.CollapseBy(graph.ByPathPrefix(1))
+	assertEqual(t, g.SortedNodes(), []graph.NodeKey{{ID: "a"}, {ID: "b"}, {ID: "c"}})
+	assertEqual(t, g.Size(), 2)
+	assertEqual(t, g.Edges[graph.NewEdgeKey("root", "a->b")].Weight(), 3.0)
+	assertEqual(t, g.Edges[graph.NewEdgeKey("root", "b->c")].Weight(), 1.0)
+	assertEqual(t, g.Nodes[graph.NodeKey{ID: "a"}].Data.Module, "a")
+	// b/y is of an unknown module.
+	assertEqual(t, g.Nodes[graph.NodeKey{ID: "b"}].Data, (*graph.NodeData)(nil))
+
+	m := f.CollapseBy(f.ByModule())
+	assertEqual(t, m.SortedNodes(), []graph.NodeKey{{ID: "a"}, {ID: "b"}})
+	assertEqual(t, m.Size(), 1)
+	assertEqual(t, m.Edges[graph.NewEdgeKey("root", "a->b")].Weight(), 2.0)
+	assertEqual(t, f.Size(), 5)
+}
+
+func TestPrune(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("x", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("y", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("z", "A", "B"))
+	f.AddEdge(graph.NewDirectedEdge("x", "B", "C"))
+	f.AddEdge(graph.NewDirectedEdge("y", "B", "C"))
+	f.AddEdge(graph.NewDirectedEdge("x", "C", "D"))
+	f.AddEdge(graph.NewDirectedEdge("x", "D", "E"))
+	f.AddNode(graph.NodeKey{ID: "F"}, nil)
+
+	g := f.Prune(2, 100)
+	assertEqual(t, g.SortedNodes(), []graph.NodeKey{{ID: "A"}, {ID: "B"}, {ID: "C"}})
+	assertEqual(t, g.Size(), 5)
+	g = f.Prune(0, 25)
+	assertEqual(t, g.SortedNodes(), []graph.NodeKey{{ID: "A"}, {ID: "B"}})
+	assertEqual(t, g.Size(), 3)
+	g = f.Prune(0, 100)
+	assertEqual(t, g.Order(), 5)
+	assertEqual(t, g.Size(), f.Size())
+	assertEqual(t, f.Prune(10, 100).Order(), 0)
+}
+
+func TestVulns(t *testing.T) {
+	const out = `{"config": {"scan_level": "symbol"}}
+{"osv": {"id": "GO-1", "aliases": ["CVE-1"], "summary": "Bad B", "affected": [{"package": {"name": "m/b"}, "ecosystem_specific": {"imports": [{"path": "m/b"}]}}]}}
+{"osv": {"id": "GO-2", "summary": "Bad C", "affected": [{"package": {"name": "m/c"}, "ecosystem_specific": {"imports": [{"path": "m/c"}, {"path": "m/c/d"}]}}]}}
+{"finding": {"osv": "GO-1", "fixed_version": "v1.0.1", "trace": [{"module": "m/b", "version": "v1.0.0", "package": "m/b"}]}}
+{"finding": {"osv": "GO-1", "fixed_version": "v1.0.1", "trace": [{"module": "m/b", "version": "v1.0.0", "package": "m/b", "function": "F"}, {"module": "a", "package": "a", "function": "main"}]}}
+{"finding": {"osv": "GO-2", "trace": [{"module": "m/c", "version": "v0.1.0"}]}}
+`
+	vulns, err := graph.ParseGovulncheck(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, vulns, []graph.Vuln{
+		{ID: "GO-1", Aliases: []string{"CVE-1"}, Summary: "Bad B", Package: "m/b", Module: "m/b", Version: "v1.0.0", FixedVersion: "v1.0.1", Called: true},
+		{ID: "GO-2", Summary: "Bad C", Package: "m/c", Module: "m/c", Version: "v0.1.0"},
+		{ID: "GO-2", Summary: "Bad C", Package: "m/c/d", Module: "m/c", Version: "v0.1.0"},
+	})
+
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("", "a", "x"))
+	f.AddEdge(graph.NewDirectedEdge("", "x", "m/b"))
+	f.AddEdge(graph.NewDirectedEdge("", "a", "m/c"))
+	f.AddEdge(graph.NewDirectedEdge("", "m/c/d", "m/c"))
+	ranks, err := f.RankVulns(graph.NodeKey{ID: "a"}, vulns, graph.PageRankCentrality)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range ranks {
+		got = append(got, fmt.Sprintf("%s %s %v %d", r.ID, r.Package, r.Reachable, r.Depth))
+	}
+	assertEqual(t, got, []string{"GO-1 m/b true 2", "GO-2 m/c true 1", "GO-2 m/c/d false 0"})
+
+	f.AddVulns(vulns)
+	ids, _ := f.Nodes[graph.NodeKey{ID: "m/c/d"}].Data.Attrs.String(graph.AttrVulns)
+	assertEqual(t, ids, "GO-2")
+	if _, err := graph.ParseGovulncheck(strings.NewReader("{")); err == nil {
+		t.Error("expected error for truncated output")
+	}
+}
+
+func TestSetNodeAttrs(t *testing.T) {
+	f := graph.Graph{}
+	f.AddNode(graph.NodeKey{ID: "a"}, &graph.NodeData{Module: "m", Attrs: graph.Attrs{"loc": int64(1)}})
+	g := f.Clone()
+	ok := g.SetNodeAttrs(graph.NodeKey{ID: "a"}, func(a *graph.Attrs) {
+		a.SetString(graph.AttrLatest, "v1.0.0")
+	})
+	assertEqual(t, ok, true)
+	assertEqual(t, g.Nodes[graph.NodeKey{ID: "a"}].Data, &graph.NodeData{
+		Module: "m",
+		Attrs:  graph.Attrs{"loc": int64(1), graph.AttrLatest: "v1.0.0"},
+	})
+	// The original graph is unchanged.
+	assertEqual(t, f.Nodes[graph.NodeKey{ID: "a"}].Data.Attrs, graph.Attrs{"loc": int64(1)})
+	assertEqual(t, g.SetNodeAttrs(graph.NodeKey{ID: "b"}, func(*graph.Attrs) {}), false)
+}
+
+func TestLicenses(t *testing.T) {
+	assertEqual(t, graph.IdentifyLicense("MIT License\n\nPermission is hereby granted, free of charge, ..."), "MIT")
+	assertEqual(t, graph.IdentifyLicense("Apache License\n  Version 2.0, January 2004"), "Apache-2.0")
+	assertEqual(t, graph.IdentifyLicense("All rights reserved."), graph.LicenseUnknown)
+
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"LICENSE-MIT":    "Permission is hereby granted, free of charge",
+		"LICENSE-APACHE": "Apache License, Version 2.0",
+		"license.go":     "package license",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	license, err := graph.DetectLicense(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, license, "Apache-2.0 OR MIT")
+
+	policy := graph.LicensePolicy{Allow: []string{"MIT", "BSD-3-Clause"}, Deny: []string{"BSD-3-Clause"}}
+	assertEqual(t, policy.Allowed("Apache-2.0 OR MIT"), true)
+	assertEqual(t, policy.Allowed("(Apache-2.0 AND MIT)"), false)
+	assertEqual(t, policy.Allowed("BSD-3-Clause"), false)
+	assertEqual(t, graph.LicensePolicy{}.Allowed(graph.LicenseUnknown), true)
+
+	f := graph.Graph{}
+	f.AddNode(graph.NodeKey{ID: "a"}, &graph.NodeData{Module: "a"})
+	f.AddNode(graph.NodeKey{ID: "m/x"}, &graph.NodeData{Module: "m", Version: "v1.0.0", Attrs: graph.Attrs{graph.AttrLicense: "GPL-3.0"}})
+	f.AddNode(graph.NodeKey{ID: "m/y"}, &graph.NodeData{Module: "m", Version: "v1.0.0", Attrs: graph.Attrs{graph.AttrLicense: "GPL-3.0"}})
+	f.AddNode(graph.NodeKey{ID: "n"}, &graph.NodeData{Module: "n", Version: "v0.1.0", Attrs: graph.Attrs{graph.AttrLicense: "MIT"}})
+	f.AddEdge(graph.NewDirectedEdge("", "a", "n"))
+	f.AddEdge(graph.NewDirectedEdge("", "n", "m/x"))
+	f.AddEdge(graph.NewDirectedEdge("", "a", "m/y"))
+	licenses := f.Licenses(graph.NodeKey{ID: "a"})
+	var got []string
+	for _, l := range licenses {
+		got = append(got, fmt.Sprintf("%s %s %v", l.License, l.Module, l.Path.Nodes))
+	}
+	assertEqual(t, got, []string{"GPL-3.0 m [a m/y]", "MIT n [a n]", "unknown a [a]"})
+
+	findings := graph.LicenseFindings(licenses, graph.LicensePolicy{Deny: []string{"GPL-3.0"}})
+	assertEqual(t, len(findings), 1)
+	assertEqual(t, findings[0].Rule, graph.RuleLicense)
+	assertEqual(t, findings[0].Nodes, []graph.NodeKey{{ID: "a"}, {ID: "m/y"}})
+}
+
+func TestSBOM(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "0")
+	f := graph.Graph{Container: "a/cmd"}
+	f.AddNode(graph.NodeKey{ID: "a/cmd"}, &graph.NodeData{Module: "a"})
+	f.AddNode(graph.NodeKey{ID: "a/lib"}, &graph.NodeData{Module: "a"})
+	f.AddNode(graph.NodeKey{ID: "m/x"}, &graph.NodeData{Module: "m", Version: "v1.0.0+incompatible", Attrs: graph.Attrs{graph.AttrLicense: "MIT"}})
+	f.AddNode(graph.NodeKey{ID: "n"}, &graph.NodeData{Module: "n", Version: "v0.1.0"})
+	f.AddEdge(graph.NewDirectedEdge("a/cmd", "a/cmd", "a/lib"))
+	f.AddEdge(graph.NewDirectedEdge("a/cmd", "a/lib", "m/x"))
+	f.AddEdge(graph.NewDirectedEdge("a/cmd", "a/cmd", "n"))
+	f.AddEdge(graph.NewDirectedEdge("a/cmd", "n", "m/x"))
+
+	var buf bytes.Buffer
+	if err := graph.Write(&buf, &f, graph.FormatCycloneDX); err != nil {
+		t.Fatal(err)
+	}
+	var bom struct {
+		Metadata struct {
+			Timestamp string
+			Component struct{ Name string }
+		}
+		Components []struct {
+			Name, PURL string
+			Licenses   []struct{ Expression string }
+			Properties []struct{ Name, Value string }
+		}
+		Dependencies []struct {
+			Ref       string
+			DependsOn []string
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &bom); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, bom.Metadata.Timestamp, "1970-01-01T00:00:00Z")
+	assertEqual(t, bom.Metadata.Component.Name, "a")
+	assertEqual(t, len(bom.Components), 2)
+	assertEqual(t, bom.Components[0].PURL, "pkg:golang/m@v1.0.0%2Bincompatible")
+	assertEqual(t, bom.Components[0].Licenses[0].Expression, "MIT")
+	assertEqual(t, bom.Components[0].Properties[0].Name, "pkgrank:score")
+	assertEqual(t, bom.Dependencies[0].Ref, "pkg:golang/a")
+	assertEqual(t, bom.Dependencies[0].DependsOn, []string{"pkg:golang/m@v1.0.0%2Bincompatible", "pkg:golang/n@v0.1.0"})
+	assertEqual(t, bom.Dependencies[2].DependsOn, []string{"pkg:golang/m@v1.0.0%2Bincompatible"})
+
+	buf.Reset()
+	if err := graph.Write(&buf, &f, graph.FormatSPDX); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Packages []struct {
+			SPDXID          string
+			LicenseDeclared string
+		}
+		Relationships []struct {
+			SPDXElementID      string `json:"spdxElementId"`
+			RelationshipType   string
+			RelatedSPDXElement string `json:"relatedSpdxElement"`
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(doc.Packages), 3)
+	assertEqual(t, doc.Packages[1].SPDXID, "SPDXRef-Package-m-v1.0.0-incompatible")
+	assertEqual(t, doc.Packages[2].LicenseDeclared, "NOASSERTION")
+	var rels []string
+	for _, r := range doc.Relationships {
+		rels = append(rels, r.SPDXElementID+" "+r.RelationshipType+" "+r.RelatedSPDXElement)
+	}
+	assertEqual(t, rels, []string{
+		"SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package-a",
+		"SPDXRef-Package-a DEPENDS_ON SPDXRef-Package-m-v1.0.0-incompatible",
+		"SPDXRef-Package-a DEPENDS_ON SPDXRef-Package-n-v0.1.0",
+		"SPDXRef-Package-n-v0.1.0 DEPENDS_ON SPDXRef-Package-m-v1.0.0-incompatible",
+	})
+}
+
+func TestModuleRisks(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := graph.Graph{Container: "a"}
+	f.AddNode(graph.NodeKey{ID: "a"}, &graph.NodeData{Module: "a"})
+	f.AddNode(graph.NodeKey{ID: "m/x"}, &graph.NodeData{Module: "m", Version: "v1.0.0", Attrs: graph.Attrs{
+		graph.AttrPublished:   "2023-01-01T00:00:00Z",
+		graph.AttrMaintainers: int64(2),
+		graph.AttrVulns:       "GO-1,GO-2",
+	}})
+	f.AddNode(graph.NodeKey{ID: "m/y"}, &graph.NodeData{Module: "m", Version: "v1.0.0", Attrs: graph.Attrs{
+		graph.AttrPublished: "2023-01-01T00:00:00Z",
+		graph.AttrVulns:     "GO-1",
+	}})
+	f.AddNode(graph.NodeKey{ID: "n"}, &graph.NodeData{Module: "n", Version: "v0.1.0"})
+	f.AddNode(graph.NodeKey{ID: "fmt"}, &graph.NodeData{Module: "std"})
+	f.AddEdge(graph.NewDirectedEdge("a", "a", "m/x"))
+	f.AddEdge(graph.NewDirectedEdge("a", "m/x", "m/y"))
+	f.AddEdge(graph.NewDirectedEdge("a", "m/y", "n"))
+	f.AddEdge(graph.NewDirectedEdge("a", "n", "fmt"))
+
+	risks, err := f.ModuleRisks(graph.DefaultRiskWeights(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(risks), 2)
+	m, n := risks[0], risks[1]
+	assertEqual(t, m.Module, "m")
+	assertEqual(t, m.Dependencies, 2)
+	assertEqual(t, m.Vulns, []string{"GO-1", "GO-2"})
+	assertEqual(t, m.Factors[graph.RiskDependencies], 1.0)
+	assertEqual(t, m.Factors[graph.RiskMaintainers], 0.5)
+	assertEqual(t, m.Factors[graph.RiskAge], 0.5)
+	assertEqual(t, n.Module, "n")
+	assertEqual(t, n.Dependencies, 1)
+	assertEqual(t, n.Factors[graph.RiskAge], 0.0)
+
+	// Only vulnerabilities matter with the other factors weighed 0.
+	risks, err = f.ModuleRisks(graph.RiskWeights{graph.RiskVulns: 1}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, risks[0].Score, 2.0/3)
+	assertEqual(t, risks[1].Score, 0.0)
+
+	if _, err := f.ModuleRisks(graph.RiskWeights{"stars": 1}, now); err == nil {
+		t.Error("expected error for unknown risk factor")
+	}
+}
+
+func TestBinaryGraph(t *testing.T) {
+	// Test binaries embed build info, if without their dependencies.
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := graph.BinaryGraph(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	std := graph.NodeKey{ID: "std"}
+	assertEqual(t, g.Nodes[std].Data, &graph.NodeData{Module: "std", Version: runtime.Version()})
+	assertEqual(t, g.Reachable(graph.NodeKey{ID: g.Container}, std), true)
+
+	if _, err := graph.BinaryGraph("graph_test.go"); err == nil {
+		t.Error("expected error for a file that is not a binary")
+	}
+}
+
+func TestSizes(t *testing.T) {
+	const nm = `  4a3f20       1000 T main.main
+  4a3f40        200 T gopkg.in/yaml.v3.(*decoder).unmarshal
+  4a3f60         50 R type:*gopkg.in/yaml.v3.Node
+  4a3f80         30 T type:.eq.gopkg.in/yaml.v3.Node
+  4a3fa0        100 D a/b.table
+  4a3fc0          7 T runtime.main
+  4a3fe0       4000 B a/b.buf
+                    0 U _cgo_init
+                    0 U go:string."a b"
+`
+	symbols, err := graph.ParseSymbolSizes(strings.NewReader(nm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(symbols), 6)
+
+	f := graph.Graph{Container: "a"}
+	f.AddEdge(graph.NewDirectedEdge("a", "a", "a/b"))
+	f.AddEdge(graph.NewDirectedEdge("a", "a/b", "gopkg.in/yaml.v3"))
+	f.AddEdge(graph.NewDirectedEdge("a", "a", "gopkg.in/yaml.v3"))
+	assertEqual(t, f.AddSizes(symbols), int64(7))
+
+	var got []string
+	for _, s := range f.PackageSizes(graph.NodeKey{ID: "a"}) {
+		got = append(got, fmt.Sprintf("%s %d %d", s.Node, s.Size, s.Retained))
+	}
+	assertEqual(t, got, []string{"a 1000 1380", "gopkg.in/yaml.v3 280 280", "a/b 100 100"})
+}
+
+func TestWeightAttr(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("x", "a", "b"))
+	f.AddEdge(graph.NewDirectedEdge("x", "a", "c"))
+	f.AddEdge(graph.NewDirectedEdge("x", "b", "c"))
+	f.AddEdge(graph.NewDirectedEdge("x", "c", "b"))
+	for id, loc := range map[string]int64{"a": 10, "b": 100, "c": 1000} {
+		f.SetNodeAttrs(graph.NodeKey{ID: id}, func(a *graph.Attrs) {
+			a.SetInt(graph.AttrLOC, loc)
+		})
+	}
+	assertEqual(t, f.TransitiveAttr(graph.AttrLOC), map[graph.NodeKey]float64{
+		{ID: "a"}: 1110, {ID: "b"}: 1100, {ID: "c"}: 1100,
+	})
+	assertEqual(t, f.TransitiveAttr(graph.AttrExported), map[graph.NodeKey]float64(nil))
+
+	plain, err := graph.Centrality(f, graph.PageRankCentrality)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weighted, err := graph.Centrality(f, graph.PageRankCentrality, graph.WithWeightAttr(graph.AttrLOC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores := make(map[graph.NodeKey]float64)
+	for _, r := range plain {
+		scores[r.Node] = r.Score
+	}
+	for _, r := range weighted {
+		if want := scores[r.Node] * f.TransitiveAttr(graph.AttrLOC)[r.Node]; math.Abs(r.Score-want) > 1e-9 {
+			t.Errorf("weighted score of %s = %g, want %g", r.Node, r.Score, want)
+		}
+	}
+	if _, err := graph.Centrality(f, graph.PageRankCentrality, graph.WithWeightAttr("nope")); err == nil {
+		t.Error("weighting by a missing attribute did not fail")
+	}
+}
+
+func TestPopularity(t *testing.T) {
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("x", "a", "b"))
+	f.AddEdge(graph.NewDirectedEdge("x", "a", "c"))
+	f.SetNodeAttrs(graph.NodeKey{ID: "c"}, func(a *graph.Attrs) {
+		a.SetInt(graph.AttrDependents, 1000)
+	})
+	rank := func(blend float64) []string {
+		ranks, err := graph.Centrality(f, graph.PageRankCentrality, graph.WithPopularity(graph.AttrDependents, blend))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, r := range ranks {
+			ids = append(ids, r.Node.ID)
+		}
+		return ids
+	}
+	assertEqual(t, rank(0), []string{"b", "c", "a"})
+	assertEqual(t, rank(0.1), []string{"c", "b", "a"})
+
+	ranks, err := graph.Centrality(f, graph.PageRankCentrality, graph.WithPopularity(graph.AttrDependents, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, ranks[0], graph.RankResult{Node: graph.NodeKey{ID: "c"}, Score: 1})
+	if _, err := graph.Centrality(f, graph.PageRankCentrality, graph.WithPopularity(graph.AttrDependents, 2)); err == nil {
+		t.Error("blending popularity by 2 did not fail")
+	}
+}
+
+func TestDependencyCosts(t *testing.T) {
+	f := graph.Graph{Container: "a"}
+	edges := [][2]string{
+		{"a", "a/internal"},
+		{"a", "x.io/big"},
+		{"a/internal", "y.io/small"},
+		{"x.io/big", "x.io/big/sub"},
+		{"x.io/big/sub", "z.io/deep"},
+		{"x.io/big", "w.io/shared"},
+		{"y.io/small", "w.io/shared"},
+		{"y.io/small", "fmt"},
+	}
+	for _, e := range edges {
+		f.AddEdge(graph.NewDirectedEdge("a", e[0], e[1]))
+	}
+	modules := map[string]string{
+		"a": "a", "a/internal": "a", "x.io/big": "x.io/big", "x.io/big/sub": "x.io/big",
+		"z.io/deep": "z.io", "w.io/shared": "w.io", "y.io/small": "y.io", "fmt": "std",
+	}
+	for id, m := range modules {
+		data := &graph.NodeData{Module: m, Version: "v1.0.0"}
+		data.Attrs.SetInt(graph.AttrLOC, 10)
+		f.AddNode(graph.NodeKey{ID: id}, data)
+	}
+	assertEqual(t, f.DependencyCosts(graph.NodeKey{ID: "a"}), []graph.DependencyCost{
+		{Module: "x.io/big", Version: "v1.0.0", Packages: 3, Modules: 2, LOC: 30},
+		{Module: "y.io", Version: "v1.0.0", Packages: 1, Modules: 1, LOC: 10},
+	})
+}
+
+func TestChurn(t *testing.T) {
+	const log = `3f2a
+
+10	2	a/b.go
+-	-	a/logo.png
+4	0	a/{old => b}/c.go
+
+9c1e
+
+1	1	a/b.go
+0	7	README.md
+`
+	commits, err := graph.ParseNumstat(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, commits, []graph.Commit{
+		{Hash: "3f2a", Files: []graph.FileChange{
+			{Path: "a/b.go", Added: 10, Deleted: 2},
+			{Path: "a/logo.png"},
+			{Path: "a/b/c.go", Added: 4},
+		}},
+		{Hash: "9c1e", Files: []graph.FileChange{
+			{Path: "a/b.go", Added: 1, Deleted: 1},
+			{Path: "README.md", Deleted: 7},
+		}},
+	})
+
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("x", "a", "b"))
+	f.AddEdge(graph.NewDirectedEdge("x", "c", "b"))
+	for id, churn := range map[string]int64{"a": 100, "b": 10} {
+		f.SetNodeAttrs(graph.NodeKey{ID: id}, func(a *graph.Attrs) {
+			a.SetInt(graph.AttrCommits, 1)
+			a.SetInt(graph.AttrChurn, churn)
+		})
+	}
+	ranks := []graph.RankResult{{Node: graph.NodeKey{ID: "b"}, Score: 0.5}, {Node: graph.NodeKey{ID: "a"}, Score: 0.25}, {Node: graph.NodeKey{ID: "c"}, Score: 0.25}}
+	assertEqual(t, f.ChurnRanks(ranks), []graph.ChurnRank{
+		{Node: graph.NodeKey{ID: "a"}, Score: 0.25, Commits: 1, Churn: 100, Danger: 0.5},
+		{Node: graph.NodeKey{ID: "b"}, Score: 0.5, Commits: 1, Churn: 10, Danger: 0.1},
+	})
+}
+
+func TestHistory(t *testing.T) {
+	v1 := &graph.Graph{}
+	v1.AddEdge(graph.NewDirectedEdge("m", "m/a", "m/b"))
+	v1.AddEdge(graph.NewDirectedEdge("m", "m/a", "m/c"))
+	v2 := &graph.Graph{}
+	v2.AddEdge(graph.NewDirectedEdge("m", "m/a", "m/b"))
+	v2.AddEdge(graph.NewDirectedEdge("m", "m/b", "m/d"))
+	v2.AddEdge(graph.NewDirectedEdge("m", "m/d", "m/b"))
+	points := graph.History([]*graph.Revision{
+		{Rev: "v1.0.0", Commit: "c1", Graph: v1},
+		{Rev: "v2.0.0", Commit: "c2", Graph: v2},
+	})
+	var got []string
+	for _, p := range points {
+		got = append(got, fmt.Sprintf("%s %d %d %d +%d-%d +%d-%d", p.Rev, p.Order, p.Size, p.Cycles,
+			p.AddedNodes, p.RemovedNodes, p.AddedEdges, p.RemovedEdges))
+	}
+	assertEqual(t, got, []string{"v1.0.0 3 2 0 +0-0 +0-0", "v2.0.0 3 3 1 +1-1 +2-1"})
+
+	movers := graph.TopMovers(points, 2)
+	assertEqual(t, len(movers), 2)
+	for _, s := range movers {
+		if s.Node.ID == "m/a" {
+			t.Errorf("m/a moved by %g, want one of the two largest movers", s.Delta())
+		}
+	}
+	assertEqual(t, movers[0].Before, points[0].Ranks[movers[0].Node])
+}
+
+func TestCodeOwners(t *testing.T) {
+	c, err := graph.ParseCodeOwners(strings.NewReader(`# Owners.
+* @org/all
+[Section]
+*.md @org/docs # inline comment
+/internal/ @org/core
+cmd/**/main.go @org/cli
+/internal/vendored
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string][]string{
+		"main.go":                       {"@org/all"},
+		"docs/README.md":                {"@org/docs"},
+		"internal/a/a.go":               {"@org/core"},
+		"internal":                      {"@org/all"},
+		"x/internal/a.go":               {"@org/all"},
+		"cmd/main.go":                   {"@org/cli"},
+		"cmd/tool/sub/main.go":          {"@org/cli"},
+		"cmd/tool/sub/util.go":          {"@org/all"},
+		"internal/vendored/v/vendor.go": nil,
+	} {
+		if got := c.Owners(file); !cmp.Equal(got, want) {
+			t.Errorf("owners of %s = %v, want %v", file, got, want)
+		}
+	}
+
+	f := graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("m", "m/a", "m/b"))
+	f.AddEdge(graph.NewDirectedEdge("m", "m/a", "m/c"))
+	f.AddEdge(graph.NewDirectedEdge("m", "m/b", "m/c"))
+	f.AddEdge(graph.NewDirectedEdge("m", "m/c", "fmt"))
+	for id, owner := range map[string]string{"m/a": "@x", "m/b": "@x", "m/c": "@y"} {
+		f.SetNodeAttrs(graph.NodeKey{ID: id}, func(a *graph.Attrs) {
+			a.SetString(graph.AttrOwner, owner)
+		})
+	}
+	var edges []string
+	for _, e := range f.CrossTeamEdges() {
+		edges = append(edges, e.String())
+	}
+	assertEqual(t, edges, []string{"m/a (@x) imports m/c (@y)", "m/b (@x) imports m/c (@y)"})
+	var teams []string
+	for _, r := range f.TeamRanks() {
+		teams = append(teams, fmt.Sprintf("%s %d %d %d", r.Team, r.Packages, r.Imports, r.ImportedBy))
+	}
+	assertEqual(t, teams, []string{"@y 1 0 2", "@x 2 2 0"})
+}
+
+func TestMergeLatest(t *testing.T) {
+	build := func(container, version string, deps ...string) *graph.Graph {
+		g := &graph.Graph{Container: container, AddedContainers: map[string]struct{}{}}
+		g.AddNode(graph.NodeKey{ID: container}, &graph.NodeData{Module: container, Version: "v1.0.0"})
+		g.AddNode(graph.NodeKey{ID: "x.io/dep"}, &graph.NodeData{Module: "x.io/dep", Version: version})
+		g.AddEdge(graph.NewDirectedEdge(container, container, "x.io/dep"))
+		g.AddedContainers[container] = struct{}{}
+		g.AddedContainers["x.io/dep"] = struct{}{}
+		for _, dep := range deps {
+			g.AddEdge(graph.NewDirectedEdge("x.io/dep", "x.io/dep", dep))
+		}
+		return g
+	}
+	graphs := []*graph.Graph{
+		build("a.io/a", "v1.2.0", "old.io/gone"),
+		build("b.io/b", "v1.10.0", "new.io/added"),
+		build("c.io/c", "v1.9.0", "mid.io/mid"),
+	}
+	for _, workers := range []int{1, 4} {
+		g, err := graph.MergeLatest("ecosystem", workers, graphs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var edges []string
+		for _, edge := range g.SortedEdges() {
+			e := edge.(*graph.DirectedEdge)
+			edges = append(edges, e.Src.ID+" "+e.Dst.ID)
+		}
+		assertEqual(t, edges, []string{"a.io/a x.io/dep", "b.io/b x.io/dep", "c.io/c x.io/dep", "x.io/dep new.io/added"})
+		assertEqual(t, g.Nodes[graph.NodeKey{ID: "x.io/dep"}].Data.Version, "v1.10.0")
+	}
+}
+
+func TestParseBuildConfig(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want graph.BuildConfig
+	}{
+		{"linux/amd64", graph.BuildConfig{GOOS: "linux", GOARCH: "amd64"}},
+		{"windows/arm64:netgo,osusergo", graph.BuildConfig{GOOS: "windows", GOARCH: "arm64", Tags: []string{"netgo", "osusergo"}}},
+	} {
+		c, err := graph.ParseBuildConfig(tt.in)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.in, err)
+		}
+		assertEqual(t, c, tt.want)
+		assertEqual(t, c.String(), tt.in)
+	}
+	for _, in := range []string{"", "linux", "linux/", "/amd64", "linux/amd64/v2", "linux/amd64:", "linux/amd64:a,,b", "linux/amd64:a:b", "linux /amd64"} {
+		if _, err := graph.ParseBuildConfig(in); err == nil {
+			t.Errorf("parsed invalid build config %q", in)
+		}
+	}
+}
+
+func TestMergeConfigs(t *testing.T) {
+	linux, windows := graph.BuildConfig{GOOS: "linux", GOARCH: "amd64"}, graph.BuildConfig{GOOS: "windows", GOARCH: "amd64"}
+	newGraph := func(weight float64, kind graph.EdgeKind, symbols ...string) *graph.Graph {
+		g := &graph.Graph{Container: "a"}
+		edge := graph.NewDirectedEdge("a", "a", "b")
+		edge.EdgeWeight = weight
+		edge.Kind = kind
+		edge.Symbols = symbols
+		g.AddEdge(edge)
+		return g
+	}
+	onLinux := newGraph(1, graph.EdgeKindTest, "F")
+	onLinux.AddEdge(graph.NewDirectedEdge("a", "b", "unix"))
+	onLinux.AddNode(graph.NodeKey{ID: "b"}, &graph.NodeData{Module: "example.com/b"})
+	onWindows := newGraph(2, graph.EdgeKindTest|graph.EdgeKindBlank, "G")
+	onWindows.AddEdge(graph.NewDirectedEdge("a", "b", "windows"))
+
+	g, err := graph.MergeConfigs([]graph.BuildConfig{linux, windows}, []*graph.Graph{onLinux, onWindows})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, g.Container, "a")
+	assertEqual(t, g.Size(), 3)
+	// An edge in both configurations has the larger weight, both symbols and
+	// the common kinds.
+	edge := g.Edges[graph.EdgeKeyFrom("a:a->b")].(*graph.DirectedEdge)
+	assertEqual(t, edge.Configs, []string{"linux/amd64", "windows/amd64"})
+	assertEqual(t, edge.Weight(), 2.0)
+	assertEqual(t, edge.Symbols, []string{"F", "G"})
+	assertEqual(t, edge.Kind, graph.EdgeKindTest)
+	assertEqual(t, g.Edges[graph.EdgeKeyFrom("a:b->unix")].(*graph.DirectedEdge).Configs, []string{"linux/amd64"})
+	assertEqual(t, g.Edges[graph.EdgeKeyFrom("a:b->windows")].(*graph.DirectedEdge).Configs, []string{"windows/amd64"})
+	assertEqual(t, g.Nodes[graph.NodeKey{ID: "b"}].Data.Module, "example.com/b")
+	// The merged graphs are unchanged.
+	assertEqual(t, onLinux.Edges[graph.EdgeKeyFrom("a:a->b")].(*graph.DirectedEdge).Configs, []string(nil))
+
+	if _, err := graph.MergeConfigs([]graph.BuildConfig{linux}, nil); err == nil {
+		t.Error("merged graphs of more build configs than graphs")
+	}
+}
+
+func TestWriteJSONLines(t *testing.T) {
+	f := &graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("a", "a", "c"))
+	f.AddEdge(graph.NewDirectedEdge("a", "a", "b"))
+	f.AddEdge(graph.NewDirectedEdge("a", "b", "c"))
+
+	var buf bytes.Buffer
+	if err := graph.Write(&buf, f, graph.FormatJSONLines); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		var edge graph.DirectedEdge
+		if err := json.Unmarshal([]byte(line), &edge); err != nil {
+			t.Fatalf("invalid line %q: %v", line, err)
+		}
+		got = append(got, edge.Key().String())
+	}
+	assertEqual(t, got, []string{"a:a->b", "a:a->c", "a:b->c"})
+}
+
+func TestWritePajek(t *testing.T) {
+	f := &graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("a", "a", "b"))
+	f.AddEdge(graph.NewDirectedEdge("b", "a", "b"))
+	f.AddEdge(graph.NewDirectedEdge("a", "b", `c"`))
+
+	var buf bytes.Buffer
+	if err := graph.Write(&buf, f, graph.FormatPajek); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, buf.String(), `*Vertices 3
+1 "a"
+2 "b"
+3 "c'"
+*Arcs
+1 2 2
+2 3 1
+`)
+	buf.Reset()
+	if err := graph.Write(&buf, f, graph.FormatMatrixMarket); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, buf.String(), `%%MatrixMarket matrix coordinate real general
+% 1 a
+% 2 b
+% 3 c"
+3 3 2
+1 2 2
+2 3 1
+`)
+}
+
+func TestReadDOT(t *testing.T) {
+	g, err := graph.ReadDOT(strings.NewReader(`digraph godep {
+	_0 [label="example.com/a\nmain", style="filled"];
+	_1 [label="example.com/b"];
+	_0 -> _1 [weight=2];
+	_0 -> c [style=dashed];
+	d;
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, g.Container, "godep")
+	assertEqual(t, g.SortedNodes(), []graph.NodeKey{{ID: "c"}, {ID: "d"}, {ID: "example.com/a"}, {ID: "example.com/b"}})
+	ab := g.Edges[graph.EdgeKeyFrom("godep:example.com/a->example.com/b")].(*graph.DirectedEdge)
+	assertEqual(t, ab.Weight(), 2.0)
+	ac := g.Edges[graph.EdgeKeyFrom("godep:example.com/a->c")].(*graph.DirectedEdge)
+	assertEqual(t, ac.Kind, graph.EdgeKindTest)
+
+	// WriteDOT output reads back as the same graph, but for weights.
+	ab.EdgeWeight = 1
+	var buf bytes.Buffer
+	if err := graph.WriteDOT(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	h, err := graph.ReadDOT(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(g)
+	got, _ := json.Marshal(h)
+	assertEqual(t, string(got), string(want))
+
+	if _, err := graph.ReadDOT(strings.NewReader(`graph { a -- b }`)); err == nil {
+		t.Error("ReadDOT of an undirected graph succeeded")
+	}
+}
+
+func TestGraphLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Level(zerolog.TraceLevel)
+	g := graph.Graph{Container: "a"}
+	g.SetLogger(&logger)
+	if _, err := g.AddEdge(graph.NewDirectedEdge("a", "a", "b")); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, strings.Contains(buf.String(), `"message":"added edge"`), true)
+
+	// Clones log to the same logger.
+	buf.Reset()
+	c := g.Clone()
+	if _, err := c.AddEdge(graph.NewDirectedEdge("a", "b", "c")); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, strings.Contains(buf.String(), `"message":"added edge"`), true)
+}
+
+func TestReportProgress(t *testing.T) {
+	// Without a ProgressFunc, progress is not reported.
+	graph.ReportProgress(context.Background(), graph.Progress{Phase: graph.PhaseFetch})
+
+	var got []graph.Progress
+	ctx := graph.WithProgress(context.Background(), func(p graph.Progress) {
+		got = append(got, p)
+	})
+	graph.ReportProgress(ctx, graph.Progress{Phase: graph.PhaseAnalyze, Done: 1, Total: 2, Package: "a"})
+	graph.ReportProgress(ctx, graph.Progress{Phase: graph.PhaseRank})
+	assertEqual(t, got, []graph.Progress{
+		{Phase: graph.PhaseAnalyze, Done: 1, Total: 2, Package: "a"},
+		{Phase: graph.PhaseRank},
+	})
+}
+
+func TestRankedNodes(t *testing.T) {
+	g := graph.Graph{Container: "a"}
+	for _, e := range [][2]string{{"a", "b"}, {"a", "c"}, {"b", "c"}} {
+		if _, err := g.AddEdge(graph.NewDirectedEdge("a", e[0], e[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.AddNode(graph.NodeKey{ID: "c"}, &graph.NodeData{Module: "m", Version: "v1.0.0"})
+	ranks, err := graph.Centrality(g, graph.PageRankCentrality)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := g.RankedNodes(ranks)
+	assertEqual(t, len(nodes), 3)
+	assertEqual(t, nodes[0], graph.RankedNode{
+		Rank: 1, Node: graph.NodeKey{ID: "c"}, Score: ranks[0].Score,
+		InDegree: 2, OutDegree: 0, Module: "m", Version: "v1.0.0",
+	})
+	assertEqual(t, nodes[2].Rank, 3)
+	assertEqual(t, nodes[2].Node.ID, "a")
+	assertEqual(t, nodes[2].OutDegree, 2)
+}
+
+func TestExecConfig(t *testing.T) {
+	t.Setenv("GOFLAGS", "-trimpath")
+	c := graph.ExecConfig{Go: "/opt/go/bin/go", Proxy: "https://proxy.example.com", Flags: "-mod=mod"}
+	cmd := c.Command(context.Background(), "go", "version")
+	assertEqual(t, cmd.Path, "/opt/go/bin/go")
+	// The last value of a variable wins.
+	env := make(map[string]string)
+	for _, kv := range cmd.Env {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	assertEqual(t, env["GOPROXY"], "https://proxy.example.com")
+	assertEqual(t, env["GOFLAGS"], "-trimpath -mod=mod")
+	assertEqual(t, strings.HasPrefix(env["PATH"], "/opt/go/bin"+string(filepath.ListSeparator)), true)
+
+	cmd = c.Command(context.Background(), "git", "status")
+	assertEqual(t, filepath.Base(cmd.Args[0]), "git")
+}
+
+func TestExecConfigSandbox(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+	dir := t.TempDir()
+	c := graph.ExecConfig{Private: "example.com/private", Sandbox: true, SandboxDir: dir}
+	env := make(map[string]string)
+	for _, kv := range c.Command(context.Background(), "go", "list").Env {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	assertEqual(t, env["GOVCS"], "*:off")
+	assertEqual(t, env["CGO_ENABLED"], "0")
+	assertEqual(t, env["GOTOOLCHAIN"], "local")
+	assertEqual(t, env["GOPRIVATE"], "")
+	assertEqual(t, env["GOMODCACHE"], filepath.Join(dir, "gopath", "pkg", "mod"))
+	assertEqual(t, env["GOFLAGS"], "-modcacherw")
+}
+
+func TestExecConfigVendor(t *testing.T) {
+	t.Setenv("GOFLAGS", "-mod=vendor -tags=x")
+	goflags := func() string {
+		env := make(map[string]string)
+		for _, kv := range (graph.ExecConfig{}).Command(context.Background(), "go", "list").Env {
+			k, v, _ := strings.Cut(kv, "=")
+			env[k] = v
+		}
+		return env["GOFLAGS"]
+	}
+	// -mod=vendor is dropped outside of modules with a vendor directory.
+	assertEqual(t, goflags(), "-tags=x")
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":             "module example.com/m\n",
+		"vendor/modules.txt": "# example.com/v v1.0.0\nexample.com/v\n",
+		"internal/p/p.go":    "package p\n",
+	} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "internal", "p")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	assertEqual(t, goflags(), "-mod=vendor -tags=x")
+}
+
+func TestWatcherPoll(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/m\n\ngo 1.21\n")
+	write("a/a.go", "package a\n\nimport _ \"example.com/m/b\"\n")
+	write("b/b.go", "package b\n")
+	write("c/c.go", "package c\n")
+	w, err := graph.NewWatcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasEdge := func(src, dst string) bool {
+		g, _ := w.Graph()
+		_, ok := g.Edges[graph.NewDirectedEdge(g.Container, src, dst).Key()]
+		return ok
+	}
+	assertEqual(t, hasEdge("example.com/m/a", "example.com/m/b"), true)
+
+	// A failed poll leaves the graph as it was, and the next poll lists the
+	// changed package again.
+	write("a/a.go", "package a\n\nimport _ \"example.com/m/c\" // was b\n")
+	write("go.mod", "modul example.com/m\n")
+	if _, err := w.Poll(); err == nil {
+		t.Fatal("polled a module with an invalid go.mod")
+	}
+	assertEqual(t, hasEdge("example.com/m/a", "example.com/m/b"), true)
+	write("go.mod", "module example.com/m\n\ngo 1.21\n")
+	changed, err := w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, changed, []string{"example.com/m/a"})
+	assertEqual(t, hasEdge("example.com/m/a", "example.com/m/b"), false)
+	assertEqual(t, hasEdge("example.com/m/a", "example.com/m/c"), true)
+}
+
+func TestGraphUnvendor(t *testing.T) {
+	assertEqual(t, graph.CanonicalPath("vendor/golang.org/x/net/http2/hpack"), "golang.org/x/net/http2/hpack")
+	assertEqual(t, graph.CanonicalPath("example.com/m/vendor/example.com/v"), "example.com/v")
+	assertEqual(t, graph.CanonicalPath("example.com/vendor"), "example.com/vendor")
+
+	f := &graph.Graph{Container: "A"}
+	f.AddEdge(graph.NewDirectedEdge("A", "A", "x.com/y"))
+	vendored := graph.NewDirectedEdge("A", "A", "A/vendor/x.com/y")
+	vendored.EdgeWeight = 3
+	f.AddEdge(vendored)
+	f.AddEdge(graph.NewDirectedEdge("B", "B", "B/vendor/x.com/y"))
+	f.AddNode(graph.NodeKey{ID: "x.com/y"}, &graph.NodeData{Module: "x.com/y"})
+	f.AddNode(graph.NodeKey{ID: "B/vendor/x.com/y"}, &graph.NodeData{Module: "B", Types: 2})
+	// Undirected edges are dropped rather than left between vendored nodes.
+	f.AddEdge(graph.NewUndirectedEdge("A", "A", "A/vendor/x.com/y"))
+
+	g, err := f.Unvendor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, g.Order(), 3)
+	assertEqual(t, g.Size(), 2)
+	// Imports of both the vendored and the canonical package are counted
+	// once, and only vendored if all are.
+	a := g.Edges[graph.EdgeKeyFrom("A:A->x.com/y")].(*graph.DirectedEdge)
+	assertEqual(t, a.Weight(), 3.0)
+	assertEqual(t, a.Kind, graph.EdgeKind(0))
+	b := g.Edges[graph.EdgeKeyFrom("B:B->x.com/y")].(*graph.DirectedEdge)
+	assertEqual(t, b.Kind, graph.EdgeKindVendored)
+	assertEqual(t, *g.Nodes[graph.NodeKey{ID: "x.com/y"}].Data, graph.NodeData{Module: "x.com/y", Types: 2})
+
+	h := &graph.Graph{}
+	h.AddEdge(graph.NewDirectedEdge("A", "A", "B"))
+	if u, _ := h.Unvendor(); u != h {
+		t.Error("Unvendor copied a graph without vendored packages")
+	}
+}
+
+func TestSplitVersion(t *testing.T) {
+	for _, tt := range []struct{ pkg, path, version string }{
+		{"golang.org/x/mod/semver", "golang.org/x/mod/semver", ""},
+		{"golang.org/x/mod/semver@v0.12.0", "golang.org/x/mod/semver", "v0.12.0"},
+		{"example.com/m@v2.0.0-rc.1+incompatible", "example.com/m", "v2.0.0-rc.1+incompatible"},
+		{"example.com/m@release/v1", "example.com/m", "release/v1"},
+	} {
+		path, version := graph.SplitVersion(tt.pkg)
+		assertEqual(t, path, tt.path)
+		assertEqual(t, version, tt.version)
+	}
+}
+
+// fakeGo writes a go command into dir that logs its arguments to log, and
+// fails to get packages other than those of example.com/m/v2 at v2 versions,
+// and a depgraph that writes a graph of an edge from its root package to
+// fmt.
+func fakeGo(t *testing.T, dir, log string) (goCmd, depgraph string) {
+	t.Helper()
+	goCmd, depgraph = filepath.Join(dir, "go"), filepath.Join(dir, "depgraph")
+	scripts := map[string]string{
+		goCmd: `#!/bin/sh
+echo "$@" >> ` + log + `
+case "$1 $2" in
+"get "*@v2.*)
+	case "$2" in
+	example.com/m/v2/*) ;;
+	*) echo "invalid version: should be v0 or v1, not v2" >&2; exit 1 ;;
+	esac ;;
+esac
+`,
+		depgraph: `#!/bin/sh
+for a in "$@"; do
+	case "$a" in -graph-out=*) out="${a#-graph-out=}" ;; esac
+done
+r="$DEPGRAPH_ROOT_PKG"
+echo '{"version":1,"container":"'$r'","nodes":[{"id":"'$r'"},{"id":"fmt"}],"edges":[{"container":"'$r'","src":"'$r'","dst":"fmt","weight":1}]}' > "$out"
+`,
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(name, []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return goCmd, depgraph
+}
+
+func TestTransitiveGraphMajorVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	goCmd, depgraph := fakeGo(t, dir, log)
+	ctx := graph.WithExecConfig(context.Background(), graph.ExecConfig{Go: goCmd})
+	ctx = graph.WithDepgraph(ctx, depgraph)
+
+	g, err := graph.TransitiveGraphContext(ctx, "example.com/m/sub@v2.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, g.Container, "example.com/m/v2/sub")
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gets []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(line, "get ") {
+			gets = append(gets, strings.TrimPrefix(line, "get "))
+		}
+	}
+	// The package is tried in modules of the longest path first.
+	assertEqual(t, gets, []string{
+		"example.com/m/sub@v2.1.0",
+		"example.com/m/sub/v2@v2.1.0",
+		"example.com/m/v2/sub@v2.1.0",
+	})
+
+	// Versions below v2 and paths with a major version are got as is.
+	for _, pkg := range []string{"example.com/m/sub@v1.2.3", "example.com/m/v2/sub@v2.1.0"} {
+		path, _ := graph.SplitVersion(pkg)
+		g, err := graph.TransitiveGraphContext(ctx, pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, g.Container, path)
+	}
+}
+
+func TestTransitiveGraphSkipGenerated(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	dir := t.TempDir()
+	goCmd, depgraph := fakeGo(t, dir, filepath.Join(dir, "log"))
+	args := filepath.Join(dir, "args")
+	wrapper := filepath.Join(dir, "depgraph-args")
+	script := "#!/bin/sh\necho \"$@\" > " + args + "\nexec " + depgraph + " \"$@\"\n"
+	if err := os.WriteFile(wrapper, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ctx := graph.WithExecConfig(context.Background(), graph.ExecConfig{Go: goCmd})
+	ctx = graph.WithSkipGenerated(graph.WithDepgraph(ctx, wrapper))
+
+	if _, err := graph.TransitiveGraphContext(ctx, "example.com/m@v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flags come before the package pattern.
+	fields := strings.Fields(string(b))
+	assertEqual(t, fields[0], "-skip-generated")
+	assertEqual(t, fields[len(fields)-1], ".")
+}
+
+func TestWorkdirs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", t.TempDir())
+	goCmd, depgraph := fakeGo(t, dir, filepath.Join(dir, "log"))
+	ctx := graph.WithDepgraph(context.Background(), depgraph)
+	workdirs := func() int {
+		t.Helper()
+		dirs, err := graph.Workdirs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(dirs)
+	}
+
+	// Scratch modules are removed whether the analysis succeeds or fails.
+	run := graph.WithExecConfig(ctx, graph.ExecConfig{Go: goCmd})
+	if _, err := graph.TransitiveGraphContext(run, "example.com/m/sub"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := graph.TransitiveGraphContext(run, "example.com/n@v2.0.0"); err == nil {
+		t.Fatal("expected an error")
+	}
+	assertEqual(t, workdirs(), 0)
+
+	keep := graph.WithExecConfig(ctx, graph.ExecConfig{Go: goCmd, KeepWorkdir: true})
+	if _, err := graph.TransitiveGraphContext(keep, "example.com/m/sub"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := graph.TransitiveGraphContext(keep, "example.com/n@v2.0.0"); err == nil {
+		t.Fatal("expected an error")
+	}
+	assertEqual(t, workdirs(), 2)
+}
+
+func TestParseError(t *testing.T) {
+	doc := `{"version":1,"container":"a","nodes":[{"id":"a"},{"id":""}],"edges":[
+		{"container":"a","src":"a","dst":"b","weight":1},
+		{"container":"a","src":"a","dst":"","weight":1},
+		{"container":"a","src":"","dst":"b","weight":1}]}`
+	var g graph.Graph
+	err := json.Unmarshal([]byte(doc), &g)
+	var parseErr *graph.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("got %v, want a *graph.ParseError", err)
+	}
+	assertEqual(t, parseErr.Count, 3)
+	assertEqual(t, len(parseErr.Errs), 3)
+	assertEqual(t, g.Size(), 1)
+}
+
+func TestTransitiveEdgesStreamMalformed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	dir := t.TempDir()
+	goCmd, _ := fakeGo(t, dir, filepath.Join(dir, "log"))
+	depgraph := filepath.Join(dir, "depgraph-edges")
+	script := `#!/bin/sh
+echo '{"container":"a","src":"a","dst":"b","weight":1}'
+echo 'not json'
+echo
+echo '{"container":"a","src":"a","dst":"","weight":1}'
+echo '{"container":"a","src":"b","dst":"c","weight":1}'
+`
+	if err := os.WriteFile(depgraph, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ctx := graph.WithExecConfig(context.Background(), graph.ExecConfig{Go: goCmd})
+	ctx = graph.WithDepgraph(ctx, depgraph)
+
+	// Valid edges are still streamed, and the malformed ones reported
+	// together at the end.
+	var got []string
+	err := graph.TransitiveEdgesStream(ctx, "example.com/m/sub", func(e *graph.DirectedEdge) error {
+		got = append(got, e.Src.ID+"->"+e.Dst.ID)
+		return nil
+	})
+	assertEqual(t, got, []string{"a->b", "b->c"})
+	var parseErr *graph.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("got %v, want a *graph.ParseError", err)
+	}
+	assertEqual(t, parseErr.Count, 2)
+	assertEqual(t, strings.HasPrefix(parseErr.Errs[0].Error(), "line 2:"), true)
+}
+
+func TestExecError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	goCmd := filepath.Join(t.TempDir(), "go")
+	script := `#!/bin/sh
+[ "$1" = get ] || exit 0
+echo "go: example.com/m@v1.0.0: reading https://proxy.golang.org/example.com/m/@v/v1.0.0.mod: 404 Not Found" >&2
+exit 2
+`
+	if err := os.WriteFile(goCmd, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ctx := graph.WithExecConfig(context.Background(), graph.ExecConfig{Go: goCmd})
+	_, err := graph.TransitiveGraphContext(ctx, "example.com/m@v1.0.0")
+	var execErr *graph.ExecError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("got %v, want a *graph.ExecError", err)
+	}
+	assertEqual(t, execErr.Name, "go")
+	assertEqual(t, execErr.ExitCode, 2)
+	assertEqual(t, errors.Is(err, graph.ErrModuleNotFound), true)
+	assertEqual(t, errors.Is(err, graph.ErrNetwork), false)
+	assertEqual(t, execErr.Hint() != "", true)
+
+	ctx = graph.WithExecConfig(context.Background(), graph.ExecConfig{Go: filepath.Join(t.TempDir(), "go")})
+	_, err = graph.TransitiveGraphContext(ctx, "example.com/m")
+	assertEqual(t, errors.Is(err, graph.ErrNoToolchain), true)
+	assertEqual(t, errors.As(err, &execErr), true)
+	assertEqual(t, execErr.ExitCode, -1)
+}
+
+func TestLookDepgraph(t *testing.T) {
+	gobin := t.TempDir()
+	name := "depgraph"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if err := os.WriteFile(filepath.Join(gobin, name), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", "")
+	t.Setenv("GOBIN", gobin)
+	path, err := graph.LookDepgraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, path, filepath.Join(gobin, name))
+}
+
+func TestTransitiveGraphInvalidPackage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	goCmd, _ := fakeGo(t, dir, log)
+	ctx := graph.WithExecConfig(context.Background(), graph.ExecConfig{Go: goCmd})
+	for _, pkg := range []string{"-toolexec=sh", `example.com/m"; import "os`, `C:\src\m`} {
+		if _, err := graph.TransitiveGraphContext(ctx, pkg); err == nil {
+			t.Errorf("%s: expected an error", pkg)
+		}
+	}
+	// No go command ran.
+	if _, err := os.Stat(log); !os.IsNotExist(err) {
+		t.Errorf("got %v, want no log", err)
+	}
+}
+
+func TestGraphClassify(t *testing.T) {
+	f := graph.Graph{Container: "example.com/m/cmd"}
+	for _, e := range [][2]string{
+		{"example.com/m/cmd", "example.com/m/lib"},
+		{"example.com/m/cmd", "example.com/tools/x"},
+		{"example.com/m/lib", "github.com/other/y"},
+		{"example.com/m/lib", "fmt"},
+		{"github.com/other/y", "fmt"},
+	} {
+		f.AddEdge(graph.NewDirectedEdge("example.com/m/cmd", e[0], e[1]))
+	}
+	var loc graph.Attrs
+	loc.SetInt(graph.AttrLOC, 10)
+	for _, n := range []string{"example.com/m/cmd", "example.com/m/lib"} {
+		f.AddNode(graph.NodeKey{ID: n}, &graph.NodeData{Module: "example.com/m", Attrs: loc})
+	}
+	f.AddNode(graph.NodeKey{ID: "example.com/tools/x"}, &graph.NodeData{Module: "example.com/tools"})
+
+	classes := f.Classify("example.com/tools/")
+	assertEqual(t, classes, map[graph.NodeKey]graph.NodeClass{
+		{ID: "example.com/m/cmd"}:   graph.ClassOwn,
+		{ID: "example.com/m/lib"}:   graph.ClassOwn,
+		{ID: "example.com/tools/x"}: graph.ClassFirstParty,
+		{ID: "github.com/other/y"}:  graph.ClassThirdParty,
+		{ID: "fmt"}:                 graph.ClassStdlib,
+	})
+	// Without first-party prefixes, other modules are third-party.
+	assertEqual(t, f.Classify()[graph.NodeKey{ID: "example.com/tools/x"}], graph.ClassThirdParty)
+
+	stats := f.ClassStats(classes)
+	var got []graph.NodeClass
+	score := 0.0
+	for _, s := range stats {
+		got = append(got, s.Class)
+		score += s.Score
+	}
+	assertEqual(t, got, graph.NodeClasses)
+	if math.Abs(score-1) > 1e-9 {
+		t.Errorf("scores sum to %v, want 1", score)
+	}
+	own := stats[0]
+	assertEqual(t, own.Packages, 2)
+	assertEqual(t, own.LOC, int64(20))
+	assertEqual(t, own.Imports, map[graph.NodeClass]int{
+		graph.ClassOwn:        1,
+		graph.ClassFirstParty: 1,
+		graph.ClassThirdParty: 1,
+		graph.ClassStdlib:     1,
+	})
+
+	if _, err := graph.ParseNodeClass("internal"); err == nil {
+		t.Error("expected an error for an unknown class")
+	}
+}
+
+func TestWriteCypher(t *testing.T) {
+	f := &graph.Graph{}
+	edge := graph.NewDirectedEdge("a", "a", `b"\\b`)
+	edge.Symbols = []string{"F"}
+	edge.Kind = graph.EdgeKindTest
+	f.AddEdge(edge)
+	data := &graph.NodeData{Module: "a", Version: "v1.0.0"}
+	data.Attrs.SetInt(graph.AttrLOC, 3)
+	f.AddNode(graph.NodeKey{ID: "a"}, data)
+
+	var buf bytes.Buffer
+	if err := graph.Write(&buf, f, graph.FormatCypher); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assertEqual(t, len(lines), 7)
+	for _, want := range []string{
+		`MERGE (p:Package {path: "a"}) SET p += {` + "`loc`: 3, `rank`: ",
+		`MERGE (m:Module {id: "a@v1.0.0"}) SET m.path = "a", m.version = "v1.0.0";`,
+		`MATCH (p:Package {path: "a"}), (m:Module {id: "a@v1.0.0"}) MERGE (p)-[:IN_MODULE]->(m);`,
+		`MATCH (a:Package {path: "a"}), (b:Package {path: "b\"\\\\b"}) MERGE (a)-[r:IMPORTS]->(b) SET r += {` +
+			"`kinds`: [\"test\"], `symbols`: [\"F\"], `weight`: 1};",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("cypher does not contain %s:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	// The golden files were checked with parquet-go. The graph is a cycle
+	// of two packages, whose ranks are exactly 0.5 on any platform.
+	f := &graph.Graph{Container: "example.com/m"}
+	edge := graph.NewDirectedEdge("example.com/m", "example.com/m", "example.com/m/internal/x")
+	edge.Symbols = []string{"X", "Y"}
+	edge.Kind = graph.EdgeKindTest
+	f.AddEdge(edge)
+	f.AddEdge(graph.NewDirectedEdge("example.com/m", "example.com/m/internal/x", "example.com/m"))
+	data := &graph.NodeData{Module: "example.com/m", Version: "v1.2.3", Types: 2, Interfaces: 1}
+	data.Attrs.SetInt(graph.AttrLOC, 120)
+	f.AddNode(graph.NodeKey{ID: "example.com/m"}, data)
+
+	// Tables without rows still have their columns.
+	for golden, g := range map[string]*graph.Graph{"graph": f, "empty": {}} {
+		dir := filepath.Join(t.TempDir(), "out")
+		if err := graph.WriteFile(dir, g, graph.FormatParquet); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{graph.ParquetNodesFile, graph.ParquetEdgesFile, graph.ParquetRanksFile} {
+			got, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", "parquet", golden, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s/%s differs from the golden file", golden, name)
+			}
+		}
+	}
+	if err := graph.Write(&bytes.Buffer{}, f, graph.FormatParquet); err == nil {
+		t.Error("Write of parquet to a writer succeeded")
+	}
+}
+
+func TestExclude(t *testing.T) {
+	f := &graph.Graph{}
+	f.AddEdge(graph.NewDirectedEdge("m", "m", "m/internal/a"))
+	f.AddEdge(graph.NewDirectedEdge("m", "m/internal/a", "m/internal/a/b"))
+	f.AddEdge(graph.NewDirectedEdge("m", "m", "x/y"))
+
+	g := f.Exclude("m/internal/...", "x/*")
+	assertEqual(t, g.SortedNodes(), []graph.NodeKey{{ID: "m"}})
+	assertEqual(t, g.Size(), 0)
+	assertEqual(t, f.Exclude("m/internal").Order(), 4)
+}
+
+func TestSortedOutput(t *testing.T) {
+	ids := []string{"a", "a/b", "a-b", "b", "c"}
+	build := func(reverse bool) *graph.Graph {
+		var f graph.Graph
+		for i := range ids {
+			src := ids[i]
+			if reverse {
+				src = ids[len(ids)-1-i]
+			}
+			for _, dst := range ids {
+				if dst != src {
+					f.AddEdge(graph.NewDirectedEdge("x", src, dst))
+				}
+			}
+		}
+		return &f
+	}
+	f := build(false)
+	var keys []string
+	for _, edge := range f.SortedEdges() {
+		e := edge.(*graph.DirectedEdge)
+		keys = append(keys, e.Src.ID+" "+e.Dst.ID)
+	}
+	assertEqual(t, keys[:5], []string{"a a-b", "a a/b", "a b", "a c", "a-b a"})
+	assertEqual(t, f.SortedNodes()[:3], []graph.NodeKey{{ID: "a"}, {ID: "a-b"}, {ID: "a/b"}})
+
+	for _, format := range []graph.Format{graph.FormatEdgeList, graph.FormatJSON, graph.FormatDOT} {
+		var want bytes.Buffer
+		if err := graph.Write(&want, f, format); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			var got bytes.Buffer
+			if err := graph.Write(&got, build(i%2 == 0), format); err != nil {
+				t.Fatal(err)
+			}
+			assertEqual(t, got.String(), want.String())
+		}
+	}
+}
+
+// mustMerge is like graph.Merge, but fails the test on error.
+func mustMerge(tb testing.TB, container string, graphs ...*graph.Graph) *graph.Graph {
+	tb.Helper()
+	g, err := graph.Merge(container, graphs...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return g
+}
+
+// syntheticGraphs returns the direct dependency graphs of n packages, each
+// importing up to deps packages before it.
+func syntheticGraphs(n, deps int) []*graph.Graph {
+	graphs := make([]*graph.Graph, n)
+	for i := range graphs {
+		src := fmt.Sprintf("pkg%d", i)
+		g := &graph.Graph{
+			Container:       src,
+			AddedContainers: map[string]struct{}{src: {}},
+		}
+		g.AddNode(graph.NodeKey{ID: src}, &graph.NodeData{Module: "m"})
+		for j := 1; j <= deps && j <= i; j++ {
+			g.AddEdge(graph.NewDirectedEdge(src, src, fmt.Sprintf("pkg%d", (i*7+j)%i)))
+		}
+		graphs[i] = g
+	}
+	return graphs
+}
+
+func TestCSRPageRank(t *testing.T) {
+	f := mustMerge(t, "root", syntheticGraphs(300, 4)...)
+	ig := f.ToImportGraph()
+	want := network.PageRank(ig.ToGonum(), 0.85, 1e-8)
+
+	c := f.CSR()
+	assertEqual(t, c.Order(), f.Order())
+	assertEqual(t, c.Size(), f.Size())
+	ranks := c.PageRank(0.85, 1e-8)
+	for _, imp := range ig.Imports() {
+		id, _ := ig.ID(imp)
+		j, ok := c.Index(imp)
+		if !ok {
+			t.Fatalf("missing %s", imp)
+		}
+		if math.Abs(ranks[j]-want[id]) > 1e-3*want[id] {
+			t.Errorf("%s: got %g, want %g", imp, ranks[j], want[id])
+		}
+	}
+}
+
+// benchSizes are the numbers of packages of the synthetic graphs benchmarked,
+// each importing up to benchDeps others.
+var benchSizes = []int{100, 1000, 10000}
+
+const benchDeps = 10
+
+func benchSized(b *testing.B, f func(b *testing.B, graphs []*graph.Graph)) {
+	for _, n := range benchSizes {
+		graphs := syntheticGraphs(n, benchDeps)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+			f(b, graphs)
+		})
+	}
+}
+
+func BenchmarkAddEdge(b *testing.B) {
+	benchSized(b, func(b *testing.B, graphs []*graph.Graph) {
+		var edges []graph.Edge
+		for _, g := range graphs {
+			for _, edge := range g.Edges {
+				edges = append(edges, edge)
+			}
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			var g graph.Graph
+			for _, edge := range edges {
+				g.AddEdge(edge)
+			}
+		}
+	})
+}
+
+func BenchmarkGraphAdd(b *testing.B) {
+	benchSized(b, func(b *testing.B, graphs []*graph.Graph) {
+		for i := 0; i < b.N; i++ {
+			g := graph.Graph{
+				Container:       "root",
+				AddedContainers: make(map[string]struct{}),
+			}
+			for _, other := range graphs {
+				if _, err := g.Add(*other); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkMerge(b *testing.B) {
+	benchSized(b, func(b *testing.B, graphs []*graph.Graph) {
+		for i := 0; i < b.N; i++ {
+			mustMerge(b, "root", graphs...)
+		}
+	})
+}
+
+func BenchmarkMergeParallel(b *testing.B) {
+	benchSized(b, func(b *testing.B, graphs []*graph.Graph) {
+		for i := 0; i < b.N; i++ {
+			if _, err := graph.MergeParallel("root", runtime.GOMAXPROCS(0), graphs...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPageRank(b *testing.B) {
+	benchSized(b, func(b *testing.B, graphs []*graph.Graph) {
+		ig := mustMerge(b, "root", graphs...).ToImportGraph()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := ig.CentralityBy(graph.PageRankCentrality); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkCSRPageRank(b *testing.B) {
+	benchSized(b, func(b *testing.B, graphs []*graph.Graph) {
+		c := mustMerge(b, "root", graphs...).CSR()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			c.PageRank(0.85, 1e-4)
+		}
+	})
+}
+
+func TestConcurrentGraph(t *testing.T) {
+	graphs := syntheticGraphs(200, 4)
+	c := graph.NewConcurrentGraph("root")
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(graphs); i += 4 {
+				c.Add(*graphs[i])
+				c.View(func(g graph.Graph) {
+					_ = g.Order()
+				})
+			}
+		}(w)
+	}
+	wg.Wait()
+	want := mustMerge(t, "root", graphs...)
+	assertEqual(t, c.Order(), want.Order())
+	assertEqual(t, c.Size(), want.Size())
+	got, _ := json.Marshal(c.Graph())
+	wantJSON, _ := json.Marshal(want)
+	assertEqual(t, string(got), string(wantJSON))
+}
+
+func TestDirectedEdgeJSON(t *testing.T) {
+	edge := graph.NewDirectedEdge("a", "a", "b")
+	edge.Kind = graph.EdgeKindTest
+	edge.Symbols = []string{"F"}
+	b, err := json.Marshal(edge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got graph.DirectedEdge
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, got.Key() == edge.Key(), true)
+	assertEqual(t, got.String(), edge.String())
+	assertEqual(t, got.Kind, edge.Kind)
+	assertEqual(t, got.Symbols, edge.Symbols)
+}
+
+func TestAllEdges(t *testing.T) {
+	g := mustMerge(t, "root", syntheticGraphs(10, 2)...)
+	n := 0
+	g.AllEdges()(func(key graph.EdgeKey, edge graph.Edge) bool {
+		assertEqual(t, edge.Key() == key, true)
+		n++
+		return true
+	})
+	assertEqual(t, n, g.Size())
+	n = 0
+	g.AllEdges()(func(graph.EdgeKey, graph.Edge) bool {
+		n++
+		return false
+	})
+	assertEqual(t, n, 1)
+}
+
+func TestIntern(t *testing.T) {
+	var in graph.Interner
+	a := in.DirectedEdge("a", "a", "b")
+	b := in.DirectedEdge("b", "b", strings.Clone("a"))
+	assertEqual(t, in.Len(), 4)
+	assertEqual(t, unsafe.StringData(a.Src.ID), unsafe.StringData(b.Dst.ID))
+	assertEqual(t, a.Key() == graph.NewDirectedEdge("a", "a", "b").Key(), true)
+
+	g := mustMerge(t, "root", syntheticGraphs(100, 3)...)
+	want, _ := json.Marshal(g)
+	g.Intern(&in)
+	got, _ := json.Marshal(g)
+	assertEqual(t, string(got), string(want))
+}
+
+func BenchmarkUnmarshalJSON(b *testing.B) {
+	data, err := json.Marshal(mustMerge(b, "root", syntheticGraphs(10000, 10)...))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var g graph.Graph
+		if err := json.Unmarshal(data, &g); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalProto(b *testing.B) {
+	data, err := mustMerge(b, "root", syntheticGraphs(10000, 10)...).MarshalProto()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var g graph.Graph
+		if err := g.UnmarshalProto(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInternGraph(b *testing.B) {
+	g := mustMerge(b, "root", syntheticGraphs(10000, 10)...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var in graph.Interner
+		g.Intern(&in)
+	}
+}
+
 func assertEqual(t *testing.T, got any, want any) {
 	t.Helper()
 	if diff := cmp.Diff(want, got); diff != "" {
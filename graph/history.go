@@ -0,0 +1,190 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arclabs561/pkgrank/cache"
+	"golang.org/x/mod/semver"
+)
+
+// historyCacheVersion is incremented whenever the graphs of revisions are
+// computed or encoded differently.
+const historyCacheVersion = 2
+
+// Tags returns the semantic version tags of the git repository of dir from
+// from to to, both inclusive and either unbounded if empty, oldest first.
+func Tags(dir, from, to string) ([]string, error) {
+	for _, v := range []string{from, to} {
+		if v != "" && !semver.IsValid(v) {
+			return nil, fmt.Errorf("invalid tag %q, must be a semantic version", v)
+		}
+	}
+	out, err := doExec(execQuiet, dir, nil, "git", "tag", "--list")
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, tag := range strings.Fields(out) {
+		if !semver.IsValid(tag) ||
+			from != "" && semver.Compare(tag, from) < 0 ||
+			to != "" && semver.Compare(tag, to) > 0 {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return semver.Compare(tags[i], tags[j]) < 0
+	})
+	return tags, nil
+}
+
+// Revision is the graph of a module at a revision of its git repository.
+type Revision struct {
+	Rev    string
+	Commit string
+	// Time is when Commit was made.
+	Time  time.Time
+	Graph *Graph
+}
+
+// ReadRevision returns the graph of the module in dir, as ListModule, at a
+// revision of its git repository, which is checked out in a temporary
+// worktree so that dir is left untouched. If c is not nil, graphs are cached
+// by commit.
+func ReadRevision(dir, rev string, c *cache.Cache) (*Revision, error) {
+	out, err := doExec(execQuiet, dir, nil, "git", "log", "-1", "--format=%H %cI", rev+"^{commit}", "--")
+	if err != nil {
+		return nil, err
+	}
+	r := &Revision{Rev: rev}
+	commit, date, _ := strings.Cut(out, " ")
+	r.Commit = commit
+	if r.Time, err = time.Parse(time.RFC3339, date); err != nil {
+		return nil, fmt.Errorf("invalid commit date of %s: %w", rev, err)
+	}
+	prefix, err := doExec(execQuiet, dir, nil, "git", "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	h := cache.NewHash("history", historyCacheVersion)
+	h.String(commit)
+	h.String(prefix)
+	key := h.Sum()
+	if c != nil {
+		if data, ok := c.Get(key); ok {
+			var g Graph
+			if err := g.UnmarshalProto(data); err == nil {
+				r.Graph = &g
+				return r, nil
+			}
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "*-pkgrank-history")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	worktree := filepath.Join(tmp, "worktree")
+	if _, err := doExec(execQuiet, dir, nil, "git", "worktree", "add", "--detach", worktree, commit); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_, _ = doExec(execQuiet, dir, nil, "git", "worktree", "remove", "--force", worktree)
+	}()
+	g, _, err := ListModule(filepath.Join(worktree, filepath.FromSlash(prefix)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list module at %s: %w", rev, err)
+	}
+	if c != nil {
+		// Failing to cache only costs listing the revision again.
+		if data, err := g.MarshalProto(); err == nil {
+			_ = c.Put(key, data)
+		}
+	}
+	r.Graph = g
+	return r, nil
+}
+
+// HistoryPoint is a summary of the graph of a module at a revision.
+type HistoryPoint struct {
+	Rev    string    `json:"rev"`
+	Commit string    `json:"commit"`
+	Time   time.Time `json:"time"`
+	Order  int       `json:"order"`
+	Size   int       `json:"size"`
+	Cycles int       `json:"cycles"`
+	// AddedNodes through RemovedEdges count the nodes and edges added and
+	// removed since the previous point, as Graph.Diff.
+	AddedNodes   int `json:"addedNodes"`
+	RemovedNodes int `json:"removedNodes"`
+	AddedEdges   int `json:"addedEdges"`
+	RemovedEdges int `json:"removedEdges"`
+	// Ranks are the PageRank centralities of the nodes.
+	Ranks map[NodeKey]float64 `json:"ranks"`
+}
+
+// History summarizes the graphs of a module at successive revisions, each
+// compared to the one before it.
+func History(revs []*Revision) []HistoryPoint {
+	points := make([]HistoryPoint, len(revs))
+	for i, r := range revs {
+		g := r.Graph
+		p := HistoryPoint{
+			Rev:    r.Rev,
+			Commit: r.Commit,
+			Time:   r.Time,
+			Order:  g.Order(),
+			Size:   g.Size(),
+			Cycles: g.Stats().Cycles,
+			Ranks:  g.pageRank(),
+		}
+		if i > 0 {
+			d := revs[i-1].Graph.Diff(*g)
+			p.AddedNodes, p.RemovedNodes = len(d.AddedNodes), len(d.RemovedNodes)
+			p.AddedEdges, p.RemovedEdges = len(d.AddedEdges), len(d.RemovedEdges)
+		}
+		points[i] = p
+	}
+	return points
+}
+
+// TopMovers returns the n nodes whose rank changed the most from the first
+// to the last point, all of them if n is not positive, largest absolute
+// change first. Nodes missing from a point have a rank of 0 there.
+func TopMovers(points []HistoryPoint, n int) []RankShift {
+	if len(points) == 0 {
+		return nil
+	}
+	first, last := points[0].Ranks, points[len(points)-1].Ranks
+	nodes := make(map[NodeKey]bool, len(last))
+	for key := range first {
+		nodes[key] = true
+	}
+	for key := range last {
+		nodes[key] = true
+	}
+	var shifts []RankShift
+	for key := range nodes {
+		if s := (RankShift{Node: key, Before: first[key], After: last[key]}); s.Delta() != 0 {
+			shifts = append(shifts, s)
+		}
+	}
+	sort.Slice(shifts, func(i, j int) bool {
+		di, dj := math.Abs(shifts[i].Delta()), math.Abs(shifts[j].Delta())
+		if di != dj {
+			return di > dj
+		}
+		return shifts[i].Node.ID < shifts[j].Node.ID
+	})
+	if n > 0 && n < len(shifts) {
+		shifts = shifts[:n]
+	}
+	return shifts
+}
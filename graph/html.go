@@ -0,0 +1,79 @@
+package graph
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+)
+
+//go:embed html.tmpl
+var htmlTemplate string
+
+var htmlTmpl = template.Must(template.New("html").Parse(htmlTemplate))
+
+type htmlNode struct {
+	ID     string  `json:"id"`
+	Module string  `json:"module"`
+	Rank   float64 `json:"rank"`
+	Attrs  Attrs   `json:"attrs,omitempty"`
+}
+
+type htmlEdge struct {
+	Src  string `json:"src"`
+	Dst  string `json:"dst"`
+	Test bool   `json:"test,omitempty"`
+}
+
+type htmlGraph struct {
+	Nodes []htmlNode `json:"nodes"`
+	Edges []htmlEdge `json:"edges"`
+}
+
+// WriteHTML writes the graph to w as a self-contained HTML page, which lays
+// it out interactively in the browser, with search and highlighting of the
+// neighborhood of a package. Nodes are sized by their PageRank and colored by
+// their module.
+func WriteHTML(w io.Writer, g *Graph) error {
+	ranks := g.pageRank()
+	doc := htmlGraph{
+		Nodes: make([]htmlNode, 0, len(g.Nodes)),
+		Edges: make([]htmlEdge, 0, len(g.Edges)),
+	}
+	for key, node := range g.Nodes {
+		n := htmlNode{ID: key.ID, Rank: ranks[key]}
+		if node.Data != nil {
+			n.Module = node.Data.Module
+			n.Attrs = node.Data.Attrs
+		}
+		doc.Nodes = append(doc.Nodes, n)
+	}
+	sort.Slice(doc.Nodes, func(i, j int) bool {
+		return doc.Nodes[i].ID < doc.Nodes[j].ID
+	})
+	for _, edge := range g.Edges {
+		edge, ok := edge.(*DirectedEdge)
+		if !ok {
+			return fmt.Errorf("unsupported edge type: %T", edge)
+		}
+		doc.Edges = append(doc.Edges, htmlEdge{
+			Src:  edge.Src.ID,
+			Dst:  edge.Dst.ID,
+			Test: edge.Kind.Has(EdgeKindTest),
+		})
+	}
+	sort.Slice(doc.Edges, func(i, j int) bool {
+		if doc.Edges[i].Src != doc.Edges[j].Src {
+			return doc.Edges[i].Src < doc.Edges[j].Src
+		}
+		return doc.Edges[i].Dst < doc.Edges[j].Dst
+	})
+	return htmlTmpl.Execute(w, struct {
+		Title string
+		Graph htmlGraph
+	}{
+		Title: g.Container,
+		Graph: doc,
+	})
+}
@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"math"
+	"sort"
+)
+
+// Impact is the result of simulating the removal of nodes and edges from a
+// graph, relative to a root node.
+type Impact struct {
+	// Before and After are the number of nodes reachable from the root
+	// before and after the removal.
+	Before, After int
+	// Unreachable are the sorted nodes that were reachable from the root,
+	// but no longer are, including any removed nodes.
+	Unreachable []NodeKey
+	// RankShifts are the changes in PageRank centrality of every node which
+	// remains in the graph, largest absolute change first.
+	RankShifts []RankShift
+}
+
+// RankShift is the change in centrality of a node.
+type RankShift struct {
+	Node          NodeKey
+	Before, After float64
+}
+
+// Delta returns the change in centrality.
+func (s RankShift) Delta() float64 {
+	return s.After - s.Before
+}
+
+// Without returns a copy of the graph without the given nodes, the edges
+// between the given pairs of nodes, and any edges incident to removed nodes.
+func (f Graph) Without(nodes []NodeKey, edges [][2]NodeKey) *Graph {
+	removedNodes := make(map[NodeKey]bool, len(nodes))
+	for _, n := range nodes {
+		removedNodes[n] = true
+	}
+	removedEdges := make(map[[2]NodeKey]bool, len(edges))
+	for _, e := range edges {
+		removedEdges[e] = true
+	}
+	g := &Graph{
+		Container:       f.Container,
+		AddedContainers: make(map[string]struct{}, len(f.AddedContainers)),
+	}
+	for c := range f.AddedContainers {
+		g.AddedContainers[c] = struct{}{}
+	}
+	for key, node := range f.Nodes {
+		if !removedNodes[key] {
+			g.AddNode(key, node.Data)
+		}
+	}
+	for key, edge := range f.Edges {
+		removed := false
+		for _, n := range edge.Nodes() {
+			removed = removed || removedNodes[n]
+		}
+		if e, ok := edge.(*DirectedEdge); ok && removedEdges[[2]NodeKey{e.Src, e.Dst}] {
+			removed = true
+		}
+		if !removed {
+			if g.Edges == nil {
+				g.Edges = make(map[EdgeKey]Edge)
+			}
+			g.Edges[key] = edge
+		}
+	}
+	return g
+}
+
+// Impact simulates removing the given nodes and edges, see Without, and
+// reports which nodes become unreachable from root and how ranks shift.
+func (f Graph) Impact(root NodeKey, nodes []NodeKey, edges [][2]NodeKey) Impact {
+	g := f.Without(nodes, edges)
+	before := f.Descendants(root)
+	after := make(map[NodeKey]bool)
+	for _, n := range g.Descendants(root) {
+		after[n] = true
+	}
+	impact := Impact{Before: len(before), After: len(after)}
+	for _, n := range before {
+		if !after[n] {
+			impact.Unreachable = append(impact.Unreachable, n)
+		}
+	}
+	ranksBefore, ranksAfter := f.pageRank(), g.pageRank()
+	for n, after := range ranksAfter {
+		impact.RankShifts = append(impact.RankShifts, RankShift{
+			Node:   n,
+			Before: ranksBefore[n],
+			After:  after,
+		})
+	}
+	sort.Slice(impact.RankShifts, func(i, j int) bool {
+		di := math.Abs(impact.RankShifts[i].Delta())
+		dj := math.Abs(impact.RankShifts[j].Delta())
+		if di != dj {
+			return di > dj
+		}
+		return impact.RankShifts[i].Node.ID < impact.RankShifts[j].Node.ID
+	})
+	return impact
+}
+
+// pageRank returns the PageRank centrality of every node with an edge.
+func (f Graph) pageRank() map[NodeKey]float64 {
+	imps, scores := f.ToImportGraph().Centrality()
+	ranks := make(map[NodeKey]float64, len(imps))
+	for i, imp := range imps {
+		ranks[NodeKey{ID: imp}] = scores[i]
+	}
+	return ranks
+}
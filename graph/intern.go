@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"sync"
+)
+
+// Interner deduplicates strings, so that the node IDs and containers repeated
+// across the edges of a graph share their memory. The zero value is ready to
+// use, a nil Interner returns strings unchanged, and it is safe for concurrent
+// use. Interned strings are kept for the lifetime of the Interner, so it
+// should be scoped to the graphs it serves.
+type Interner struct {
+	mu      sync.RWMutex
+	strings map[string]string
+}
+
+// String returns the interned string equal to s.
+func (in *Interner) String(s string) string {
+	if in == nil {
+		return s
+	}
+	in.mu.RLock()
+	interned, ok := in.strings[s]
+	in.mu.RUnlock()
+	if ok {
+		return interned
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if interned, ok := in.strings[s]; ok {
+		return interned
+	}
+	if in.strings == nil {
+		in.strings = make(map[string]string)
+	}
+	in.strings[s] = s
+	return s
+}
+
+// Len returns the number of interned strings.
+func (in *Interner) Len() int {
+	if in == nil {
+		return 0
+	}
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.strings)
+}
+
+// NodeKey returns a key of the node with the given interned ID.
+func (in *Interner) NodeKey(id string) NodeKey {
+	return NodeKey{ID: in.String(id)}
+}
+
+// DirectedEdge is like NewDirectedEdge, but with its key and nodes interned.
+func (in *Interner) DirectedEdge(container, srcID, dstID string) *DirectedEdge {
+	e := NewDirectedEdge(in.String(container), in.String(srcID), in.String(dstID))
+	e.EdgeKey.id = in.String(e.EdgeKey.id)
+	return e
+}
+
+// Intern replaces the keys of the graph's nodes and directed edges with
+// interned ones, so that equal strings share memory.
+func (f *Graph) Intern(in *Interner) {
+	f.Container = in.String(f.Container)
+	if f.Nodes != nil {
+		nodes := make(map[NodeKey]Node, len(f.Nodes))
+		for key, node := range f.Nodes {
+			key = in.NodeKey(key.ID)
+			node.NodeKey = key
+			nodes[key] = node
+		}
+		f.Nodes = nodes
+	}
+	if f.Edges != nil {
+		edges := make(map[EdgeKey]Edge, len(f.Edges))
+		for key, edge := range f.Edges {
+			key = EdgeKey{container: in.String(key.container), id: in.String(key.id)}
+			if e, ok := edge.(*DirectedEdge); ok {
+				e.EdgeKey = key
+				e.Src = in.NodeKey(e.Src.ID)
+				e.Dst = in.NodeKey(e.Dst.ID)
+			}
+			edges[key] = edge
+		}
+		f.Edges = edges
+		f.adj = nil
+	}
+}
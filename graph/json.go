@@ -0,0 +1,204 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// JSONVersion is the version of the JSON document produced by
+// Graph.MarshalJSON. It is incremented on incompatible changes.
+const JSONVersion = 1
+
+type jsonGraph struct {
+	Version   int        `json:"version"`
+	Container string     `json:"container"`
+	Nodes     []jsonNode `json:"nodes"`
+	Edges     []jsonEdge `json:"edges"`
+}
+
+type jsonNode struct {
+	ID         string `json:"id"`
+	Module     string `json:"module,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Types      int    `json:"types,omitempty"`
+	Interfaces int    `json:"interfaces,omitempty"`
+	Attrs      Attrs  `json:"attrs,omitempty"`
+}
+
+type jsonEdge struct {
+	Container  string       `json:"container"`
+	Src        string       `json:"src"`
+	Dst        string       `json:"dst"`
+	Weight     float64      `json:"weight"`
+	Symbols    []string     `json:"symbols,omitempty"`
+	Kind       EdgeKind     `json:"kind,omitempty"`
+	Configs    []string     `json:"configs,omitempty"`
+	Provenance []Provenance `json:"provenance,omitempty"`
+	Attrs      Attrs        `json:"attrs,omitempty"`
+}
+
+var (
+	_ json.Marshaler   = Graph{}
+	_ json.Unmarshaler = (*Graph)(nil)
+)
+
+// MarshalJSON encodes the graph as a versioned JSON document, with nodes and
+// edges sorted by key. Only directed edges are supported.
+func (f Graph) MarshalJSON() ([]byte, error) {
+	doc, err := f.document()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// document returns the graph's document, with nodes and edges sorted by key,
+// as encoded by MarshalJSON and MarshalProto.
+func (f Graph) document() (jsonGraph, error) {
+	doc := jsonGraph{
+		Version:   JSONVersion,
+		Container: f.Container,
+		Nodes:     make([]jsonNode, 0, len(f.Nodes)),
+		Edges:     make([]jsonEdge, 0, len(f.Edges)),
+	}
+	for key, node := range f.Nodes {
+		n := jsonNode{ID: key.ID}
+		if node.Data != nil {
+			n.Module = node.Data.Module
+			n.Version = node.Data.Version
+			n.Types = node.Data.Types
+			n.Interfaces = node.Data.Interfaces
+			n.Attrs = node.Data.Attrs
+		}
+		doc.Nodes = append(doc.Nodes, n)
+	}
+	sort.Slice(doc.Nodes, func(i, j int) bool {
+		return doc.Nodes[i].ID < doc.Nodes[j].ID
+	})
+	for _, edge := range f.Edges {
+		edge, ok := edge.(*DirectedEdge)
+		if !ok {
+			return jsonGraph{}, fmt.Errorf("unsupported edge type: %T", edge)
+		}
+		doc.Edges = append(doc.Edges, newJSONEdge(edge))
+	}
+	sort.Slice(doc.Edges, func(i, j int) bool {
+		if doc.Edges[i].Container != doc.Edges[j].Container {
+			return doc.Edges[i].Container < doc.Edges[j].Container
+		}
+		if doc.Edges[i].Src != doc.Edges[j].Src {
+			return doc.Edges[i].Src < doc.Edges[j].Src
+		}
+		return doc.Edges[i].Dst < doc.Edges[j].Dst
+	})
+	return doc, nil
+}
+
+// UnmarshalJSON decodes a graph from the JSON document produced by
+// MarshalJSON, replacing the graph's contents.
+func (f *Graph) UnmarshalJSON(b []byte) error {
+	var doc jsonGraph
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	if doc.Version != JSONVersion {
+		return fmt.Errorf("unsupported graph json version: %d", doc.Version)
+	}
+	return f.setDocument(doc)
+}
+
+// setDocument replaces the graph's contents with those of a document. Its
+// malformed edges and nodes are left out, and reported together as a
+// *ParseError.
+func (f *Graph) setDocument(doc jsonGraph) error {
+	*f = Graph{
+		Container:       doc.Container,
+		AddedContainers: make(map[string]struct{}),
+	}
+	// Node IDs and containers repeat across edges, so share their memory.
+	var in Interner
+	parseErr := ParseError{Source: "graph document"}
+	for i, e := range doc.Edges {
+		edge, err := e.directedEdge(&in)
+		if err != nil {
+			parseErr.add(fmt.Errorf("edge %d: %w", i, err))
+			continue
+		}
+		if _, err := f.AddEdge(edge); err != nil {
+			return err
+		}
+		f.AddedContainers[edge.Key().container] = struct{}{}
+	}
+	for i, n := range doc.Nodes {
+		if n.ID == "" {
+			parseErr.add(fmt.Errorf("node %d: empty id", i))
+			continue
+		}
+		var data *NodeData
+		if n.Module != "" || n.Version != "" || n.Types != 0 || len(n.Attrs) > 0 {
+			data = &NodeData{
+				Module:     n.Module,
+				Version:    n.Version,
+				Types:      n.Types,
+				Interfaces: n.Interfaces,
+				Attrs:      n.Attrs,
+			}
+		}
+		f.AddNode(in.NodeKey(n.ID), data)
+	}
+	return parseErr.err()
+}
+
+func newJSONEdge(edge *DirectedEdge) jsonEdge {
+	return jsonEdge{
+		Container:  edge.Key().container,
+		Src:        edge.Src.ID,
+		Dst:        edge.Dst.ID,
+		Weight:     edge.Weight(),
+		Symbols:    edge.Symbols,
+		Kind:       edge.Kind,
+		Configs:    edge.Configs,
+		Provenance: edge.Provenance,
+		Attrs:      edge.Attrs,
+	}
+}
+
+func (e jsonEdge) directedEdge(in *Interner) (*DirectedEdge, error) {
+	edge := in.DirectedEdge(e.Container, e.Src, e.Dst)
+	edge.EdgeWeight = e.Weight
+	edge.Symbols = e.Symbols
+	edge.Kind = e.Kind
+	edge.Configs = e.Configs
+	edge.Provenance = e.Provenance
+	edge.Attrs = e.Attrs
+	if err := edge.Valid(); err != nil {
+		return nil, fmt.Errorf("invalid edge %v: %w", edge, err)
+	}
+	return edge, nil
+}
+
+var (
+	_ json.Marshaler   = (*DirectedEdge)(nil)
+	_ json.Unmarshaler = (*DirectedEdge)(nil)
+)
+
+// MarshalJSON encodes the edge as in the edges of a graph's JSON document, so
+// that edges can be streamed one at a time.
+func (e *DirectedEdge) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newJSONEdge(e))
+}
+
+// UnmarshalJSON decodes an edge from the JSON produced by MarshalJSON.
+func (e *DirectedEdge) UnmarshalJSON(b []byte) error {
+	var doc jsonEdge
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	edge, err := doc.directedEdge(nil)
+	if err != nil {
+		return err
+	}
+	*e = *edge
+	return nil
+}
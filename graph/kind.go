@@ -0,0 +1,167 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// EdgeKind is a set of classifications of an edge. The zero value is a plain
+// production edge, between packages of the analyzed modules written by hand.
+type EdgeKind uint
+
+// Available edge kinds.
+const (
+	// EdgeKindTest marks an edge only present in test files.
+	EdgeKindTest EdgeKind = 1 << iota
+	// EdgeKindBlank marks an edge only introduced by blank imports, e.g.
+	// for side effects such as driver registration.
+	EdgeKindBlank
+	// EdgeKindDot marks an edge only introduced by dot imports.
+	EdgeKindDot
+	// EdgeKindGenerated marks an edge only introduced by generated files,
+	// those with a "// Code generated ... DO NOT EDIT." comment.
+	EdgeKindGenerated
+	// EdgeKindVendored marks an edge to a package of a vendor directory.
+	EdgeKindVendored
+	// EdgeKindStdlib marks an edge to a package of the standard library.
+	EdgeKindStdlib
+)
+
+var edgeKindNames = []struct {
+	kind EdgeKind
+	name string
+}{
+	{EdgeKindTest, "test"},
+	{EdgeKindBlank, "blank"},
+	{EdgeKindDot, "dot"},
+	{EdgeKindGenerated, "generated"},
+	{EdgeKindVendored, "vendored"},
+	{EdgeKindStdlib, "stdlib"},
+}
+
+// ImportKind returns the kinds of an edge introduced by the given imports,
+// which is EdgeKindBlank or EdgeKindDot if all of them are of that style.
+func ImportKind(provenance []Provenance) EdgeKind {
+	if len(provenance) == 0 {
+		return 0
+	}
+	kind := EdgeKindBlank | EdgeKindDot
+	for _, p := range provenance {
+		switch p.Style {
+		case ImportStyleBlank:
+			kind &= EdgeKindBlank
+		case ImportStyleDot:
+			kind &= EdgeKindDot
+		default:
+			kind = 0
+		}
+	}
+	return kind
+}
+
+// GeneratedKind returns EdgeKindGenerated if all of the given imports are in
+// generated files, those whose identifiers are in generated.
+func GeneratedKind(provenance []Provenance, generated map[string]bool) EdgeKind {
+	if len(provenance) == 0 {
+		return 0
+	}
+	for _, p := range provenance {
+		if !generated[p.File] {
+			return 0
+		}
+	}
+	return EdgeKindGenerated
+}
+
+// PackageKind returns the kinds of an edge to the package with the given
+// import path in dir, which is empty if unknown: EdgeKindStdlib for the
+// standard library, whose paths have no dot in their first element, and
+// EdgeKindVendored for a package in a vendor directory.
+func PackageKind(path, dir string) EdgeKind {
+	var kind EdgeKind
+	if first, _, _ := strings.Cut(path, "/"); !strings.Contains(first, ".") && path != "C" {
+		kind |= EdgeKindStdlib
+	}
+	if InVendor(path) || dir != "" && InVendor(filepath.ToSlash(dir)) {
+		kind |= EdgeKindVendored
+	}
+	return kind
+}
+
+// InVendor reports whether the slash-separated path has a vendor element
+// followed by others, like the directory of a vendored package.
+func InVendor(path string) bool {
+	elems := strings.Split(path, "/")
+	for _, elem := range elems[:len(elems)-1] {
+		if elem == "vendor" {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether k includes all of the kinds in other.
+func (k EdgeKind) Has(other EdgeKind) bool {
+	return k&other == other
+}
+
+// Names returns the names of the kinds in k.
+func (k EdgeKind) Names() []string {
+	var names []string
+	for _, n := range edgeKindNames {
+		if k.Has(n.kind) {
+			names = append(names, n.name)
+		}
+	}
+	return names
+}
+
+func (k EdgeKind) String() string {
+	return strings.Join(k.Names(), ",")
+}
+
+// ParseEdgeKind returns the EdgeKind with the given comma separated names.
+func ParseEdgeKind(s string) (EdgeKind, error) {
+	var k EdgeKind
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, n := range edgeKindNames {
+			if n.name == name {
+				k |= n.kind
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown edge kind: %q", name)
+		}
+	}
+	return k, nil
+}
+
+func (k EdgeKind) MarshalJSON() ([]byte, error) {
+	names := k.Names()
+	if names == nil {
+		names = []string{}
+	}
+	return json.Marshal(names)
+}
+
+func (k *EdgeKind) UnmarshalJSON(b []byte) error {
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return err
+	}
+	kind, err := ParseEdgeKind(strings.Join(names, ","))
+	if err != nil {
+		return err
+	}
+	*k = kind
+	return nil
+}
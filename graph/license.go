@@ -0,0 +1,272 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	"golang.org/x/mod/module"
+)
+
+// LicenseUnknown is the license of modules with license files that are not
+// recognized.
+const LicenseUnknown = "unknown"
+
+// licensePatterns identify licenses by distinctive phrases of their text, in
+// order of precedence, since some licenses quote others.
+var licensePatterns = []struct {
+	id string
+	re *regexp.Regexp
+}{
+	{"AGPL-3.0", regexp.MustCompile(`(?i)GNU AFFERO GENERAL PUBLIC LICENSE`)},
+	{"LGPL-3.0", regexp.MustCompile(`(?is)GNU LESSER GENERAL PUBLIC LICENSE\s+Version 3`)},
+	{"LGPL-2.1", regexp.MustCompile(`(?is)GNU LESSER GENERAL PUBLIC LICENSE\s+Version 2\.1`)},
+	{"GPL-3.0", regexp.MustCompile(`(?is)GNU GENERAL PUBLIC LICENSE\s+Version 3`)},
+	{"GPL-2.0", regexp.MustCompile(`(?is)GNU GENERAL PUBLIC LICENSE\s+Version 2`)},
+	{"MPL-2.0", regexp.MustCompile(`(?i)Mozilla Public License,? Version 2\.0`)},
+	{"EPL-2.0", regexp.MustCompile(`(?i)Eclipse Public License - v 2\.0`)},
+	{"Apache-2.0", regexp.MustCompile(`(?is)Apache License,?\s+Version 2\.0`)},
+	{"BSD-3-Clause", regexp.MustCompile(`(?is)Redistribution and use in source and binary forms.*Neither the name`)},
+	{"BSD-2-Clause", regexp.MustCompile(`(?i)Redistribution and use in source and binary forms`)},
+	{"MIT", regexp.MustCompile(`(?i)Permission is hereby granted, free of charge`)},
+	{"ISC", regexp.MustCompile(`(?i)Permission to use, copy, modify, and(/or)? distribute this software for any`)},
+	{"Unlicense", regexp.MustCompile(`(?i)This is free and unencumbered software released into the public domain`)},
+	{"CC0-1.0", regexp.MustCompile(`(?i)CC0 1\.0 Universal`)},
+}
+
+// IdentifyLicense returns the SPDX identifier of the license of the given
+// text, or LicenseUnknown if it is not recognized.
+func IdentifyLicense(text string) string {
+	for _, p := range licensePatterns {
+		if p.re.MatchString(text) {
+			return p.id
+		}
+	}
+	return LicenseUnknown
+}
+
+// isLicenseFile reports whether name is the name of a license file, like
+// LICENSE, LICENSE-MIT, COPYING or UNLICENSE.
+func isLicenseFile(name string) bool {
+	name = strings.ToUpper(name)
+	for _, prefix := range []string{"LICENSE", "LICENCE", "COPYING", "UNLICENSE"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectLicense returns the SPDX license expression of the module in dir,
+// from the license files at its root, or an empty string if there are none.
+// Several license files, as of dual-licensed modules, are alternatives.
+func DetectLicense(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() || !isLicenseFile(e.Name()) {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return "", err
+		}
+		ids = append(ids, IdentifyLicense(string(b)))
+	}
+	ids = lo.Uniq(ids)
+	if len(ids) > 1 {
+		// A recognized license says more than an unknown one.
+		ids = lo.Without(ids, LicenseUnknown)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, " OR "), nil
+}
+
+// AddLicenses sets the AttrLicense attribute of nodes to the license of
+// their module, detected from its license files in the module cache, or in
+// GOROOT for the standard library. Nodes of modules that are not there, like
+// the main module, or whose license is not recognized keep any license they
+// already have, e.g. from deps.dev.
+func (f *Graph) AddLicenses() error {
+	env, err := doExec(execQuiet, "", nil, "go", "env", "-json", "GOMODCACHE", "GOROOT")
+	if err != nil {
+		return err
+	}
+	var goEnv struct{ GOMODCACHE, GOROOT string }
+	if err := json.Unmarshal([]byte(env), &goEnv); err != nil {
+		return fmt.Errorf("failed to decode go env output: %w", err)
+	}
+	licenses := make(map[string]string)
+	for _, key := range f.SortedNodes() {
+		data := f.Nodes[key].Data
+		if data == nil || data.Module == "" {
+			continue
+		}
+		mv := data.ModuleVersion()
+		license, ok := licenses[mv]
+		if !ok {
+			dir, err := moduleDir(goEnv.GOMODCACHE, goEnv.GOROOT, data.Module, data.Version)
+			if err == nil && dir != "" {
+				license, err = DetectLicense(dir)
+			}
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to detect license of %s: %w", mv, err)
+			}
+			licenses[mv] = license
+		}
+		if _, known := data.Attrs.String(AttrLicense); license == "" || license == LicenseUnknown && known {
+			continue
+		}
+		f.SetNodeAttrs(key, func(a *Attrs) {
+			a.SetString(AttrLicense, license)
+		})
+	}
+	return nil
+}
+
+// moduleDir returns the directory of a module version, or an empty string if
+// it is not known.
+func moduleDir(modCache, goRoot, path, version string) (string, error) {
+	switch {
+	case path == "std":
+		return goRoot, nil
+	case version == "":
+		return "", nil
+	}
+	escPath, err := module.EscapePath(path)
+	if err != nil {
+		return "", err
+	}
+	escVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(modCache, escPath+"@"+escVersion), nil
+}
+
+// LicensePolicy declares which licenses may enter the dependency tree.
+type LicensePolicy struct {
+	// Allow are the only allowed licenses, if any.
+	Allow []string `json:"allow,omitempty"`
+	// Deny are disallowed licenses.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// DefaultLicensePolicyFile is the name of the license policy file that is used
+// if no other is given.
+const DefaultLicensePolicyFile = ".pkgrank-licenses.json"
+
+// LoadLicensePolicy reads a license policy from the given JSON file.
+func LoadLicensePolicy(name string) (*LicensePolicy, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var p LicensePolicy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode license policy %s: %w", name, err)
+	}
+	return &p, nil
+}
+
+// Allowed reports whether the policy allows a license expression. An
+// expression of alternatives joined by OR is allowed if any alternative is,
+// and one of licenses joined by AND if all of them are.
+func (p LicensePolicy) Allowed(license string) bool {
+	license = strings.NewReplacer("(", "", ")", "").Replace(license)
+	for _, alt := range strings.Split(license, " OR ") {
+		if lo.EveryBy(strings.Split(alt, " AND "), func(id string) bool {
+			id = strings.TrimSpace(id)
+			return !lo.Contains(p.Deny, id) && (len(p.Allow) == 0 || lo.Contains(p.Allow, id))
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// ModuleLicense is a module that brings a license into the tree of a root.
+type ModuleLicense struct {
+	Module  string `json:"module"`
+	Version string `json:"version,omitempty"`
+	License string `json:"license"`
+	// Path is a shortest import path from the root to a package of the
+	// module, through which the license enters the tree.
+	Path Path `json:"path"`
+}
+
+// Licenses returns the modules that root imports, directly or not, with the
+// licenses set by AddLicenses or deps.dev, sorted by license and module.
+// Modules without any are listed with LicenseUnknown.
+func (f Graph) Licenses(root NodeKey) []ModuleLicense {
+	depths := f.depths(root)
+	byModule := make(map[string]NodeKey)
+	for key, depth := range depths {
+		data := f.Nodes[key].Data
+		if data == nil || data.Module == "" {
+			continue
+		}
+		mv := data.ModuleVersion()
+		if prev, ok := byModule[mv]; !ok || depth < depths[prev] || depth == depths[prev] && key.ID < prev.ID {
+			byModule[mv] = key
+		}
+	}
+	licenses := make([]ModuleLicense, 0, len(byModule))
+	for _, key := range byModule {
+		data := f.Nodes[key].Data
+		license, ok := data.Attrs.String(AttrLicense)
+		if !ok || license == "" {
+			license = LicenseUnknown
+		}
+		path, _ := f.ShortestPath(root, key)
+		if key == root {
+			path = Path{Nodes: []NodeKey{root}}
+		}
+		licenses = append(licenses, ModuleLicense{
+			Module:  data.Module,
+			Version: data.Version,
+			License: license,
+			Path:    path,
+		})
+	}
+	sort.Slice(licenses, func(i, j int) bool {
+		if licenses[i].License != licenses[j].License {
+			return licenses[i].License < licenses[j].License
+		}
+		if licenses[i].Module != licenses[j].Module {
+			return licenses[i].Module < licenses[j].Module
+		}
+		return licenses[i].Version < licenses[j].Version
+	})
+	return licenses
+}
+
+// LicenseFindings returns a finding for each of the module licenses that the
+// policy does not allow, about the import path through which it enters.
+func LicenseFindings(licenses []ModuleLicense, p LicensePolicy) []Finding {
+	var findings []Finding
+	for _, l := range licenses {
+		if p.Allowed(l.License) {
+			continue
+		}
+		mv := l.Module
+		if l.Version != "" {
+			mv += "@" + l.Version
+		}
+		findings = append(findings, Finding{
+			Rule:    RuleLicense,
+			Level:   LevelError,
+			Message: fmt.Sprintf("%s is licensed under %s, which the license policy does not allow", mv, l.License),
+			Nodes:   l.Path.Nodes,
+		})
+	}
+	return findings
+}
@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"math"
+)
+
+// PackageMetrics are Robert Martin's package coupling metrics of a node.
+type PackageMetrics struct {
+	// Afferent coupling, Ca, is the number of nodes depending on the node.
+	Afferent int `json:"afferent"`
+	// Efferent coupling, Ce, is the number of nodes the node depends on.
+	Efferent int `json:"efferent"`
+	// Instability, I = Ce / (Ca + Ce), is 0 for a maximally stable node
+	// and 1 for a maximally unstable one.
+	Instability float64 `json:"instability"`
+	// Abstractness, A, is the ratio of interfaces to all named types.
+	Abstractness float64 `json:"abstractness"`
+	// Distance from the main sequence, D = |A + I - 1|.
+	Distance float64 `json:"distance"`
+}
+
+// PackageMetrics returns the coupling metrics of every node. Abstractness is
+// only known for nodes whose NodeData counts their types.
+func (f Graph) PackageMetrics() map[NodeKey]PackageMetrics {
+	succs := f.successors()
+	preds := f.predecessors()
+	metrics := make(map[NodeKey]PackageMetrics, len(f.Nodes))
+	for key, node := range f.Nodes {
+		m := PackageMetrics{
+			Afferent: len(preds[key]),
+			Efferent: len(succs[key]),
+		}
+		if total := m.Afferent + m.Efferent; total > 0 {
+			m.Instability = float64(m.Efferent) / float64(total)
+		}
+		if node.Data != nil && node.Data.Types > 0 {
+			m.Abstractness = float64(node.Data.Interfaces) / float64(node.Data.Types)
+		}
+		m.Distance = math.Abs(m.Abstractness + m.Instability - 1)
+		metrics[key] = m
+	}
+	return metrics
+}
+
+// TransitiveAttr returns the sum of the numeric attribute key over each node
+// and the nodes that it reaches, such as the lines of code that a package
+// pulls in with AttrLOC, or nil if no node has the attribute.
+func (f Graph) TransitiveAttr(key string) map[NodeKey]float64 {
+	own := make(map[NodeKey]float64)
+	for n, node := range f.Nodes {
+		if node.Data == nil {
+			continue
+		}
+		if v, ok := node.Data.Attrs.Float(key); ok {
+			own[n] = v
+		}
+	}
+	if len(own) == 0 {
+		return nil
+	}
+	succs := f.successors()
+	sums := make(map[NodeKey]float64, len(f.Nodes))
+	for n := range f.Nodes {
+		sum := own[n]
+		for _, m := range reachable(succs, n) {
+			if m != n {
+				sum += own[m]
+			}
+		}
+		sums[n] = sum
+	}
+	return sums
+}
@@ -0,0 +1,161 @@
+package graph
+
+import "sort"
+
+// ModuleHyperEdges returns a hyperedge for each module of the nodes of the
+// graph, connecting all of its packages, sorted by module. The container of
+// each hyperedge is the path of its module. Nodes of unknown modules are left
+// out.
+func (f Graph) ModuleHyperEdges() []*HyperEdge {
+	pkgs := make(map[string][]string)
+	for key, node := range f.Nodes {
+		if node.Data == nil || node.Data.Module == "" {
+			continue
+		}
+		pkgs[node.Data.Module] = append(pkgs[node.Data.Module], key.ID)
+	}
+	modules := make([]string, 0, len(pkgs))
+	for module := range pkgs {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	edges := make([]*HyperEdge, len(modules))
+	for i, module := range modules {
+		edges[i] = NewHyperEdge(module, pkgs[module]...)
+	}
+	return edges
+}
+
+// AddModuleHyperEdges adds the module hyperedges of ModuleHyperEdges to the
+// graph, replacing any that were added before, so that its packages can be
+// aggregated by module without losing their own edges.
+func (f *Graph) AddModuleHyperEdges() error {
+	for _, edge := range f.hyperEdges() {
+		f.RemoveEdge(edge.Key())
+	}
+	for _, edge := range f.ModuleHyperEdges() {
+		if _, err := f.AddEdge(edge, WithOverwrite()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hyperEdges returns the hyperedges of the graph, sorted by key.
+func (f Graph) hyperEdges() []*HyperEdge {
+	var edges []*HyperEdge
+	for _, edge := range f.SortedEdges() {
+		if edge, ok := edge.(*HyperEdge); ok {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// ModuleRank is the centrality of a module, the sum of that of its packages.
+type ModuleRank struct {
+	Module string  `json:"module"`
+	Score  float64 `json:"score"`
+	// Packages are the ranked packages of the module, most important first.
+	Packages []RankResult `json:"packages"`
+}
+
+// ModuleCentrality is like Centrality, but rolls the scores of packages up
+// into their modules, with the most important module listed first. Modules
+// are the hyperedges of g if it has any, as added by AddModuleHyperEdges, or
+// its ModuleHyperEdges otherwise. WithTop keeps the most important modules.
+func ModuleCentrality(g Graph, measure CentralityMeasure, opts ...CentralityOption) ([]ModuleRank, error) {
+	o := newCentralityOptions(opts)
+	ranks, err := centrality(g, measure, o)
+	if err != nil {
+		return nil, err
+	}
+	byNode := make(map[NodeKey]RankResult, len(ranks))
+	for _, r := range ranks {
+		byNode[r.Node] = r
+	}
+	edges := g.hyperEdges()
+	if len(edges) == 0 {
+		edges = g.ModuleHyperEdges()
+	}
+	modules := make([]ModuleRank, 0, len(edges))
+	for _, edge := range edges {
+		m := ModuleRank{Module: edge.Key().Container()}
+		for _, n := range edge.Nodes() {
+			r, ok := byNode[n]
+			if !ok {
+				r = RankResult{Node: n}
+			}
+			m.Score += r.Score
+			m.Packages = append(m.Packages, r)
+		}
+		sort.SliceStable(m.Packages, func(i, j int) bool {
+			return m.Packages[i].Score > m.Packages[j].Score
+		})
+		modules = append(modules, m)
+	}
+	sort.SliceStable(modules, func(i, j int) bool {
+		return modules[i].Score > modules[j].Score
+	})
+	if o.top > 0 && o.top < len(modules) {
+		modules = modules[:o.top]
+	}
+	return modules, nil
+}
+
+// moduleNode is a module of a graph, as a node of the graph of modules.
+type moduleNode struct {
+	Module  string
+	Version string
+	License string
+	// Score is the PageRank of the module, the sum of that of its packages.
+	Score float64
+	// DependsOn are the modules that packages of the module import, sorted.
+	DependsOn []string
+	// Packages are the nodes of the module's packages, sorted.
+	Packages []NodeKey
+}
+
+// moduleNodes returns the modules of the graph sorted by path, and the main
+// module, that of the graph's container, if known. Dependencies between
+// modules are those of the imports between their packages, rather than their
+// go.mod requirements, so that modules required but never imported are left
+// out.
+func (f Graph) moduleNodes() ([]moduleNode, string) {
+	ranks := f.pageRank()
+	modules := make(map[string]*moduleNode)
+	for _, key := range f.SortedNodes() {
+		data := f.Nodes[key].Data
+		if data == nil || data.Module == "" {
+			continue
+		}
+		m, ok := modules[data.Module]
+		if !ok {
+			m = &moduleNode{Module: data.Module, Version: data.Version}
+			modules[data.Module] = m
+		}
+		if license, ok := data.Attrs.String(AttrLicense); ok && m.License == "" {
+			m.License = license
+		}
+		m.Score += ranks[key]
+		m.Packages = append(m.Packages, key)
+	}
+	collapsed := f.CollapseBy(f.ByModule())
+	for _, edge := range collapsed.SortedEdges() {
+		edge := edge.(*DirectedEdge)
+		m := modules[edge.Src.ID]
+		m.DependsOn = append(m.DependsOn, edge.Dst.ID)
+	}
+	list := make([]moduleNode, 0, len(modules))
+	for _, m := range modules {
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Module < list[j].Module
+	})
+	var main string
+	if data := f.Nodes[NodeKey{ID: f.Container}].Data; data != nil {
+		main = data.Module
+	}
+	return list, main
+}
@@ -0,0 +1,335 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultOwnershipFile is the name of the ownership file that is used if no
+// other is given and it exists.
+const DefaultOwnershipFile = ".pkgrank-owners.json"
+
+// codeOwnersFiles are where GitHub and GitLab look for a CODEOWNERS file,
+// relative to the root of a repository, in order.
+var codeOwnersFiles = []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwners are the rules of a CODEOWNERS file, of which the last matching
+// a file gives its owners.
+type CodeOwners struct {
+	Rules []OwnerRule
+}
+
+// OwnerRule is a line of a CODEOWNERS file.
+type OwnerRule struct {
+	// Pattern is a gitignore-style pattern of the paths of files, relative
+	// to the root of the repository.
+	Pattern string
+	// Owners are the users, teams or emails owning the matched files, none
+	// if the rule removes their owners.
+	Owners []string
+
+	re *regexp.Regexp
+}
+
+// ParseCodeOwners reads a CODEOWNERS file. GitLab section headers are
+// ignored, along with their default owners.
+func ParseCodeOwners(r io.Reader) (*CodeOwners, error) {
+	var c CodeOwners
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") ||
+			strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		fields := strings.Fields(line)
+		rule := OwnerRule{Pattern: fields[0]}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			rule.Owners = append(rule.Owners, owner)
+		}
+		re, err := codeOwnersRegexp(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CODEOWNERS pattern %q: %w", rule.Pattern, err)
+		}
+		rule.re = re
+		c.Rules = append(c.Rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read CODEOWNERS: %w", err)
+	}
+	return &c, nil
+}
+
+// FindCodeOwners reads the CODEOWNERS file of the repository at root, from
+// wherever GitHub or GitLab would look for it. It returns an error wrapping
+// os.ErrNotExist if there is none.
+func FindCodeOwners(root string) (*CodeOwners, error) {
+	for _, name := range codeOwnersFiles {
+		f, err := os.Open(filepath.Join(root, filepath.FromSlash(name)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return ParseCodeOwners(f)
+	}
+	return nil, fmt.Errorf("no CODEOWNERS file in %s: %w", root, os.ErrNotExist)
+}
+
+// codeOwnersRegexp compiles a gitignore-style pattern into a regexp matching
+// the paths it matches, and the paths of the files below those it matches.
+// Patterns with a slash other than at their end are relative to the root,
+// and others match at any depth.
+func codeOwnersRegexp(pattern string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
+
+// Owners returns the owners of the file at the given slash-separated path,
+// relative to the root of the repository, from the last rule matching it.
+func (c CodeOwners) Owners(file string) []string {
+	for i := len(c.Rules) - 1; i >= 0; i-- {
+		if c.Rules[i].re.MatchString(file) {
+			return c.Rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// Ownership assigns packages to teams by import path, taking precedence over
+// CODEOWNERS.
+type Ownership struct {
+	Teams []Team `json:"teams"`
+}
+
+// Team is a named set of packages.
+type Team struct {
+	Name string `json:"name"`
+	// Packages are import path patterns of the packages of the team, as in
+	// a Layer.
+	Packages []string `json:"packages"`
+}
+
+// LoadOwnership reads and validates an ownership file.
+func LoadOwnership(name string) (*Ownership, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var o Ownership
+	if err := json.Unmarshal(b, &o); err != nil {
+		return nil, fmt.Errorf("failed to decode ownership %s: %w", name, err)
+	}
+	for _, team := range o.Teams {
+		if team.Name == "" {
+			return nil, fmt.Errorf("invalid ownership %s: team without name", name)
+		}
+		for _, pattern := range team.Packages {
+			if _, err := path.Match(strings.TrimSuffix(pattern, "/..."), ""); err != nil {
+				return nil, fmt.Errorf("invalid ownership %s: team %q: invalid pattern %q: %w", name, team.Name, pattern, err)
+			}
+		}
+	}
+	return &o, nil
+}
+
+// Team returns the first team that pkg belongs to, and whether there is any.
+func (o Ownership) Team(pkg string) (string, bool) {
+	for _, team := range o.Teams {
+		for _, pattern := range team.Packages {
+			if matchPattern(pattern, pkg) {
+				return team.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// AddOwners sets the AttrOwner attribute of the nodes of the packages of the
+// module in dir to their owning team: that of the ownership, if not nil and
+// it assigns one, or otherwise the owners in CODEOWNERS of most of the
+// package's Go files, if codeOwners is not nil. root is the root of the
+// repository, which CODEOWNERS paths are relative to.
+func (f *Graph) AddOwners(dir, root string, codeOwners *CodeOwners, ownership *Ownership) error {
+	pkgs, err := listPackages(dir, "./...")
+	if err != nil {
+		return err
+	}
+	for _, p := range pkgs {
+		key := NodeKey{ID: p.ImportPath}
+		var owner string
+		if ownership != nil {
+			owner, _ = ownership.Team(p.ImportPath)
+		}
+		if owner == "" && codeOwners != nil {
+			owner, err = packageCodeOwner(*codeOwners, root, p)
+			if err != nil {
+				return err
+			}
+		}
+		if owner != "" {
+			f.SetNodeAttrs(key, func(a *Attrs) {
+				a.SetString(AttrOwner, owner)
+			})
+		}
+	}
+	return nil
+}
+
+// packageCodeOwner returns the space-separated owners of most of the Go files
+// of a package, the first of them on ties.
+func packageCodeOwner(c CodeOwners, root string, p listPackage) (string, error) {
+	counts := make(map[string]int)
+	var best string
+	for _, name := range p.GoFiles {
+		rel, err := filepath.Rel(root, filepath.Join(p.Dir, name))
+		if err != nil {
+			return "", err
+		}
+		owner := strings.Join(c.Owners(filepath.ToSlash(rel)), " ")
+		counts[owner]++
+		if counts[owner] > counts[best] {
+			best = owner
+		}
+	}
+	return best, nil
+}
+
+// TeamEdge is an import between packages of different teams.
+type TeamEdge struct {
+	Src  NodeKey `json:"src"`
+	Dst  NodeKey `json:"dst"`
+	From string  `json:"from"`
+	To   string  `json:"to"`
+}
+
+func (e TeamEdge) String() string {
+	return fmt.Sprintf("%s (%s) imports %s (%s)", e.Src.ID, e.From, e.Dst.ID, e.To)
+}
+
+// CrossTeamEdges returns the imports between packages with different
+// AttrOwner attributes, sorted by source and destination. Packages without
+// an owner are left out.
+func (f Graph) CrossTeamEdges() []TeamEdge {
+	var edges []TeamEdge
+	for src, succs := range f.successors() {
+		from := f.owner(src)
+		if from == "" {
+			continue
+		}
+		for dst := range succs {
+			if to := f.owner(dst); to != "" && to != from {
+				edges = append(edges, TeamEdge{Src: src, Dst: dst, From: from, To: to})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Src != edges[j].Src {
+			return edges[i].Src.ID < edges[j].Src.ID
+		}
+		return edges[i].Dst.ID < edges[j].Dst.ID
+	})
+	return edges
+}
+
+// owner returns the AttrOwner attribute of a node, if any.
+func (f Graph) owner(key NodeKey) string {
+	if data := f.Nodes[key].Data; data != nil {
+		owner, _ := data.Attrs.String(AttrOwner)
+		return owner
+	}
+	return ""
+}
+
+// TeamRank is the centrality of a team, the sum of that of its packages.
+type TeamRank struct {
+	Team     string  `json:"team"`
+	Score    float64 `json:"score"`
+	Packages int     `json:"packages"`
+	// Imports and ImportedBy are the numbers of imports from the team's
+	// packages into other teams', and the other way around.
+	Imports    int `json:"imports"`
+	ImportedBy int `json:"importedBy"`
+}
+
+// TeamRanks rolls the PageRank of packages up into the teams of their
+// AttrOwner attributes, the most important team first.
+func (f Graph) TeamRanks() []TeamRank {
+	ranks := f.pageRank()
+	teams := make(map[string]*TeamRank)
+	team := func(name string) *TeamRank {
+		if teams[name] == nil {
+			teams[name] = &TeamRank{Team: name}
+		}
+		return teams[name]
+	}
+	for key := range f.Nodes {
+		if owner := f.owner(key); owner != "" {
+			t := team(owner)
+			t.Score += ranks[key]
+			t.Packages++
+		}
+	}
+	for _, e := range f.CrossTeamEdges() {
+		team(e.From).Imports++
+		team(e.To).ImportedBy++
+	}
+	list := make([]TeamRank, 0, len(teams))
+	for _, t := range teams {
+		list = append(list, *t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].Team < list[j].Team
+	})
+	return list
+}
@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WritePajek writes the graph to w in the Pajek .net format, read by Pajek,
+// igraph and networkx: the nodes as vertices numbered from 1 in the order of
+// their sorted IDs, labeled by ID, and the arcs between them weighted like
+// those of the graph's CSR.
+func WritePajek(w io.Writer, g *Graph) error {
+	c := g.CSR()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "*Vertices %d\n", c.Order())
+	for i, id := range c.IDs {
+		// Pajek has no escapes within labels.
+		fmt.Fprintf(bw, "%d \"%s\"\n", i+1, strings.ReplaceAll(id, `"`, "'"))
+	}
+	fmt.Fprintln(bw, "*Arcs")
+	for i := range c.IDs {
+		for j := c.Offsets[i]; j < c.Offsets[i+1]; j++ {
+			fmt.Fprintf(bw, "%d %d %g\n", i+1, c.Targets[j]+1, c.Weights[j])
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteMatrixMarket writes the weighted adjacency matrix of the graph's CSR
+// to w as a sparse Matrix Market coordinate matrix, read by MATLAB, scipy's
+// mmread and igraph. Row and column i, numbered from 1, are the node of the
+// i-th sorted ID, as listed in comments of the header.
+func WriteMatrixMarket(w io.Writer, g *Graph) error {
+	c := g.CSR()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "%%MatrixMarket matrix coordinate real general")
+	for i, id := range c.IDs {
+		fmt.Fprintf(bw, "%% %d %s\n", i+1, id)
+	}
+	fmt.Fprintf(bw, "%d %d %d\n", c.Order(), c.Order(), c.Size())
+	for i := range c.IDs {
+		for j := c.Offsets[i]; j < c.Offsets[i+1]; j++ {
+			fmt.Fprintf(bw, "%d %d %g\n", i+1, c.Targets[j]+1, c.Weights[j])
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,167 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"sync"
+)
+
+// MergeParallel is like Merge, but merges the graphs with the given number of
+// workers. Edges and nodes are sharded by key, so that each worker merges its
+// own shard without locking, before the shards are combined. The result is the
+// same as Merge's, except that the errors of edges that fail to be added in
+// different shards are joined.
+func MergeParallel(container string, workers int, graphs ...*Graph) (*Graph, error) {
+	if workers <= 1 || len(graphs) < 2 {
+		return Merge(container, graphs...)
+	}
+	g := &Graph{
+		Container:       container,
+		AddedContainers: make(map[string]struct{}),
+	}
+	// Decide which containers of each graph are kept, as Graph.Add does,
+	// which depends on the order of the graphs.
+	keeps := make([]map[string]struct{}, len(graphs))
+	for i, other := range graphs {
+		for container := range other.AddedContainers {
+			if _, ok := g.AddedContainers[container]; ok {
+				continue
+			}
+			if keeps[i] == nil {
+				keeps[i] = make(map[string]struct{})
+			}
+			keeps[i][container] = struct{}{}
+			g.AddedContainers[container] = struct{}{}
+		}
+	}
+
+	// Route the edges and nodes of contiguous chunks of graphs to shards.
+	seed := maphash.MakeSeed()
+	chunks := make([][]shardBucket, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			buckets := make([]shardBucket, workers)
+			lo, hi := w*len(graphs)/workers, (w+1)*len(graphs)/workers
+			for i := lo; i < hi; i++ {
+				other := graphs[i]
+				if keeps[i] == nil && len(other.AddedContainers) > 0 {
+					continue
+				}
+				for key, edge := range other.Edges {
+					if _, ok := keeps[i][key.container]; !ok && len(other.AddedContainers) > 0 {
+						continue
+					}
+					if err := checkEdge(edge, true); err != nil {
+						errs[w] = fmt.Errorf("failed to merge graph of %s: %w", other.Container, err)
+						return
+					}
+					s := shard(seed, key.container+key.id, workers)
+					buckets[s].edges = append(buckets[s].edges, edge)
+					for _, n := range edge.Nodes() {
+						s := shard(seed, n.ID, workers)
+						buckets[s].nodes = append(buckets[s].nodes, nodeEntry{key: n, ensure: true})
+					}
+				}
+				for key, node := range other.Nodes {
+					s := shard(seed, key.ID, workers)
+					buckets[s].nodes = append(buckets[s].nodes, nodeEntry{key: key, data: node.Data})
+				}
+			}
+			chunks[w] = buckets
+		}(w)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	// Merge each shard, visiting chunks in order so that edges and nodes
+	// are merged in the same order as by Merge.
+	edgeShards := make([]map[EdgeKey]Edge, workers)
+	nodeShards := make([]map[NodeKey]Node, workers)
+	for s := 0; s < workers; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			edges := make(map[EdgeKey]Edge)
+			nodes := make(map[NodeKey]Node)
+			for _, buckets := range chunks {
+				for _, edge := range buckets[s].edges {
+					merged, err := mergeEdge(edges[edge.Key()], edge, DefaultMergeFunc)
+					if err != nil {
+						errs[s] = err
+						return
+					}
+					edges[edge.Key()] = merged
+				}
+				for _, entry := range buckets[s].nodes {
+					prev, ok := nodes[entry.key]
+					switch {
+					case entry.ensure:
+						if !ok {
+							nodes[entry.key] = Node{NodeKey: entry.key}
+						}
+					case !ok || prev.Data == nil:
+						nodes[entry.key] = Node{NodeKey: entry.key, Data: entry.data}
+					case entry.data != nil:
+						data := *prev.Data
+						data.merge(entry.data)
+						nodes[entry.key] = Node{NodeKey: entry.key, Data: &data}
+					}
+				}
+			}
+			edgeShards[s] = edges
+			nodeShards[s] = nodes
+		}(s)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	// The shards have disjoint keys, so combining them is a plain copy.
+	var numEdges, numNodes int
+	for s := range edgeShards {
+		numEdges += len(edgeShards[s])
+		numNodes += len(nodeShards[s])
+	}
+	if numEdges > 0 {
+		g.Edges = make(map[EdgeKey]Edge, numEdges)
+	}
+	if numNodes > 0 {
+		g.Nodes = make(map[NodeKey]Node, numNodes)
+	}
+	for s := range edgeShards {
+		for key, edge := range edgeShards[s] {
+			g.Edges[key] = edge
+		}
+		for key, node := range nodeShards[s] {
+			g.Nodes[key] = node
+		}
+	}
+	return g, nil
+}
+
+// shardBucket holds the edges and nodes of a chunk of graphs that belong to
+// one shard, in the order they are added.
+type shardBucket struct {
+	edges []Edge
+	nodes []nodeEntry
+}
+
+// nodeEntry is a node to merge into a shard. An ensure entry only adds the
+// node if missing, like adding an edge does for its nodes.
+type nodeEntry struct {
+	key    NodeKey
+	data   *NodeData
+	ensure bool
+}
+
+func shard(seed maphash.Seed, s string, n int) int {
+	return int(maphash.String(seed, s) % uint64(n))
+}
@@ -0,0 +1,329 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Parquet files written by WriteParquet, in its directory.
+const (
+	ParquetNodesFile = "nodes.parquet"
+	ParquetEdgesFile = "edges.parquet"
+	ParquetRanksFile = "ranks.parquet"
+)
+
+// WriteParquet writes the graph to the directory dir, creating it if needed,
+// as three Parquet tables for tools like DuckDB or Spark: the nodes with
+// their module, PageRank and attributes as a JSON object, the directed edges,
+// and the ranks of nodes by PageRank. Files are uncompressed and every
+// column is required, with empty values for unknown ones.
+func WriteParquet(dir string, g *Graph) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	ranks, err := Centrality(*g, PageRankCentrality)
+	if err != nil {
+		return err
+	}
+	scores := make(map[NodeKey]float64, len(ranks))
+	for _, r := range ranks {
+		scores[r.Node] = r.Score
+	}
+
+	nodes := newParquetTable(
+		parquetField{"id", parquetByteArray},
+		parquetField{"module", parquetByteArray},
+		parquetField{"version", parquetByteArray},
+		parquetField{"rank", parquetDouble},
+		parquetField{"types", parquetInt64},
+		parquetField{"interfaces", parquetInt64},
+		parquetField{"attrs", parquetByteArray},
+	)
+	for _, key := range g.SortedNodes() {
+		var data NodeData
+		if d := g.Nodes[key].Data; d != nil {
+			data = *d
+		}
+		attrs := []byte("{}")
+		if len(data.Attrs) > 0 {
+			if attrs, err = json.Marshal(data.Attrs); err != nil {
+				return err
+			}
+		}
+		nodes.addString("id", key.ID)
+		nodes.addString("module", data.Module)
+		nodes.addString("version", data.Version)
+		nodes.addFloat("rank", scores[key])
+		nodes.addInt("types", int64(data.Types))
+		nodes.addInt("interfaces", int64(data.Interfaces))
+		nodes.addString("attrs", string(attrs))
+	}
+	edges := newParquetTable(
+		parquetField{"container", parquetByteArray},
+		parquetField{"src", parquetByteArray},
+		parquetField{"dst", parquetByteArray},
+		parquetField{"weight", parquetDouble},
+		parquetField{"kinds", parquetByteArray},
+		parquetField{"symbols", parquetByteArray},
+		parquetField{"configs", parquetByteArray},
+	)
+	for _, edge := range g.SortedEdges() {
+		edge, ok := edge.(*DirectedEdge)
+		if !ok {
+			continue
+		}
+		edges.addString("container", edge.Key().Container())
+		edges.addString("src", edge.Src.ID)
+		edges.addString("dst", edge.Dst.ID)
+		edges.addFloat("weight", edge.Weight())
+		edges.addString("kinds", strings.Join(edge.Kind.Names(), ","))
+		edges.addString("symbols", strings.Join(edge.Symbols, ","))
+		edges.addString("configs", strings.Join(edge.Configs, ","))
+	}
+	rankTable := newParquetTable(
+		parquetField{"position", parquetInt64},
+		parquetField{"node", parquetByteArray},
+		parquetField{"score", parquetDouble},
+	)
+	for i, r := range ranks {
+		rankTable.addInt("position", int64(i+1))
+		rankTable.addString("node", r.Node.ID)
+		rankTable.addFloat("score", r.Score)
+	}
+	for name, t := range map[string]*parquetTable{
+		ParquetNodesFile: nodes,
+		ParquetEdgesFile: edges,
+		ParquetRanksFile: rankTable,
+	} {
+		if err := writeParquetFile(filepath.Join(dir, name), t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeParquetFile(name string, t *parquetTable) (err error) {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return t.write(file)
+}
+
+// Parquet physical types.
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+// parquetField is the name and physical type of a column.
+type parquetField struct {
+	name string
+	typ  int32
+}
+
+// parquetColumn is a required column of a parquetTable, with its values
+// PLAIN encoded.
+type parquetColumn struct {
+	parquetField
+	values bytes.Buffer
+	n      int64
+}
+
+// parquetTable is a table written as a Parquet file of a single row group of
+// uncompressed columns, each of a single data page.
+type parquetTable struct {
+	columns []*parquetColumn
+}
+
+// newParquetTable returns a table of the given columns, in order, so that
+// tables without rows still have them.
+func newParquetTable(fields ...parquetField) *parquetTable {
+	t := &parquetTable{}
+	for _, f := range fields {
+		t.columns = append(t.columns, &parquetColumn{parquetField: f})
+	}
+	return t
+}
+
+// column returns the column with the given name and type, which must be one
+// of the table's.
+func (t *parquetTable) column(name string, typ int32) *parquetColumn {
+	for _, c := range t.columns {
+		if c.name == name && c.typ == typ {
+			return c
+		}
+	}
+	panic("undeclared parquet column " + name)
+}
+
+func (t *parquetTable) addString(name, v string) {
+	c := t.column(name, parquetByteArray)
+	_ = binary.Write(&c.values, binary.LittleEndian, uint32(len(v)))
+	c.values.WriteString(v)
+	c.n++
+}
+
+func (t *parquetTable) addInt(name string, v int64) {
+	c := t.column(name, parquetInt64)
+	_ = binary.Write(&c.values, binary.LittleEndian, v)
+	c.n++
+}
+
+func (t *parquetTable) addFloat(name string, v float64) {
+	c := t.column(name, parquetDouble)
+	_ = binary.Write(&c.values, binary.LittleEndian, math.Float64bits(v))
+	c.n++
+}
+
+// write writes the table to w in the Parquet format.
+func (t *parquetTable) write(w io.Writer) error {
+	var rows int64
+	for i, c := range t.columns {
+		if i > 0 && c.n != rows {
+			return errors.New("parquet columns of different lengths")
+		}
+		rows = c.n
+	}
+	var buf bytes.Buffer
+	buf.WriteString("PAR1")
+	schema := thriftList{elem: thriftStruct{}.typ(), items: []thriftValue{
+		thriftStruct{{4, thriftString("schema")}, {5, thriftI32(len(t.columns))}},
+	}}
+	chunks := thriftList{elem: thriftStruct{}.typ()}
+	var total int64
+	for _, c := range t.columns {
+		element := thriftStruct{{1, thriftI32(c.typ)}, {3, thriftI32(0)}, {4, thriftString(c.name)}}
+		if c.typ == parquetByteArray {
+			// The UTF8 converted type.
+			element = append(element, thriftField{6, thriftI32(0)})
+		}
+		schema.items = append(schema.items, element)
+
+		offset := int64(buf.Len())
+		header := thriftStruct{
+			{1, thriftI32(0)}, // DATA_PAGE
+			{2, thriftI32(c.values.Len())},
+			{3, thriftI32(c.values.Len())},
+			{5, thriftStruct{
+				{1, thriftI32(c.n)},
+				{2, thriftI32(0)}, // PLAIN
+				{3, thriftI32(3)}, // RLE
+				{4, thriftI32(3)}, // RLE
+			}},
+		}
+		header.encode(&buf)
+		buf.Write(c.values.Bytes())
+		size := int64(buf.Len()) - offset
+		total += size
+		chunks.items = append(chunks.items, thriftStruct{
+			{2, thriftI64(offset)},
+			{3, thriftStruct{
+				{1, thriftI32(c.typ)},
+				{2, thriftList{elem: thriftI32(0).typ(), items: []thriftValue{thriftI32(0), thriftI32(3)}}},
+				{3, thriftList{elem: thriftString("").typ(), items: []thriftValue{thriftString(c.name)}}},
+				{4, thriftI32(0)}, // UNCOMPRESSED
+				{5, thriftI64(c.n)},
+				{6, thriftI64(size)},
+				{7, thriftI64(size)},
+				{9, thriftI64(offset)},
+			}},
+		})
+	}
+	meta := thriftStruct{
+		{1, thriftI32(1)},
+		{2, schema},
+		{3, thriftI64(rows)},
+		{4, thriftList{elem: thriftStruct{}.typ(), items: []thriftValue{
+			thriftStruct{{1, chunks}, {2, thriftI64(total)}, {3, thriftI64(rows)}},
+		}}},
+		{6, thriftString("pkgrank")},
+	}
+	start := buf.Len()
+	meta.encode(&buf)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(buf.Len()-start))
+	buf.WriteString("PAR1")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// thriftValue is a value encoded with the Thrift compact protocol, which
+// Parquet uses for its metadata.
+type thriftValue interface {
+	typ() byte
+	encode(*bytes.Buffer)
+}
+
+type (
+	thriftI32    int32
+	thriftI64    int64
+	thriftString string
+	thriftList   struct {
+		elem  byte
+		items []thriftValue
+	}
+	thriftStruct []thriftField
+	thriftField  struct {
+		id int16
+		v  thriftValue
+	}
+)
+
+func (thriftI32) typ() byte    { return 5 }
+func (thriftI64) typ() byte    { return 6 }
+func (thriftString) typ() byte { return 8 }
+func (thriftList) typ() byte   { return 9 }
+func (thriftStruct) typ() byte { return 12 }
+
+func (v thriftI32) encode(b *bytes.Buffer) { thriftVarint(b, int64(v)) }
+func (v thriftI64) encode(b *bytes.Buffer) { thriftVarint(b, int64(v)) }
+
+func (v thriftString) encode(b *bytes.Buffer) {
+	b.Write(binary.AppendUvarint(nil, uint64(len(v))))
+	b.WriteString(string(v))
+}
+
+func (v thriftList) encode(b *bytes.Buffer) {
+	if n := len(v.items); n < 15 {
+		b.WriteByte(byte(n)<<4 | v.elem)
+	} else {
+		b.WriteByte(0xf0 | v.elem)
+		b.Write(binary.AppendUvarint(nil, uint64(n)))
+	}
+	for _, item := range v.items {
+		item.encode(b)
+	}
+}
+
+func (v thriftStruct) encode(b *bytes.Buffer) {
+	var last int16
+	for _, f := range v {
+		if delta := f.id - last; delta > 0 && delta <= 15 {
+			b.WriteByte(byte(delta)<<4 | f.v.typ())
+		} else {
+			b.WriteByte(f.v.typ())
+			thriftVarint(b, int64(f.id))
+		}
+		f.v.encode(b)
+		last = f.id
+	}
+	b.WriteByte(0)
+}
+
+// thriftVarint writes the zigzag varint of v.
+func thriftVarint(b *bytes.Buffer, v int64) {
+	b.Write(binary.AppendVarint(nil, v))
+}
@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// MaxOutputSize is the largest output of depgraph that is read, be it its
+// graph file or a line of its streamed edges, so that a runaway analyzer
+// fails the analysis rather than exhausting memory.
+var MaxOutputSize int64 = 1 << 30
+
+// maxParseErrors is the number of errors of malformed records that a
+// ParseError keeps.
+const maxParseErrors = 10
+
+// errLineTooLong is the error of a line longer than MaxOutputSize.
+var errLineTooLong = errors.New("line longer than MaxOutputSize")
+
+// ParseError is the error of output with malformed records, such as edges
+// that are not valid JSON or lack a package. Rather than stopping at the
+// first, it reports how many there were along with the first errors.
+type ParseError struct {
+	// Source names the output, e.g. the graph file of depgraph.
+	Source string
+	// Errs are the errors of the first malformed records.
+	Errs []error
+	// Count is the number of malformed records.
+	Count int
+}
+
+func (e *ParseError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	msg := fmt.Sprintf("%d malformed records in %s: %s", e.Count, e.Source, strings.Join(msgs, "; "))
+	if more := e.Count - len(e.Errs); more > 0 {
+		msg += fmt.Sprintf("; and %d more", more)
+	}
+	return msg
+}
+
+// Unwrap returns the errors of the first malformed records.
+func (e *ParseError) Unwrap() []error {
+	return e.Errs
+}
+
+// add records the error of a malformed record.
+func (e *ParseError) add(err error) {
+	e.Count++
+	if len(e.Errs) < maxParseErrors {
+		e.Errs = append(e.Errs, err)
+	}
+}
+
+// err returns e if it recorded any malformed record, and nil otherwise.
+func (e *ParseError) err() error {
+	if e.Count == 0 {
+		return nil
+	}
+	return e
+}
+
+// readOutput reads the file name written by depgraph, failing if it is
+// larger than MaxOutputSize.
+func readOutput(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, MaxOutputSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > MaxOutputSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, MaxOutputSize)
+	}
+	return b, nil
+}
+
+// readLine returns the next line of r without its newline, or io.EOF after
+// the last one. Lines longer than MaxOutputSize are skipped, returning
+// errLineTooLong, so that reading can go on with the next line.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !tooLong && int64(len(line)+len(chunk)) > MaxOutputSize {
+			tooLong, line = true, nil
+		} else if !tooLong {
+			line = append(line, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && (len(line) > 0 || tooLong) {
+			// The last line has no newline.
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tooLong {
+			return nil, errLineTooLong
+		}
+		return bytes.TrimSuffix(line, []byte("\n")), nil
+	}
+}
@@ -0,0 +1,341 @@
+package graph
+
+import (
+	"sort"
+)
+
+// Path is a sequence of nodes connected by directed edges.
+type Path struct {
+	Nodes []NodeKey `json:"nodes"`
+	// Weight is the sum of the weights of the edges along the path.
+	Weight float64 `json:"weight"`
+}
+
+// Len returns the number of edges in the path.
+func (p Path) Len() int {
+	return len(p.Nodes) - 1
+}
+
+// successors returns the directed adjacency of the graph, with the total
+// weight of all edges between each pair of nodes, from the index of its
+// adjacency queries. It must not be modified.
+func (f Graph) successors() map[NodeKey]map[NodeKey]float64 {
+	return f.adjacency().succWeights
+}
+
+// ShortestPath returns a shortest path from src to dst by number of edges,
+// and whether there is any such path.
+func (f Graph) ShortestPath(src, dst NodeKey) (Path, bool) {
+	adj := f.successors()
+	nodes := bfsPath(adj, src, dst, nil, nil)
+	if nodes == nil {
+		return Path{}, false
+	}
+	return Path{Nodes: nodes, Weight: pathWeight(adj, nodes)}, true
+}
+
+// Reachable reports whether there is a path from src to dst.
+func (f Graph) Reachable(src, dst NodeKey) bool {
+	_, ok := f.ShortestPath(src, dst)
+	return ok
+}
+
+// Descendants returns the sorted nodes reachable from src, excluding src
+// itself unless it is on a cycle.
+func (f Graph) Descendants(src NodeKey) []NodeKey {
+	return reachable(f.successors(), src)
+}
+
+// Ancestors returns the sorted nodes from which dst is reachable, excluding
+// dst itself unless it is on a cycle.
+func (f Graph) Ancestors(dst NodeKey) []NodeKey {
+	return reachable(f.predecessors(), dst)
+}
+
+// predecessors is the reverse of successors. It must not be modified.
+func (f Graph) predecessors() map[NodeKey]map[NodeKey]float64 {
+	return f.adjacency().predWeights
+}
+
+// reachable returns the sorted nodes reachable from n in adj.
+func reachable(adj map[NodeKey]map[NodeKey]float64, n NodeKey) []NodeKey {
+	seen := make(map[NodeKey]bool)
+	queue := []NodeKey{n}
+	var nodes []NodeKey
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		for next := range adj[m] {
+			if seen[next] {
+				continue
+			}
+			seen[next] = true
+			nodes = append(nodes, next)
+			queue = append(queue, next)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID < nodes[j].ID
+	})
+	return nodes
+}
+
+// Paths returns up to k of the shortest simple paths from src to dst, by
+// number of edges, with ties broken by the heavier path first, then by node
+// IDs. All simple paths are returned if k is non-positive, which may be
+// exponentially many.
+func (f Graph) Paths(src, dst NodeKey, k int) []Path {
+	adj := f.successors()
+	var paths [][]NodeKey
+	if k <= 0 {
+		paths = allPaths(adj, src, dst)
+	} else {
+		paths = yen(adj, src, dst, k)
+	}
+	result := make([]Path, len(paths))
+	for i, nodes := range paths {
+		result[i] = Path{Nodes: nodes, Weight: pathWeight(adj, nodes)}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Len() != result[j].Len() {
+			return result[i].Len() < result[j].Len()
+		}
+		return result[i].Weight > result[j].Weight
+	})
+	return result
+}
+
+func pathWeight(adj map[NodeKey]map[NodeKey]float64, nodes []NodeKey) float64 {
+	var w float64
+	for i := 1; i < len(nodes); i++ {
+		w += adj[nodes[i-1]][nodes[i]]
+	}
+	return w
+}
+
+// bfsPath returns a shortest path from src to dst by number of edges, which
+// avoids the removed nodes and edges, or nil if there is none. Successors are
+// visited in sorted order, so that the result is deterministic.
+func bfsPath(
+	adj map[NodeKey]map[NodeKey]float64,
+	src, dst NodeKey,
+	removedNodes map[NodeKey]bool,
+	removedEdges map[[2]NodeKey]bool,
+) []NodeKey {
+	if removedNodes[src] {
+		return nil
+	}
+	prev := map[NodeKey]NodeKey{src: src}
+	queue := []NodeKey{src}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n == dst {
+			var path []NodeKey
+			for ; n != src; n = prev[n] {
+				path = append(path, n)
+			}
+			path = append(path, src)
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+		for _, m := range sortedKeys(adj[n]) {
+			if _, ok := prev[m]; ok || removedNodes[m] || removedEdges[[2]NodeKey{n, m}] {
+				continue
+			}
+			prev[m] = n
+			queue = append(queue, m)
+		}
+	}
+	return nil
+}
+
+// bestPath returns the best path from src to dst by lessPath, which avoids
+// the removed nodes and edges, or nil if there is none: the heaviest of the
+// shortest paths by number of edges, and the first of those by node IDs.
+func bestPath(
+	adj map[NodeKey]map[NodeKey]float64,
+	src, dst NodeKey,
+	removedNodes map[NodeKey]bool,
+	removedEdges map[[2]NodeKey]bool,
+) []NodeKey {
+	if removedNodes[src] {
+		return nil
+	}
+	if src == dst {
+		return []NodeKey{src}
+	}
+	// Paths to the nodes of each layer of a breadth-first search are
+	// extended from the best paths to the previous layer.
+	type best struct {
+		nodes  []NodeKey
+		weight float64
+	}
+	visited := map[NodeKey]bool{src: true}
+	layer := map[NodeKey]best{src: {nodes: []NodeKey{src}}}
+	for len(layer) > 0 {
+		next := make(map[NodeKey]best)
+		for n, b := range layer {
+			for m, w := range adj[n] {
+				if visited[m] || removedNodes[m] || removedEdges[[2]NodeKey{n, m}] {
+					continue
+				}
+				c := best{nodes: append(append(make([]NodeKey, 0, len(b.nodes)+1), b.nodes...), m), weight: b.weight + w}
+				if prev, ok := next[m]; !ok || c.weight > prev.weight ||
+					c.weight == prev.weight && lessNodes(c.nodes, prev.nodes) {
+					next[m] = c
+				}
+			}
+		}
+		if b, ok := next[dst]; ok {
+			return b.nodes
+		}
+		for m := range next {
+			visited[m] = true
+		}
+		layer = next
+	}
+	return nil
+}
+
+// lessPath reports whether path a comes before path b: if it is shorter by
+// number of edges, heavier if as short, and first by node IDs if as heavy.
+func lessPath(adj map[NodeKey]map[NodeKey]float64, a, b []NodeKey) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	if wa, wb := pathWeight(adj, a), pathWeight(adj, b); wa != wb {
+		return wa > wb
+	}
+	return lessNodes(a, b)
+}
+
+// lessNodes compares paths of nodes lexicographically by their IDs.
+func lessNodes(a, b []NodeKey) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].ID != b[i].ID {
+			return a[i].ID < b[i].ID
+		}
+	}
+	return len(a) < len(b)
+}
+
+// yen returns up to k of the best simple paths from src to dst by lessPath
+// using Yen's algorithm, whose spur paths are best paths too.
+func yen(adj map[NodeKey]map[NodeKey]float64, src, dst NodeKey, k int) [][]NodeKey {
+	first := bestPath(adj, src, dst, nil, nil)
+	if first == nil {
+		return nil
+	}
+	found := [][]NodeKey{first}
+	var candidates [][]NodeKey
+	for len(found) < k {
+		last := found[len(found)-1]
+		for i := 0; i < len(last)-1; i++ {
+			spur := last[i]
+			root := last[:i+1]
+			removedEdges := make(map[[2]NodeKey]bool)
+			for _, p := range found {
+				if len(p) > i && equalPaths(p[:i+1], root) {
+					removedEdges[[2]NodeKey{p[i], p[i+1]}] = true
+				}
+			}
+			removedNodes := make(map[NodeKey]bool)
+			for _, n := range root[:i] {
+				removedNodes[n] = true
+			}
+			spurPath := bestPath(adj, spur, dst, removedNodes, removedEdges)
+			if spurPath == nil {
+				continue
+			}
+			candidate := append(append([]NodeKey{}, root[:i]...), spurPath...)
+			if !containsPath(candidates, candidate) && !containsPath(found, candidate) {
+				candidates = append(candidates, candidate)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			return lessPath(adj, candidates[i], candidates[j])
+		})
+		found = append(found, candidates[0])
+		candidates = candidates[1:]
+	}
+	return found
+}
+
+// allPaths returns every simple path from src to dst.
+func allPaths(adj map[NodeKey]map[NodeKey]float64, src, dst NodeKey) [][]NodeKey {
+	// Only nodes that can reach dst are worth visiting.
+	pred := make(map[NodeKey]map[NodeKey]float64)
+	for n, succs := range adj {
+		for m, w := range succs {
+			if pred[m] == nil {
+				pred[m] = make(map[NodeKey]float64)
+			}
+			pred[m][n] = w
+		}
+	}
+	reaches := map[NodeKey]bool{dst: true}
+	for _, n := range reachable(pred, dst) {
+		reaches[n] = true
+	}
+	var paths [][]NodeKey
+	onPath := make(map[NodeKey]bool)
+	var path []NodeKey
+	var visit func(n NodeKey)
+	visit = func(n NodeKey) {
+		path = append(path, n)
+		onPath[n] = true
+		if n == dst {
+			paths = append(paths, append([]NodeKey{}, path...))
+		} else {
+			for _, m := range sortedKeys(adj[n]) {
+				if reaches[m] && !onPath[m] {
+					visit(m)
+				}
+			}
+		}
+		onPath[n] = false
+		path = path[:len(path)-1]
+	}
+	if reaches[src] {
+		visit(src)
+	}
+	return paths
+}
+
+func sortedKeys[V any](m map[NodeKey]V) []NodeKey {
+	keys := make([]NodeKey, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+func equalPaths(a, b []NodeKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsPath(paths [][]NodeKey, path []NodeKey) bool {
+	for _, p := range paths {
+		if equalPaths(p, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultPolicyFile is the name of the architecture policy file that is used
+// if no other is given.
+const DefaultPolicyFile = ".pkgrank-arch.json"
+
+// Policy declares the layers of an architecture, and which layers each may
+// import. Packages in no layer are unrestricted.
+type Policy struct {
+	Layers []Layer `json:"layers"`
+}
+
+// Layer is a named set of packages.
+type Layer struct {
+	Name string `json:"name"`
+	// Packages are import path patterns of the packages in the layer. A
+	// pattern ending in "/..." matches a path and all paths below it, and
+	// any other pattern is matched with path.Match.
+	Packages []string `json:"packages"`
+	// Allow names the other layers that packages in the layer may import.
+	Allow []string `json:"allow,omitempty"`
+}
+
+// LoadPolicy reads and validates a policy from the given JSON file.
+func LoadPolicy(name string) (*Policy, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode policy %s: %w", name, err)
+	}
+	if err := p.Valid(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", name, err)
+	}
+	return &p, nil
+}
+
+// Valid returns an error if any layer is unnamed or duplicated, has an
+// invalid pattern, or allows an unknown layer.
+func (p Policy) Valid() error {
+	names := make(map[string]bool, len(p.Layers))
+	for _, layer := range p.Layers {
+		if layer.Name == "" {
+			return fmt.Errorf("layer without name")
+		}
+		if names[layer.Name] {
+			return fmt.Errorf("duplicate layer %q", layer.Name)
+		}
+		names[layer.Name] = true
+		for _, pattern := range layer.Packages {
+			if _, err := path.Match(strings.TrimSuffix(pattern, "/..."), ""); err != nil {
+				return fmt.Errorf("layer %q: invalid pattern %q: %w", layer.Name, pattern, err)
+			}
+		}
+	}
+	for _, layer := range p.Layers {
+		for _, allowed := range layer.Allow {
+			if !names[allowed] {
+				return fmt.Errorf("layer %q allows unknown layer %q", layer.Name, allowed)
+			}
+		}
+	}
+	return nil
+}
+
+// Layer returns the first layer that pkg belongs to, and whether there is any.
+func (p Policy) Layer(pkg string) (Layer, bool) {
+	for _, layer := range p.Layers {
+		for _, pattern := range layer.Packages {
+			if matchPattern(pattern, pkg) {
+				return layer, true
+			}
+		}
+	}
+	return Layer{}, false
+}
+
+// Violation is an import that the policy does not allow.
+type Violation struct {
+	Src  NodeKey `json:"src"`
+	Dst  NodeKey `json:"dst"`
+	From string  `json:"from"`
+	To   string  `json:"to"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s (%s) must not import %s (%s)", v.Src.ID, v.From, v.Dst.ID, v.To)
+}
+
+// Check returns the violation if src may not import dst.
+func (p Policy) Check(src, dst string) (Violation, bool) {
+	from, ok := p.Layer(src)
+	if !ok {
+		return Violation{}, false
+	}
+	to, ok := p.Layer(dst)
+	if !ok || to.Name == from.Name {
+		return Violation{}, false
+	}
+	for _, allowed := range from.Allow {
+		if allowed == to.Name {
+			return Violation{}, false
+		}
+	}
+	return Violation{
+		Src:  NodeKey{ID: src},
+		Dst:  NodeKey{ID: dst},
+		From: from.Name,
+		To:   to.Name,
+	}, true
+}
+
+// Violations returns the edges of the graph that the policy does not allow,
+// sorted by source and destination.
+func (f Graph) Violations(p Policy) []Violation {
+	var violations []Violation
+	for src, succs := range f.successors() {
+		for dst := range succs {
+			if v, ok := p.Check(src.ID, dst.ID); ok {
+				violations = append(violations, v)
+			}
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Src != violations[j].Src {
+			return violations[i].Src.ID < violations[j].Src.ID
+		}
+		return violations[i].Dst.ID < violations[j].Dst.ID
+	})
+	return violations
+}
+
+// matchPattern reports whether pkg matches pattern. A pattern ending in "/..."
+// matches if any of pkg and its parents match the rest of the pattern.
+func matchPattern(pattern, pkg string) bool {
+	prefix, recursive := strings.CutSuffix(pattern, "/...")
+	if !recursive {
+		ok, _ := path.Match(pattern, pkg)
+		return ok
+	}
+	for {
+		if ok, _ := path.Match(prefix, pkg); ok {
+			return true
+		}
+		i := strings.LastIndex(pkg, "/")
+		if i < 0 {
+			return false
+		}
+		pkg = pkg[:i]
+	}
+}
@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// Phase is a phase of the analysis of a package's dependency graph.
+type Phase string
+
+// Phases of an analysis, in order.
+const (
+	// PhaseFetch downloads the package's module and its dependencies.
+	PhaseFetch Phase = "fetch"
+	// PhaseLoad lists the packages to analyze.
+	PhaseLoad Phase = "load"
+	// PhaseAnalyze computes the imports of each package.
+	PhaseAnalyze Phase = "analyze"
+	// PhaseRank ranks the packages of the graph, which callers of
+	// TransitiveGraphContext report themselves, see ReportProgress.
+	PhaseRank Phase = "rank"
+)
+
+// Progress is an update on the progress of an analysis.
+type Progress struct {
+	Phase Phase `json:"phase"`
+	// Done is the number of packages analyzed so far, and Total the number
+	// of packages to analyze, once known in PhaseAnalyze. Done may exceed
+	// Total where packages are analyzed under several build variants.
+	Done  int `json:"done"`
+	Total int `json:"total,omitempty"`
+	// Package is the package that was last analyzed, and Cached whether its
+	// edges were loaded from the cache of depgraph.
+	Package string `json:"pkg,omitempty"`
+	Cached  bool   `json:"cached,omitempty"`
+}
+
+// ProgressFunc is called with each update on the progress of an analysis.
+// It may be called concurrently by the analyses of different packages.
+type ProgressFunc func(Progress)
+
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx to which TransitiveGraphContext and
+// the other functions taking a context report their progress by calling fn.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress calls the ProgressFunc of ctx, if any, with p.
+func ReportProgress(ctx context.Context, p Progress) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+		fn(p)
+	}
+}
+
+// hasProgress reports whether ctx has a ProgressFunc.
+func hasProgress(ctx context.Context) bool {
+	_, ok := ctx.Value(progressKey{}).(ProgressFunc)
+	return ok
+}
+
+// PassEvent is a line of the -progress-out stream of depgraph, written as
+// the pass over each package finishes.
+type PassEvent struct {
+	Package string `json:"pkg"`
+	Cached  bool   `json:"cached,omitempty"`
+}
+
+// maxEventSize is the longest line that progressWriter buffers.
+const maxEventSize = 1 << 16
+
+// progressWriter reports the PassEvents written to it, one JSON object per
+// line, as progress of PhaseAnalyze out of total packages.
+type progressWriter struct {
+	ctx   context.Context
+	total int
+	done  int
+	buf   []byte
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			if len(w.buf) > maxEventSize {
+				// Not an event either, and not worth buffering.
+				w.buf = w.buf[:0]
+			}
+			return len(p), nil
+		}
+		line := w.buf[:i]
+		w.buf = w.buf[i+1:]
+		var e PassEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			// Not an event, e.g. stray output of the analyzer.
+			continue
+		}
+		w.done++
+		ReportProgress(w.ctx, Progress{
+			Phase:   PhaseAnalyze,
+			Done:    w.done,
+			Total:   w.total,
+			Package: e.Package,
+			Cached:  e.Cached,
+		})
+	}
+}
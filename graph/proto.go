@@ -0,0 +1,308 @@
+package graph
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ProtoVersion is the version of the protocol buffers encoding produced by
+// Graph.MarshalProto. It is incremented on incompatible changes.
+const ProtoVersion = 1
+
+// Protocol buffers wire types.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+)
+
+// MarshalProto encodes the graph as a Graph message of graph.proto, a
+// compact alternative to MarshalJSON for large graphs and for consumers in
+// other languages, with nodes and edges sorted by key. Only directed edges
+// are supported.
+func (f Graph) MarshalProto() ([]byte, error) {
+	doc, err := f.document()
+	if err != nil {
+		return nil, err
+	}
+	var e protoEncoder
+	e.varint(1, ProtoVersion)
+	e.string(2, doc.Container)
+	for _, n := range doc.Nodes {
+		e.message(3, func(e *protoEncoder) {
+			e.string(1, n.ID)
+			e.string(2, n.Module)
+			e.string(3, n.Version)
+			e.varint(4, uint64(n.Types))
+			e.varint(5, uint64(n.Interfaces))
+			e.attrs(6, n.Attrs)
+		})
+	}
+	for _, edge := range doc.Edges {
+		e.message(4, func(e *protoEncoder) {
+			e.string(1, edge.Container)
+			e.string(2, edge.Src)
+			e.string(3, edge.Dst)
+			e.double(4, edge.Weight)
+			for _, s := range edge.Symbols {
+				e.bytes(5, []byte(s))
+			}
+			e.varint(6, uint64(edge.Kind))
+			for _, s := range edge.Configs {
+				e.bytes(7, []byte(s))
+			}
+			for _, p := range edge.Provenance {
+				e.message(8, func(e *protoEncoder) {
+					e.string(1, p.File)
+					e.varint(2, uint64(p.Line))
+					e.string(3, string(p.Style))
+				})
+			}
+			e.attrs(9, edge.Attrs)
+		})
+	}
+	return e.b, nil
+}
+
+// UnmarshalProto decodes a graph from the message produced by MarshalProto,
+// replacing the graph's contents. Unknown fields are skipped.
+func (f *Graph) UnmarshalProto(b []byte) error {
+	var doc jsonGraph
+	err := decodeProto(b, func(num int, d *protoField) error {
+		switch num {
+		case 1:
+			doc.Version = int(d.v)
+		case 2:
+			doc.Container = string(d.b)
+		case 3:
+			var n jsonNode
+			if err := decodeProto(d.b, func(num int, d *protoField) (err error) {
+				switch num {
+				case 1:
+					n.ID = string(d.b)
+				case 2:
+					n.Module = string(d.b)
+				case 3:
+					n.Version = string(d.b)
+				case 4:
+					n.Types = int(d.v)
+				case 5:
+					n.Interfaces = int(d.v)
+				case 6:
+					err = d.attr(&n.Attrs)
+				}
+				return err
+			}); err != nil {
+				return fmt.Errorf("invalid node: %w", err)
+			}
+			doc.Nodes = append(doc.Nodes, n)
+		case 4:
+			var e jsonEdge
+			if err := decodeProto(d.b, func(num int, d *protoField) (err error) {
+				switch num {
+				case 1:
+					e.Container = string(d.b)
+				case 2:
+					e.Src = string(d.b)
+				case 3:
+					e.Dst = string(d.b)
+				case 4:
+					e.Weight = math.Float64frombits(d.v)
+				case 5:
+					e.Symbols = append(e.Symbols, string(d.b))
+				case 6:
+					e.Kind = EdgeKind(d.v)
+				case 7:
+					e.Configs = append(e.Configs, string(d.b))
+				case 8:
+					var p Provenance
+					err = decodeProto(d.b, func(num int, d *protoField) error {
+						switch num {
+						case 1:
+							p.File = string(d.b)
+						case 2:
+							p.Line = int(d.v)
+						case 3:
+							p.Style = ImportStyle(d.b)
+						}
+						return nil
+					})
+					e.Provenance = append(e.Provenance, p)
+				case 9:
+					err = d.attr(&e.Attrs)
+				}
+				return err
+			}); err != nil {
+				return fmt.Errorf("invalid edge: %w", err)
+			}
+			doc.Edges = append(doc.Edges, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("invalid graph proto: %w", err)
+	}
+	if doc.Version != ProtoVersion {
+		return fmt.Errorf("unsupported graph proto version: %d", doc.Version)
+	}
+	return f.setDocument(doc)
+}
+
+// protoEncoder appends fields to a protocol buffers message, leaving out
+// those with default values as proto3 does.
+type protoEncoder struct {
+	b []byte
+}
+
+func (e *protoEncoder) tag(num, wire int) {
+	e.b = binary.AppendUvarint(e.b, uint64(num)<<3|uint64(wire))
+}
+
+func (e *protoEncoder) varint(num int, v uint64) {
+	if v != 0 {
+		e.tag(num, protoVarint)
+		e.b = binary.AppendUvarint(e.b, v)
+	}
+}
+
+func (e *protoEncoder) double(num int, v float64) {
+	if v != 0 {
+		e.tag(num, protoFixed64)
+		e.b = binary.LittleEndian.AppendUint64(e.b, math.Float64bits(v))
+	}
+}
+
+func (e *protoEncoder) string(num int, s string) {
+	if s != "" {
+		e.bytes(num, []byte(s))
+	}
+}
+
+// bytes appends a length-delimited field, even if empty, as for elements of
+// repeated fields.
+func (e *protoEncoder) bytes(num int, b []byte) {
+	e.tag(num, protoBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(b)))
+	e.b = append(e.b, b...)
+}
+
+// message appends the embedded message that fields encodes.
+func (e *protoEncoder) message(num int, fields func(*protoEncoder)) {
+	var m protoEncoder
+	fields(&m)
+	e.bytes(num, m.b)
+}
+
+// attrs appends attributes as the entries of a map<string, Value> field, in
+// the order of their keys.
+func (e *protoEncoder) attrs(num int, a Attrs) {
+	for _, key := range a.Keys() {
+		e.message(num, func(e *protoEncoder) {
+			e.string(1, key)
+			e.message(2, func(e *protoEncoder) {
+				// Oneof fields are set even with default values.
+				switch v := a[key].(type) {
+				case string:
+					e.bytes(1, []byte(v))
+				case int64:
+					e.tag(2, protoVarint)
+					e.b = binary.AppendUvarint(e.b, uint64(v))
+				case float64:
+					e.tag(3, protoFixed64)
+					e.b = binary.LittleEndian.AppendUint64(e.b, math.Float64bits(v))
+				case bool:
+					var b uint64
+					if v {
+						b = 1
+					}
+					e.tag(4, protoVarint)
+					e.b = binary.AppendUvarint(e.b, b)
+				}
+			})
+		})
+	}
+}
+
+// protoField is a decoded field of a message: v holds varint and fixed64
+// values, and b length-delimited ones.
+type protoField struct {
+	wire int
+	v    uint64
+	b    []byte
+}
+
+// attr decodes a map<string, Value> entry into a.
+func (d *protoField) attr(a *Attrs) error {
+	var key string
+	var value any
+	err := decodeProto(d.b, func(num int, d *protoField) error {
+		switch num {
+		case 1:
+			key = string(d.b)
+		case 2:
+			return decodeProto(d.b, func(num int, d *protoField) error {
+				switch num {
+				case 1:
+					value = string(d.b)
+				case 2:
+					value = int64(d.v)
+				case 3:
+					value = math.Float64frombits(d.v)
+				case 4:
+					value = d.v != 0
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err == nil && value != nil {
+		a.set(key, value)
+	}
+	return err
+}
+
+var errProtoTruncated = errors.New("truncated message")
+
+// decodeProto calls field for each field of a message in turn.
+func decodeProto(b []byte, field func(num int, d *protoField) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		b = b[n:]
+		d := protoField{wire: int(tag & 7)}
+		switch d.wire {
+		case protoVarint:
+			if d.v, n = binary.Uvarint(b); n <= 0 {
+				return errProtoTruncated
+			}
+			b = b[n:]
+		case protoFixed64:
+			if len(b) < 8 {
+				return errProtoTruncated
+			}
+			d.v, b = binary.LittleEndian.Uint64(b), b[8:]
+		case protoBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errProtoTruncated
+			}
+			d.b, b = b[n:n+int(size)], b[n+int(size):]
+		case 5: // fixed32
+			if len(b) < 4 {
+				return errProtoTruncated
+			}
+			b = b[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", d.wire)
+		}
+		if err := field(int(tag>>3), &d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
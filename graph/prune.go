@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"math"
+	"sort"
+)
+
+// Prune returns the subgraph of the heaviest imports, to keep large graphs
+// readable. The weight of an import is the total weight of the directed edges
+// from its importer to the imported node, across containers. Imports lighter
+// than minWeight are dropped, then all but the heaviest topPercent of the
+// rest if topPercent is below 100, and then the nodes left without an edge.
+// Edges of other types than directed are dropped.
+func (f Graph) Prune(minWeight, topPercent float64) *Graph {
+	weights := make(map[[2]NodeKey]float64)
+	for _, edge := range f.Edges {
+		if edge, ok := edge.(*DirectedEdge); ok {
+			weights[[2]NodeKey{edge.Src, edge.Dst}] += edge.Weight()
+		}
+	}
+	pairs := make([][2]NodeKey, 0, len(weights))
+	for pair, w := range weights {
+		if w >= minWeight {
+			pairs = append(pairs, pair)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		wi, wj := weights[pairs[i]], weights[pairs[j]]
+		if wi != wj {
+			return wi > wj
+		}
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0].ID < pairs[j][0].ID
+		}
+		return pairs[i][1].ID < pairs[j][1].ID
+	})
+	if topPercent < 100 {
+		n := int(math.Ceil(float64(len(pairs)) * math.Max(topPercent, 0) / 100))
+		pairs = pairs[:n]
+	}
+	kept := make(map[[2]NodeKey]bool, len(pairs))
+	nodes := make(map[NodeKey]bool)
+	for _, pair := range pairs {
+		kept[pair] = true
+		nodes[pair[0]], nodes[pair[1]] = true, true
+	}
+	g := f.Subgraph(func(key NodeKey) bool {
+		return nodes[key]
+	})
+	for key, edge := range g.Edges {
+		if edge, ok := edge.(*DirectedEdge); !ok || !kept[[2]NodeKey{edge.Src, edge.Dst}] {
+			g.RemoveEdge(key)
+		}
+	}
+	return g
+}
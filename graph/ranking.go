@@ -0,0 +1,31 @@
+package graph
+
+// RankedNode is a node of a ranking with the details of its row in a table:
+// its position, score, degrees and module.
+type RankedNode struct {
+	// Rank is the position of the node in the ranking, from 1.
+	Rank      int     `json:"rank"`
+	Node      NodeKey `json:"node"`
+	Score     float64 `json:"score"`
+	InDegree  int     `json:"inDegree"`
+	OutDegree int     `json:"outDegree"`
+	Module    string  `json:"module,omitempty"`
+	Version   string  `json:"version,omitempty"`
+	// Class is the class of the node, if classified, see Graph.Classify.
+	Class NodeClass `json:"class,omitempty"`
+}
+
+// RankedNodes returns the ranked nodes of ranks, as returned by Centrality,
+// in the same order.
+func (f *Graph) RankedNodes(ranks []RankResult) []RankedNode {
+	nodes := make([]RankedNode, len(ranks))
+	for i, r := range ranks {
+		n := &nodes[i]
+		n.Rank, n.Node, n.Score = i+1, r.Node, r.Score
+		n.InDegree, n.OutDegree = f.Degree(r.Node)
+		if data := f.Nodes[r.Node].Data; data != nil {
+			n.Module, n.Version = data.Module, data.Version
+		}
+	}
+	return nodes
+}
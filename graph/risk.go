@@ -0,0 +1,189 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Factors of the supply-chain risk of a module, as keys of RiskWeights.
+const (
+	// RiskCentrality is the centrality of the module, relative to the most
+	// central module.
+	RiskCentrality = "centrality"
+	// RiskDependencies is the number of other modules that the module
+	// imports, directly or not, relative to the module importing the most.
+	RiskDependencies = "dependencies"
+	// RiskAge is the time since the module version was published, reaching
+	// the most risk at riskMaxAge.
+	RiskAge = "age"
+	// RiskMaintainers is the inverse of the number of maintainers of the
+	// module, the most risk with a single maintainer.
+	RiskMaintainers = "maintainers"
+	// RiskVulns is the number n of known vulnerabilities of the module's
+	// packages, as n/(n+1).
+	RiskVulns = "vulns"
+)
+
+// riskMaxAge is the age of a module version from which it is riskiest.
+const riskMaxAge = 2 * 365 * 24 * time.Hour
+
+// RiskWeights weigh the factors of the risk score of modules, by name.
+// Factors without a weight are ignored.
+type RiskWeights map[string]float64
+
+// DefaultRiskWeights returns the default weights of the risk factors.
+func DefaultRiskWeights() RiskWeights {
+	return RiskWeights{
+		RiskCentrality:   2,
+		RiskDependencies: 1,
+		RiskAge:          1,
+		RiskMaintainers:  1,
+		RiskVulns:        3,
+	}
+}
+
+// Valid returns an error if a weight is of an unknown factor, or negative.
+func (w RiskWeights) Valid() error {
+	for name, weight := range w {
+		switch name {
+		case RiskCentrality, RiskDependencies, RiskAge, RiskMaintainers, RiskVulns:
+		default:
+			return fmt.Errorf("unknown risk factor %q", name)
+		}
+		if weight < 0 || math.IsNaN(weight) {
+			return fmt.Errorf("invalid weight %g of risk factor %s", weight, name)
+		}
+	}
+	return nil
+}
+
+// ModuleRisk is the supply-chain risk of a module, from 0 to 1.
+type ModuleRisk struct {
+	Module  string `json:"module"`
+	Version string `json:"version,omitempty"`
+	// Score is the mean of Factors, weighted by the RiskWeights.
+	Score float64 `json:"score"`
+	// Factors are the risk factors of the module, from 0 to 1, by name.
+	// Factors that are unknown, like the age of a module without
+	// AttrPublished, are 0.
+	Factors map[string]float64 `json:"factors"`
+
+	// Centrality is the PageRank of the module, the sum of that of its
+	// packages.
+	Centrality float64 `json:"centrality"`
+	// Dependencies is the number of other modules that the module imports,
+	// directly or not.
+	Dependencies int `json:"dependencies"`
+	// Published is when the module version was published, if known.
+	Published time.Time `json:"published"`
+	// Maintainers is the number of maintainers of the module, 0 if unknown.
+	Maintainers int `json:"maintainers,omitempty"`
+	// Vulns are the IDs of the known vulnerabilities of the module's
+	// packages.
+	Vulns []string `json:"vulns,omitempty"`
+}
+
+// ModuleRisks scores the supply-chain risk of each dependency module of the
+// graph at the time now, riskiest first. The main module, that of the graph's
+// container, and the standard library are left out. Module metadata is read
+// from the attributes of their packages, as set by AddVulns and deps.dev.
+func (f Graph) ModuleRisks(weights RiskWeights, now time.Time) ([]ModuleRisk, error) {
+	if err := weights.Valid(); err != nil {
+		return nil, err
+	}
+	modules, main := f.moduleNodes()
+	deps := make(map[string][]string, len(modules))
+	for _, m := range modules {
+		deps[m.Module] = m.DependsOn
+	}
+	var risks []ModuleRisk
+	var maxCentrality, maxDependencies float64
+	for _, m := range modules {
+		if m.Module == main || m.Module == "std" {
+			continue
+		}
+		r := ModuleRisk{
+			Module:       m.Module,
+			Version:      m.Version,
+			Centrality:   m.Score,
+			Dependencies: transitiveModules(deps, m.Module),
+		}
+		attrs := f.Nodes[m.Packages[0]].Data.Attrs
+		if published, ok := attrs.String(AttrPublished); ok {
+			if t, err := time.Parse(time.RFC3339, published); err == nil {
+				r.Published = t
+			}
+		}
+		if n, ok := attrs.Int(AttrMaintainers); ok {
+			r.Maintainers = int(n)
+		}
+		for _, key := range m.Packages {
+			if ids, ok := f.Nodes[key].Data.Attrs.String(AttrVulns); ok && ids != "" {
+				r.Vulns = append(r.Vulns, strings.Split(ids, ",")...)
+			}
+		}
+		r.Vulns = lo.Uniq(r.Vulns)
+		sort.Strings(r.Vulns)
+		maxCentrality = math.Max(maxCentrality, r.Centrality)
+		maxDependencies = math.Max(maxDependencies, float64(r.Dependencies))
+		risks = append(risks, r)
+	}
+
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+	for i := range risks {
+		r := &risks[i]
+		r.Factors = map[string]float64{
+			RiskCentrality:   ratio(r.Centrality, maxCentrality),
+			RiskDependencies: ratio(float64(r.Dependencies), maxDependencies),
+			RiskVulns:        float64(len(r.Vulns)) / float64(len(r.Vulns)+1),
+		}
+		if !r.Published.IsZero() {
+			r.Factors[RiskAge] = math.Min(ratio(float64(now.Sub(r.Published)), float64(riskMaxAge)), 1)
+		}
+		if r.Maintainers > 0 {
+			r.Factors[RiskMaintainers] = 1 / float64(r.Maintainers)
+		}
+		for name, weight := range weights {
+			r.Score += weight * r.Factors[name]
+		}
+		r.Score = ratio(r.Score, total)
+	}
+	sort.SliceStable(risks, func(i, j int) bool {
+		return risks[i].Score > risks[j].Score
+	})
+	return risks, nil
+}
+
+// ratio returns a/b, or 0 if b is not positive.
+func ratio(a, b float64) float64 {
+	if b <= 0 {
+		return 0
+	}
+	return math.Max(a, 0) / b
+}
+
+// transitiveModules returns the number of modules that module reaches in the
+// graph of modules deps, other than itself.
+func transitiveModules(deps map[string][]string, module string) int {
+	seen := map[string]bool{module: true}
+	queue := []string{module}
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		for _, d := range deps[m] {
+			if !seen[d] {
+				seen[d] = true
+				queue = append(queue, d)
+			}
+		}
+	}
+	return len(seen) - 1
+}
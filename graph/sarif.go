@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SARIFVersion is the version of the SARIF format written by WriteSARIF.
+const SARIFVersion = "2.1.0"
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string                 `json:"ruleId"`
+	Level            Level                  `json:"level"`
+	Message          sarifMessage           `json:"message"`
+	Locations        []sarifLocation        `json:"locations,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// WriteSARIF writes the findings to w as a SARIF log, so that code scanning
+// services may annotate them. Locations in files of packages below the module
+// path root are written relative to the source root, as %SRCROOT%.
+func WriteSARIF(w io.Writer, findings []Finding, root string) error {
+	used := make(map[string]bool)
+	results := make([]sarifResult, 0, len(findings))
+	for _, f := range findings {
+		used[f.Rule] = true
+		result := sarifResult{
+			RuleID:  f.Rule,
+			Level:   f.Level,
+			Message: sarifMessage{Text: f.Message},
+		}
+		for _, p := range f.Locations {
+			artifact := sarifArtifactLocation{URI: p.File}
+			if rel, ok := strings.CutPrefix(p.File, root+"/"); ok && root != "" {
+				artifact = sarifArtifactLocation{URI: rel, URIBaseID: "%SRCROOT%"}
+			}
+			loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: artifact}}
+			if p.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: p.Line}
+			}
+			result.Locations = append(result.Locations, loc)
+		}
+		for _, n := range f.Nodes {
+			result.LogicalLocations = append(result.LogicalLocations, sarifLogicalLocation{
+				FullyQualifiedName: n.ID,
+				Kind:               "package",
+			})
+		}
+		results = append(results, result)
+	}
+	rules := make([]sarifRule, 0, len(used))
+	for id := range used {
+		rules = append(rules, sarifRule{
+			ID:               id,
+			ShortDescription: sarifMessage{Text: ruleDescriptions[id]},
+		})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: SARIFVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "pkgrank",
+				InformationURI: "https://github.com/arclabs561/pkgrank",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	})
+}
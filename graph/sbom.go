@@ -0,0 +1,264 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CycloneDXVersion and SPDXVersion are the versions of the SBOM formats
+// written by WriteCycloneDX and WriteSPDX.
+const (
+	CycloneDXVersion = "1.5"
+	SPDXVersion      = "SPDX-2.3"
+)
+
+// sbomScoreProperty is the name of the property or annotation that carries
+// the centrality of a component.
+const sbomScoreProperty = "pkgrank:score"
+
+// PURL returns the package URL of the module.
+func (m moduleNode) PURL() string {
+	purl := "pkg:golang/" + m.Module
+	if m.Version != "" {
+		// Versions like v2.0.0+incompatible must be percent-encoded.
+		purl += "@" + strings.ReplaceAll(url.PathEscape(m.Version), "+", "%2B")
+	}
+	return purl
+}
+
+// sbomTime returns the creation time of SBOMs, from SOURCE_DATE_EPOCH if set
+// so that they are reproducible.
+func sbomTime() time.Time {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		return time.Unix(epoch, 0).UTC()
+	}
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+type cdxBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string        `json:"timestamp"`
+	Tools     cdxTools      `json:"tools"`
+	Component *cdxComponent `json:"component,omitempty"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Type       string        `json:"type"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Licenses   []cdxLicense  `json:"licenses,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxLicense struct {
+	Expression string `json:"expression"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// WriteCycloneDX writes a CycloneDX SBOM of the modules of the graph to w,
+// with the module of its container as the main component. Each component
+// carries its PageRank as the pkgrank:score property, and depends on the
+// modules that its packages import.
+func WriteCycloneDX(w io.Writer, g *Graph) error {
+	modules, main := g.moduleNodes()
+	bom := cdxBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: CycloneDXVersion,
+		Version:     1,
+		Metadata: cdxMetadata{
+			Timestamp: sbomTime().Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{{
+				Type: "application",
+				Name: "pkgrank",
+			}}},
+		},
+		Components:   make([]cdxComponent, 0, len(modules)),
+		Dependencies: make([]cdxDependency, 0, len(modules)),
+	}
+	refs := make(map[string]string, len(modules))
+	for _, m := range modules {
+		refs[m.Module] = m.PURL()
+	}
+	for _, m := range modules {
+		c := cdxComponent{
+			BOMRef:  refs[m.Module],
+			Type:    "library",
+			Name:    m.Module,
+			Version: m.Version,
+			PURL:    refs[m.Module],
+			Properties: []cdxProperty{{
+				Name:  sbomScoreProperty,
+				Value: strconv.FormatFloat(m.Score, 'f', 6, 64),
+			}},
+		}
+		if m.License != "" && m.License != LicenseUnknown {
+			c.Licenses = []cdxLicense{{Expression: m.License}}
+		}
+		if m.Module == main {
+			c.Type = "application"
+			bom.Metadata.Component = &c
+		} else {
+			bom.Components = append(bom.Components, c)
+		}
+		dep := cdxDependency{Ref: refs[m.Module], DependsOn: make([]string, len(m.DependsOn))}
+		for i, d := range m.DependsOn {
+			dep.DependsOn[i] = refs[d]
+		}
+		bom.Dependencies = append(bom.Dependencies, dep)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bom)
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+	Annotations      []spdxAnnotation  `json:"annotations"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxAnnotation struct {
+	AnnotationType string `json:"annotationType"`
+	Annotator      string `json:"annotator"`
+	AnnotationDate string `json:"annotationDate"`
+	Comment        string `json:"comment"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxInvalid matches the characters that SPDX identifiers may not contain.
+var spdxInvalid = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// WriteSPDX writes an SPDX SBOM of the modules of the graph to w, describing
+// the module of its container. Each package carries its PageRank in an
+// annotation, as pkgrank:score=<score>, and depends on the modules that its
+// packages import.
+func WriteSPDX(w io.Writer, g *Graph) error {
+	modules, main := g.moduleNodes()
+	created := sbomTime().Format(time.RFC3339)
+	doc := spdxDocument{
+		SPDXVersion:       SPDXVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              g.Container,
+		DocumentNamespace: "https://github.com/arclabs561/pkgrank/spdx/" + g.Container + "-" + created,
+		CreationInfo: spdxCreationInfo{
+			Created:  created,
+			Creators: []string{"Tool: pkgrank"},
+		},
+		Packages:      make([]spdxPackage, 0, len(modules)),
+		Relationships: []spdxRelationship{},
+	}
+	ids := make(map[string]string, len(modules))
+	for _, m := range modules {
+		name := m.Module
+		if m.Version != "" {
+			name += "-" + m.Version
+		}
+		ids[m.Module] = "SPDXRef-Package-" + spdxInvalid.ReplaceAllString(name, "-")
+	}
+	if main != "" {
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      doc.SPDXID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: ids[main],
+		})
+	}
+	for _, m := range modules {
+		license := "NOASSERTION"
+		if m.License != "" && m.License != LicenseUnknown {
+			license = m.License
+		}
+		doc.Packages = append(doc.Packages, spdxPackage{
+			SPDXID:           ids[m.Module],
+			Name:             m.Module,
+			VersionInfo:      m.Version,
+			DownloadLocation: "NOASSERTION",
+			LicenseConcluded: "NOASSERTION",
+			LicenseDeclared:  license,
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  m.PURL(),
+			}},
+			Annotations: []spdxAnnotation{{
+				AnnotationType: "OTHER",
+				Annotator:      "Tool: pkgrank",
+				AnnotationDate: created,
+				Comment:        fmt.Sprintf("%s=%.6f", sbomScoreProperty, m.Score),
+			}},
+		})
+		for _, d := range m.DependsOn {
+			doc.Relationships = append(doc.Relationships, spdxRelationship{
+				SPDXElementID:      ids[m.Module],
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: ids[d],
+			})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
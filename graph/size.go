@@ -0,0 +1,159 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SymbolSize is a symbol of a binary and the bytes it takes, as listed by go
+// tool nm -size.
+type SymbolSize struct {
+	Name string
+	Size int64
+	// Type is the nm symbol type, e.g. T for text or D for data.
+	Type string
+}
+
+// ParseSymbolSizes reads the output of go tool nm -size, and returns the
+// symbols that take space in the binary, leaving out undefined and BSS
+// symbols.
+func ParseSymbolSizes(r io.Reader) ([]SymbolSize, error) {
+	var symbols []SymbolSize
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && len(fields[1]) == 1 && unicode.IsLetter(rune(fields[1][0])) {
+			// Undefined symbols have no address.
+			fields = append([]string{""}, fields...)
+		}
+		if len(fields) < 4 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid symbol size %q: %w", fields[1], err)
+		}
+		switch typ := fields[2]; typ {
+		case "U", "B", "b":
+		default:
+			symbols = append(symbols, SymbolSize{
+				Name: strings.Join(fields[3:], " "),
+				Size: size,
+				Type: typ,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read nm output: %w", err)
+	}
+	return symbols, nil
+}
+
+// ReadSymbolSizes returns the symbols of the named binary that take space in
+// it, from go tool nm -size.
+func ReadSymbolSizes(binary string) ([]SymbolSize, error) {
+	out, err := doExec(execQuiet, "", nil, "go", "tool", "nm", "-size", binary)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSymbolSizes(strings.NewReader(out))
+}
+
+// symbolPackage returns the node of the package of a symbol, the longest of
+// pkgs that its name starts with followed by a dot once stripped of the
+// prefixes of compiler-generated symbols like type:*, and whether there is
+// any.
+func symbolPackage(name string, pkgs map[string]NodeKey) (NodeKey, bool) {
+	name = strings.TrimPrefix(name, "type:")
+	name = strings.TrimPrefix(name, ".eq.")
+	name = strings.TrimPrefix(name, ".hash.")
+	name = strings.TrimLeft(name, "*[]")
+	// Package paths end before any method receiver or type arguments.
+	if i := strings.IndexAny(name, "([ {,;"); i >= 0 {
+		name = name[:i]
+	}
+	for i := len(name) - 1; i > 0; i-- {
+		if name[i] != '.' {
+			continue
+		}
+		if key, ok := pkgs[name[:i]]; ok {
+			return key, true
+		}
+	}
+	return NodeKey{}, false
+}
+
+// AddSizes sets the AttrSize attribute of the nodes of the graph to the total
+// size of the symbols of their package, with those of package main
+// attributed to the graph's container, and returns the size of the symbols
+// that belong to no node, like those of the runtime's tables.
+func (f *Graph) AddSizes(symbols []SymbolSize) int64 {
+	pkgs := make(map[string]NodeKey, len(f.Nodes)+1)
+	for key := range f.Nodes {
+		pkgs[key.ID] = key
+	}
+	if root := (NodeKey{ID: f.Container}); f.Container != "" {
+		if _, ok := f.Nodes[root]; ok {
+			pkgs["main"] = root
+		}
+	}
+	sizes := make(map[NodeKey]int64)
+	var unattributed int64
+	for _, s := range symbols {
+		if key, ok := symbolPackage(s.Name, pkgs); ok {
+			sizes[key] += s.Size
+		} else {
+			unattributed += s.Size
+		}
+	}
+	for key, size := range sizes {
+		f.SetNodeAttrs(key, func(a *Attrs) {
+			a.SetInt(AttrSize, size)
+		})
+	}
+	return unattributed
+}
+
+// PackageSize is the size that a package contributes to a binary.
+type PackageSize struct {
+	Node NodeKey `json:"node"`
+	// Size is the size of the package's own symbols.
+	Size int64 `json:"size"`
+	// Retained is the size of the package and of all packages that it
+	// dominates, i.e. that the binary would no longer contain without it.
+	Retained int64 `json:"retained"`
+}
+
+// PackageSizes returns the sizes of the packages that root reaches, as set
+// by AddSizes, largest retained size first.
+func (f Graph) PackageSizes(root NodeKey) []PackageSize {
+	size := func(key NodeKey) int64 {
+		if data := f.Nodes[key].Data; data != nil {
+			n, _ := data.Attrs.Int(AttrSize)
+			return n
+		}
+		return 0
+	}
+	tree := f.Dominators(root)
+	nodes := append([]NodeKey{root}, tree.Dominated(root)...)
+	sizes := make([]PackageSize, len(nodes))
+	for i, n := range nodes {
+		sizes[i] = PackageSize{Node: n, Size: size(n), Retained: size(n)}
+		for _, m := range tree.Dominated(n) {
+			sizes[i].Retained += size(m)
+		}
+	}
+	sort.SliceStable(sizes, func(i, j int) bool {
+		if sizes[i].Retained != sizes[j].Retained {
+			return sizes[i].Retained > sizes[j].Retained
+		}
+		return sizes[i].Node.ID < sizes[j].Node.ID
+	})
+	return sizes
+}
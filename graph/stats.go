@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"sort"
+
+	"gonum.org/v1/gonum/graph/topo"
+)
+
+// Stats summarizes the structure of a graph. Degrees count distinct
+// neighbours, and path lengths count edges.
+type Stats struct {
+	Order   int     `json:"order"`
+	Size    int     `json:"size"`
+	Density float64 `json:"density"`
+
+	MaxInDegree  int     `json:"maxInDegree"`
+	MaxOutDegree int     `json:"maxOutDegree"`
+	AvgDegree    float64 `json:"avgDegree"`
+	// InDegrees and OutDegrees are histograms of the number of nodes with
+	// each degree.
+	InDegrees  map[int]int `json:"inDegrees"`
+	OutDegrees map[int]int `json:"outDegrees"`
+
+	// Diameter is the longest shortest path between any two nodes.
+	Diameter int `json:"diameter"`
+	// SCCs is the number of strongly connected components, of which
+	// Cycles have more than one node.
+	SCCs   int `json:"sccs"`
+	Cycles int `json:"cycles"`
+	// LongestChain is the longest dependency chain, counting each strongly
+	// connected component as a single step.
+	LongestChain int `json:"longestChain"`
+}
+
+// Stats computes a summary of the graph's structure.
+func (f Graph) Stats() Stats {
+	succs := f.successors()
+	preds := f.predecessors()
+	s := Stats{
+		Order:      f.Order(),
+		Size:       f.Size(),
+		InDegrees:  make(map[int]int),
+		OutDegrees: make(map[int]int),
+	}
+	if s.Order > 1 {
+		s.Density = float64(s.Size) / float64(s.Order*(s.Order-1))
+	}
+	pairs := 0
+	for key := range f.Nodes {
+		in, out := len(preds[key]), len(succs[key])
+		pairs += out
+		s.InDegrees[in]++
+		s.OutDegrees[out]++
+		s.MaxInDegree = max(s.MaxInDegree, in)
+		s.MaxOutDegree = max(s.MaxOutDegree, out)
+	}
+	if s.Order > 0 {
+		s.AvgDegree = float64(pairs) / float64(s.Order)
+	}
+	for key := range f.Nodes {
+		s.Diameter = max(s.Diameter, eccentricity(succs, key))
+	}
+	sccs := f.StronglyConnectedComponents()
+	s.SCCs = len(sccs)
+	for _, scc := range sccs {
+		if len(scc) > 1 {
+			s.Cycles++
+		}
+	}
+	s.LongestChain = longestChain(succs, sccs)
+	return s
+}
+
+// eccentricity returns the longest shortest path from n to any node.
+func eccentricity(adj map[NodeKey]map[NodeKey]float64, n NodeKey) int {
+	dist := map[NodeKey]int{n: 0}
+	queue := []NodeKey{n}
+	longest := 0
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		for next := range adj[m] {
+			if _, ok := dist[next]; ok {
+				continue
+			}
+			dist[next] = dist[m] + 1
+			longest = max(longest, dist[next])
+			queue = append(queue, next)
+		}
+	}
+	return longest
+}
+
+// StronglyConnectedComponents returns the strongly connected components of
+// the graph, each sorted, in reverse topological order of the components.
+func (f Graph) StronglyConnectedComponents() [][]NodeKey {
+	ig := f.ToImportGraph()
+	for key := range f.Nodes {
+		ig.AddNode(key.ID)
+	}
+	var sccs [][]NodeKey
+	for _, component := range topo.TarjanSCC(ig.g) {
+		scc := make([]NodeKey, len(component))
+		for i, n := range component {
+			scc[i] = NodeKey{ID: ig.idToImport[n.ID()]}
+		}
+		sort.Slice(scc, func(i, j int) bool {
+			return scc[i].ID < scc[j].ID
+		})
+		sccs = append(sccs, scc)
+	}
+	return sccs
+}
+
+// longestChain returns the longest path through the condensation of the
+// graph, given its components in reverse topological order.
+func longestChain(adj map[NodeKey]map[NodeKey]float64, sccs [][]NodeKey) int {
+	component := make(map[NodeKey]int)
+	for i, scc := range sccs {
+		for _, n := range scc {
+			component[n] = i
+		}
+	}
+	// Components are in reverse topological order, so all components that
+	// a component depends on come before it.
+	longest := make([]int, len(sccs))
+	result := 0
+	for i, scc := range sccs {
+		for _, n := range scc {
+			for m := range adj[n] {
+				if j := component[m]; j != i {
+					longest[i] = max(longest[i], longest[j]+1)
+				}
+			}
+		}
+		result = max(result, longest[i])
+	}
+	return result
+}
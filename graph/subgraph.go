@@ -0,0 +1,146 @@
+package graph
+
+// RemoveEdge removes the edge with the given key, and reports whether it was
+// in the graph. Its nodes are kept.
+func (f *Graph) RemoveEdge(key EdgeKey) bool {
+	edge, ok := f.Edges[key]
+	if !ok {
+		return false
+	}
+	delete(f.Edges, key)
+	if f.adj != nil {
+		f.adj.remove(edge, f.Edges)
+	}
+	return true
+}
+
+// RemoveNode removes a node and every edge incident to it, and reports
+// whether it was in the graph.
+func (f *Graph) RemoveNode(key NodeKey) bool {
+	if _, ok := f.Nodes[key]; !ok {
+		return false
+	}
+	for _, edgeKey := range f.incident(key) {
+		f.RemoveEdge(edgeKey)
+	}
+	delete(f.Nodes, key)
+	return true
+}
+
+// Subgraph returns a new graph of the nodes for which keep returns true, and
+// the edges between them. Nodes and edges are shared with the graph.
+func (f Graph) Subgraph(keep func(NodeKey) bool) *Graph {
+	g := &Graph{
+		Container:       f.Container,
+		AddedContainers: make(map[string]struct{}, len(f.AddedContainers)),
+		logger:          f.logger,
+	}
+	for c := range f.AddedContainers {
+		g.AddedContainers[c] = struct{}{}
+	}
+	for key, node := range f.Nodes {
+		if keep(key) {
+			g.AddNode(key, node.Data)
+		}
+	}
+	for key, edge := range f.Edges {
+		kept := true
+		for _, n := range edge.Nodes() {
+			if _, ok := g.Nodes[n]; !ok {
+				kept = false
+				break
+			}
+		}
+		if kept {
+			if g.Edges == nil {
+				g.Edges = make(map[EdgeKey]Edge)
+			}
+			g.Edges[key] = edge
+		}
+	}
+	return g
+}
+
+// Exclude returns the subgraph of the nodes matching none of the patterns,
+// which are path.Match patterns of package paths as in policy files, e.g.
+// "example.com/m/internal/...".
+func (f Graph) Exclude(patterns ...string) *Graph {
+	return f.Subgraph(func(key NodeKey) bool {
+		for _, pattern := range patterns {
+			if matchPattern(pattern, key.ID) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// ExcludeKinds returns the subgraph without the directed edges of any of the
+// given kinds, e.g. EdgeKindTest|EdgeKindStdlib, and without the nodes that
+// only such edges connected. The graph's container and edgeless nodes are
+// kept.
+func (f Graph) ExcludeKinds(kinds EdgeKind) *Graph {
+	excluded := make(map[EdgeKey]struct{})
+	connected := make(map[NodeKey]bool)
+	for key, edge := range f.Edges {
+		e, ok := edge.(*DirectedEdge)
+		drop := ok && e.Kind&kinds != 0
+		if drop {
+			excluded[key] = struct{}{}
+		}
+		for _, n := range edge.Nodes() {
+			connected[n] = connected[n] || !drop
+		}
+	}
+	g := f.Subgraph(func(key NodeKey) bool {
+		kept, ok := connected[key]
+		return kept || !ok || key.ID == f.Container
+	})
+	for key := range excluded {
+		g.RemoveEdge(key)
+	}
+	return g
+}
+
+// Neighborhood returns the subgraph of the nodes within radius edges of root,
+// following edges in either direction, e.g. a radius of one keeps root, its
+// imports and its importers. It is empty if root is not in the graph.
+func (f Graph) Neighborhood(root NodeKey, radius int) *Graph {
+	return f.within(root, radius, f.successors(), f.predecessors())
+}
+
+// WithinDepth returns the subgraph of the nodes that root reaches by at most
+// depth edges, e.g. a depth of one keeps root and its direct imports. It is
+// empty if root is not in the graph.
+func (f Graph) WithinDepth(root NodeKey, depth int) *Graph {
+	return f.within(root, depth, f.successors())
+}
+
+// within returns the subgraph of the nodes within radius edges of root,
+// following the given adjacencies.
+func (f Graph) within(root NodeKey, radius int, adjs ...map[NodeKey]map[NodeKey]float64) *Graph {
+	dist := make(map[NodeKey]int)
+	if _, ok := f.Nodes[root]; ok {
+		dist[root] = 0
+		queue := []NodeKey{root}
+		for len(queue) > 0 {
+			n := queue[0]
+			queue = queue[1:]
+			if dist[n] == radius {
+				continue
+			}
+			for _, adj := range adjs {
+				for m := range adj[n] {
+					if _, ok := dist[m]; !ok {
+						dist[m] = dist[n] + 1
+						queue = append(queue, m)
+					}
+				}
+			}
+		}
+	}
+	return f.Subgraph(func(key NodeKey) bool {
+		_, ok := dist[key]
+		return ok
+	})
+}
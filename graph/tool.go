@@ -3,40 +3,32 @@ package graph
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 	"github.com/samber/lo"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
 	"gonum.org/v1/gonum/graph"
-	"gonum.org/v1/gonum/graph/network"
 	"gonum.org/v1/gonum/graph/simple"
 )
 
-type execError struct {
-	Command string
-	Args    []string
-	Stdout  string
-	Stderr  string
-	Err     error
-}
-
-func (e execError) Error() string {
-	msg := fmt.Sprintf("failed to run cmd '%v': %v", e.Command, e.Err)
-	if e.Stderr != "" {
-		msg = fmt.Sprintf("%s: %s", msg, e.Stderr)
-	}
-	return msg
-}
-
 type doExecMode int
 
 const (
@@ -61,12 +53,40 @@ func doExec(
 	envs map[string]string,
 	name string,
 	args ...string,
+) (string, error) {
+	return doExecContext(context.Background(), mode, dir, envs, name, args...)
+}
+
+// doExecContext is like doExec, but kills the command if ctx is done before
+// it finishes.
+func doExecContext(
+	ctx context.Context,
+	mode doExecMode,
+	dir string,
+	envs map[string]string,
+	name string,
+	args ...string,
+) (string, error) {
+	return doExecOutput(ctx, mode, dir, envs, nil, name, args...)
+}
+
+// doExecOutput is like doExecContext, but also copies the command's stdout
+// to out if it is not nil.
+func doExecOutput(
+	ctx context.Context,
+	mode doExecMode,
+	dir string,
+	envs map[string]string,
+	out io.Writer,
+	name string,
+	args ...string,
 ) (_ string, err error) {
 	start := time.Now()
-	cmd := exec.Command(name, args...)
+	config := execConfig(ctx)
+	cmd := config.Command(ctx, name, args...)
 	cmd.Dir = dir
 	envSlice := lo.MapToSlice(envs, func(k, v string) string { return fmt.Sprintf("%s=%s", k, v) })
-	cmd.Env = append(os.Environ(), envSlice...)
+	cmd.Env = config.environ(dir, envs)
 	var bufStderr bytes.Buffer
 	var bufStdout bytes.Buffer
 	var stdout io.Writer = &bufStdout
@@ -79,10 +99,13 @@ func doExec(
 	default:
 		panic(fmt.Errorf("unknown mode %v", mode))
 	}
+	if out != nil {
+		stdout = io.MultiWriter(stdout, out)
+	}
 	cmd.Stderr = stderr
 	cmd.Stdout = stdout
 	defer func() {
-		log.Debug().
+		zerolog.Ctx(ctx).Debug().
 			Err(err).
 			Str("dir", dir).
 			Strs("env", envSlice).
@@ -91,62 +114,603 @@ func doExec(
 			Stringer("mode", mode).
 			Msg("exec")
 	}()
-	b, err := cmd.Output()
-	if err != nil {
-		return "", execError{
-			Command: fmt.Sprintf("%v", cmd),
-			Stdout:  bufStdout.String(),
-			Stderr:  bufStderr.String(),
-			Err:     err,
-		}
+	if err := cmd.Run(); err != nil {
+		return "", newExecError(cmd, bufStdout.String(), bufStderr.String(), err)
 	}
-	out := strings.TrimSpace(string(b))
-	return out, nil
+	return strings.TrimSpace(bufStdout.String()), nil
 }
 
 func TransitiveEdges(pkg string) ([]*DirectedEdge, error) {
-	target := reModVersion.ReplaceAllString(pkg, "")
-	log := log.With().Str("pkg", pkg).Str("target", target).Logger()
-	log.Debug().Msg("listing packages")
-	dir, err := os.MkdirTemp("", "*-pkgrank")
+	return TransitiveEdgesContext(context.Background(), pkg)
+}
+
+// TransitiveEdgesContext is like TransitiveEdges, but stops and returns an
+// error once ctx is done, and logs to the logger of ctx, see zerolog.Ctx.
+func TransitiveEdgesContext(ctx context.Context, pkg string) ([]*DirectedEdge, error) {
+	g, err := TransitiveGraphContext(ctx, pkg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp dir: %w", err)
-	}
-	log.Debug().Str("dir", dir).Msg("using temp dir")
-	const rootPkg = "pkgrank"
-	if _, err := doExec(execQuiet, dir, nil, "go", "mod", "init", rootPkg); err != nil {
 		return nil, err
 	}
-	if _, err := doExec(execQuiet, dir, nil, "go", "get", pkg); err != nil {
+	return directedEdges(g)
+}
+
+// TransitiveGraph returns the transitive dependency graph of pkg, which may
+// have an @version suffix. The graph's Container is the import path of pkg.
+func TransitiveGraph(pkg string) (*Graph, error) {
+	return TransitiveGraphContext(context.Background(), pkg)
+}
+
+// TransitiveGraphContext is like TransitiveGraph, but stops and returns an
+// error once ctx is done. It logs to the logger of ctx, see zerolog.Ctx, as
+// does the returned graph.
+func TransitiveGraphContext(ctx context.Context, pkg string) (*Graph, error) {
+	dir, target, err := prepareModule(ctx, pkg)
+	if err != nil {
 		return nil, err
 	}
-	mainContent := fmt.Sprintf("package main \n import _ \"%s\"", target)
-	mainFile := filepath.Join(dir, "main.go")
-	if err := os.WriteFile(mainFile, []byte(mainContent), 0644); err != nil {
+	defer removeModule(ctx, dir)
+	return runDepgraph(ctx, dir, target, nil)
+}
+
+type depgraphKey struct{}
+
+// WithDepgraph returns a copy of ctx with which TransitiveGraphContext and
+// the other functions taking a context run the depgraph binary at path,
+// rather than the one named depgraph in the PATH.
+func WithDepgraph(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, depgraphKey{}, path)
+}
+
+// depgraphPath returns the depgraph binary of ctx, or else the one that
+// LookDepgraph finds.
+func depgraphPath(ctx context.Context) string {
+	if path, ok := ctx.Value(depgraphKey{}).(string); ok && path != "" {
+		return path
+	}
+	if path, err := LookDepgraph(); err == nil {
+		return path
+	}
+	return "depgraph"
+}
+
+type skipGeneratedKey struct{}
+
+// WithSkipGenerated returns a copy of ctx with which TransitiveGraphContext
+// and the other functions taking a context leave generated files out of the
+// graph, with their imports and uses, rather than tagging the imports that
+// only they make as EdgeKindGenerated. Generated protobuf or mock files
+// otherwise tend to dominate the weights of the packages they import.
+func WithSkipGenerated(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipGeneratedKey{}, true)
+}
+
+// depgraphArgs returns the flags of depgraph that ctx asks for, followed by
+// args.
+func depgraphArgs(ctx context.Context, args ...string) []string {
+	var flags []string
+	if skip, _ := ctx.Value(skipGeneratedKey{}).(bool); skip {
+		flags = append(flags, "-skip-generated")
+	}
+	return append(flags, args...)
+}
+
+// LookDepgraph returns the path of the depgraph binary in the PATH, or else
+// of the one next to the running executable, as when both are unpacked from
+// a release archive, or else of the one in GOBIN or GOPATH/bin, where go
+// install puts it even if they are not in the PATH.
+func LookDepgraph() (string, error) {
+	if path, err := exec.LookPath("depgraph"); err == nil {
+		return path, nil
+	}
+	name := "depgraph"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	var dirs []string
+	if exe, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(exe))
+	}
+	if gobin := os.Getenv("GOBIN"); gobin != "" {
+		dirs = append(dirs, gobin)
+	}
+	for _, gopath := range filepath.SplitList(build.Default.GOPATH) {
+		dirs = append(dirs, filepath.Join(gopath, "bin"))
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("depgraph is not in the PATH, next to the executable, or in GOBIN or GOPATH/bin: %w", exec.ErrNotFound)
+}
+
+// TransitiveEdgesStream calls fn with each edge of the transitive dependency
+// graph of pkg as depgraph discovers it, rather than once the whole graph is
+// built. Edges are not deduplicated across build variants of a package, and
+// the order is unspecified. Like those of TransitiveGraph, they are between
+// the import paths of vendored packages. Streaming stops at the first error
+// from fn or when ctx is done, which is returned.
+func TransitiveEdgesStream(ctx context.Context, pkg string, fn func(*DirectedEdge) error) (err error) {
+	dir, target, err := prepareModule(ctx, pkg)
+	if err != nil {
+		return err
+	}
+	defer removeModule(ctx, dir)
+	cmd := exec.CommandContext(ctx, depgraphPath(ctx), depgraphArgs(ctx, "-output=json",
+		"-graph-out="+filepath.Join(dir, "graph.json"), "-edges-out=-", ".")...)
+	cmd.Dir = dir
+	cmd.Env = execConfig(ctx).environ(dir, map[string]string{
+		"DEPGRAPH_ROOT_PKG": target,
+		"LOG_LEVEL":         "info",
+		"LOG_FORMAT":        "console",
+	})
+	var bufStderr bytes.Buffer
+	cmd.Stderr = io.MultiWriter(&bufStderr, os.Stderr)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return newExecError(cmd, "", "", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+			return
+		}
+		if werr := cmd.Wait(); werr != nil {
+			err = newExecError(cmd, "", bufStderr.String(), werr)
+		}
+	}()
+	// Malformed edges are reported together once depgraph is done, rather
+	// than leaving it blocked on a pipe that is no longer read.
+	r := bufio.NewReader(stdout)
+	parseErr := ParseError{Source: "depgraph edges"}
+	for n := 1; ; n++ {
+		line, err := readLine(r)
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+		if err == io.EOF {
+			return parseErr.err()
+		} else if err == errLineTooLong {
+			parseErr.add(fmt.Errorf("line %d: %w", n, err))
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to read depgraph edges: %w", err)
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var edge DirectedEdge
+		if err := json.Unmarshal(line, &edge); err != nil {
+			parseErr.add(fmt.Errorf("line %d: %w", n, err))
+			continue
+		}
+		// The scratch package only imports pkg, and is not part of its graph.
+		if edge.Key().container == scratchPkg {
+			continue
+		}
+		if err := fn(unvendorEdge(&edge)); err != nil {
+			return err
+		}
+	}
+}
+
+// BuildConfig is a build configuration to construct a graph under.
+type BuildConfig struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string
+}
+
+// ParseBuildConfig parses a build configuration of the form
+// "goos/goarch[:tag,...]", e.g. "linux/amd64" or "windows/arm64:netgo,osusergo".
+func ParseBuildConfig(s string) (BuildConfig, error) {
+	invalid := errors.Errorf("invalid build config %q, want goos/goarch[:tag,...]", s)
+	platform, tags, hasTags := strings.Cut(s, ":")
+	goos, goarch, ok := strings.Cut(platform, "/")
+	if !ok || goos == "" || goarch == "" || strings.ContainsAny(goarch, "/ ") || strings.Contains(goos, " ") {
+		return BuildConfig{}, invalid
+	}
+	c := BuildConfig{GOOS: goos, GOARCH: goarch}
+	if hasTags {
+		c.Tags = strings.Split(tags, ",")
+		for _, tag := range c.Tags {
+			if tag == "" || strings.ContainsAny(tag, ": ") {
+				return BuildConfig{}, invalid
+			}
+		}
+	}
+	return c, nil
+}
+
+func (c BuildConfig) String() string {
+	s := c.GOOS + "/" + c.GOARCH
+	if len(c.Tags) > 0 {
+		s += ":" + strings.Join(c.Tags, ",")
+	}
+	return s
+}
+
+func (c BuildConfig) envs() map[string]string {
+	envs := map[string]string{
+		"GOOS":   c.GOOS,
+		"GOARCH": c.GOARCH,
+	}
+	if len(c.Tags) > 0 {
+		envs["GOFLAGS"] = "-tags=" + strings.Join(c.Tags, ",")
+	}
+	return envs
+}
+
+// TransitiveEdgesMatrix is like TransitiveEdges, but constructs the graph
+// once under each of the given build configurations, see
+// TransitiveGraphMatrix.
+func TransitiveEdgesMatrix(pkg string, configs ...BuildConfig) ([]*DirectedEdge, error) {
+	g, err := TransitiveGraphMatrix(context.Background(), pkg, configs...)
+	if err != nil {
 		return nil, err
 	}
-	if _, err := doExec(execQuiet, dir, nil, "go", "fmt", mainFile); err != nil {
+	return directedEdges(g)
+}
+
+// TransitiveGraphMatrix is like TransitiveGraphContext, but constructs the
+// graph once under each of the given build configurations, and merges them
+// with MergeConfigs.
+func TransitiveGraphMatrix(ctx context.Context, pkg string, configs ...BuildConfig) (*Graph, error) {
+	dir, target, err := prepareModule(ctx, pkg)
+	if err != nil {
 		return nil, err
 	}
-	if _, err := doExec(execQuiet, dir, nil, "go", "mod", "tidy"); err != nil {
-		fmt.Println("FAILED", dir)
+	defer removeModule(ctx, dir)
+	graphs := make([]*Graph, len(configs))
+	for i, config := range configs {
+		zerolog.Ctx(ctx).Debug().Str("pkg", pkg).Stringer("config", config).Msg("constructing graph")
+		if graphs[i], err = runDepgraph(ctx, dir, target, config.envs()); err != nil {
+			return nil, fmt.Errorf("failed to construct graph for %v: %w", config, err)
+		}
+	}
+	return MergeConfigs(configs, graphs)
+}
+
+// MergeConfigs merges the graphs of a package constructed under each of the
+// given build configurations, in the same order. Each edge's Configs lists
+// the configurations in which it exists, and its weight is the largest
+// weight among them. Its symbols and provenance are the union of theirs, and
+// its kind the kinds common to all. Nodes keep the data of the last graph
+// having them. The graphs are left unchanged.
+func MergeConfigs(configs []BuildConfig, graphs []*Graph) (*Graph, error) {
+	if len(configs) != len(graphs) {
+		return nil, fmt.Errorf("got %d graphs of %d build configs", len(graphs), len(configs))
+	}
+	merged := &Graph{}
+	opt := WithMergeFunc(maxWeightMerge)
+	for i, g := range graphs {
+		if merged.Container == "" {
+			merged.Container = g.Container
+		}
+		edges, err := directedEdges(g)
+		if err != nil {
+			return nil, err
+		}
+		for _, edge := range edges {
+			edge := *edge
+			edge.Configs = []string{configs[i].String()}
+			if _, err := merged.AddEdge(&edge, opt); err != nil {
+				return nil, err
+			}
+		}
+		for key, node := range g.Nodes {
+			if node.Data != nil {
+				merged.AddNode(key, node.Data)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// scratchPkg is the path of the scratch module created by prepareModule, and
+// of its main package.
+const scratchPkg = "pkgrank"
+
+// prepareModule creates a scratch module in a temp dir that imports pkg at
+// its version, if any, and returns the dir and the import path of pkg
+// without the version suffix, including the major version element of the
+// module path that the version requires, see majorVersionPaths.
+func prepareModule(ctx context.Context, pkg string) (dir, target string, err error) {
+	target, version := SplitVersion(pkg)
+	// A valid import path can neither be taken for a flag of go get nor
+	// break out of the import of the generated main.go.
+	if err := module.CheckImportPath(target); err != nil {
+		return "", "", fmt.Errorf("invalid package %q: %w", pkg, err)
+	}
+	log := zerolog.Ctx(ctx).With().Str("pkg", pkg).Str("target", target).Logger()
+	log.Debug().Msg("listing packages")
+	dir, err = os.MkdirTemp("", "*-pkgrank")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	log.Debug().Str("dir", dir).Msg("using temp dir")
+	defer func(dir string) {
+		if err != nil {
+			removeModule(ctx, dir)
+		}
+	}(dir)
+	ReportProgress(ctx, Progress{Phase: PhaseFetch})
+	if _, err := doExecContext(ctx, execQuiet, dir, nil, "go", "mod", "init", scratchPkg); err != nil {
+		return "", "", err
+	}
+	if _, err := doExecContext(ctx, execQuiet, dir, nil, "go", "get", pkg); err != nil {
+		found := false
+		for _, path := range majorVersionPaths(target, version) {
+			if _, perr := doExecContext(ctx, execQuiet, dir, nil, "go", "get", path+"@"+version); perr == nil {
+				log.Debug().Str("path", path).Msg("using major version path")
+				target, found = path, true
+				break
+			}
+		}
+		if !found {
+			return "", "", err
+		}
+	}
+	mainContent := fmt.Sprintf("package main\n\nimport _ %s\n", strconv.Quote(target))
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(mainContent), 0644); err != nil {
+		return "", "", err
+	}
+	if _, err := doExecContext(ctx, execQuiet, dir, nil, "go", "mod", "tidy"); err != nil {
+		return "", "", err
+	}
+	return dir, target, nil
+}
+
+// removeModule removes the scratch module in dir once it has been analyzed,
+// or failed to be, unless the ExecConfig of ctx keeps it.
+func removeModule(ctx context.Context, dir string) {
+	if execConfig(ctx).KeepWorkdir {
+		zerolog.Ctx(ctx).Warn().Str("dir", dir).Msg("keeping temp dir")
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Str("dir", dir).Msg("failed to remove temp dir")
+	}
+}
+
+// Workdirs returns the scratch directories that this package left in the
+// temp directory, because they were kept, see ExecConfig.KeepWorkdir, or
+// because the process was killed before removing them.
+func Workdirs() ([]string, error) {
+	entries, err := os.ReadDir(os.TempDir())
+	if err != nil {
 		return nil, err
 	}
+	var dirs []string
+	for _, e := range entries {
+		prefix, ok := strings.CutSuffix(e.Name(), "-pkgrank")
+		if !ok {
+			prefix, ok = strings.CutSuffix(e.Name(), "-pkgrank-history")
+		}
+		// os.MkdirTemp replaces the * of the patterns with digits.
+		if !ok || !e.IsDir() || prefix == "" || strings.Trim(prefix, "0123456789") != "" {
+			continue
+		}
+		dirs = append(dirs, filepath.Join(os.TempDir(), e.Name()))
+	}
+	return dirs, nil
+}
+
+// runDepgraph runs the depgraph analyzer over the scratch module in dir with
+// the given additional environment, and returns the graph of target, with
+// vendored packages renamed to their import paths, see Graph.Unvendor.
+func runDepgraph(ctx context.Context, dir, target string, extraEnvs map[string]string) (*Graph, error) {
 	envs := map[string]string{
 		"DEPGRAPH_ROOT_PKG": target,
 		"LOG_LEVEL":         "info",
 		"LOG_FORMAT":        "console",
 	}
-	out, err := doExec(execPipeCombined, dir, envs, "depgraph", ".")
+	for k, v := range extraEnvs {
+		envs[k] = v
+	}
+	graphFile := filepath.Join(dir, "graph.json")
+	args := depgraphArgs(ctx, "-output=json", "-graph-out="+graphFile)
+	mode := execPipeCombined
+	var out io.Writer
+	if hasProgress(ctx) {
+		// Count the packages to analyze, and read the events of depgraph
+		// from its stdout rather than interleaving its logs with progress.
+		ReportProgress(ctx, Progress{Phase: PhaseLoad})
+		deps, err := doExecContext(ctx, execQuiet, dir, extraEnvs, "go", "list", "-deps", target)
+		if err != nil {
+			return nil, err
+		}
+		total := len(strings.Fields(deps))
+		ReportProgress(ctx, Progress{Phase: PhaseAnalyze, Total: total})
+		args = append(args, "-progress-out=-")
+		mode, out = execQuiet, &progressWriter{ctx: ctx, total: total}
+	}
+	if _, err := doExecOutput(ctx, mode, dir, envs, out, depgraphPath(ctx), append(args, ".")...); err != nil {
+		return nil, err
+	}
+	b, err := readOutput(graphFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read depgraph output: %w", err)
+	}
+	var g Graph
+	if err := json.Unmarshal(b, &g); err != nil {
+		return nil, fmt.Errorf("failed to decode depgraph output: %w", err)
+	}
+	g.SetLogger(zerolog.Ctx(ctx))
+	return g.Unvendor()
+}
+
+// ListModule returns the import graph of the packages of the module in dir,
+// including the imports of their tests, and its main packages. Packages
+// imported from outside the module are nodes without data, whose own imports
+// are omitted. The graph's Container is the module path. Vendored packages,
+// as of GOPATH mode, are renamed to their import paths, see Graph.Unvendor.
+func ListModule(dir string) (*Graph, []NodeKey, error) {
+	pkgs, err := listPackages(dir, "./...")
+	if err != nil {
+		return nil, nil, err
+	}
+	g := &Graph{}
+	var mains []NodeKey
+	for _, p := range pkgs {
+		if p.Module != nil {
+			g.Container = p.Module.Path
+		}
+		if p.Name == "main" {
+			mains = append(mains, NodeKey{ID: p.ImportPath})
+		}
+	}
+	for _, p := range pkgs {
+		if err := addListedPackage(g, p); err != nil {
+			return nil, nil, err
+		}
+	}
+	if g, err = g.Unvendor(); err != nil {
+		return nil, nil, err
+	}
+	return g, mains, nil
+}
+
+// listPackage is a package as listed by go list -json.
+type listPackage struct {
+	Dir          string
+	ImportPath   string
+	Name         string
+	GoFiles      []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+	Module       *struct{ Path, Dir string }
+}
+
+// listPackages lists the packages matching the patterns in dir.
+func listPackages(dir string, patterns ...string) ([]listPackage, error) {
+	out, err := doExec(execQuiet, dir, nil, "go", append([]string{"list", "-e", "-json"}, patterns...)...)
 	if err != nil {
 		return nil, err
 	}
-	scanner := bufio.NewScanner(strings.NewReader(out))
-	var edges []*DirectedEdge
-	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
-		src, dst := parts[0], parts[1]
-		edges = append(edges, NewDirectedEdge("", src, dst))
+	var pkgs []listPackage
+	dec := json.NewDecoder(strings.NewReader(out))
+	for dec.More() {
+		var p listPackage
+		if err := dec.Decode(&p); err != nil {
+			return nil, fmt.Errorf("failed to decode go list output: %w", err)
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// addListedPackage adds a listed package and the edges of its imports to g.
+func addListedPackage(g *Graph, p listPackage) error {
+	data := &NodeData{}
+	if p.Module != nil {
+		data.Module = p.Module.Path
+	}
+	g.AddNode(NodeKey{ID: p.ImportPath}, data)
+	provenance, generated := fileImports(g.Logger(), p.ImportPath, p.Dir, p.GoFiles)
+	for _, imp := range p.Imports {
+		if imp != "C" {
+			edge := NewDirectedEdge(g.Container, p.ImportPath, imp)
+			edge.Provenance = provenance[imp]
+			edge.Kind = ImportKind(edge.Provenance) | GeneratedKind(edge.Provenance, generated) | p.importKind(imp)
+			if _, err := g.AddEdge(edge); err != nil {
+				return err
+			}
+		}
+	}
+	testProvenance, testGenerated := fileImports(g.Logger(), p.ImportPath, p.Dir, append(p.TestGoFiles, p.XTestGoFiles...))
+	for _, imp := range append(p.TestImports, p.XTestImports...) {
+		if imp != "C" && imp != p.ImportPath {
+			edge := NewDirectedEdge(g.Container, p.ImportPath, imp)
+			edge.Provenance = testProvenance[imp]
+			edge.Kind = EdgeKindTest | ImportKind(edge.Provenance) | GeneratedKind(edge.Provenance, testGenerated) | p.importKind(imp)
+			if _, err := g.AddEdge(edge); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// importKind returns the kinds of an edge from the package to the package it
+// imports by path, by PackageKind, except that packages of its own module are
+// never of the standard library, and those of its module's vendor directory
+// are vendored.
+func (p listPackage) importKind(path string) EdgeKind {
+	kind := PackageKind(path, "")
+	if p.Module == nil {
+		return kind
+	}
+	if path == p.Module.Path || strings.HasPrefix(path, p.Module.Path+"/") {
+		kind &^= EdgeKindStdlib
+	}
+	if p.Module.Dir != "" {
+		if _, err := os.Stat(filepath.Join(p.Module.Dir, "vendor", filepath.FromSlash(path))); err == nil {
+			kind |= EdgeKindVendored
+		}
+	}
+	return kind
+}
+
+// fileImports parses the imports of the given files of the package at path in
+// dir, and returns where each import path is imported and which of the files
+// are generated. Files are identified by the package path and their base
+// name, like the depgraph analyzer does. Files that fail to parse are logged
+// to log and skipped.
+func fileImports(log *zerolog.Logger, path, dir string, files []string) (map[string][]Provenance, map[string]bool) {
+	provenance := make(map[string][]Provenance)
+	generated := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, name := range files {
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			log.Debug().Err(err).Str("file", name).Msg("failed to parse imports")
+			continue
+		}
+		if ast.IsGenerated(file) {
+			generated[path+"/"+name] = true
+		}
+		for _, spec := range file.Imports {
+			imp, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			p := Provenance{
+				File: path + "/" + name,
+				Line: fset.Position(spec.Pos()).Line,
+			}
+			if spec.Name != nil {
+				switch spec.Name.Name {
+				case "_":
+					p.Style = ImportStyleBlank
+				case ".":
+					p.Style = ImportStyleDot
+				default:
+					p.Style = ImportStyleNamed
+				}
+			}
+			provenance[imp] = append(provenance[imp], p)
+		}
+	}
+	return provenance, generated
+}
+
+func directedEdges(g *Graph) ([]*DirectedEdge, error) {
+	edges := make([]*DirectedEdge, 0, g.Size())
+	for _, edge := range g.SortedEdges() {
+		edge, ok := edge.(*DirectedEdge)
+		if !ok {
+			return nil, fmt.Errorf("unsupported edge type: %T", edge)
+		}
+		edges = append(edges, edge)
 	}
 	return edges, nil
 }
@@ -157,7 +721,40 @@ func TransitiveEdges(pkg string) ([]*DirectedEdge, error) {
 // https://github.com/golang/go/wiki/Modules#quick-start
 // https://dave.cheney.net/2014/09/14/go-list-your-swiss-army-knife
 
-var reModVersion = regexp.MustCompile(`(@\w+)$`)
+// SplitVersion splits a package path with an optional version suffix, e.g.
+// "golang.org/x/mod/semver@v0.12.0", into the path and the version, which
+// is empty if there is none. The version may be any query of go get, such
+// as a semantic version, a branch, a commit or "latest".
+func SplitVersion(pkg string) (path, version string) {
+	if i := strings.LastIndex(pkg, "@"); i >= 0 {
+		return pkg[:i], pkg[i+1:]
+	}
+	return pkg, ""
+}
+
+// majorVersionPaths returns the paths to try for the package path at a
+// semantic version of major version 2 or higher, if path lacks its major
+// version element: those of the package in modules whose path is a prefix
+// of path followed by the element, e.g. example.com/m/v2/sub for
+// example.com/m/sub at v2.1.0, longest module path first like go get.
+func majorVersionPaths(path, version string) []string {
+	major := semver.Major(version)
+	if major == "" || major == "v0" || major == "v1" || strings.HasSuffix(semver.Build(version), "+incompatible") {
+		return nil
+	}
+	elems := strings.Split(path, "/")
+	for _, elem := range elems {
+		if elem == major {
+			return nil
+		}
+	}
+	var paths []string
+	// A module path has at least a host and another element.
+	for i := len(elems); i >= 2; i-- {
+		paths = append(paths, strings.Join(append(append(elems[:i:i], major), elems[i:]...), "/"))
+	}
+	return paths
+}
 
 type ImportGraph struct {
 	g          *simple.WeightedDirectedGraph
@@ -185,6 +782,7 @@ type CentralityMeasure string
 const (
 	InvalidCentrality  CentralityMeasure = "invalid"
 	PageRankCentrality CentralityMeasure = "pagerank"
+	CorenessCentrality CentralityMeasure = "coreness"
 )
 
 // NewCentralityMeasure returns a new CentralityMeasure from the given raw
@@ -193,6 +791,8 @@ func NewCentralityMeasure(s string) (CentralityMeasure, error) {
 	switch s {
 	case "pagerank":
 		return PageRankCentrality, nil
+	case "coreness":
+		return CorenessCentrality, nil
 	default:
 		return InvalidCentrality, errors.Errorf("unsupported centrality measure: %s", s)
 	}
@@ -202,10 +802,54 @@ func NewCentralityMeasure(s string) (CentralityMeasure, error) {
 // import graph, with the most important listed first. A corresponding slice of
 // importances is also returned.
 func (g *ImportGraph) Centrality() ([]string, []float64) {
+	imps, scores, _ := g.CentralityBy(PageRankCentrality)
+	return imps, scores
+}
+
+// CentralityBy is like Centrality, but measures importance by the given
+// centrality measure. Ties are listed in order of import path.
+func (g *ImportGraph) CentralityBy(measure CentralityMeasure) ([]string, []float64, error) {
+	return g.centrality(measure, newCentralityOptions(nil))
+}
+
+func (g *ImportGraph) centrality(measure CentralityMeasure, o centralityOptions) ([]string, []float64, error) {
 	if g.Len() == 0 {
-		return nil, nil
+		return nil, nil, nil
+	}
+	var centrality map[int64]float64
+	switch measure {
+	case PageRankCentrality:
+		// Unlike network.PageRank, which starts from random ranks, the
+		// CSR's are the same from run to run.
+		centrality = g.csrPageRank(o.damping, o.tolerance)
+	case CorenessCentrality:
+		adj := make(map[int64]map[int64]int)
+		edges := g.g.Edges()
+		for edges.Next() {
+			e := edges.Edge()
+			from, to := e.From().ID(), e.To().ID()
+			if from == to {
+				continue
+			}
+			if adj[from] == nil {
+				adj[from] = make(map[int64]int)
+			}
+			if adj[to] == nil {
+				adj[to] = make(map[int64]int)
+			}
+			adj[from][to]++
+			adj[to][from]++
+		}
+		centrality = make(map[int64]float64, g.Len())
+		for id := range g.idToImport {
+			centrality[id] = 0
+		}
+		for id, k := range coreness(adj) {
+			centrality[id] = float64(k)
+		}
+	default:
+		return nil, nil, errors.Errorf("unsupported centrality measure: %s", measure)
 	}
-	centrality := network.PageRank(g.g, 0.85, 0.0001)
 	type sortable struct {
 		imp   string
 		score float64
@@ -218,7 +862,10 @@ func (g *ImportGraph) Centrality() ([]string, []float64) {
 		})
 	}
 	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].score > sorted[j].score
+		if sorted[i].score != sorted[j].score {
+			return sorted[i].score > sorted[j].score
+		}
+		return sorted[i].imp < sorted[j].imp
 	})
 	imps := make([]string, 0, len(centrality))
 	scores := make([]float64, 0, len(centrality))
@@ -226,7 +873,36 @@ func (g *ImportGraph) Centrality() ([]string, []float64) {
 		imps = append(imps, s.imp)
 		scores = append(scores, s.score)
 	}
-	return imps, scores
+	return imps, scores, nil
+}
+
+// csrPageRank computes PageRank on a CSR of the graph, keyed by node ID.
+func (g *ImportGraph) csrPageRank(damp, tol float64) map[int64]float64 {
+	ids := make([]string, 0, len(g.importToID))
+	for imp := range g.importToID {
+		ids = append(ids, imp)
+	}
+	sort.Strings(ids)
+	index := make(map[int64]int32, len(ids))
+	for i, imp := range ids {
+		index[g.importToID[imp]] = int32(i)
+	}
+	edges := make([]csrEdge, 0, g.g.Edges().Len())
+	it := g.g.WeightedEdges()
+	for it.Next() {
+		e := it.WeightedEdge()
+		edges = append(edges, csrEdge{
+			src:    index[e.From().ID()],
+			dst:    index[e.To().ID()],
+			weight: float32(e.Weight()),
+		})
+	}
+	ranks := newCSR(ids, edges).PageRank(damp, tol)
+	centrality := make(map[int64]float64, len(ranks))
+	for i, rank := range ranks {
+		centrality[g.importToID[ids[i]]] = rank
+	}
+	return centrality
 }
 
 // UpdateEdge increases the weight on a directed edge between two imports in
@@ -234,20 +910,24 @@ func (g *ImportGraph) Centrality() ([]string, []float64) {
 // exist. If nodes coressponding to the imports don't already exist, then they
 // are created.
 func (g *ImportGraph) UpdateEdge(imp1, imp2 string) {
+	g.addWeight(imp1, imp2, 1)
+}
+
+// addWeight is like UpdateEdge, but increases the weight by the given amount.
+func (g *ImportGraph) addWeight(imp1, imp2 string, weight float64) {
 	n1, n2 := g.AddNode(imp1), g.AddNode(imp2)
 	we := g.g.WeightedEdge(n1.ID(), n2.ID())
 	if we == nil {
-		we = g.g.NewWeightedEdge(n1, n2, 1)
+		we = g.g.NewWeightedEdge(n1, n2, weight)
 	} else {
 		// Note that this case won't occur if we only loop over the
 		// unique set of package imports, since imp1 is listed
 		// uniquely. But it can occur if we iterate over imports
 		// duplicately such as by file, or additionally including test
 		// imports.
-		we = g.g.NewWeightedEdge(n1, n2, we.Weight()+1)
+		we = g.g.NewWeightedEdge(n1, n2, we.Weight()+weight)
 	}
 	g.g.SetWeightedEdge(we)
-
 }
 
 // AddNode idempotently returns a node representing the given import in the
@@ -0,0 +1,186 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// TypedGraph is a directed graph whose nodes and edges carry payloads of the
+// user-defined types N and E, for analyses that need richer data than
+// NodeData and DirectedEdge hold, such as functions or symbols. There is at
+// most one edge from a node to another, and adding it again merges the
+// payloads. It is not safe for concurrent use.
+type TypedGraph[N, E any] struct {
+	// Container is the name of the container that the graph represents.
+	Container string
+
+	nodes map[NodeKey]N
+	succs map[NodeKey]map[NodeKey]E
+	preds map[NodeKey]map[NodeKey]struct{}
+	size  int
+	merge func(prev, next E) E
+}
+
+// PackageGraph is the TypedGraph of an import graph, holding the same data as
+// a Graph of directed edges.
+type PackageGraph = TypedGraph[*NodeData, *DirectedEdge]
+
+// NewTypedGraph returns an empty graph for the given container. Adding an
+// edge that already exists replaces its payload with merge(prev, next), or
+// with next if merge is nil.
+func NewTypedGraph[N, E any](container string, merge func(prev, next E) E) *TypedGraph[N, E] {
+	return &TypedGraph[N, E]{
+		Container: container,
+		nodes:     make(map[NodeKey]N),
+		succs:     make(map[NodeKey]map[NodeKey]E),
+		preds:     make(map[NodeKey]map[NodeKey]struct{}),
+		merge:     merge,
+	}
+}
+
+// Order returns the number of nodes in the graph.
+func (g *TypedGraph[N, E]) Order() int {
+	return len(g.nodes)
+}
+
+// Size returns the number of edges in the graph.
+func (g *TypedGraph[N, E]) Size() int {
+	return g.size
+}
+
+// AddNode adds a node to the graph, replacing the payload of any existing
+// node with the same key.
+func (g *TypedGraph[N, E]) AddNode(key NodeKey, data N) {
+	g.nodes[key] = data
+}
+
+// Node returns the payload of a node, and whether it is in the graph.
+func (g *TypedGraph[N, E]) Node(key NodeKey) (N, bool) {
+	data, ok := g.nodes[key]
+	return data, ok
+}
+
+// AddEdge adds an edge from src to dst, along with any of its nodes that are
+// missing, with zero payloads. It reports whether the edge already existed,
+// in which case the payloads are merged.
+func (g *TypedGraph[N, E]) AddEdge(src, dst NodeKey, data E) bool {
+	for _, key := range []NodeKey{src, dst} {
+		if _, ok := g.nodes[key]; !ok {
+			var zero N
+			g.nodes[key] = zero
+		}
+	}
+	if g.succs[src] == nil {
+		g.succs[src] = make(map[NodeKey]E)
+	}
+	prev, ok := g.succs[src][dst]
+	if ok && g.merge != nil {
+		data = g.merge(prev, data)
+	}
+	g.succs[src][dst] = data
+	if !ok {
+		if g.preds[dst] == nil {
+			g.preds[dst] = make(map[NodeKey]struct{})
+		}
+		g.preds[dst][src] = struct{}{}
+		g.size++
+	}
+	return ok
+}
+
+// Edge returns the payload of the edge from src to dst, and whether it is in
+// the graph.
+func (g *TypedGraph[N, E]) Edge(src, dst NodeKey) (E, bool) {
+	data, ok := g.succs[src][dst]
+	return data, ok
+}
+
+// Successors returns the sorted nodes that n has an edge to.
+func (g *TypedGraph[N, E]) Successors(n NodeKey) []NodeKey {
+	return sortedKeys(g.succs[n])
+}
+
+// Predecessors returns the sorted nodes that have an edge to n.
+func (g *TypedGraph[N, E]) Predecessors(n NodeKey) []NodeKey {
+	return sortedKeys(g.preds[n])
+}
+
+// AllNodes returns an iterator over the nodes of the graph and their
+// payloads, in no particular order. It is an iter.Seq2[NodeKey, N].
+func (g *TypedGraph[N, E]) AllNodes() func(yield func(NodeKey, N) bool) {
+	return func(yield func(NodeKey, N) bool) {
+		for key, data := range g.nodes {
+			if !yield(key, data) {
+				return
+			}
+		}
+	}
+}
+
+// AllEdges returns an iterator over the edges of the graph, as the pair of
+// their nodes, and their payloads, in no particular order. It is an
+// iter.Seq2[[2]NodeKey, E].
+func (g *TypedGraph[N, E]) AllEdges() func(yield func([2]NodeKey, E) bool) {
+	return func(yield func([2]NodeKey, E) bool) {
+		for src, dsts := range g.succs {
+			for dst, data := range dsts {
+				if !yield([2]NodeKey{src, dst}, data) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Graph returns a Graph of the typed graph's container, so that the rankings
+// and analyses of Graph apply to it. Each edge is weighted by weight, or by
+// one if weight is nil, and each node's data is given by nodeData, or is nil
+// if nodeData is nil.
+func (g *TypedGraph[N, E]) Graph(nodeData func(N) *NodeData, weight func(E) float64) *Graph {
+	f := &Graph{
+		Container:       g.Container,
+		AddedContainers: map[string]struct{}{g.Container: {}},
+		Edges:           make(map[EdgeKey]Edge, g.size),
+	}
+	for key, data := range g.nodes {
+		var d *NodeData
+		if nodeData != nil {
+			d = nodeData(data)
+		}
+		f.AddNode(key, d)
+	}
+	for src, dsts := range g.succs {
+		for dst, data := range dsts {
+			edge := NewDirectedEdge(g.Container, src.ID, dst.ID)
+			if weight != nil {
+				edge.EdgeWeight = weight(data)
+			}
+			f.Edges[edge.Key()] = edge
+		}
+	}
+	return f
+}
+
+// NewPackageGraph returns the typed graph of the directed edges of f, sharing
+// their data. Edges with the same nodes in different containers are merged
+// as by DefaultMergeFunc. Other types of edges are unsupported.
+func NewPackageGraph(f *Graph) (*PackageGraph, error) {
+	g := NewTypedGraph[*NodeData, *DirectedEdge](f.Container, nil)
+	for key, node := range f.Nodes {
+		g.AddNode(key, node.Data)
+	}
+	for _, edge := range f.Edges {
+		edge, ok := edge.(*DirectedEdge)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T", ErrUnsupportedEdge, edge)
+		}
+		if prev, ok := g.Edge(edge.Src, edge.Dst); ok {
+			merged := *edge
+			if err := DefaultMergeFunc(prev, &merged); err != nil {
+				return nil, err
+			}
+			edge = &merged
+		}
+		g.AddEdge(edge.Src, edge.Dst, edge)
+	}
+	return g, nil
+}
@@ -0,0 +1,53 @@
+package graph
+
+import (
+	"sort"
+)
+
+// Unused returns the sorted nodes of the given module that no node imports,
+// other than the given roots, such as main packages. Nodes belong to module
+// by their NodeData.
+func (f Graph) Unused(module string, roots ...NodeKey) []NodeKey {
+	preds := f.predecessors()
+	isRoot := make(map[NodeKey]bool, len(roots))
+	for _, root := range roots {
+		isRoot[root] = true
+	}
+	var unused []NodeKey
+	for key, node := range f.Nodes {
+		if node.Data == nil || node.Data.Module != module || isRoot[key] {
+			continue
+		}
+		if len(preds[key]) == 0 {
+			unused = append(unused, key)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		return unused[i].ID < unused[j].ID
+	})
+	return unused
+}
+
+// Dead returns the sorted nodes of the given module that are not reachable
+// from any of the roots. Unlike Unused, it includes nodes only imported by
+// other dead nodes.
+func (f Graph) Dead(module string, roots ...NodeKey) []NodeKey {
+	succs := f.successors()
+	live := make(map[NodeKey]bool)
+	for _, root := range roots {
+		live[root] = true
+		for _, n := range reachable(succs, root) {
+			live[n] = true
+		}
+	}
+	var dead []NodeKey
+	for key, node := range f.Nodes {
+		if node.Data != nil && node.Data.Module == module && !live[key] {
+			dead = append(dead, key)
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool {
+		return dead[i].ID < dead[j].ID
+	})
+	return dead
+}
@@ -0,0 +1,150 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CanonicalPath returns the import path of a package in a vendor directory,
+// e.g. "golang.org/x/net/http2/hpack" for the
+// "vendor/golang.org/x/net/http2/hpack" of the standard library or the
+// "example.com/m/vendor/golang.org/x/net/http2/hpack" of GOPATH mode. Other
+// paths are returned unchanged.
+func CanonicalPath(path string) string {
+	if i := strings.LastIndex(path, "/vendor/"); i >= 0 {
+		return path[i+len("/vendor/"):]
+	}
+	if rest, ok := strings.CutPrefix(path, "vendor/"); ok {
+		return rest
+	}
+	return path
+}
+
+// Unvendor returns the graph with the packages of vendor directories renamed
+// to their canonical import paths, see CanonicalPath, so that a package that
+// is both vendored and imported by its own path is counted once. The data of
+// merged nodes is filled in from one another, and merged edges keep the
+// largest weight and the union of their symbols, configurations and
+// provenance, rather than adding up. Edges to renamed packages are tagged
+// EdgeKindVendored. Edges of other types than directed are dropped, as by
+// CollapseBy. Graphs without vendored packages are returned as is.
+func (f *Graph) Unvendor() (*Graph, error) {
+	vendored := false
+	for key := range f.Nodes {
+		if CanonicalPath(key.ID) != key.ID {
+			vendored = true
+			break
+		}
+	}
+	if !vendored {
+		return f, nil
+	}
+	g := &Graph{
+		Container:       CanonicalPath(f.Container),
+		AddedContainers: make(map[string]struct{}, len(f.AddedContainers)),
+		logger:          f.logger,
+	}
+	for c := range f.AddedContainers {
+		g.AddedContainers[CanonicalPath(c)] = struct{}{}
+	}
+	// Add the nodes of canonical paths first, so that their data wins.
+	var renamed []NodeKey
+	for _, key := range f.SortedNodes() {
+		if CanonicalPath(key.ID) != key.ID {
+			renamed = append(renamed, key)
+			continue
+		}
+		g.AddNode(key, f.Nodes[key].Data)
+	}
+	for _, key := range renamed {
+		n := NodeKey{ID: CanonicalPath(key.ID)}
+		data := f.Nodes[key].Data
+		if prev, ok := g.Nodes[n]; ok && prev.Data != nil {
+			merged := *prev.Data
+			if data != nil {
+				merged.merge(data)
+			}
+			data = &merged
+		}
+		g.AddNode(n, data)
+	}
+	opt := WithMergeFunc(maxWeightMerge)
+	for _, edge := range f.SortedEdges() {
+		directed, ok := edge.(*DirectedEdge)
+		if !ok {
+			continue
+		}
+		unvendored := unvendorEdge(directed)
+		if unvendored.Src == unvendored.Dst {
+			continue
+		}
+		if _, err := g.AddEdge(unvendored, opt); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// unvendorEdge returns a copy of edge between the canonical paths of its
+// packages, tagged EdgeKindVendored if its destination is vendored.
+func unvendorEdge(edge *DirectedEdge) *DirectedEdge {
+	src, dst := CanonicalPath(edge.Src.ID), CanonicalPath(edge.Dst.ID)
+	unvendored := NewDirectedEdge(CanonicalPath(edge.Key().container), src, dst)
+	unvendored.EdgeWeight = edge.EdgeWeight
+	unvendored.Attrs = edge.Attrs
+	unvendored.Symbols = edge.Symbols
+	unvendored.Kind = edge.Kind
+	unvendored.Configs = edge.Configs
+	unvendored.Provenance = edge.Provenance
+	if dst != edge.Dst.ID {
+		unvendored.Kind |= EdgeKindVendored
+	}
+	return unvendored
+}
+
+// hasVendor reports whether dir, or the working directory if empty, is in a
+// module with a vendor directory.
+func hasVendor(dir string) bool {
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			_, err := os.Stat(filepath.Join(dir, "vendor", "modules.txt"))
+			return err == nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// vendorFlags returns the go flags without -mod=vendor, unless dir is in a
+// module with a vendor directory. Flags such as GOFLAGS=-mod=vendor of a
+// vendoring repository thus apply to the go commands run in it, but not to
+// those run in scratch modules, which fail with inconsistent vendoring.
+func vendorFlags(dir string, flags []string) []string {
+	if !strings.Contains(strings.Join(flags, " "), "mod=vendor") || hasVendor(dir) {
+		return flags
+	}
+	var kept []string
+	for _, f := range flags {
+		var fields []string
+		for _, field := range strings.Fields(f) {
+			if field != "-mod=vendor" && field != "--mod=vendor" {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) > 0 {
+			kept = append(kept, strings.Join(fields, " "))
+		}
+	}
+	return kept
+}
@@ -0,0 +1,215 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// Vuln is a known vulnerability of a package, as reported by govulncheck.
+type Vuln struct {
+	// ID is the ID of the vulnerability in the Go vulnerability database,
+	// e.g. GO-2023-1234, with Aliases such as CVE IDs.
+	ID      string   `json:"id"`
+	Aliases []string `json:"aliases,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+	// Package is the import path of the vulnerable package, of Module at
+	// Version.
+	Package string `json:"package"`
+	Module  string `json:"module"`
+	Version string `json:"version,omitempty"`
+	// FixedVersion is the earliest version of Module that fixes it, if any.
+	FixedVersion string `json:"fixedVersion,omitempty"`
+	// Called is whether a vulnerable function is called, rather than the
+	// package only being imported.
+	Called bool `json:"called"`
+}
+
+// govulncheckMessage is a message of the JSON stream of govulncheck -json,
+// of which only OSV entries and findings are used.
+type govulncheckMessage struct {
+	OSV *struct {
+		ID       string   `json:"id"`
+		Aliases  []string `json:"aliases"`
+		Summary  string   `json:"summary"`
+		Affected []struct {
+			Package struct {
+				Name string `json:"name"`
+			} `json:"package"`
+			EcosystemSpecific struct {
+				Imports []struct {
+					Path string `json:"path"`
+				} `json:"imports"`
+			} `json:"ecosystem_specific"`
+		} `json:"affected"`
+	} `json:"osv"`
+	Finding *struct {
+		OSV          string `json:"osv"`
+		FixedVersion string `json:"fixed_version"`
+		Trace        []struct {
+			Module   string `json:"module"`
+			Version  string `json:"version"`
+			Package  string `json:"package"`
+			Function string `json:"function"`
+		} `json:"trace"`
+	} `json:"finding"`
+}
+
+// ParseGovulncheck reads the output of govulncheck -json, and returns the
+// vulnerabilities of its findings, one per vulnerable package, sorted by ID
+// and package. Findings of a module-level scan, which do not name a package,
+// are attributed to every package of the module that the vulnerability
+// affects.
+func ParseGovulncheck(r io.Reader) ([]Vuln, error) {
+	type osvInfo struct {
+		aliases []string
+		summary string
+		imports map[string][]string
+	}
+	osvs := make(map[string]osvInfo)
+	found := make(map[[2]string]*Vuln)
+	dec := json.NewDecoder(r)
+	for {
+		var msg govulncheckMessage
+		if err := dec.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode govulncheck output: %w", err)
+		}
+		if o := msg.OSV; o != nil {
+			info := osvInfo{aliases: o.Aliases, summary: o.Summary, imports: make(map[string][]string)}
+			for _, a := range o.Affected {
+				for _, imp := range a.EcosystemSpecific.Imports {
+					info.imports[a.Package.Name] = append(info.imports[a.Package.Name], imp.Path)
+				}
+			}
+			osvs[o.ID] = info
+		}
+		if f := msg.Finding; f != nil && len(f.Trace) > 0 {
+			frame := f.Trace[0]
+			pkgs := []string{frame.Package}
+			if frame.Package == "" {
+				pkgs = osvs[f.OSV].imports[frame.Module]
+			}
+			for _, pkg := range pkgs {
+				key := [2]string{f.OSV, pkg}
+				v, ok := found[key]
+				if !ok {
+					v = &Vuln{
+						ID:           f.OSV,
+						Package:      pkg,
+						Module:       frame.Module,
+						Version:      frame.Version,
+						FixedVersion: f.FixedVersion,
+					}
+					found[key] = v
+				}
+				v.Called = v.Called || frame.Function != ""
+			}
+		}
+	}
+	vulns := make([]Vuln, 0, len(found))
+	for _, v := range found {
+		info := osvs[v.ID]
+		v.Aliases, v.Summary = info.aliases, info.summary
+		vulns = append(vulns, *v)
+	}
+	sort.Slice(vulns, func(i, j int) bool {
+		if vulns[i].ID != vulns[j].ID {
+			return vulns[i].ID < vulns[j].ID
+		}
+		return vulns[i].Package < vulns[j].Package
+	})
+	return vulns, nil
+}
+
+// AddVulns sets the AttrVulns attribute of the nodes of vulnerable packages
+// to the sorted IDs of their vulnerabilities, separated by commas. Packages
+// not in the graph are ignored.
+func (f *Graph) AddVulns(vulns []Vuln) {
+	ids := make(map[NodeKey][]string)
+	for _, v := range vulns {
+		key := NodeKey{ID: v.Package}
+		if _, ok := f.Nodes[key]; ok {
+			ids[key] = append(ids[key], v.ID)
+		}
+	}
+	for key, vs := range ids {
+		sort.Strings(vs)
+		f.SetNodeAttrs(key, func(a *Attrs) {
+			a.SetString(AttrVulns, strings.Join(lo.Uniq(vs), ","))
+		})
+	}
+}
+
+// VulnRank is a vulnerability ranked by the importance of its package.
+type VulnRank struct {
+	Vuln
+	// Score is the centrality of the package.
+	Score float64 `json:"score"`
+	// Reachable is whether the root imports the package, directly or not,
+	// and Depth the fewest imports from the root to it, if so.
+	Reachable bool `json:"reachable"`
+	Depth     int  `json:"depth"`
+}
+
+// depths returns the fewest edges from root to each node that it reaches.
+func (f Graph) depths(root NodeKey) map[NodeKey]int {
+	succs := f.successors()
+	depths := map[NodeKey]int{root: 0}
+	queue := []NodeKey{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for m := range succs[n] {
+			if _, ok := depths[m]; !ok {
+				depths[m] = depths[n] + 1
+				queue = append(queue, m)
+			}
+		}
+	}
+	return depths
+}
+
+// RankVulns ranks vulnerabilities by how much they matter to root: those of
+// packages that root reaches come first, then those with called functions,
+// then those of the most central packages, as measured by measure.
+func (f Graph) RankVulns(root NodeKey, vulns []Vuln, measure CentralityMeasure) ([]VulnRank, error) {
+	ranks, err := Centrality(f, measure)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[NodeKey]float64, len(ranks))
+	for _, r := range ranks {
+		scores[r.Node] = r.Score
+	}
+	depths := f.depths(root)
+	result := make([]VulnRank, len(vulns))
+	for i, v := range vulns {
+		key := NodeKey{ID: v.Package}
+		depth, ok := depths[key]
+		result[i] = VulnRank{Vuln: v, Score: scores[key], Reachable: ok, Depth: depth}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Reachable != b.Reachable {
+			return a.Reachable
+		}
+		if a.Called != b.Called {
+			return a.Called
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return a.Package < b.Package
+	})
+	return result, nil
+}
@@ -0,0 +1,251 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Watcher keeps the graph of a local module up to date, see ListModule. It
+// polls the Go files of the module, and re-lists only the packages whose
+// files changed.
+type Watcher struct {
+	dir   string
+	g     *Graph
+	mains map[NodeKey]bool
+	// dirs maps the directory of each package to its import path and the
+	// fingerprint of its files.
+	dirs map[string]watchedDir
+}
+
+type watchedDir struct {
+	// pkg is the zero key if the directory has no package, e.g. if build
+	// constraints exclude all of its files.
+	pkg         NodeKey
+	fingerprint uint64
+}
+
+// NewWatcher lists the module in dir.
+func NewWatcher(dir string) (*Watcher, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := listPackages(dir, "./...")
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		dir:   dir,
+		g:     &Graph{},
+		mains: make(map[NodeKey]bool),
+		dirs:  make(map[string]watchedDir),
+	}
+	for _, p := range pkgs {
+		if p.Module != nil {
+			w.g.Container = p.Module.Path
+		}
+	}
+	fingerprints, err := w.fingerprints()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pkgs {
+		if err := w.add(p, fingerprints[p.Dir]); err != nil {
+			return nil, err
+		}
+	}
+	// Remember directories without packages, so they are not polled as new.
+	for dir, fingerprint := range fingerprints {
+		if _, ok := w.dirs[dir]; !ok {
+			w.dirs[dir] = watchedDir{fingerprint: fingerprint}
+		}
+	}
+	return w, nil
+}
+
+// Graph returns the graph of the module and its main packages. The graph
+// must not be modified.
+func (w *Watcher) Graph() (*Graph, []NodeKey) {
+	mains := make([]NodeKey, 0, len(w.mains))
+	for key := range w.mains {
+		mains = append(mains, key)
+	}
+	sort.Slice(mains, func(i, j int) bool {
+		return mains[i].ID < mains[j].ID
+	})
+	return w.g, mains
+}
+
+func (w *Watcher) add(p listPackage, fingerprint uint64) error {
+	if len(p.GoFiles)+len(p.TestGoFiles)+len(p.XTestGoFiles) == 0 {
+		w.dirs[p.Dir] = watchedDir{fingerprint: fingerprint}
+		return nil
+	}
+	key := NodeKey{ID: p.ImportPath}
+	if err := addListedPackage(w.g, p); err != nil {
+		return err
+	}
+	if p.Name == "main" {
+		w.mains[key] = true
+	}
+	w.dirs[p.Dir] = watchedDir{pkg: key, fingerprint: fingerprint}
+	return nil
+}
+
+// Poll re-lists the packages whose files were added, changed or removed
+// since the last poll, and returns their sorted import paths. The graph is
+// replaced if any changed. If listing fails, the watcher is left unchanged,
+// so that the next poll lists the same packages again.
+func (w *Watcher) Poll() ([]string, error) {
+	fingerprints, err := w.fingerprints()
+	if err != nil {
+		return nil, err
+	}
+	var changedDirs, removedDirs []string
+	for dir, fingerprint := range fingerprints {
+		if prev, ok := w.dirs[dir]; !ok || prev.fingerprint != fingerprint {
+			changedDirs = append(changedDirs, dir)
+		}
+	}
+	for dir := range w.dirs {
+		if _, ok := fingerprints[dir]; !ok {
+			removedDirs = append(removedDirs, dir)
+		}
+	}
+	if len(changedDirs) == 0 && len(removedDirs) == 0 {
+		return nil, nil
+	}
+	var pkgs []listPackage
+	if len(changedDirs) > 0 {
+		patterns := make([]string, len(changedDirs))
+		for i, dir := range changedDirs {
+			rel, err := filepath.Rel(w.dir, dir)
+			if err != nil {
+				return nil, err
+			}
+			patterns[i] = "./" + filepath.ToSlash(rel)
+		}
+		if pkgs, err = listPackages(w.dir, patterns...); err != nil {
+			return nil, err
+		}
+	}
+	// The changes are applied to a copy of the watcher, which replaces it
+	// once they all are.
+	next := &Watcher{
+		dir:   w.dir,
+		mains: make(map[NodeKey]bool, len(w.mains)),
+		dirs:  make(map[string]watchedDir, len(w.dirs)),
+	}
+	for key := range w.mains {
+		next.mains[key] = true
+	}
+	for dir, d := range w.dirs {
+		next.dirs[dir] = d
+	}
+	var removed []NodeKey
+	for _, dir := range removedDirs {
+		if pkg := next.dirs[dir].pkg; pkg != (NodeKey{}) {
+			removed = append(removed, pkg)
+			delete(next.mains, pkg)
+		}
+		delete(next.dirs, dir)
+	}
+	// Drop the imports of changed packages, which are added again below.
+	var edges [][2]NodeKey
+	for _, dir := range changedDirs {
+		prev, ok := next.dirs[dir]
+		if !ok || prev.pkg == (NodeKey{}) {
+			continue
+		}
+		for _, edge := range w.g.Edges {
+			if edge, ok := edge.(*DirectedEdge); ok && edge.Src == prev.pkg {
+				edges = append(edges, [2]NodeKey{edge.Src, edge.Dst})
+			}
+		}
+		delete(next.mains, prev.pkg)
+	}
+	next.g = w.g.Without(removed, edges)
+	changed := make([]string, 0, len(changedDirs)+len(removed))
+	for _, key := range removed {
+		changed = append(changed, key.ID)
+	}
+	for _, p := range pkgs {
+		if err := next.add(p, fingerprints[p.Dir]); err != nil {
+			return nil, err
+		}
+		if next.dirs[p.Dir].pkg != (NodeKey{}) {
+			changed = append(changed, p.ImportPath)
+		}
+	}
+	*w = *next
+	sort.Strings(changed)
+	w.g.Logger().Debug().Strs("changed", changed).Msg("re-listed packages")
+	return changed, nil
+}
+
+// Watch polls at the given interval until ctx is done, and returns its error.
+// It calls onChange with the changed packages after each poll that found any.
+// Polls that fail are logged to the logger of ctx, see zerolog.Ctx, and
+// retried at the next interval, e.g. once a go.mod being edited is valid.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration, onChange func(changed []string)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			changed, err := w.Poll()
+			if err != nil {
+				zerolog.Ctx(ctx).Warn().Err(err).Str("dir", w.dir).Msg("failed to poll module")
+				continue
+			}
+			if len(changed) > 0 {
+				onChange(changed)
+			}
+		}
+	}
+}
+
+// fingerprints returns a fingerprint of the names, sizes and modification
+// times of the Go files in every package directory of the module.
+func (w *Watcher) fingerprints() (map[string]uint64, error) {
+	hashes := make(map[string]uint64)
+	err := filepath.WalkDir(w.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != w.dir && (name == "vendor" || name == "testdata" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); path != w.dir && err == nil {
+				return filepath.SkipDir // a nested module
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%s %d %d", d.Name(), info.Size(), info.ModTime().UnixNano())
+		hashes[filepath.Dir(path)] += h.Sum64()
+		return nil
+	})
+	return hashes, err
+}
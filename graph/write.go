@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Format is an output format of a graph.
+type Format string
+
+// Available output formats.
+const (
+	// FormatEdgeList writes one "src dst" line per directed edge, sorted.
+	FormatEdgeList Format = "edgelist"
+	// FormatJSON writes the versioned document of Graph.MarshalJSON.
+	FormatJSON Format = "json"
+	// FormatJSONLines writes one JSON object per directed edge and line,
+	// sorted, as encoded by DirectedEdge.MarshalJSON.
+	FormatJSONLines Format = "ndjson"
+	// FormatDOT writes the Graphviz DOT language, see WriteDOT.
+	FormatDOT Format = "dot"
+	// FormatHTML writes an interactive HTML page, see WriteHTML.
+	FormatHTML Format = "html"
+	// FormatCycloneDX writes a CycloneDX SBOM of modules, see WriteCycloneDX.
+	FormatCycloneDX Format = "cyclonedx"
+	// FormatSPDX writes an SPDX SBOM of modules, see WriteSPDX.
+	FormatSPDX Format = "spdx"
+	// FormatCypher writes Cypher statements loading the graph into Neo4j,
+	// see WriteCypher.
+	FormatCypher Format = "cypher"
+	// FormatPajek writes the Pajek .net format, see WritePajek.
+	FormatPajek Format = "pajek"
+	// FormatMatrixMarket writes a sparse adjacency matrix in the Matrix
+	// Market format, see WriteMatrixMarket.
+	FormatMatrixMarket Format = "mtx"
+	// FormatProto writes the protocol buffers encoding of
+	// Graph.MarshalProto.
+	FormatProto Format = "proto"
+	// FormatParquet writes Parquet tables of nodes, edges and ranks into a
+	// directory, see WriteParquet. It is only supported by WriteFile.
+	FormatParquet Format = "parquet"
+)
+
+// Formats are the available output formats.
+var Formats = []Format{
+	FormatEdgeList, FormatJSON, FormatJSONLines, FormatDOT, FormatHTML, FormatCycloneDX,
+	FormatSPDX, FormatCypher, FormatPajek, FormatMatrixMarket, FormatProto, FormatParquet,
+}
+
+// ParseFormat returns the Format named by s.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported output format: %q", s)
+}
+
+// Write writes the graph to w in the given format.
+func Write(w io.Writer, g *Graph, format Format) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(g)
+	case FormatJSONLines:
+		enc := json.NewEncoder(w)
+		for _, edge := range g.SortedEdges() {
+			edge, ok := edge.(*DirectedEdge)
+			if !ok {
+				return fmt.Errorf("unsupported edge type: %T", edge)
+			}
+			if err := enc.Encode(edge); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatEdgeList:
+		for _, edge := range g.SortedEdges() {
+			edge, ok := edge.(*DirectedEdge)
+			if !ok {
+				return fmt.Errorf("unsupported edge type: %T", edge)
+			}
+			if _, err := fmt.Fprintln(w, edge.Src, edge.Dst); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatDOT:
+		return WriteDOT(w, g)
+	case FormatHTML:
+		return WriteHTML(w, g)
+	case FormatCycloneDX:
+		return WriteCycloneDX(w, g)
+	case FormatSPDX:
+		return WriteSPDX(w, g)
+	case FormatCypher:
+		return WriteCypher(w, g)
+	case FormatPajek:
+		return WritePajek(w, g)
+	case FormatMatrixMarket:
+		return WriteMatrixMarket(w, g)
+	case FormatProto:
+		b, err := g.MarshalProto()
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case FormatParquet:
+		return errors.New("parquet tables can only be written to a directory")
+	default:
+		return fmt.Errorf("unsupported output format: %q", format)
+	}
+}
+
+// WriteFile writes the graph to the named file in the given format, or to
+// stdout if name is empty. FormatParquet writes to the named directory
+// instead, which must be given.
+func WriteFile(name string, g *Graph, format Format) (err error) {
+	if format == FormatParquet {
+		if name == "" {
+			return errors.New("parquet tables need an output directory")
+		}
+		return WriteParquet(name, g)
+	}
+	if name == "" {
+		return Write(os.Stdout, g, format)
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return Write(file, g, format)
+}
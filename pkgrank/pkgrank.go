@@ -0,0 +1,284 @@
+// Package pkgrank ranks the dependencies of a Go package in one call. It
+// wraps fetching the package's module, analyzing its transitive imports with
+// the depgraph analyzer, building the graph and measuring centrality, so that
+// programs can embed pkgrank without orchestrating scratch modules and
+// binaries themselves, e.g.
+//
+//	report, err := pkgrank.Rank(ctx, "golang.org/x/tools/go/analysis", pkgrank.WithTop(10))
+//	for _, n := range report.Ranks {
+//		fmt.Println(n.Rank, n.Node, n.Score)
+//	}
+//
+// The graph package provides the building blocks for anything else.
+package pkgrank
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/arclabs561/pkgrank/graph"
+	"github.com/rs/zerolog"
+)
+
+// Report is the result of Rank.
+type Report struct {
+	// Target is the package or directory that was ranked.
+	Target string
+	// Graph is the dependency graph of Target.
+	Graph *graph.Graph
+	// Ranks are the packages of Graph, the most central first.
+	Ranks []graph.RankedNode
+}
+
+// Option configures Rank.
+type Option func(*options)
+
+type options struct {
+	measure       graph.CentralityMeasure
+	centrality    []graph.CentralityOption
+	top           int
+	local         bool
+	exclude       []string
+	kinds         graph.EdgeKind
+	skipGenerated bool
+	depgraph      string
+	install       bool
+	exec          *graph.ExecConfig
+	logger        *zerolog.Logger
+	progress      graph.ProgressFunc
+}
+
+// WithCentrality sets the centrality measure of packages, PageRank by
+// default, along with options such as graph.WithDamping.
+func WithCentrality(measure graph.CentralityMeasure, opts ...graph.CentralityOption) Option {
+	return func(o *options) {
+		o.measure, o.centrality = measure, opts
+	}
+}
+
+// WithTop keeps only the n most central packages in Report.Ranks, or all of
+// them if n is not positive, which is the default.
+func WithTop(n int) Option {
+	return func(o *options) {
+		o.top = n
+	}
+}
+
+// WithLocal ranks the packages of the module in the directory target,
+// including the imports of their tests, rather than a package fetched from
+// the module proxy. Imports from outside the module are not followed, see
+// graph.ListModule.
+func WithLocal() Option {
+	return func(o *options) {
+		o.local = true
+	}
+}
+
+// WithExclude leaves the packages matching any of the patterns out of the
+// graph before ranking it, e.g. "example.com/m/internal/...".
+func WithExclude(patterns ...string) Option {
+	return func(o *options) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// WithExcludeKinds leaves the imports of any of the given kinds out of the
+// graph before ranking it, along with the packages that only they import,
+// e.g. graph.EdgeKindTest|graph.EdgeKindStdlib.
+func WithExcludeKinds(kinds graph.EdgeKind) Option {
+	return func(o *options) {
+		o.kinds |= kinds
+	}
+}
+
+// WithSkipGenerated leaves generated files out of the graph, with their
+// imports and uses, see graph.WithSkipGenerated.
+func WithSkipGenerated() Option {
+	return func(o *options) {
+		o.skipGenerated = true
+	}
+}
+
+// WithDepgraph runs the depgraph binary at path. By default, Rank runs the
+// one that graph.LookDepgraph finds, or fails if there is none, unless
+// WithInstall is given.
+func WithDepgraph(path string) Option {
+	return func(o *options) {
+		o.depgraph = path
+	}
+}
+
+// WithInstall installs the depgraph binary with go install into the user
+// cache directory if graph.LookDepgraph finds none, which downloads and
+// builds pkgrank at the version that the running program was built with, or
+// else its latest version.
+func WithInstall() Option {
+	return func(o *options) {
+		o.install = true
+	}
+}
+
+// WithExecConfig runs go commands as c configures, e.g. to fetch modules
+// from a private proxy, instead of graph.DefaultExecConfig.
+func WithExecConfig(c graph.ExecConfig) Option {
+	return func(o *options) {
+		o.exec = &c
+	}
+}
+
+// WithLogger logs to l instead of the logger of the context, see
+// zerolog.Ctx.
+func WithLogger(l *zerolog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithProgress calls fn with the progress of the analysis, see
+// graph.WithProgress.
+func WithProgress(fn graph.ProgressFunc) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// Rank returns the dependency graph of target, a package with an optional
+// @version suffix, and its packages ranked by centrality. It stops and
+// returns an error once ctx is done.
+func Rank(ctx context.Context, target string, opts ...Option) (*Report, error) {
+	o := options{measure: graph.PageRankCentrality}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger != nil {
+		ctx = o.logger.WithContext(ctx)
+	}
+	if o.progress != nil {
+		ctx = graph.WithProgress(ctx, o.progress)
+	}
+	if o.skipGenerated {
+		ctx = graph.WithSkipGenerated(ctx)
+	}
+	config := graph.DefaultExecConfig
+	if o.exec != nil {
+		config = *o.exec
+		ctx = graph.WithExecConfig(ctx, config)
+	}
+
+	var g *graph.Graph
+	var err error
+	if o.local {
+		g, _, err = graph.ListModule(target)
+	} else {
+		bin := o.depgraph
+		if bin == "" {
+			if bin, err = findDepgraph(ctx, config, o.install); err != nil {
+				return nil, err
+			}
+		}
+		g, err = graph.TransitiveGraphContext(graph.WithDepgraph(ctx, bin), target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(o.exclude) > 0 {
+		g = g.Exclude(o.exclude...)
+	}
+	if o.kinds != 0 {
+		g = g.ExcludeKinds(o.kinds)
+	}
+
+	graph.ReportProgress(ctx, graph.Progress{Phase: graph.PhaseRank})
+	ranks, err := graph.Centrality(*g, o.measure, append(o.centrality, graph.WithTop(o.top))...)
+	if err != nil {
+		return nil, err
+	}
+	return &Report{Target: target, Graph: g, Ranks: g.RankedNodes(ranks)}, nil
+}
+
+// Paths of the module of pkgrank and of the main package of the depgraph
+// binary.
+const (
+	modulePath  = "github.com/arclabs561/pkgrank"
+	depgraphPkg = modulePath + "/cmd/depgraph"
+)
+
+// findDepgraph returns the path of the depgraph binary that
+// graph.LookDepgraph finds, or else, if install is set, of one installed
+// into the user cache directory by the go command of config, at the version
+// of pkgrank that the running program was built with, or else at its latest
+// version, resolved so that a later release is installed anew.
+func findDepgraph(ctx context.Context, config graph.ExecConfig, install bool) (string, error) {
+	path, err := graph.LookDepgraph()
+	if err == nil {
+		return path, nil
+	}
+	var version string
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = buildVersion(info)
+	}
+	if !install {
+		v := version
+		if v == "" {
+			v = "latest"
+		}
+		return "", fmt.Errorf("%w: install it with go install %s@%s, or rank with WithInstall or WithDepgraph", err, depgraphPkg, v)
+	}
+	if version == "" {
+		if version, err = latestVersion(ctx, config); err != nil {
+			return "", err
+		}
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("no depgraph in PATH, and no cache directory to install it in: %w", err)
+	}
+	dir := filepath.Join(cache, "pkgrank", "bin", version)
+	name := "depgraph"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	path = filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	zerolog.Ctx(ctx).Info().Str("version", version).Str("dir", dir).Msg("installing depgraph")
+	cmd := config.Command(ctx, "go", "install", depgraphPkg+"@"+version)
+	cmd.Env = append(cmd.Env, "GOBIN="+dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to install depgraph: %w: %s", err, out)
+	}
+	return path, nil
+}
+
+// buildVersion returns the version of pkgrank in info, as the main module,
+// as for its own commands, or as a dependency, or "" if it is unknown, as for
+// builds in a working tree.
+func buildVersion(info *debug.BuildInfo) string {
+	mods := append([]*debug.Module{&info.Main}, info.Deps...)
+	for _, m := range mods {
+		if m.Replace != nil {
+			m = m.Replace
+		}
+		if m.Path == modulePath && m.Version != "" && m.Version != "(devel)" {
+			return m.Version
+		}
+	}
+	return ""
+}
+
+// latestVersion returns the latest version of pkgrank, by the go command of
+// config.
+func latestVersion(ctx context.Context, config graph.ExecConfig) (string, error) {
+	cmd := config.Command(ctx, "go", "list", "-m", "-f", "{{.Version}}", modulePath+"@latest")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the latest version of pkgrank: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,89 @@
+package pkgrank
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/arclabs561/pkgrank/graph"
+)
+
+func TestRankLocal(t *testing.T) {
+	dir := filepath.Join("testdata", "local")
+	report, err := Rank(context.Background(), dir, WithLocal(), WithTop(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Target != dir || report.Graph.Container != "example.com/local" {
+		t.Errorf("got target %s of module %s", report.Target, report.Graph.Container)
+	}
+	if len(report.Ranks) != 1 || report.Ranks[0].Node.ID != "example.com/local/c" {
+		t.Errorf("got ranks %v, want example.com/local/c only", report.Ranks)
+	}
+
+	report, err = Rank(context.Background(), dir, WithLocal(), WithExclude("example.com/local/c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range report.Ranks {
+		if n.Node.ID == "example.com/local/c" {
+			t.Errorf("got excluded package in %v", report.Ranks)
+		}
+	}
+}
+
+func TestBuildVersion(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		info debug.BuildInfo
+		want string
+	}{
+		{
+			name: "main module",
+			info: debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "v1.2.3"}},
+			want: "v1.2.3",
+		},
+		{
+			name: "dependency",
+			info: debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/m", Version: "(devel)"},
+				Deps: []*debug.Module{{Path: modulePath, Version: "v1.2.3"}},
+			},
+			want: "v1.2.3",
+		},
+		{
+			name: "working tree",
+			info: debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "(devel)"}},
+		},
+		{
+			name: "replaced by a directory",
+			info: debug.BuildInfo{Deps: []*debug.Module{{
+				Path: modulePath, Version: "v1.2.3", Replace: &debug.Module{Path: "../pkgrank"},
+			}}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildVersion(&tt.info); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindDepgraphNoInstall(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("GOBIN", t.TempDir())
+	if _, err := graph.LookDepgraph(); err == nil {
+		t.Skip("depgraph is installed in GOPATH/bin")
+	}
+	// The go command is never run without WithInstall.
+	config := graph.ExecConfig{Go: filepath.Join(t.TempDir(), "go")}
+	_, err := findDepgraph(context.Background(), config, false)
+	if !errors.Is(err, exec.ErrNotFound) || !strings.Contains(err.Error(), "WithInstall") {
+		t.Errorf("got error %v, want depgraph not found", err)
+	}
+}
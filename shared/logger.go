@@ -1,6 +1,8 @@
 package shared
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -12,78 +14,168 @@ import (
 	"golang.org/x/term"
 )
 
-var logLevel zerolog.Level
-var logOutput io.Writer
+// Option configures the logger of NewLogger. Settings left empty fall back
+// to the LOG_LEVEL, LOG_FORMAT and LOG_OUTPUT environment variables.
+type Option func(*loggerConfig)
 
-func NewLogger() zerolog.Logger {
-	return log.Level(logLevel).
-		Output(logOutput).
-		With().
-		// Caller().
-		Logger()
+type loggerConfig struct {
+	level      string
+	format     string
+	outputFile string
+	output     io.Writer
 }
 
-func SetGlobalLogger() {
-	log.Logger = NewLogger()
-	zerolog.DefaultContextLogger = &log.Logger
+// WithLevel sets the minimum level of logged events, e.g. "info", or
+// "disabled" to log nothing, which is the default.
+func WithLevel(level string) Option {
+	return func(c *loggerConfig) {
+		if level != "" {
+			c.level = level
+		}
+	}
 }
 
-func init() {
-	initLogOutput()
-	initLogFormat()
-	initLogLevel()
+// WithFormat sets the format of logged events: "json", or "console" for
+// human-readable lines. By default, events are formatted for the console if
+// stdout is a terminal, and as JSON otherwise.
+func WithFormat(format string) Option {
+	return func(c *loggerConfig) {
+		if format != "" {
+			c.format = format
+		}
+	}
+}
 
-	SetGlobalLogger()
+// WithOutputFile appends logged events to the named file, creating it and
+// its directory if needed, instead of writing them to stderr.
+func WithOutputFile(name string) Option {
+	return func(c *loggerConfig) {
+		if name != "" {
+			c.outputFile = name
+			c.output = nil
+		}
+	}
 }
 
-func initLogOutput() {
-	logOutputRaw := os.Getenv("LOG_OUTPUT")
-	if logOutputRaw == "" {
-		logOutput = os.Stderr
-		return
+// WithOutput writes logged events to w instead of stderr.
+func WithOutput(w io.Writer) Option {
+	return func(c *loggerConfig) {
+		if w != nil {
+			c.output = w
+			c.outputFile = ""
+		}
 	}
+}
 
-	dir := filepath.Dir(logOutputRaw)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		panic(fmt.Sprintf("unable to create directory for log output %q: %v", dir, err))
+// NewLogger returns a logger configured by the options, or by the
+// environment variables for those left unset.
+func NewLogger(opts ...Option) (zerolog.Logger, error) {
+	c := loggerConfig{
+		level:      os.Getenv("LOG_LEVEL"),
+		format:     os.Getenv("LOG_FORMAT"),
+		outputFile: os.Getenv("LOG_OUTPUT"),
+	}
+	for _, opt := range opts {
+		opt(&c)
 	}
 
-	file, err := os.OpenFile(logOutputRaw, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if c.level == "" {
+		c.level = "disabled"
+	}
+	level, err := zerolog.ParseLevel(c.level)
 	if err != nil {
-		panic(fmt.Sprintf("unable to open log output file %q: %v", logOutputRaw, err))
+		return zerolog.Logger{}, fmt.Errorf("invalid log level %q: %w", c.level, err)
 	}
 
-	logOutput = file
-}
+	output := c.output
+	switch {
+	case output != nil:
+	case c.outputFile != "":
+		dir := filepath.Dir(c.outputFile)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return zerolog.Logger{}, fmt.Errorf("unable to create directory for log output %q: %w", dir, err)
+		}
+		file, err := os.OpenFile(c.outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return zerolog.Logger{}, fmt.Errorf("unable to open log output file %q: %w", c.outputFile, err)
+		}
+		output = file
+	default:
+		output = os.Stderr
+	}
 
-func initLogFormat() {
-	logFormatRaw := os.Getenv("LOG_FORMAT")
-	if logFormatRaw == "" {
+	if c.format == "" {
 		if term.IsTerminal(int(os.Stdout.Fd())) {
-			logFormatRaw = "console"
+			c.format = "console"
 		} else {
-			logFormatRaw = "json"
+			c.format = "json"
 		}
 	}
-
 	switch {
-	case strings.EqualFold(logFormatRaw, "console"):
-		logOutput = zerolog.ConsoleWriter{Out: logOutput}
-	case strings.EqualFold(logFormatRaw, "json"):
-		// LogOutput remains the same for JSON
+	case strings.EqualFold(c.format, "console"):
+		output = zerolog.ConsoleWriter{Out: output}
+	case strings.EqualFold(c.format, "json"):
+		// The output remains the same for JSON.
 	default:
-		panic(fmt.Sprintf("invalid log format %q", logFormatRaw))
+		return zerolog.Logger{}, fmt.Errorf("invalid log format %q", c.format)
 	}
+
+	return log.Level(level).
+		Output(output).
+		With().
+		// Caller().
+		Logger(), nil
 }
 
-func initLogLevel() {
-	logLevelRaw := os.Getenv("LOG_LEVEL")
-	if logLevelRaw == "" {
-		logLevelRaw = "disabled"
-	}
-	lvl, err := zerolog.ParseLevel(logLevelRaw)
+// SetGlobalLogger sets the global logger to NewLogger(opts...).
+func SetGlobalLogger(opts ...Option) error {
+	logger, err := NewLogger(opts...)
 	if err != nil {
-		panic(fmt.Sprintf("invalid log level %q: %v", logLevelRaw, err))
+		return err
+	}
+	log.Logger = logger
+	zerolog.DefaultContextLogger = &log.Logger
+	return nil
+}
+
+// Logger returns l, or if l is nil the default logger of the analyzers'
+// passes: zerolog.DefaultContextLogger, as set by SetGlobalLogger, or a
+// disabled logger if that is nil.
+func Logger(l *zerolog.Logger) *zerolog.Logger {
+	if l != nil {
+		return l
+	}
+	return zerolog.Ctx(context.Background())
+}
+
+// Flags are logging settings bound to command-line flags by Register.
+type Flags struct {
+	Level  string
+	Format string
+	Output string
+}
+
+// Register adds the -log-level, -log-format and -log-output flags to fs,
+// defaulting to the environment variables, or else to the values of f.
+// Cobra commands can add them with AddGoFlagSet.
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.Level, "log-level", envOr("LOG_LEVEL", f.Level),
+		"minimum level of logged events, e.g. debug, info or disabled.")
+	fs.StringVar(&f.Format, "log-format", envOr("LOG_FORMAT", f.Format),
+		"format of logged events, console or json, console on a terminal if empty.")
+	fs.StringVar(&f.Output, "log-output", envOr("LOG_OUTPUT", f.Output),
+		"file to append logged events to, stderr if empty.")
+}
+
+// Options returns the options of the flags.
+func (f *Flags) Options() []Option {
+	return []Option{WithLevel(f.Level), WithFormat(f.Format), WithOutputFile(f.Output)}
+}
+
+// envOr returns the environment variable key, or def if it is empty.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
-	logLevel = lvl
+	return def
 }